@@ -0,0 +1,37 @@
+package main
+
+import (
+	"github.com/go-kit/kit/log"
+	"github.com/xmidt-org/bascule/acquire"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+// FallbackAcquirerConfig configures the auth acquirer used when the JWT one fails
+type FallbackAcquirerConfig struct {
+	// Token is the fixed Authorization header value (i.e. "Basic xyz==") used when no JWT can be acquired
+	Token string
+}
+
+// fallbackAcquirer acquires auth header values from primary and, when that fails, from fallback
+type fallbackAcquirer struct {
+	primary  acquire.Acquirer
+	fallback acquire.Acquirer
+	logger   log.Logger
+}
+
+// Acquire implements acquire.Acquirer. The primary acquirer error is reported when both fail
+func (f *fallbackAcquirer) Acquire() (string, error) {
+	token, err := f.primary.Acquire()
+	if err == nil {
+		logging.Debug(f.logger).Log(logging.MessageKey(), "acquired auth token", "acquirer", "JWT")
+		return token, nil
+	}
+
+	token, fallbackErr := f.fallback.Acquire()
+	if fallbackErr != nil {
+		return "", err
+	}
+
+	logging.Debug(f.logger).Log(logging.MessageKey(), "acquired auth token", "acquirer", "fallback", logging.ErrorKey(), err)
+	return token, nil
+}
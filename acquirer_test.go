@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+type testAcquirer struct {
+	token string
+	err   error
+}
+
+func (t testAcquirer) Acquire() (string, error) {
+	return t.token, t.err
+}
+
+func TestFallbackAcquirer(t *testing.T) {
+	primaryErr := errors.New("issuer is down")
+
+	tests := []struct {
+		name          string
+		primary       testAcquirer
+		fallback      testAcquirer
+		expectedToken string
+		expectedErr   error
+	}{
+		{name: "Primary", primary: testAcquirer{token: "Bearer jwt"}, fallback: testAcquirer{token: "Basic xyz=="}, expectedToken: "Bearer jwt"},
+		{name: "Fallback", primary: testAcquirer{err: primaryErr}, fallback: testAcquirer{token: "Basic xyz=="}, expectedToken: "Basic xyz=="},
+		{name: "BothFail", primary: testAcquirer{err: primaryErr}, fallback: testAcquirer{err: errors.New("no token")}, expectedErr: primaryErr},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			f := &fallbackAcquirer{primary: test.primary, fallback: test.fallback, logger: logging.NewTestLogger(nil, t)}
+			token, err := f.Acquire()
+			assert.Equal(test.expectedToken, token)
+			assert.Equal(test.expectedErr, err)
+		})
+	}
+}
+
+func TestCreateAuthAcquirerFallback(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	require.Nil(v.ReadConfig(strings.NewReader(`
+authAcquirer:
+  JWT:
+    authURL: "http://localhost:6501/issue"
+    timeout: "1m"
+    buffer: "2m"
+  fallback:
+    token: "Basic xyz=="
+`)))
+
+	acquirer, err := createAuthAcquirer(v, logging.NewTestLogger(nil, t))
+	require.Nil(err)
+
+	f, ok := acquirer.(*fallbackAcquirer)
+	require.True(ok)
+
+	token, err := f.fallback.Acquire()
+	assert.Nil(err)
+	assert.Equal("Basic xyz==", token)
+}
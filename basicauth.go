@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/xmidt-org/bascule"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	errInvalidBasicAuth     = errors.New("invalid basic auth value")
+	errPrincipalNotFound    = errors.New("principal not found")
+	errInvalidBasicPassword = errors.New("invalid password")
+)
+
+// basicCredentials are the basic auth credentials tr1d1um accepts: the ones inlined in the authHeader config
+// merged with the ones of basicAuthFile, which win on conflicts. The file can be reloaded at runtime.
+// When hashed, all passwords must be bcrypt hashes. It implements basculehttp.TokenFactory.
+type basicCredentials struct {
+	inline  map[string]string
+	file    string
+	hashed  bool
+	current atomic.Value
+}
+
+// newBasicCredentials is the constructor for basicCredentials. file is optional
+func newBasicCredentials(inline map[string]string, file string, hashed bool) (*basicCredentials, error) {
+	b := &basicCredentials{inline: inline, file: file, hashed: hashed}
+	if err := b.load(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// load (re)reads the credentials file. The current credentials are kept if it fails
+func (b *basicCredentials) load() error {
+	credentials := make(map[string]string, len(b.inline))
+	for user, password := range b.inline {
+		credentials[user] = password
+	}
+
+	if b.file != "" {
+		fromFile, err := readBasicAuthFile(b.file)
+		if err != nil {
+			return err
+		}
+
+		for user, password := range fromFile {
+			credentials[user] = password
+		}
+	}
+
+	if b.hashed {
+		for user, password := range credentials {
+			if _, err := bcrypt.Cost([]byte(password)); err != nil {
+				return fmt.Errorf("password of user '%s' is not a bcrypt hash: %v", user, err)
+			}
+		}
+	}
+
+	b.current.Store(credentials)
+	return nil
+}
+
+// enabled reports whether basic auth may succeed at all
+func (b *basicCredentials) enabled() bool {
+	return b.file != "" || len(b.inline) > 0
+}
+
+// readBasicAuthFile parses a file of "user:password" lines where passwords may be bcrypt hashes.
+// Blank lines and lines starting with # are ignored.
+func readBasicAuthFile(file string) (map[string]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	credentials := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		i := strings.IndexByte(line, ':')
+		if i < 1 {
+			return nil, fmt.Errorf("%s:%d: expected user:password", file, n)
+		}
+
+		credentials[line[:i]] = line[i+1:]
+	}
+
+	return credentials, scanner.Err()
+}
+
+func isBcryptHash(password string) bool {
+	return strings.HasPrefix(password, "$2a$") || strings.HasPrefix(password, "$2b$") || strings.HasPrefix(password, "$2y$")
+}
+
+// ParseAndValidate implements basculehttp.TokenFactory
+func (b *basicCredentials) ParseAndValidate(_ context.Context, _ *http.Request, _ bascule.Authorization, value string) (bascule.Token, error) {
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errInvalidBasicAuth
+	}
+
+	i := bytes.IndexByte(decoded, ':')
+	if i <= 0 {
+		return nil, errInvalidBasicAuth
+	}
+
+	principal, password := string(decoded[:i]), decoded[i+1:]
+	expected, ok := b.current.Load().(map[string]string)[principal]
+	if !ok {
+		return nil, errPrincipalNotFound
+	}
+
+	if b.hashed || isBcryptHash(expected) {
+		if bcrypt.CompareHashAndPassword([]byte(expected), password) != nil {
+			return nil, errInvalidBasicPassword
+		}
+	} else if subtle.ConstantTimeCompare([]byte(expected), password) != 1 {
+		return nil, errInvalidBasicPassword
+	}
+
+	return bascule.NewToken("basic", principal, bascule.NewAttributesFromMap(map[string]interface{}{})), nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func basicValue(user, password string) string {
+	return base64.StdEncoding.EncodeToString([]byte(user + ":" + password))
+}
+
+func TestBasicCredentials(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "tr1d1um")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("hashed-pass"), bcrypt.MinCost)
+	require.Nil(err)
+
+	file := filepath.Join(dir, "basic")
+	require.Nil(ioutil.WriteFile(file, []byte("# partners\nfile-user:file:pass\n\nhashed-user:"+string(hash)+"\nuser:override\n"), 0600))
+
+	b, err := newBasicCredentials(map[string]string{"user": "pass", "inline": "inline-pass"}, file, false)
+	require.Nil(err)
+	assert.True(b.enabled())
+
+	tests := []struct {
+		user, password string
+		expectedErr    error
+	}{
+		{user: "inline", password: "inline-pass"},
+		{user: "file-user", password: "file:pass"},
+		{user: "hashed-user", password: "hashed-pass"},
+		{user: "hashed-user", password: "wrong", expectedErr: errInvalidBasicPassword},
+		{user: "user", password: "override"},
+		{user: "user", password: "pass", expectedErr: errInvalidBasicPassword},
+		{user: "nobody", password: "pass", expectedErr: errPrincipalNotFound},
+	}
+
+	for _, test := range tests {
+		token, err := b.ParseAndValidate(context.Background(), nil, "Basic", basicValue(test.user, test.password))
+		assert.Equal(test.expectedErr, err, test.user)
+		if test.expectedErr == nil && assert.NotNil(token) {
+			assert.Equal(test.user, token.Principal())
+		}
+	}
+
+	_, err = b.ParseAndValidate(context.Background(), nil, "Basic", "%%%")
+	assert.Equal(errInvalidBasicAuth, err)
+
+	// reloads pick up file changes while keeping the inline credentials
+	require.Nil(ioutil.WriteFile(file, []byte("new-user:new-pass\n"), 0600))
+	require.Nil(b.load())
+
+	_, err = b.ParseAndValidate(context.Background(), nil, "Basic", basicValue("new-user", "new-pass"))
+	assert.Nil(err)
+	_, err = b.ParseAndValidate(context.Background(), nil, "Basic", basicValue("file-user", "file:pass"))
+	assert.Equal(errPrincipalNotFound, err)
+	_, err = b.ParseAndValidate(context.Background(), nil, "Basic", basicValue("user", "pass"))
+	assert.Nil(err)
+
+	// broken files leave the current credentials alone
+	require.Nil(ioutil.WriteFile(file, []byte("no-separator\n"), 0600))
+	assert.NotNil(b.load())
+	_, err = b.ParseAndValidate(context.Background(), nil, "Basic", basicValue("new-user", "new-pass"))
+	assert.Nil(err)
+}
+
+func TestBasicCredentialsMissingFile(t *testing.T) {
+	_, err := newBasicCredentials(nil, "/does/not/exist", false)
+	assert.NotNil(t, err)
+
+	b, err := newBasicCredentials(nil, "", false)
+	assert.Nil(t, err)
+	assert.False(t, b.enabled())
+}
+
+func TestBasicCredentialsHashed(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "tr1d1um")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	inlineHash, err := bcrypt.GenerateFromPassword([]byte("inline-pass"), bcrypt.MinCost)
+	require.Nil(err)
+	fileHash, err := bcrypt.GenerateFromPassword([]byte("file-pass"), bcrypt.MinCost)
+	require.Nil(err)
+
+	file := filepath.Join(dir, "basic")
+	require.Nil(ioutil.WriteFile(file, []byte("file-user:"+string(fileHash)+"\n"), 0600))
+
+	b, err := newBasicCredentials(map[string]string{"inline": string(inlineHash)}, file, true)
+	require.Nil(err)
+
+	tests := []struct {
+		user, password string
+		expectedErr    error
+	}{
+		{user: "inline", password: "inline-pass"},
+		{user: "inline", password: "wrong", expectedErr: errInvalidBasicPassword},
+		{user: "inline", password: string(inlineHash), expectedErr: errInvalidBasicPassword},
+		{user: "file-user", password: "file-pass"},
+		{user: "file-user", password: "inline-pass", expectedErr: errInvalidBasicPassword},
+	}
+
+	for _, test := range tests {
+		_, err := b.ParseAndValidate(context.Background(), nil, "Basic", basicValue(test.user, test.password))
+		assert.Equal(test.expectedErr, err, test.user+":"+test.password)
+	}
+
+	// plaintext passwords are rejected rather than compared as is
+	_, err = newBasicCredentials(map[string]string{"inline": "inline-pass"}, "", true)
+	assert.NotNil(err)
+
+	require.Nil(ioutil.WriteFile(file, []byte("file-user:file-pass\n"), 0600))
+	assert.NotNil(b.load())
+	_, err = b.ParseAndValidate(context.Background(), nil, "Basic", basicValue("file-user", "file-pass"))
+	assert.Nil(err)
+}
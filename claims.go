@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/xmidt-org/bascule"
+)
+
+const (
+	claimMatchAny = "any"
+	claimMatchAll = "all"
+
+	// serviceClaimValue is replaced by the service of the request (i.e. "config" or "stat") when matching claim values
+	serviceClaimValue = "{service}"
+)
+
+var (
+	errNoAuth           = errors.New("no authentication found in context")
+	errClaimNotFound    = errors.New("required claim not found")
+	errClaimNotMatching = errors.New("claim does not have the required values")
+)
+
+// ClaimRule requires a JWT claim to hold some values
+type ClaimRule struct {
+	// Claim is the path to the claim, with nested claims separated by "." (i.e. "ext.subsystems")
+	Claim string
+
+	// Values are the values the claim must hold. The "{service}" value matches the service of the request
+	Values []string
+
+	// Match is either "any" (default) for at least one of Values to be present or "all" for all of them
+	Match string
+}
+
+type claimRules []ClaimRule
+
+// newClaimRules validates the given rules and returns a bascule validator enforcing all of them
+func newClaimRules(rules []ClaimRule) (bascule.Validator, error) {
+	for i, rule := range rules {
+		if rule.Claim == "" {
+			return nil, fmt.Errorf("rule %d: claim must not be empty", i)
+		}
+
+		if len(rule.Values) == 0 {
+			return nil, fmt.Errorf("rule %d: values must not be empty", i)
+		}
+
+		switch strings.ToLower(rule.Match) {
+		case "", claimMatchAny, claimMatchAll:
+		default:
+			return nil, fmt.Errorf("rule %d: unsupported match mode '%s'", i, rule.Match)
+		}
+	}
+
+	return claimRules(rules), nil
+}
+
+// Check fails if the token of the request doesn't satisfy every rule
+func (c claimRules) Check(ctx context.Context, token bascule.Token) error {
+	auth, ok := bascule.FromContext(ctx)
+	if !ok {
+		return errNoAuth
+	}
+
+	service := requestedService(auth)
+
+	for _, rule := range c {
+		claim, ok := nestedClaim(token.Attributes(), rule.Claim)
+		if !ok {
+			return fmt.Errorf("%v: %s", errClaimNotFound, rule.Claim)
+		}
+
+		held := claimValues(claim)
+		matches := 0
+		for _, value := range rule.Values {
+			if value == serviceClaimValue {
+				value = service
+			}
+
+			if value != "" && held[value] {
+				matches++
+			}
+		}
+
+		if matches == 0 || (strings.EqualFold(rule.Match, claimMatchAll) && matches < len(rule.Values)) {
+			return fmt.Errorf("%v: %s", errClaimNotMatching, rule.Claim)
+		}
+	}
+
+	return nil
+}
+
+// nestedClaim walks the "." separated path through the token attributes
+func nestedClaim(attributes bascule.Attributes, path string) (interface{}, bool) {
+	if attributes == nil {
+		return nil, false
+	}
+
+	keys := strings.Split(path, ".")
+	value, ok := attributes.Get(keys[0])
+	for _, key := range keys[1:] {
+		if !ok {
+			break
+		}
+
+		m, isMap := value.(map[string]interface{})
+		if !isMap {
+			return nil, false
+		}
+
+		value, ok = m[key]
+	}
+
+	return value, ok
+}
+
+// claimValues returns the set of string values a claim holds, be it a single string or a list of them
+func claimValues(claim interface{}) map[string]bool {
+	values := make(map[string]bool)
+
+	switch v := claim.(type) {
+	case string:
+		values[v] = true
+	case []string:
+		for _, s := range v {
+			values[s] = true
+		}
+	case []interface{}:
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				values[s] = true
+			}
+		}
+	}
+
+	return values
+}
+
+// requestedService returns the service segment of device request paths (i.e. "config" for device/{deviceid}/config)
+func requestedService(auth bascule.Authentication) string {
+	if auth.Request.URL == nil {
+		return ""
+	}
+
+	segments := strings.Split(strings.Trim(auth.Request.URL.Path, "/"), "/")
+	for i, segment := range segments {
+		if segment == "device" && i+2 < len(segments) {
+			return segments[i+2]
+		}
+	}
+
+	return ""
+}
+
+// exemptPaths skips check for requests to any of the given paths, relative to the API prefix
+func exemptPaths(check bascule.Validator, paths ...string) bascule.Validator {
+	return bascule.ValidatorFunc(func(ctx context.Context, token bascule.Token) error {
+		if auth, ok := bascule.FromContext(ctx); ok && auth.Request.URL != nil {
+			requested := strings.Trim(auth.Request.URL.Path, "/")
+			for _, path := range paths {
+				if requested == strings.Trim(path, "/") {
+					return nil
+				}
+			}
+		}
+
+		return check.Check(ctx, token)
+	})
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/bascule"
+)
+
+func TestNewClaimRules(t *testing.T) {
+	tests := []struct {
+		name        string
+		rules       []ClaimRule
+		expectedErr bool
+	}{
+		{
+			name:  "Valid",
+			rules: []ClaimRule{{Claim: "sub", Values: []string{"a"}}, {Claim: "ext.tiers", Values: []string{"a", "b"}, Match: "ALL"}},
+		},
+		{
+			name:        "EmptyClaim",
+			rules:       []ClaimRule{{Values: []string{"a"}}},
+			expectedErr: true,
+		},
+		{
+			name:        "EmptyValues",
+			rules:       []ClaimRule{{Claim: "sub"}},
+			expectedErr: true,
+		},
+		{
+			name:        "UnknownMatch",
+			rules:       []ClaimRule{{Claim: "sub", Values: []string{"a"}, Match: "most"}},
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := newClaimRules(test.rules)
+			assert.Equal(t, test.expectedErr, err != nil)
+		})
+	}
+}
+
+func TestClaimRulesCheck(t *testing.T) {
+	attrs := map[string]interface{}{
+		"sub":               "client0",
+		"allowedSubsystems": []interface{}{"config", "stat"},
+		"ext": map[string]interface{}{
+			"tiers": []interface{}{"gold", "silver"},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		rule        ClaimRule
+		path        string
+		expectedErr error
+	}{
+		{
+			name: "SingleValue",
+			rule: ClaimRule{Claim: "sub", Values: []string{"client1", "client0"}},
+		},
+		{
+			name: "Service",
+			rule: ClaimRule{Claim: "allowedSubsystems", Values: []string{serviceClaimValue}},
+			path: "device/mac:112233445566/config",
+		},
+		{
+			name:        "ServiceNotAllowed",
+			rule:        ClaimRule{Claim: "allowedSubsystems", Values: []string{serviceClaimValue}},
+			path:        "device/mac:112233445566/hooks",
+			expectedErr: errClaimNotMatching,
+		},
+		{
+			name:        "NoService",
+			rule:        ClaimRule{Claim: "allowedSubsystems", Values: []string{serviceClaimValue}},
+			path:        "hooks",
+			expectedErr: errClaimNotMatching,
+		},
+		{
+			name: "NestedAny",
+			rule: ClaimRule{Claim: "ext.tiers", Values: []string{"bronze", "silver"}},
+		},
+		{
+			name: "NestedAll",
+			rule: ClaimRule{Claim: "ext.tiers", Values: []string{"gold", "silver"}, Match: claimMatchAll},
+		},
+		{
+			name:        "NestedAllMissingOne",
+			rule:        ClaimRule{Claim: "ext.tiers", Values: []string{"gold", "bronze"}, Match: claimMatchAll},
+			expectedErr: errClaimNotMatching,
+		},
+		{
+			name:        "MissingClaim",
+			rule:        ClaimRule{Claim: "ext.regions", Values: []string{"us"}},
+			expectedErr: errClaimNotFound,
+		},
+		{
+			name:        "NotAMap",
+			rule:        ClaimRule{Claim: "sub.name", Values: []string{"client0"}},
+			expectedErr: errClaimNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			validator, err := newClaimRules([]ClaimRule{test.rule})
+			require.Nil(err)
+
+			token := bascule.NewToken("jwt", "client0", bascule.NewAttributesFromMap(attrs))
+			ctx := bascule.WithAuthentication(context.Background(), bascule.Authentication{
+				Token:   token,
+				Request: bascule.Request{URL: &url.URL{Path: test.path}},
+			})
+
+			err = validator.Check(ctx, token)
+			if test.expectedErr == nil {
+				assert.Nil(err)
+			} else if assert.NotNil(err) {
+				assert.Contains(err.Error(), test.expectedErr.Error())
+			}
+		})
+	}
+
+	t.Run("NoAuth", func(t *testing.T) {
+		validator, err := newClaimRules([]ClaimRule{{Claim: "sub", Values: []string{"client0"}}})
+		require.Nil(t, err)
+		assert.Equal(t, errNoAuth, validator.Check(context.Background(), bascule.NewToken("jwt", "client0", bascule.NewAttributesFromMap(attrs))))
+	})
+}
+
+func TestExemptPaths(t *testing.T) {
+	assert := assert.New(t)
+
+	errDenied := errors.New("denied")
+	check := exemptPaths(bascule.ValidatorFunc(func(context.Context, bascule.Token) error {
+		return errDenied
+	}), "/services")
+
+	token := bascule.NewToken("jwt", "client0", bascule.NewAttributesFromMap(map[string]interface{}{}))
+	withPath := func(path string) context.Context {
+		return bascule.WithAuthentication(context.Background(), bascule.Authentication{
+			Token:   token,
+			Request: bascule.Request{URL: &url.URL{Path: path}},
+		})
+	}
+
+	assert.Nil(check.Check(withPath("services"), token))
+	assert.Equal(errDenied, check.Check(withPath("device/mac:112233445566/config"), token))
+	assert.Equal(errDenied, check.Check(context.Background(), token))
+}
@@ -0,0 +1,147 @@
+package common
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/xmidt-org/bascule/acquire"
+)
+
+// Outcomes of the auth token cache lookups
+const (
+	TokenCacheHit  = "hit"
+	TokenCacheMiss = "miss"
+)
+
+// CachingAcquirerOptions are the configuration options for CachingAcquirer
+type CachingAcquirerOptions struct {
+	//Acquirer is the source of the tokens
+	Acquirer acquire.Acquirer
+
+	//Buffer is how long before their expiry JWTs are refreshed
+	//(Optional)
+	Buffer time.Duration
+
+	//TTL is how long tokens without an expiry (i.e. Basic auth values) are cached for
+	//(Optional) such tokens are cached indefinitely when it's not positive
+	TTL time.Duration
+
+	//Lookups counts cache lookups labeled by outcome (hit or miss)
+	//(Optional)
+	Lookups metrics.Counter
+
+	//RefreshFailures counts failures to acquire a new token
+	//(Optional)
+	RefreshFailures metrics.Counter
+}
+
+type cachedToken struct {
+	value string
+
+	//expires is when the token must be refreshed. The zero value means never
+	expires time.Time
+}
+
+// CachingAcquirer is an acquire.Acquirer which reuses the tokens of another one until shortly before
+// they expire. Concurrent requests for an expired token result in a single refresh.
+type CachingAcquirer struct {
+	o   CachingAcquirerOptions
+	now func() time.Time
+
+	lock    sync.RWMutex
+	current *cachedToken
+
+	// refreshLock makes concurrent callers wait for the refresh in progress rather than starting their own
+	refreshLock sync.Mutex
+}
+
+// NewCachingAcquirer is the constructor for CachingAcquirer
+func NewCachingAcquirer(o CachingAcquirerOptions) *CachingAcquirer {
+	if o.Lookups == nil {
+		o.Lookups = discard.NewCounter()
+	}
+
+	if o.RefreshFailures == nil {
+		o.RefreshFailures = discard.NewCounter()
+	}
+
+	return &CachingAcquirer{o: o, now: time.Now}
+}
+
+// Acquire implements acquire.Acquirer
+func (c *CachingAcquirer) Acquire() (string, error) {
+	if token, ok := c.cached(); ok {
+		c.o.Lookups.With(OutcomeLabel, TokenCacheHit).Add(1)
+		return token, nil
+	}
+
+	c.refreshLock.Lock()
+	defer c.refreshLock.Unlock()
+
+	// a concurrent caller may have refreshed the token while we waited
+	if token, ok := c.cached(); ok {
+		c.o.Lookups.With(OutcomeLabel, TokenCacheHit).Add(1)
+		return token, nil
+	}
+
+	c.o.Lookups.With(OutcomeLabel, TokenCacheMiss).Add(1)
+
+	token, err := c.o.Acquirer.Acquire()
+	if err != nil {
+		c.o.RefreshFailures.Add(1)
+		return "", err
+	}
+
+	c.lock.Lock()
+	c.current = &cachedToken{value: token, expires: c.expiry(token)}
+	c.lock.Unlock()
+
+	return token, nil
+}
+
+func (c *CachingAcquirer) cached() (string, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.current == nil || (!c.current.expires.IsZero() && !c.now().Before(c.current.expires)) {
+		return "", false
+	}
+
+	return c.current.value, true
+}
+
+// expiry returns when token must be refreshed: Buffer before the expiry of JWTs or TTL from now otherwise
+func (c *CachingAcquirer) expiry(token string) time.Time {
+	if exp, ok := jwtExpiry(token); ok {
+		return exp.Add(-c.o.Buffer)
+	}
+
+	if c.o.TTL > 0 {
+		return c.now().Add(c.o.TTL)
+	}
+
+	return time.Time{}
+}
+
+// jwtExpiry returns the exp claim of token, with or without its "Bearer " prefix, if it's a JWT
+func jwtExpiry(token string) (time.Time, bool) {
+	if i := strings.IndexByte(token, ' '); i >= 0 {
+		token = token[i+1:]
+	}
+
+	claims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(token, claims); err != nil {
+		return time.Time{}, false
+	}
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return time.Unix(int64(exp), 0), true
+}
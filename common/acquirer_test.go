@@ -0,0 +1,114 @@
+package common
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingAcquirer struct {
+	tokens []string
+	err    error
+	calls  int32
+	delay  time.Duration
+}
+
+func (c *countingAcquirer) Acquire() (string, error) {
+	n := atomic.AddInt32(&c.calls, 1)
+	time.Sleep(c.delay)
+	if c.err != nil {
+		return "", c.err
+	}
+	return c.tokens[int(n-1)%len(c.tokens)], nil
+}
+
+func signedJWT(t *testing.T, expires time.Time) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"exp": expires.Unix()}).SignedString([]byte("secret"))
+	require.Nil(t, err)
+	return "Bearer " + token
+}
+
+func TestCachingAcquirerJWT(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Now()
+	first, second := signedJWT(t, now.Add(10*time.Minute)), signedJWT(t, now.Add(20*time.Minute))
+
+	source := &countingAcquirer{tokens: []string{first, second}}
+	c := NewCachingAcquirer(CachingAcquirerOptions{Acquirer: source, Buffer: 2 * time.Minute})
+	c.now = func() time.Time { return now }
+
+	token, err := c.Acquire()
+	assert.Nil(err)
+	assert.Equal(first, token)
+
+	now = now.Add(7 * time.Minute)
+	token, _ = c.Acquire()
+	assert.Equal(first, token)
+	assert.EqualValues(1, source.calls)
+
+	// within the buffer
+	now = now.Add(time.Minute)
+	token, _ = c.Acquire()
+	assert.Equal(second, token)
+	assert.EqualValues(2, source.calls)
+}
+
+func TestCachingAcquirerTTL(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Now()
+	source := &countingAcquirer{tokens: []string{"Basic xyz=="}}
+	c := NewCachingAcquirer(CachingAcquirerOptions{Acquirer: source, TTL: time.Minute})
+	c.now = func() time.Time { return now }
+
+	c.Acquire()
+	c.Acquire()
+	assert.EqualValues(1, source.calls)
+
+	now = now.Add(time.Minute)
+	token, err := c.Acquire()
+	assert.Nil(err)
+	assert.Equal("Basic xyz==", token)
+	assert.EqualValues(2, source.calls)
+}
+
+func TestCachingAcquirerFailure(t *testing.T) {
+	assert := assert.New(t)
+
+	source := &countingAcquirer{err: errors.New("issuer is down")}
+	c := NewCachingAcquirer(CachingAcquirerOptions{Acquirer: source})
+
+	_, err := c.Acquire()
+	assert.Equal(source.err, err)
+
+	// failures aren't cached
+	_, err = c.Acquire()
+	assert.Equal(source.err, err)
+	assert.EqualValues(2, source.calls)
+}
+
+func TestCachingAcquirerSingleFlight(t *testing.T) {
+	source := &countingAcquirer{tokens: []string{"Basic xyz=="}, delay: 20 * time.Millisecond}
+	c := NewCachingAcquirer(CachingAcquirerOptions{Acquirer: source})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := c.Acquire()
+			assert.Nil(t, err)
+			assert.Equal(t, "Basic xyz==", token)
+		}()
+	}
+
+	wg.Wait()
+	assert.EqualValues(t, 1, atomic.LoadInt32(&source.calls))
+}
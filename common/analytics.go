@@ -0,0 +1,58 @@
+package common
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	kitlog "github.com/go-kit/kit/log"
+)
+
+// Supported analytics log formats
+const (
+	AnalyticsFormatJSON   = "json"
+	AnalyticsFormatLogfmt = "logfmt"
+)
+
+// AnalyticsConfig describes where and how analytics records of device transactions are written
+type AnalyticsConfig struct {
+	//Sink is where records are written: "stdout", "stderr" or the path of a file records are appended to.
+	//An empty value disables analytics
+	Sink string
+
+	//Format is the encoding of the records: "json" or "logfmt"
+	//(Optional) defaults to "json"
+	Format string
+}
+
+// NewAnalyticsLogger builds the logger for analytics records per the given configuration.
+// A nil logger is returned when analytics are disabled.
+func NewAnalyticsLogger(c AnalyticsConfig) (kitlog.Logger, error) {
+	var w io.Writer
+
+	switch c.Sink {
+	case "":
+		return nil, nil
+	case "stdout":
+		w = os.Stdout
+	case "stderr":
+		w = os.Stderr
+	default:
+		f, err := os.OpenFile(c.Sink, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, err
+		}
+		w = f
+	}
+
+	w = kitlog.NewSyncWriter(w)
+
+	switch c.Format {
+	case "", AnalyticsFormatJSON:
+		return kitlog.NewJSONLogger(w), nil
+	case AnalyticsFormatLogfmt:
+		return kitlog.NewLogfmtLogger(w), nil
+	default:
+		return nil, fmt.Errorf("unsupported analytics format '%s'", c.Format)
+	}
+}
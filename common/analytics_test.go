@@ -0,0 +1,56 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAnalyticsLogger(t *testing.T) {
+	t.Run("Disabled", func(t *testing.T) {
+		assert := assert.New(t)
+		logger, err := NewAnalyticsLogger(AnalyticsConfig{})
+		assert.Nil(err)
+		assert.Nil(logger)
+	})
+
+	t.Run("UnsupportedFormat", func(t *testing.T) {
+		assert := assert.New(t)
+		logger, err := NewAnalyticsLogger(AnalyticsConfig{Sink: "stdout", Format: "xml"})
+		assert.NotNil(err)
+		assert.Nil(logger)
+	})
+
+	t.Run("File", func(t *testing.T) {
+		tests := []struct {
+			format   string
+			expected string
+		}{
+			{format: "", expected: "{\"device\":\"mac:112233445566\",\"status\":200}\n"},
+			{format: AnalyticsFormatLogfmt, expected: "device=mac:112233445566 status=200\n"},
+		}
+
+		for _, test := range tests {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			dir, err := ioutil.TempDir("", "analytics")
+			require.Nil(err)
+			defer os.RemoveAll(dir)
+
+			sink := filepath.Join(dir, "analytics.log")
+			logger, err := NewAnalyticsLogger(AnalyticsConfig{Sink: sink, Format: test.format})
+			require.Nil(err)
+
+			logger.Log("device", "mac:112233445566", "status", 200)
+
+			actual, err := ioutil.ReadFile(sink)
+			require.Nil(err)
+			assert.Equal(test.expected, string(actual))
+		}
+	})
+}
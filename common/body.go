@@ -0,0 +1,73 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+)
+
+// Request body errors
+var (
+	// ErrRequestBodyTooLarge is returned while reading request bodies larger than the configured limit
+	ErrRequestBodyTooLarge = NewCodedError(errors.New("request body is too large"), http.StatusRequestEntityTooLarge)
+
+	// ErrContentLengthRequired is returned for write requests of unknown length (i.e. chunked) when their length is required
+	ErrContentLengthRequired = NewCodedError(errors.New("Content-Length header is required for write requests"), http.StatusLengthRequired)
+)
+
+// limitedBody reports ErrRequestBodyTooLarge once more than max bytes are read from an http.MaxBytesReader
+type limitedBody struct {
+	io.ReadCloser
+	max  int64
+	read int64
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	n, err := l.ReadCloser.Read(p)
+	l.read += int64(n)
+
+	if err != nil && err != io.EOF && l.read >= l.max {
+		err = ErrRequestBodyTooLarge
+	}
+
+	return n, err
+}
+
+// LimitRequestBody is an Alice-style constructor which caps the size of request bodies to max bytes.
+// Reading past the limit fails with ErrRequestBodyTooLarge. Values less than 1 disable the limit.
+func LimitRequestBody(max int64) func(http.Handler) http.Handler {
+	return func(delegate http.Handler) http.Handler {
+		if max < 1 {
+			return delegate
+		}
+
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				r.Body = &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, max), max: max}
+				delegate.ServeHTTP(w, r)
+			})
+	}
+}
+
+// RequireContentLength decorates decoder such that write requests (PATCH, PUT and POST) which don't declare
+// the length of their body (i.e. chunked requests) are rejected with a 411.
+// Unless required is set, decoder is returned as is.
+func RequireContentLength(required bool, decoder kithttp.DecodeRequestFunc) kithttp.DecodeRequestFunc {
+	if !required {
+		return decoder
+	}
+
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		switch r.Method {
+		case http.MethodPatch, http.MethodPut, http.MethodPost:
+			if r.ContentLength < 0 {
+				return nil, ErrContentLengthRequired
+			}
+		}
+
+		return decoder(ctx, r)
+	}
+}
@@ -0,0 +1,85 @@
+package common
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitRequestBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		max         int64
+		body        string
+		expectedErr error
+	}{
+		{name: "Disabled", body: "0123456789"},
+		{name: "UnderLimit", max: 20, body: "0123456789"},
+		{name: "AtLimit", max: 10, body: "0123456789"},
+		{name: "OverLimit", max: 5, body: "0123456789", expectedErr: ErrRequestBodyTooLarge},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var (
+				data []byte
+				err  error
+			)
+
+			handler := LimitRequestBody(test.max)(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					data, err = ioutil.ReadAll(r.Body)
+				}))
+
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "http://localhost", strings.NewReader(test.body)))
+
+			assert.Equal(test.expectedErr, err)
+			if test.expectedErr == nil {
+				assert.Equal(test.body, string(data))
+			}
+		})
+	}
+}
+
+func TestRequireContentLength(t *testing.T) {
+	tests := []struct {
+		name          string
+		required      bool
+		method        string
+		contentLength int64
+		expectedErr   error
+	}{
+		{name: "NotRequiredChunked", method: http.MethodPatch, contentLength: -1},
+		{name: "NotRequiredContentLength", method: http.MethodPatch, contentLength: 10},
+		{name: "RequiredContentLength", required: true, method: http.MethodPatch, contentLength: 10},
+		{name: "RequiredChunked", required: true, method: http.MethodPatch, contentLength: -1, expectedErr: ErrContentLengthRequired},
+		{name: "RequiredChunkedPost", required: true, method: http.MethodPost, contentLength: -1, expectedErr: ErrContentLengthRequired},
+		{name: "RequiredRead", required: true, method: http.MethodGet, contentLength: -1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var decoded bool
+			decoder := RequireContentLength(test.required, func(_ context.Context, _ *http.Request) (interface{}, error) {
+				decoded = true
+				return nil, nil
+			})
+
+			r := httptest.NewRequest(test.method, "http://localhost", strings.NewReader("0123456789"))
+			r.ContentLength = test.contentLength
+
+			_, err := decoder(context.Background(), r)
+			assert.Equal(test.expectedErr, err)
+			assert.Equal(test.expectedErr == nil, decoded)
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package common
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+// ErrCircuitOpen is the cause of the errors returned while the circuit breaker rejects requests to XMiDT
+var ErrCircuitOpen = errors.New("XMiDT API is unavailable. Requests are temporarily rejected")
+
+// Circuit breaker states
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// CircuitBreakerOptions are the configuration options for CircuitBreaker
+type CircuitBreakerOptions struct {
+	//FailureThreshold is the number of consecutive failed requests which opens the circuit.
+	//Values less than 1 disable the circuit breaker
+	FailureThreshold int
+
+	//Cooldown is how long the circuit stays open before probe requests are let through
+	Cooldown time.Duration
+
+	//HalfOpenProbes is the number of requests let through while half-open. The circuit closes
+	//once all of them succeed and opens again as soon as one fails
+	//(Optional) defaults to 1
+	HalfOpenProbes int
+
+	//Logger is used to report state transitions
+	//(Optional) defaults to the webpa-common default logger
+	Logger kitlog.Logger
+
+	//StateTransitions counts state transitions labeled by the new state
+	//(Optional)
+	StateTransitions metrics.Counter
+
+	//Now returns the current time
+	//(Optional) defaults to time.Now
+	Now func() time.Time
+}
+
+// CircuitBreaker stops requests from reaching XMiDT during sustained outages so that they
+// fail fast rather than piling up
+type CircuitBreaker struct {
+	o CircuitBreakerOptions
+
+	lock      sync.Mutex
+	state     string
+	failures  int
+	openedAt  time.Time
+	probes    int
+	successes int
+}
+
+// NewCircuitBreaker is the constructor for CircuitBreaker
+func NewCircuitBreaker(o CircuitBreakerOptions) *CircuitBreaker {
+	if o.HalfOpenProbes < 1 {
+		o.HalfOpenProbes = 1
+	}
+
+	if o.Logger == nil {
+		o.Logger = logging.DefaultLogger()
+	}
+
+	if o.StateTransitions == nil {
+		o.StateTransitions = discard.NewCounter()
+	}
+
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+
+	return &CircuitBreaker{o: o, state: CircuitClosed}
+}
+
+// State returns the current state of the circuit
+func (c *CircuitBreaker) State() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.state
+}
+
+// transition must be called while holding the lock
+func (c *CircuitBreaker) transition(state string) {
+	c.state = state
+	c.o.StateTransitions.With("state", state).Add(1)
+	logging.Info(c.o.Logger).Log(logging.MessageKey(), "circuit breaker state transition", "state", state)
+}
+
+// allow reports whether a request may go through. Otherwise, it returns how long
+// clients should wait before trying again
+func (c *CircuitBreaker) allow() (bool, time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	switch c.state {
+	case CircuitOpen:
+		if elapsed := c.o.Now().Sub(c.openedAt); elapsed < c.o.Cooldown {
+			return false, c.o.Cooldown - elapsed
+		}
+
+		c.probes, c.successes = 0, 0
+		c.transition(CircuitHalfOpen)
+		fallthrough
+
+	case CircuitHalfOpen:
+		if c.probes >= c.o.HalfOpenProbes {
+			return false, time.Second
+		}
+		c.probes++
+	}
+
+	return true, 0
+}
+
+func (c *CircuitBreaker) record(success bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	switch c.state {
+	case CircuitClosed:
+		if success {
+			c.failures = 0
+			return
+		}
+
+		if c.failures++; c.failures >= c.o.FailureThreshold {
+			c.openedAt = c.o.Now()
+			c.transition(CircuitOpen)
+		}
+
+	case CircuitHalfOpen:
+		if !success {
+			c.openedAt = c.o.Now()
+			c.transition(CircuitOpen)
+			return
+		}
+
+		if c.successes++; c.successes >= c.o.HalfOpenProbes {
+			c.failures = 0
+			c.transition(CircuitClosed)
+		}
+	}
+}
+
+// release gives back the half-open probe taken by a request whose outcome says nothing about XMiDT
+func (c *CircuitBreaker) release() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.state == CircuitHalfOpen && c.probes > 0 {
+		c.probes--
+	}
+}
+
+// failed reports whether a transaction failed because of XMiDT itself, that is a transport error
+// or a 5xx response. Errors tr1d1um reports with a lower status code are not XMiDT failures.
+func failed(resp *http.Response, err error) bool {
+	if err != nil {
+		var coded CodedError
+		if errors.As(err, &coded) {
+			return coded.StatusCode() >= http.StatusInternalServerError
+		}
+		return true
+	}
+
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+// Do decorates next such that requests are rejected with a 503 while the circuit is open.
+// Only transport errors and 5xx responses count as failures. Requests whose context is done, because
+// the client hung up or its own timeout expired, don't count at all.
+func (c *CircuitBreaker) Do(next func(*http.Request) (*http.Response, error)) func(*http.Request) (*http.Response, error) {
+	if c.o.FailureThreshold < 1 {
+		return next
+	}
+
+	return func(r *http.Request) (*http.Response, error) {
+		ok, retryAfter := c.allow()
+		if !ok {
+			return nil, NewCodedErrorWithHeaders(ErrCircuitOpen, http.StatusServiceUnavailable, http.Header{
+				"Retry-After": []string{strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))},
+			})
+		}
+
+		resp, err := next(r)
+		if r.Context().Err() != nil {
+			c.release()
+		} else {
+			c.record(!failed(resp, err))
+		}
+		return resp, err
+	}
+}
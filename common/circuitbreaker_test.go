@@ -0,0 +1,202 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Run("Disabled", func(t *testing.T) {
+		assert := assert.New(t)
+		c := NewCircuitBreaker(CircuitBreakerOptions{})
+
+		calls := 0
+		do := c.Do(func(*http.Request) (*http.Response, error) {
+			calls++
+			return nil, errors.New("connection refused")
+		})
+
+		for i := 0; i < 5; i++ {
+			do(httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+		}
+
+		assert.Equal(5, calls)
+		assert.Equal(CircuitClosed, c.State())
+	})
+
+	t.Run("Transitions", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		var (
+			now         = time.Now()
+			fail        = true
+			calls       int
+			transitions = new(capturingCounter)
+		)
+
+		c := NewCircuitBreaker(CircuitBreakerOptions{
+			FailureThreshold: 3,
+			Cooldown:         10 * time.Second,
+			HalfOpenProbes:   2,
+			StateTransitions: transitions,
+			Now:              func() time.Time { return now },
+		})
+
+		do := c.Do(func(*http.Request) (*http.Response, error) {
+			calls++
+			if fail {
+				return nil, errors.New("connection refused")
+			}
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		})
+
+		request := func() error {
+			_, err := do(httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+			return err
+		}
+
+		// a success resets the consecutive failures
+		request()
+		request()
+		fail = false
+		request()
+		fail = true
+		assert.Equal(CircuitClosed, c.State())
+
+		request()
+		request()
+		request()
+		assert.Equal(CircuitOpen, c.State())
+		assert.Equal(6, calls)
+
+		// requests fail fast while open
+		now = now.Add(4 * time.Second)
+		err := request()
+		require.NotNil(err)
+		assert.Equal(6, calls)
+		assert.Equal(http.StatusServiceUnavailable, err.(CodedError).StatusCode())
+		assert.Equal("6", err.(interface{ Headers() http.Header }).Headers().Get("Retry-After"))
+
+		// a failed probe opens the circuit again
+		now = now.Add(6 * time.Second)
+		request()
+		assert.Equal(7, calls)
+		assert.Equal(CircuitOpen, c.State())
+
+		// circuit closes after all probes succeed
+		now = now.Add(10 * time.Second)
+		fail = false
+		assert.Nil(request())
+		assert.Equal(CircuitHalfOpen, c.State())
+		assert.Nil(request())
+		assert.Equal(CircuitClosed, c.State())
+		assert.Equal(9, calls)
+
+		assert.EqualValues(5, transitions.value)
+		assert.Equal([]string{
+			"state", CircuitOpen,
+			"state", CircuitHalfOpen,
+			"state", CircuitOpen,
+			"state", CircuitHalfOpen,
+			"state", CircuitClosed,
+		}, transitions.labelValues)
+	})
+
+	t.Run("Outcomes", func(t *testing.T) {
+		assert := assert.New(t)
+
+		c := NewCircuitBreaker(CircuitBreakerOptions{FailureThreshold: 1, Cooldown: time.Minute})
+
+		var (
+			resp *http.Response
+			err  error
+		)
+
+		do := c.Do(func(*http.Request) (*http.Response, error) {
+			return resp, err
+		})
+
+		request := func() {
+			do(httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+		}
+
+		// errors and responses which don't come from an XMiDT failure
+		resp, err = &http.Response{StatusCode: http.StatusNotFound}, nil
+		request()
+		resp, err = nil, NewBadRequestError(errors.New("invalid request"))
+		request()
+		assert.Equal(CircuitClosed, c.State())
+
+		resp, err = &http.Response{StatusCode: http.StatusServiceUnavailable}, nil
+		request()
+		assert.Equal(CircuitOpen, c.State())
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		assert := assert.New(t)
+		now := time.Now()
+
+		c := NewCircuitBreaker(CircuitBreakerOptions{
+			FailureThreshold: 2,
+			Cooldown:         time.Second,
+			Now:              func() time.Time { return now },
+		})
+
+		do := c.Do(func(r *http.Request) (*http.Response, error) {
+			return nil, r.Context().Err()
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		for i := 0; i < 5; i++ {
+			do(httptest.NewRequest(http.MethodGet, "http://localhost", nil).WithContext(ctx))
+		}
+
+		// requests abandoned by their clients say nothing about XMiDT
+		assert.Equal(CircuitClosed, c.State())
+
+		// nor do they use up the half-open probes
+		c.record(false)
+		c.record(false)
+		assert.Equal(CircuitOpen, c.State())
+
+		now = now.Add(time.Second)
+		do(httptest.NewRequest(http.MethodGet, "http://localhost", nil).WithContext(ctx))
+		assert.Equal(CircuitHalfOpen, c.State())
+
+		ok, _ := c.allow()
+		assert.True(ok)
+	})
+
+	t.Run("HalfOpenProbeLimit", func(t *testing.T) {
+		assert := assert.New(t)
+		now := time.Now()
+
+		c := NewCircuitBreaker(CircuitBreakerOptions{
+			FailureThreshold: 1,
+			Cooldown:         time.Second,
+			Now:              func() time.Time { return now },
+		})
+
+		c.record(false)
+		assert.Equal(CircuitOpen, c.State())
+
+		now = now.Add(time.Second)
+		ok, _ := c.allow()
+		assert.True(ok)
+
+		// the probe is still in flight
+		ok, retryAfter := c.allow()
+		assert.False(ok)
+		assert.Equal(time.Second, retryAfter)
+	})
+}
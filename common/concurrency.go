@@ -0,0 +1,160 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+)
+
+// ErrTooManyOutboundRequests is returned when the max number of concurrent requests to XMiDT is reached
+var ErrTooManyOutboundRequests = NewCodedErrorWithErrorCode(errors.New("too many concurrent requests to XMiDT. Please try again"),
+	http.StatusServiceUnavailable, ErrorCodeOverloaded)
+
+// OverLimitBehavior is what happens to requests to XMiDT once the concurrency limit is reached
+type OverLimitBehavior string
+
+// Supported over limit behaviors
+const (
+	// OverLimitReject fails requests right away
+	OverLimitReject OverLimitBehavior = "reject"
+
+	// OverLimitQueue has requests wait for a slot, up to the queue timeout
+	OverLimitQueue OverLimitBehavior = "queue"
+)
+
+// ParseOverLimitBehavior converts the given configuration value into an OverLimitBehavior.
+// An empty value defaults to OverLimitReject
+func ParseOverLimitBehavior(s string) (OverLimitBehavior, error) {
+	switch OverLimitBehavior(s) {
+	case "", OverLimitReject:
+		return OverLimitReject, nil
+	case OverLimitQueue:
+		return OverLimitQueue, nil
+	default:
+		return "", fmt.Errorf("unsupported over limit behavior '%s'", s)
+	}
+}
+
+// ConcurrencyLimiterOptions are the configuration options for ConcurrencyLimiter
+type ConcurrencyLimiterOptions struct {
+	//MaxConcurrentRequests is the max number of requests to XMiDT in flight at once
+	//A non-positive value disables the limit
+	MaxConcurrentRequests int
+
+	//OverLimitBehavior is what happens to requests once the limit is reached
+	//(Optional) defaults to OverLimitReject
+	OverLimitBehavior OverLimitBehavior
+
+	//QueueTimeout is the max time requests wait for a slot when queueing. Requests never wait past their deadline
+	//(Optional) a non-positive value limits the wait by the request deadline only
+	QueueTimeout time.Duration
+
+	//QueueLength is the max number of requests waiting for a slot when queueing. Requests over it are rejected
+	//(Optional) a non-positive value doesn't bound the queue
+	QueueLength int
+
+	//InFlight reports the number of requests to XMiDT currently in flight
+	//(Optional)
+	InFlight metrics.Gauge
+
+	//QueueDepth reports the number of requests currently waiting for a slot
+	//(Optional)
+	QueueDepth metrics.Gauge
+}
+
+// ConcurrencyLimiter caps the number of requests to XMiDT in flight so that traffic spikes don't open
+// an unbounded number of connections
+type ConcurrencyLimiter struct {
+	slots        chan struct{}
+	behavior     OverLimitBehavior
+	queueTimeout time.Duration
+	queueLength  int64
+	queued       int64
+	inFlight     metrics.Gauge
+	queueDepth   metrics.Gauge
+}
+
+// NewConcurrencyLimiter is the constructor for ConcurrencyLimiter. It returns nil, which disables the limit,
+// when MaxConcurrentRequests isn't positive
+func NewConcurrencyLimiter(o ConcurrencyLimiterOptions) *ConcurrencyLimiter {
+	if o.MaxConcurrentRequests <= 0 {
+		return nil
+	}
+
+	if o.OverLimitBehavior == "" {
+		o.OverLimitBehavior = OverLimitReject
+	}
+
+	if o.InFlight == nil {
+		o.InFlight = discard.NewGauge()
+	}
+
+	if o.QueueDepth == nil {
+		o.QueueDepth = discard.NewGauge()
+	}
+
+	return &ConcurrencyLimiter{
+		slots:        make(chan struct{}, o.MaxConcurrentRequests),
+		behavior:     o.OverLimitBehavior,
+		queueTimeout: o.QueueTimeout,
+		queueLength:  int64(o.QueueLength),
+		inFlight:     o.InFlight,
+		queueDepth:   o.QueueDepth,
+	}
+}
+
+// acquire reserves a slot for a request to XMiDT. The returned function must be called to release it
+// once the request completes. It's a no-op for nil limiters
+func (c *ConcurrencyLimiter) acquire(ctx context.Context) (func(), error) {
+	if c == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case c.slots <- struct{}{}:
+		return c.reserved(), nil
+	default:
+	}
+
+	if c.behavior != OverLimitQueue {
+		return nil, ErrTooManyOutboundRequests
+	}
+
+	if queued := atomic.AddInt64(&c.queued, 1); c.queueLength > 0 && queued > c.queueLength {
+		atomic.AddInt64(&c.queued, -1)
+		return nil, ErrTooManyOutboundRequests
+	}
+
+	c.queueDepth.Add(1)
+	defer func() {
+		c.queueDepth.Add(-1)
+		atomic.AddInt64(&c.queued, -1)
+	}()
+
+	if c.queueTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.queueTimeout)
+		defer cancel()
+	}
+
+	select {
+	case c.slots <- struct{}{}:
+		return c.reserved(), nil
+	case <-ctx.Done():
+		return nil, ErrTooManyOutboundRequests
+	}
+}
+
+func (c *ConcurrencyLimiter) reserved() func() {
+	c.inFlight.Add(1)
+	return func() {
+		c.inFlight.Add(-1)
+		<-c.slots
+	}
+}
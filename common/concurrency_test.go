@@ -0,0 +1,147 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOverLimitBehavior(t *testing.T) {
+	assert := assert.New(t)
+
+	b, err := ParseOverLimitBehavior("")
+	assert.Nil(err)
+	assert.Equal(OverLimitReject, b)
+
+	b, err = ParseOverLimitBehavior("queue")
+	assert.Nil(err)
+	assert.Equal(OverLimitQueue, b)
+
+	_, err = ParseOverLimitBehavior("drop")
+	assert.NotNil(err)
+}
+
+func TestConcurrencyLimiter(t *testing.T) {
+	t.Run("Disabled", func(t *testing.T) {
+		assert := assert.New(t)
+
+		c := NewConcurrencyLimiter(ConcurrencyLimiterOptions{})
+		assert.Nil(c)
+
+		release, err := c.acquire(context.Background())
+		assert.Nil(err)
+		release()
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		inFlight := generic.NewGauge("inFlight")
+		c := NewConcurrencyLimiter(ConcurrencyLimiterOptions{MaxConcurrentRequests: 1, InFlight: inFlight})
+
+		release, err := c.acquire(context.Background())
+		require.Nil(err)
+		assert.Equal(1.0, inFlight.Value())
+
+		_, err = c.acquire(context.Background())
+		assert.Equal(ErrTooManyOutboundRequests, err)
+
+		release()
+		assert.Equal(0.0, inFlight.Value())
+
+		release, err = c.acquire(context.Background())
+		assert.Nil(err)
+		release()
+	})
+
+	t.Run("Queue", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		c := NewConcurrencyLimiter(ConcurrencyLimiterOptions{MaxConcurrentRequests: 1, OverLimitBehavior: OverLimitQueue, QueueTimeout: time.Minute})
+
+		release, err := c.acquire(context.Background())
+		require.Nil(err)
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			release()
+		}()
+
+		release, err = c.acquire(context.Background())
+		require.Nil(err)
+		release()
+
+		release, err = c.acquire(context.Background())
+		require.Nil(err)
+		defer release()
+
+		c.queueTimeout = 10 * time.Millisecond
+		_, err = c.acquire(context.Background())
+		assert.Equal(ErrTooManyOutboundRequests, err)
+	})
+
+	t.Run("QueueFull", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		queueDepth := generic.NewGauge("queueDepth")
+		c := NewConcurrencyLimiter(ConcurrencyLimiterOptions{MaxConcurrentRequests: 1, OverLimitBehavior: OverLimitQueue,
+			QueueTimeout: time.Minute, QueueLength: 1, QueueDepth: queueDepth})
+
+		release, err := c.acquire(context.Background())
+		require.Nil(err)
+
+		queued := make(chan error, 1)
+		go func() {
+			r, err := c.acquire(context.Background())
+			if err == nil {
+				r()
+			}
+			queued <- err
+		}()
+
+		require.True(waitFor(time.Second, func() bool { return queueDepth.Value() == 1 }))
+
+		_, err = c.acquire(context.Background())
+		assert.Equal(ErrTooManyOutboundRequests, err)
+
+		release()
+		assert.Nil(<-queued)
+		assert.Equal(0.0, queueDepth.Value())
+	})
+}
+
+func TestTransactConcurrencyLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewConcurrencyLimiter(ConcurrencyLimiterOptions{MaxConcurrentRequests: 1})
+	transactor := NewTr1d1umTransactor(&Tr1d1umTransactorOptions{
+		RequestTimeout: time.Minute,
+		Do: func(_ *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(bytes.NewBufferString("ok"))}, nil
+		},
+		ConcurrencyLimiter: c,
+	})
+
+	release, err := c.acquire(context.Background())
+	assert.Nil(err)
+
+	_, err = transactor.Transact(httptest.NewRequest(http.MethodGet, "localhost:6003/test", nil))
+	assert.Equal(ErrTooManyOutboundRequests, err)
+
+	release()
+
+	resp, err := transactor.Transact(httptest.NewRequest(http.MethodGet, "localhost:6003/test", nil))
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, resp.Code)
+}
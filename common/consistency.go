@@ -0,0 +1,134 @@
+package common
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/xmidt-org/webpa-common/device"
+)
+
+// Modes of handling reads issued while a write to the same device is in flight
+const (
+	// ReadDuringWriteWait makes reads wait for in-flight writes to the device to complete
+	ReadDuringWriteWait = "wait"
+
+	// ReadDuringWriteFlag lets reads through but flags their responses through HeaderTr1d1umWriteInProgress
+	ReadDuringWriteFlag = "flag"
+)
+
+// HeaderTr1d1umWriteInProgress is set on responses to reads which ran while a write to the same device was in flight,
+// meaning they may report inconsistent state
+const HeaderTr1d1umWriteInProgress = "X-Tr1d1um-Write-In-Progress"
+
+// deviceWrites tracks the in-flight writes to a device. done is closed once all of them complete
+type deviceWrites struct {
+	count int
+	done  chan struct{}
+}
+
+// ReadDuringWrite tracks in-flight writes per device so that reads to the same device can be
+// handled consistently. A nil *ReadDuringWrite disables the tracking.
+type ReadDuringWrite struct {
+	mode string
+
+	lock   sync.Mutex
+	writes map[string]*deviceWrites
+}
+
+// NewReadDuringWrite is the constructor for ReadDuringWrite. An empty mode disables the tracking,
+// in which case nil is returned
+func NewReadDuringWrite(mode string) (*ReadDuringWrite, error) {
+	switch mode {
+	case "":
+		return nil, nil
+	case ReadDuringWriteWait, ReadDuringWriteFlag:
+		return &ReadDuringWrite{mode: mode, writes: make(map[string]*deviceWrites)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported read during write mode '%s'", mode)
+	}
+}
+
+// beginWrite registers a write to the given device. The returned function must be called once it completes
+func (c *ReadDuringWrite) beginWrite(id string) func() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	w, ok := c.writes[id]
+	if !ok {
+		w = &deviceWrites{done: make(chan struct{})}
+		c.writes[id] = w
+	}
+	w.count++
+
+	return func() {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+
+		if w.count--; w.count == 0 {
+			close(w.done)
+			delete(c.writes, id)
+		}
+	}
+}
+
+// pendingWrites returns a channel closed once the in-flight writes to the given device complete
+// or nil if there are none
+func (c *ReadDuringWrite) pendingWrites(id string) <-chan struct{} {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if w, ok := c.writes[id]; ok {
+		return w.done
+	}
+	return nil
+}
+
+func isWrite(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodPatch, http.MethodPut, http.MethodDelete:
+		return true
+	case http.MethodPost:
+		// POSTs to a service rather than to one of its tables are batch GETs
+		return mux.Vars(r)["parameter"] != ""
+	}
+	return false
+}
+
+// Then decorates delegate such that writes to devices (identified by the "deviceid" path variable) are tracked
+// and reads issued while a write to the same device is in flight are handled per the configured mode
+func (c *ReadDuringWrite) Then(delegate http.Handler) http.Handler {
+	if c == nil {
+		return delegate
+	}
+
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			id, err := device.ParseID(mux.Vars(r)["deviceid"])
+			if err != nil {
+				// let the handler reject the request
+				delegate.ServeHTTP(w, r)
+				return
+			}
+
+			if isWrite(r) {
+				defer c.beginWrite(string(id))()
+				delegate.ServeHTTP(w, r)
+				return
+			}
+
+			if pending := c.pendingWrites(string(id)); pending != nil {
+				if c.mode == ReadDuringWriteFlag {
+					w.Header().Set(HeaderTr1d1umWriteInProgress, "true")
+				} else {
+					select {
+					case <-pending:
+					case <-r.Context().Done():
+					}
+				}
+			}
+
+			delegate.ServeHTTP(w, r)
+		})
+}
@@ -0,0 +1,116 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewReadDuringWrite(t *testing.T) {
+	assert := assert.New(t)
+
+	c, err := NewReadDuringWrite("")
+	assert.Nil(c)
+	assert.Nil(err)
+
+	c, err = NewReadDuringWrite(ReadDuringWriteWait)
+	assert.NotNil(c)
+	assert.Nil(err)
+
+	_, err = NewReadDuringWrite("sometimes")
+	assert.NotNil(err)
+}
+
+// newDeviceRequest builds a request to the given device as routed by mux
+func newDeviceRequest(method, deviceID string) *http.Request {
+	r := httptest.NewRequest(method, "http://localhost/api/v2/device/"+deviceID+"/config", nil)
+	return mux.SetURLVars(r, map[string]string{"deviceid": deviceID, "service": "config"})
+}
+
+func TestReadDuringWrite(t *testing.T) {
+	t.Run("Disabled", func(t *testing.T) {
+		var c *ReadDuringWrite
+		delegate := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+		assert.NotNil(t, c.Then(delegate))
+	})
+
+	tests := []struct {
+		name            string
+		mode            string
+		readDevice      string
+		expectedWait    bool
+		expectedFlagged bool
+	}{
+		{name: "Wait", mode: ReadDuringWriteWait, readDevice: "mac:112233445566", expectedWait: true},
+		{name: "WaitOtherDevice", mode: ReadDuringWriteWait, readDevice: "mac:665544332211"},
+		{name: "WaitCanonicalID", mode: ReadDuringWriteWait, readDevice: "mac:11-22-33-44-55-66", expectedWait: true},
+		{name: "Flag", mode: ReadDuringWriteFlag, readDevice: "mac:112233445566", expectedFlagged: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			c, err := NewReadDuringWrite(test.mode)
+			require.Nil(err)
+
+			var (
+				writeStarted = make(chan struct{})
+				releaseWrite = make(chan struct{})
+				lock         sync.Mutex
+				events       []string
+				wg           sync.WaitGroup
+			)
+
+			record := func(event string) {
+				lock.Lock()
+				defer lock.Unlock()
+				events = append(events, event)
+			}
+
+			handler := c.Then(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPatch {
+					close(writeStarted)
+					<-releaseWrite
+				}
+				record(r.Method)
+			}))
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				handler.ServeHTTP(httptest.NewRecorder(), newDeviceRequest(http.MethodPatch, "mac:112233445566"))
+			}()
+
+			<-writeStarted
+
+			go func() {
+				time.Sleep(100 * time.Millisecond)
+				close(releaseWrite)
+			}()
+
+			read := httptest.NewRecorder()
+			handler.ServeHTTP(read, newDeviceRequest(http.MethodGet, test.readDevice))
+			wg.Wait()
+
+			if test.expectedWait {
+				assert.Equal([]string{http.MethodPatch, http.MethodGet}, events)
+			} else {
+				assert.Equal([]string{http.MethodGet, http.MethodPatch}, events)
+			}
+
+			if test.expectedFlagged {
+				assert.Equal("true", read.Header().Get(HeaderTr1d1umWriteInProgress))
+			} else {
+				assert.Empty(read.Header().Get(HeaderTr1d1umWriteInProgress))
+			}
+		})
+	}
+}
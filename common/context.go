@@ -7,4 +7,9 @@ const (
 	ContextKeyRequestArrivalTime contextKey = iota
 	ContextKeyRequestTID
 	ContextKeyTransactionInfoLogger
+	ContextKeyRequestTimeout
+	ContextKeyRetryCount
+	ContextKeyTransactionID
+	ContextKeyOperationLogger
+	ContextKeyIdempotent
 )
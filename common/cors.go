@@ -0,0 +1,150 @@
+package common
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORS request and response headers
+const (
+	headerOrigin                     = "Origin"
+	headerAccessControlRequestMethod = "Access-Control-Request-Method"
+	headerAllowOrigin                = "Access-Control-Allow-Origin"
+	headerAllowMethods               = "Access-Control-Allow-Methods"
+	headerAllowHeaders               = "Access-Control-Allow-Headers"
+	headerExposeHeaders              = "Access-Control-Expose-Headers"
+	headerMaxAge                     = "Access-Control-Max-Age"
+	headerVary                       = "Vary"
+)
+
+// default CORS settings for the methods and headers the API uses
+var (
+	defaultCORSMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	defaultCORSHeaders = []string{"Authorization", "Content-Type", HeaderWPATID}
+)
+
+// CORSConfig configures the cross-origin requests browsers are allowed to make
+type CORSConfig struct {
+	//AllowedOrigins are the origins (i.e. https://ui.example.com) allowed to call the API. "*" allows any origin.
+	//CORS is disabled when empty
+	AllowedOrigins []string
+
+	//AllowedMethods (Optional) defaults to GET, POST, PUT, PATCH and DELETE
+	AllowedMethods []string
+
+	//AllowedHeaders (Optional) are the request headers browsers may send. Defaults to Authorization, Content-Type
+	//and X-WebPA-Transaction-Id
+	AllowedHeaders []string
+
+	//ExposedHeaders (Optional) are the response headers, besides the CORS-safelisted ones, scripts may read
+	ExposedHeaders []string
+
+	//MaxAge (Optional) is how long browsers may cache preflight responses
+	MaxAge time.Duration
+}
+
+// CORS answers preflight requests and adds the CORS headers to the responses of the API
+type CORS struct {
+	pathPrefix     string
+	anyOrigin      bool
+	origins        map[string]bool
+	allowedMethods string
+	allowedHeaders string
+	exposedHeaders string
+	maxAge         string
+}
+
+// NewCORS is the constructor for CORS, which applies to the requests whose path starts with pathPrefix.
+// It returns nil, which disables CORS, when no origins are allowed
+func NewCORS(config CORSConfig, pathPrefix string) *CORS {
+	if len(config.AllowedOrigins) == 0 {
+		return nil
+	}
+
+	c := &CORS{
+		pathPrefix: pathPrefix,
+		origins:    make(map[string]bool, len(config.AllowedOrigins)),
+	}
+
+	for _, origin := range config.AllowedOrigins {
+		if origin == "*" {
+			c.anyOrigin = true
+		}
+		c.origins[strings.ToLower(origin)] = true
+	}
+
+	methods, headers := config.AllowedMethods, config.AllowedHeaders
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	if len(headers) == 0 {
+		headers = defaultCORSHeaders
+	}
+
+	c.allowedMethods = strings.ToUpper(strings.Join(methods, ", "))
+	c.allowedHeaders = strings.Join(headers, ", ")
+	c.exposedHeaders = strings.Join(config.ExposedHeaders, ", ")
+
+	if config.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(config.MaxAge / time.Second))
+	}
+
+	return c
+}
+
+// Then decorates delegate with CORS. Preflight requests are answered right away, so they never reach
+// the authentication chain of the API routes and don't need a token. It's a no-op for nil CORS
+func (c *CORS) Then(delegate http.Handler) http.Handler {
+	if c == nil {
+		return delegate
+	}
+
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get(headerOrigin)
+			if origin == "" || !strings.HasPrefix(r.URL.Path, c.pathPrefix) {
+				delegate.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add(headerVary, headerOrigin)
+			allowed := c.anyOrigin || c.origins[strings.ToLower(origin)]
+
+			if r.Method == http.MethodOptions && r.Header.Get(headerAccessControlRequestMethod) != "" {
+				if !allowed {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+
+				c.allow(w, origin)
+				w.Header().Set(headerAllowMethods, c.allowedMethods)
+				w.Header().Set(headerAllowHeaders, c.allowedHeaders)
+				if c.maxAge != "" {
+					w.Header().Set(headerMaxAge, c.maxAge)
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			// browsers hide the responses of disallowed origins from scripts, servers don't need to reject them
+			if allowed {
+				c.allow(w, origin)
+				if c.exposedHeaders != "" {
+					w.Header().Set(headerExposeHeaders, c.exposedHeaders)
+				}
+			}
+
+			delegate.ServeHTTP(w, r)
+		})
+}
+
+func (c *CORS) allow(w http.ResponseWriter, origin string) {
+	if c.anyOrigin {
+		w.Header().Set(headerAllowOrigin, "*")
+		return
+	}
+
+	w.Header().Set(headerAllowOrigin, origin)
+}
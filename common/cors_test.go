@@ -0,0 +1,84 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCORS(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(NewCORS(CORSConfig{}, "/api/v2/"))
+
+	c := NewCORS(CORSConfig{AllowedOrigins: []string{"https://ui.example.com"}, MaxAge: 10 * time.Minute}, "/api/v2/")
+	assert.NotNil(c)
+	assert.Equal("GET, POST, PUT, PATCH, DELETE", c.allowedMethods)
+	assert.Equal("Authorization, Content-Type, X-WebPA-Transaction-Id", c.allowedHeaders)
+	assert.Equal("600", c.maxAge)
+}
+
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name            string
+		origins         []string
+		method          string
+		path            string
+		origin          string
+		requestMethod   string
+		expectedCode    int
+		expectedOrigin  string
+		expectedHandled bool
+	}{
+		{name: "NoOrigin", origins: []string{"https://ui.example.com"}, method: http.MethodGet, path: "/api/v2/device", expectedCode: http.StatusOK, expectedHandled: true},
+		{name: "Allowed", origins: []string{"https://ui.example.com"}, method: http.MethodGet, path: "/api/v2/device", origin: "https://ui.example.com", expectedCode: http.StatusOK, expectedOrigin: "https://ui.example.com", expectedHandled: true},
+		{name: "Disallowed", origins: []string{"https://ui.example.com"}, method: http.MethodGet, path: "/api/v2/device", origin: "https://evil.example.com", expectedCode: http.StatusOK, expectedHandled: true},
+		{name: "AnyOrigin", origins: []string{"*"}, method: http.MethodGet, path: "/api/v2/device", origin: "https://ui.example.com", expectedCode: http.StatusOK, expectedOrigin: "*", expectedHandled: true},
+		{name: "OutsidePrefix", origins: []string{"*"}, method: http.MethodGet, path: "/health", origin: "https://ui.example.com", expectedCode: http.StatusOK, expectedHandled: true},
+		{name: "Preflight", origins: []string{"https://ui.example.com"}, method: http.MethodOptions, path: "/api/v2/device", origin: "https://ui.example.com", requestMethod: http.MethodPost, expectedCode: http.StatusNoContent, expectedOrigin: "https://ui.example.com"},
+		{name: "PreflightDisallowed", origins: []string{"https://ui.example.com"}, method: http.MethodOptions, path: "/api/v2/device", origin: "https://evil.example.com", requestMethod: http.MethodPost, expectedCode: http.StatusForbidden},
+		{name: "PlainOptions", origins: []string{"https://ui.example.com"}, method: http.MethodOptions, path: "/api/v2/device", origin: "https://ui.example.com", expectedCode: http.StatusOK, expectedOrigin: "https://ui.example.com", expectedHandled: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			handled := false
+			c := NewCORS(CORSConfig{AllowedOrigins: test.origins, MaxAge: time.Minute}, "/api/v2/")
+			handler := c.Then(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				handled = true
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			r := httptest.NewRequest(test.method, "http://localhost"+test.path, nil)
+			if test.origin != "" {
+				r.Header.Set(headerOrigin, test.origin)
+			}
+			if test.requestMethod != "" {
+				r.Header.Set(headerAccessControlRequestMethod, test.requestMethod)
+			}
+
+			rw := httptest.NewRecorder()
+			handler.ServeHTTP(rw, r)
+
+			assert.Equal(test.expectedCode, rw.Code)
+			assert.Equal(test.expectedHandled, handled)
+			assert.Equal(test.expectedOrigin, rw.Header().Get(headerAllowOrigin))
+
+			if test.expectedCode == http.StatusNoContent {
+				assert.Equal("GET, POST, PUT, PATCH, DELETE", rw.Header().Get(headerAllowMethods))
+				assert.Equal("60", rw.Header().Get(headerMaxAge))
+			}
+		})
+	}
+}
+
+func TestCORSDisabled(t *testing.T) {
+	var c *CORS
+	handler := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	assert.NotNil(t, c.Then(handler))
+}
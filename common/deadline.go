@@ -0,0 +1,27 @@
+package common
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DeadlineHeader decorates next such that outbound requests carry the time left (in milliseconds) until
+// their context deadline in the given header. This lets XMiDT shed work tr1d1um has given up on.
+// Requests without a deadline, or whose deadline has passed, go through as they are.
+// An empty header disables the decoration.
+func DeadlineHeader(header string, next func(*http.Request) (*http.Response, error)) func(*http.Request) (*http.Response, error) {
+	if header == "" {
+		return next
+	}
+
+	return func(r *http.Request) (*http.Response, error) {
+		if deadline, ok := r.Context().Deadline(); ok {
+			if remaining := time.Until(deadline); remaining > 0 {
+				r.Header.Set(header, strconv.FormatInt(int64(remaining/time.Millisecond), 10))
+			}
+		}
+
+		return next(r)
+	}
+}
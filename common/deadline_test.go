@@ -0,0 +1,83 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadlineHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		timeout  time.Duration
+		expected bool
+	}{
+		{name: "Disabled", timeout: time.Minute},
+		{name: "NoDeadline", header: "X-Midt-Deadline"},
+		{name: "Deadline", header: "X-Midt-Deadline", timeout: time.Minute, expected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var actual http.Header
+			do := DeadlineHeader(test.header, func(r *http.Request) (*http.Response, error) {
+				actual = r.Header
+				return nil, nil
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "http://localhost/api/v2/device", nil)
+			if test.timeout > 0 {
+				ctx, cancel := context.WithTimeout(r.Context(), test.timeout)
+				defer cancel()
+				r = r.WithContext(ctx)
+			}
+
+			_, err := do(r)
+			require.Nil(err)
+
+			if !test.expected {
+				assert.Empty(actual.Get("X-Midt-Deadline"))
+				return
+			}
+
+			remaining, err := strconv.ParseInt(actual.Get(test.header), 10, 64)
+			require.Nil(err)
+			assert.True(remaining > (time.Minute - time.Second).Milliseconds())
+			assert.True(remaining <= time.Minute.Milliseconds())
+		})
+	}
+}
+
+func TestTransactDeadlineHeader(t *testing.T) {
+	assert := assert.New(t)
+
+	var actual string
+	transactor := NewTr1d1umTransactor(&Tr1d1umTransactorOptions{
+		RequestTimeout: time.Minute,
+		Do: DeadlineHeader("X-Midt-Deadline", func(r *http.Request) (*http.Response, error) {
+			actual = r.Header.Get("X-Midt-Deadline")
+			return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+		}),
+	})
+
+	// the per-request timeout override is the remaining budget
+	r := httptest.NewRequest(http.MethodGet, "localhost:6003/test", nil)
+	r = r.WithContext(context.WithValue(r.Context(), ContextKeyRequestTimeout, 5*time.Second))
+
+	_, err := transactor.Transact(r)
+	assert.Nil(err)
+
+	remaining, err := strconv.ParseInt(actual, 10, 64)
+	assert.Nil(err)
+	assert.True(remaining > 4000 && remaining <= 5000)
+}
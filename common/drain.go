@@ -0,0 +1,135 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+// ErrDraining is the cause of the errors returned for requests which arrive while tr1d1um shuts down
+var ErrDraining = errors.New("tr1d1um is shutting down. Please try again")
+
+// drainPollInterval is how often in-flight requests are checked while draining
+const drainPollInterval = 100 * time.Millisecond
+
+// Drainer tracks in-flight requests so that shutdowns can give them a bounded grace period to complete.
+// It has no hold on the listeners of the servers requests come through though, which keep accepting
+// connections until the servers are closed: new requests are rejected instead.
+type Drainer struct {
+	logger   kitlog.Logger
+	inFlight int64
+	draining int32
+
+	// servers are the servers requests came through, keyed by *http.Server
+	servers sync.Map
+}
+
+// NewDrainer is the constructor for Drainer
+// logger is optional and defaults to the webpa-common default logger
+func NewDrainer(logger kitlog.Logger) *Drainer {
+	if logger == nil {
+		logger = logging.DefaultLogger()
+	}
+
+	return &Drainer{logger: logger}
+}
+
+// Then decorates delegate such that in-flight requests are tracked. Once draining starts, new requests
+// are rejected with a 503 and their connections are closed.
+func (d *Drainer) Then(delegate http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			// counting the request before checking the state avoids racing against Drain
+			atomic.AddInt64(&d.inFlight, 1)
+			defer atomic.AddInt64(&d.inFlight, -1)
+
+			if s, ok := r.Context().Value(http.ServerContextKey).(*http.Server); ok {
+				d.servers.LoadOrStore(s, struct{}{})
+			}
+
+			if atomic.LoadInt32(&d.draining) == 1 {
+				w.Header().Set("Connection", "close")
+				w.Header().Set("Content-Type", "application/json; charset=utf-8")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				json.NewEncoder(w).Encode(map[string]string{"message": ErrDraining.Error()})
+				return
+			}
+
+			delegate.ServeHTTP(&drainingWriter{ResponseWriter: w, d: d}, r)
+		})
+}
+
+// drainingWriter closes the connections of the responses written once draining starts so that clients
+// don't keep sending requests over them
+type drainingWriter struct {
+	http.ResponseWriter
+	d           *Drainer
+	wroteHeader bool
+}
+
+func (w *drainingWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if atomic.LoadInt32(&w.d.draining) == 1 {
+			w.Header().Set("Connection", "close")
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *drainingWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+// InFlight returns the number of requests currently being served
+func (d *Drainer) InFlight() int64 {
+	return atomic.LoadInt64(&d.inFlight)
+}
+
+// Drain stops new requests from being served and waits up to timeout for in-flight ones to complete.
+// It reports whether all requests completed in time. Once the timeout is hit, the servers requests came
+// through are closed, which force-closes the requests still in flight.
+//
+// Keep-alives are disabled on the servers requests came through, which closes their idle connections
+// right away and the others once their response is written, so that load balancers move traffic elsewhere.
+// Unlike http.Server.Shutdown, Drain leaves their listeners open while it waits: the webpa-common servers
+// close each other, active connections included, as soon as one of them stops serving, which would cut
+// the in-flight requests off.
+func (d *Drainer) Drain(timeout time.Duration) bool {
+	atomic.StoreInt32(&d.draining, 1)
+
+	d.servers.Range(func(s, _ interface{}) bool {
+		s.(*http.Server).SetKeepAlivesEnabled(false)
+		return true
+	})
+
+	deadline := time.Now().Add(timeout)
+	for d.InFlight() > 0 {
+		if !time.Now().Before(deadline) {
+			logging.Error(d.logger).Log(logging.MessageKey(), "drain timeout hit. Closing in-flight requests",
+				"inFlight", d.InFlight(), "timeout", timeout)
+
+			d.servers.Range(func(s, _ interface{}) bool {
+				s.(*http.Server).Close()
+				return true
+			})
+			return false
+		}
+
+		time.Sleep(drainPollInterval)
+	}
+
+	logging.Info(d.logger).Log(logging.MessageKey(), "all in-flight requests completed")
+	return true
+}
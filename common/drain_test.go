@@ -0,0 +1,173 @@
+package common
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+func TestDrainer(t *testing.T) {
+	t.Run("Drained", func(t *testing.T) {
+		assert := assert.New(t)
+
+		var (
+			d       = NewDrainer(logging.NewTestLogger(nil, t))
+			release = make(chan struct{})
+			started = make(chan struct{})
+			wg      sync.WaitGroup
+		)
+
+		handler := d.Then(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			close(started)
+			<-release
+			w.Write([]byte("done"))
+		}))
+
+		inFlight := httptest.NewRecorder()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler.ServeHTTP(inFlight, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+		}()
+
+		<-started
+		assert.EqualValues(1, d.InFlight())
+
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			close(release)
+		}()
+
+		assert.True(d.Drain(5 * time.Second))
+		wg.Wait()
+		assert.Equal(http.StatusOK, inFlight.Code)
+		assert.Equal("done", inFlight.Body.String())
+
+		// the connections of in-flight requests are closed once they complete
+		assert.Equal("close", inFlight.Header().Get("Connection"))
+
+		// requests arriving after the drain started are rejected
+		rejected := httptest.NewRecorder()
+		handler.ServeHTTP(rejected, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+		assert.Equal(http.StatusServiceUnavailable, rejected.Code)
+		assert.Equal("close", rejected.Header().Get("Connection"))
+		assert.EqualValues(0, d.InFlight())
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		assert := assert.New(t)
+
+		var (
+			d       = NewDrainer(logging.NewTestLogger(nil, t))
+			release = make(chan struct{})
+			started = make(chan struct{})
+		)
+
+		defer close(release)
+
+		handler := d.Then(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			close(started)
+			<-release
+		}))
+
+		go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+
+		<-started
+		assert.False(d.Drain(150 * time.Millisecond))
+		assert.EqualValues(1, d.InFlight())
+	})
+}
+
+func TestDrainerKeepsConnections(t *testing.T) {
+	d := NewDrainer(logging.NewTestLogger(nil, t))
+	handler := d.Then(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+	assert.Equal(t, http.StatusAccepted, rw.Code)
+	assert.Empty(t, rw.Header().Get("Connection"))
+}
+
+func TestDrainerClosesIdleConnections(t *testing.T) {
+	assert := assert.New(t)
+
+	d := NewDrainer(logging.NewTestLogger(nil, t))
+	server := httptest.NewUnstartedServer(d.Then(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("ok"))
+	})))
+
+	closed := make(chan struct{})
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			close(closed)
+		}
+	}
+
+	server.Start()
+	defer server.Close()
+
+	// the client keeps the connection of the response open
+	resp, err := server.Client().Get(server.URL)
+	if !assert.Nil(err) {
+		return
+	}
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assert.True(d.Drain(time.Second))
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		assert.Fail("idle connection was not closed")
+	}
+}
+
+func TestDrainerClosesServersOnTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	var (
+		d       = NewDrainer(logging.NewTestLogger(nil, t))
+		release = make(chan struct{})
+		started = make(chan struct{})
+	)
+
+	server := httptest.NewServer(d.Then(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		close(started)
+		<-release
+	})))
+	defer server.Close()
+	defer close(release)
+
+	errs := make(chan error, 1)
+	go func() {
+		resp, err := server.Client().Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		errs <- err
+	}()
+
+	<-started
+	assert.False(d.Drain(150 * time.Millisecond))
+
+	// the in-flight request is force-closed along with its server
+	select {
+	case err := <-errs:
+		assert.NotNil(err)
+	case <-time.After(time.Second):
+		assert.Fail("in-flight request was not closed")
+	}
+
+	_, err := server.Client().Get(server.URL)
+	assert.NotNil(err)
+}
@@ -0,0 +1,75 @@
+package common
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/gorilla/mux"
+)
+
+// Route label values of EndpointRequestsCounter
+const (
+	RouteStat        = "stat"
+	RouteTranslation = "translation"
+	RouteHooks       = "hooks"
+	RouteOther       = "other"
+)
+
+// OtherMethod is the method label value of requests whose HTTP method isn't a standard one
+const OtherMethod = "OTHER"
+
+// routeOf derives the route label of r from the path template of the mux route it matched
+func routeOf(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return RouteOther
+	}
+
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return RouteOther
+	}
+
+	switch {
+	case strings.HasSuffix(template, "/stat"):
+		return RouteStat
+	case strings.Contains(template, "/hook"):
+		return RouteHooks
+	case strings.Contains(template, "/device/") || strings.HasSuffix(template, "/services"):
+		return RouteTranslation
+	default:
+		return RouteOther
+	}
+}
+
+// methodOf returns the method label of r, capping the cardinality of the label to the standard methods
+func methodOf(r *http.Request) string {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+		http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return r.Method
+	default:
+		return OtherMethod
+	}
+}
+
+// InstrumentEndpointRequests is an Alice-style constructor which counts requests into c labeled by route,
+// HTTP method and response status code. It must decorate the handlers of matched mux routes (i.e. through
+// Router.Use) as the route label comes from the route each request matched.
+func InstrumentEndpointRequests(c metrics.Counter) func(http.Handler) http.Handler {
+	return func(delegate http.Handler) http.Handler {
+		if c == nil {
+			return delegate
+		}
+
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				recorder := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+				delegate.ServeHTTP(recorder, r)
+
+				c.With(RouteLabel, routeOf(r), MethodLabel, methodOf(r), CodeLabel, strconv.Itoa(recorder.code)).Add(1)
+			})
+	}
+}
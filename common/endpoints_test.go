@@ -0,0 +1,54 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrumentEndpointRequests(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedLabels []string
+	}{
+		{name: "Stat", method: http.MethodGet, path: "/api/v2/device/mac:112233445566/stat", expectedLabels: []string{RouteLabel, RouteStat, MethodLabel, "GET", CodeLabel, "200"}},
+		{name: "BatchStat", method: http.MethodPost, path: "/api/v2/device/stat", expectedLabels: []string{RouteLabel, RouteStat, MethodLabel, "POST", CodeLabel, "200"}},
+		{name: "Translation", method: http.MethodPatch, path: "/api/v2/device/mac:112233445566/config", expectedLabels: []string{RouteLabel, RouteTranslation, MethodLabel, "PATCH", CodeLabel, "202"}},
+		{name: "Hooks", method: http.MethodGet, path: "/api/v2/hooks", expectedLabels: []string{RouteLabel, RouteHooks, MethodLabel, "GET", CodeLabel, "200"}},
+		{name: "Other", method: "PURGE", path: "/api/v2/version", expectedLabels: []string{RouteLabel, RouteOther, MethodLabel, OtherMethod, CodeLabel, "200"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			counter := new(capturingCounter)
+			ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {})
+
+			router := mux.NewRouter()
+			api := router.PathPrefix("/api/v2/").Subrouter()
+			api.Use(InstrumentEndpointRequests(counter))
+			api.Handle("/device/{deviceid}/stat", ok)
+			api.Handle("/device/stat", ok)
+			api.Handle("/device/{deviceid}/{service}", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			api.Handle("/hooks", ok)
+			api.Handle("/version", ok)
+
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(test.method, test.path, nil))
+			assert.Equal(test.expectedLabels, counter.labelValues)
+			assert.Equal(1.0, counter.value)
+		})
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		delegate := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+		assert.NotNil(t, InstrumentEndpointRequests(nil)(delegate))
+	})
+}
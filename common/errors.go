@@ -14,15 +14,53 @@ type CodedError interface {
 	StatusCode() int
 }
 
+// ErrorCoder describes errors which additionally carry a machine readable code
+// API consumers can use to tell apart failures which share an HTTP status code
+type ErrorCoder interface {
+	ErrorCode() string
+}
+
+// Error codes reported to API consumers
+const (
+	// ErrorCodeGatewayTLS signals the TLS handshake between tr1d1um and the XMiDT API failed
+	ErrorCodeGatewayTLS = "GATEWAY_TLS_HANDSHAKE_FAILED"
+
+	// ErrorCodeInvalidQueryParameter signals a request query parameter is either unsupported or malformed
+	ErrorCodeInvalidQueryParameter = "INVALID_QUERY_PARAMETER"
+
+	// ErrorCodeTransactionIDMismatch signals the XMiDT response transaction id didn't match the request one
+	ErrorCodeTransactionIDMismatch = "TRANSACTION_ID_MISMATCH"
+
+	// ErrorCodeDeviceOffline signals the device isn't connected to XMiDT
+	ErrorCodeDeviceOffline = "DEVICE_OFFLINE"
+
+	// ErrorCodeOverloaded signals tr1d1um reached its max number of concurrent requests to the XMiDT API
+	ErrorCodeOverloaded = "OVERLOADED"
+
+	// ErrorCodeInvalidSetPayload signals the body of a SET request is malformed. The response lists the problems found
+	ErrorCodeInvalidSetPayload = "INVALID_SET_PAYLOAD"
+)
+
 type codedError struct {
 	error
 	statusCode int
+	errorCode  string
+	headers    http.Header
 }
 
 func (c *codedError) StatusCode() int {
 	return c.statusCode
 }
 
+func (c *codedError) ErrorCode() string {
+	return c.errorCode
+}
+
+// Headers allows error encoders to include additional headers in the response (i.e. Retry-After)
+func (c *codedError) Headers() http.Header {
+	return c.headers
+}
+
 // NewBadRequestError is the constructor for an error returned for bad HTTP requests to tr1d1um
 func NewBadRequestError(e error) CodedError {
 	return NewCodedError(e, http.StatusBadRequest)
@@ -36,3 +74,24 @@ func NewCodedError(e error, code int) CodedError {
 		statusCode: code,
 	}
 }
+
+// NewCodedErrorWithErrorCode upgrades an Error to a CodedError which reports the given
+// error code to API consumers
+// e must not be non-nil to avoid panics
+func NewCodedErrorWithErrorCode(e error, code int, errorCode string) CodedError {
+	return &codedError{
+		error:      e,
+		statusCode: code,
+		errorCode:  errorCode,
+	}
+}
+
+// NewCodedErrorWithHeaders upgrades an Error to a CodedError whose HTTP response includes the given headers
+// e must not be non-nil to avoid panics
+func NewCodedErrorWithHeaders(e error, code int, headers http.Header) CodedError {
+	return &codedError{
+		error:      e,
+		statusCode: code,
+		headers:    headers,
+	}
+}
@@ -2,6 +2,7 @@ package common
 
 import (
 	"errors"
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -22,3 +23,21 @@ func TestBadRequestError(t *testing.T) {
 	assert.EqualValues(400, ce.StatusCode())
 	assert.EqualValues("test", ce.Error())
 }
+
+func TestNewCodedErrorWithErrorCode(t *testing.T) {
+	assert := assert.New(t)
+	var ce = NewCodedErrorWithErrorCode(errors.New("test"), 502, ErrorCodeGatewayTLS)
+	assert.NotNil(ce)
+	assert.EqualValues(502, ce.StatusCode())
+	assert.EqualValues("test", ce.Error())
+	assert.EqualValues(ErrorCodeGatewayTLS, ce.(ErrorCoder).ErrorCode())
+}
+
+func TestNewCodedErrorWithHeaders(t *testing.T) {
+	assert := assert.New(t)
+	var ce = NewCodedErrorWithHeaders(errors.New("test"), 503, http.Header{"Retry-After": []string{"10"}})
+	assert.NotNil(ce)
+	assert.EqualValues(503, ce.StatusCode())
+	assert.EqualValues("test", ce.Error())
+	assert.EqualValues("10", ce.(interface{ Headers() http.Header }).Headers().Get("Retry-After"))
+}
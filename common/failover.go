@@ -0,0 +1,204 @@
+package common
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/xhttp"
+)
+
+// ErrNoHealthyTargets is returned when all the XMiDT targets are cooling down after connection errors
+var ErrNoHealthyTargets = NewCodedError(errors.New("no healthy XMiDT targets available"), http.StatusServiceUnavailable)
+
+// TargetPoolOptions are the configuration options for TargetPool
+type TargetPoolOptions struct {
+	//TargetURLs are the base URLs of the XMiDT clusters. The first one is the primary target
+	//against which the services build their request URLs
+	TargetURLs []string
+
+	//Cooldown is how long a target is skipped after a connection error
+	Cooldown time.Duration
+
+	//Logger is used to report targets entering their cooldown
+	//(Optional) defaults to the webpa-common default logger
+	Logger kitlog.Logger
+
+	//Now returns the current time
+	//(Optional) defaults to time.Now
+	Now func() time.Time
+}
+
+type target struct {
+	url            *url.URL
+	unhealthyUntil time.Time
+}
+
+// TargetPool round-robins requests across a set of XMiDT targets, failing over to
+// the next one when a target can't be reached
+type TargetPool struct {
+	primary  *url.URL
+	targets  []*target
+	cooldown time.Duration
+	logger   kitlog.Logger
+	now      func() time.Time
+
+	lock sync.Mutex
+	next int
+}
+
+// NewTargetPool is the constructor for TargetPool
+func NewTargetPool(o TargetPoolOptions) (*TargetPool, error) {
+	if len(o.TargetURLs) < 1 {
+		return nil, errors.New("at least one target URL is required")
+	}
+
+	p := &TargetPool{
+		cooldown: o.Cooldown,
+		logger:   o.Logger,
+		now:      o.Now,
+	}
+
+	if p.logger == nil {
+		p.logger = logging.DefaultLogger()
+	}
+
+	if p.now == nil {
+		p.now = time.Now
+	}
+
+	for _, t := range o.TargetURLs {
+		u, err := url.Parse(t)
+		if err != nil {
+			return nil, err
+		}
+		p.targets = append(p.targets, &target{url: u})
+	}
+
+	p.primary = p.targets[0].url
+	return p, nil
+}
+
+// Primary returns the base URL against which request URLs should be built
+func (p *TargetPool) Primary() string {
+	return p.primary.String()
+}
+
+// pick returns the next healthy target which hasn't been tried yet
+func (p *TargetPool) pick(tried map[*target]bool) *target {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := p.now()
+	for i := 0; i < len(p.targets); i++ {
+		t := p.targets[(p.next+i)%len(p.targets)]
+		if tried[t] || now.Before(t.unhealthyUntil) {
+			continue
+		}
+
+		p.next = (p.next + i + 1) % len(p.targets)
+		return t
+	}
+
+	return nil
+}
+
+func (p *TargetPool) markUnhealthy(t *target) {
+	p.lock.Lock()
+	t.unhealthyUntil = p.now().Add(p.cooldown)
+	p.lock.Unlock()
+}
+
+// rewrite points u, built against the primary target, to t
+func (p *TargetPool) rewrite(u *url.URL, t *target) {
+	u.Scheme = t.url.Scheme
+	u.Host = t.url.Host
+	u.Path = strings.TrimSuffix(t.url.Path, "/") + "/" + strings.TrimPrefix(strings.TrimPrefix(u.Path, strings.TrimSuffix(p.primary.Path, "/")), "/")
+	u.RawPath = ""
+}
+
+// dialError reports whether err happened while connecting to a target, in which case the request
+// is known not to have been sent
+func dialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// connectionError reports whether err is a failure of the connection to a target rather than of
+// the request itself
+func connectionError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// Do decorates next such that requests are spread across the healthy targets. Targets which fail with
+// a connection error cool down and the request is attempted against the remaining healthy ones, as long
+// as it's idempotent (see ContextKeyIdempotent) or it's known not to have been sent. Cancelled requests
+// and other errors are returned as is. A pool with a single target leaves next untouched.
+func (p *TargetPool) Do(next func(*http.Request) (*http.Response, error)) func(*http.Request) (*http.Response, error) {
+	if len(p.targets) < 2 {
+		return next
+	}
+
+	return func(r *http.Request) (*http.Response, error) {
+		if err := xhttp.EnsureRewindable(r); err != nil {
+			return nil, err
+		}
+
+		var (
+			tried    = make(map[*target]bool, len(p.targets))
+			lastErr  error
+			original = r.URL
+		)
+
+		// leave the request pointing at the primary target for decorators such as retries
+		defer func() { r.URL = original }()
+
+		for t := p.pick(tried); t != nil; t = p.pick(tried) {
+			tried[t] = true
+
+			if err := xhttp.Rewind(r); err != nil {
+				return nil, err
+			}
+
+			rewritten := *original
+			p.rewrite(&rewritten, t)
+			r.URL = &rewritten
+			r.Host = ""
+
+			resp, err := next(r)
+			if err == nil {
+				return resp, nil
+			}
+
+			// the target is not to blame for requests which were given up on, nor would others do better
+			if r.Context().Err() != nil || !connectionError(err) {
+				return nil, err
+			}
+
+			lastErr = err
+			p.markUnhealthy(t)
+			logging.Error(p.logger).Log(logging.MessageKey(), "XMiDT target unreachable, cooling down", "target", t.url.String(),
+				"cooldown", p.cooldown, logging.ErrorKey(), err)
+
+			// the target may have acted on the request before the connection failed
+			if !dialError(err) && !isIdempotent(r) {
+				return nil, err
+			}
+		}
+
+		// all targets failed during this request. Keep the last error so callers can tell what went wrong
+		if lastErr != nil {
+			return nil, lastErr
+		}
+
+		return nil, ErrNoHealthyTargets
+	}
+}
@@ -0,0 +1,239 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTargetPool(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := NewTargetPool(TargetPoolOptions{})
+	assert.NotNil(err)
+
+	_, err = NewTargetPool(TargetPoolOptions{TargetURLs: []string{"http://xmidt-a:6000", ":invalid"}})
+	assert.NotNil(err)
+
+	p, err := NewTargetPool(TargetPoolOptions{TargetURLs: []string{"http://xmidt-a:6000", "http://xmidt-b:6000"}})
+	assert.Nil(err)
+	assert.Equal("http://xmidt-a:6000", p.Primary())
+}
+
+func TestTargetPoolDo(t *testing.T) {
+	newPool := func(now *time.Time) *TargetPool {
+		p, err := NewTargetPool(TargetPoolOptions{
+			TargetURLs: []string{"http://xmidt-a:6000", "https://xmidt-b:6100/prefix"},
+			Cooldown:   time.Minute,
+			Now:        func() time.Time { return *now },
+		})
+		require.Nil(t, err)
+		return p
+	}
+
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	reset := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "http://xmidt-a:6000/api/v2/device?q=1", bytes.NewBufferString("payload"))
+		r.RequestURI = ""
+		return r
+	}
+
+	t.Run("SingleTarget", func(t *testing.T) {
+		assert := assert.New(t)
+		p, err := NewTargetPool(TargetPoolOptions{TargetURLs: []string{"http://xmidt-a:6000"}})
+		require.Nil(t, err)
+
+		calls := 0
+		do := p.Do(func(*http.Request) (*http.Response, error) {
+			calls++
+			return nil, refused
+		})
+
+		do(newRequest())
+		_, err = do(newRequest())
+		assert.Equal(refused, err)
+		assert.Equal(2, calls)
+	})
+
+	t.Run("RoundRobin", func(t *testing.T) {
+		assert := assert.New(t)
+		now := time.Now()
+		p := newPool(&now)
+
+		var urls []string
+		do := p.Do(func(r *http.Request) (*http.Response, error) {
+			urls = append(urls, r.URL.String())
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		})
+
+		for i := 0; i < 3; i++ {
+			r := newRequest()
+			do(r)
+			assert.Equal("http://xmidt-a:6000/api/v2/device?q=1", r.URL.String())
+		}
+
+		assert.Equal([]string{
+			"http://xmidt-a:6000/api/v2/device?q=1",
+			"https://xmidt-b:6100/prefix/api/v2/device?q=1",
+			"http://xmidt-a:6000/api/v2/device?q=1",
+		}, urls)
+	})
+
+	t.Run("Failover", func(t *testing.T) {
+		assert := assert.New(t)
+		now := time.Now()
+		p := newPool(&now)
+
+		var (
+			hosts  []string
+			bodies []string
+		)
+
+		do := p.Do(func(r *http.Request) (*http.Response, error) {
+			hosts = append(hosts, r.URL.Host)
+			body, _ := ioutil.ReadAll(r.Body)
+			bodies = append(bodies, string(body))
+
+			if r.URL.Host == "xmidt-a:6000" {
+				return nil, refused
+			}
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		})
+
+		resp, err := do(newRequest())
+		assert.Nil(err)
+		assert.Equal(http.StatusOK, resp.StatusCode)
+		assert.Equal([]string{"xmidt-a:6000", "xmidt-b:6100"}, hosts)
+		assert.Equal([]string{"payload", "payload"}, bodies)
+
+		// xmidt-a is cooling down
+		hosts = nil
+		do(newRequest())
+		do(newRequest())
+		assert.Equal([]string{"xmidt-b:6100", "xmidt-b:6100"}, hosts)
+
+		// cooldown is over
+		hosts = nil
+		now = now.Add(time.Minute)
+		do(newRequest())
+		do(newRequest())
+		assert.Contains(hosts, "xmidt-a:6000")
+	})
+
+	t.Run("AllUnhealthy", func(t *testing.T) {
+		assert := assert.New(t)
+		now := time.Now()
+		p := newPool(&now)
+
+		calls := 0
+		do := p.Do(func(*http.Request) (*http.Response, error) {
+			calls++
+			return nil, refused
+		})
+
+		_, err := do(newRequest())
+		assert.Equal(refused, err)
+		assert.Equal(2, calls)
+
+		_, err = do(newRequest())
+		assert.Equal(ErrNoHealthyTargets, err)
+		assert.Equal(2, calls)
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		assert := assert.New(t)
+		now := time.Now()
+		p := newPool(&now)
+
+		var hosts []string
+		do := p.Do(func(r *http.Request) (*http.Response, error) {
+			hosts = append(hosts, r.URL.Host)
+			if err := r.Context().Err(); err != nil {
+				return nil, &net.OpError{Op: "dial", Net: "tcp", Err: err}
+			}
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := do(newRequest().WithContext(ctx))
+		assert.True(errors.Is(err, context.Canceled))
+		assert.Len(hosts, 1)
+
+		// no target was put in cooldown
+		hosts = nil
+		do(newRequest())
+		do(newRequest())
+		assert.ElementsMatch([]string{"xmidt-a:6000", "xmidt-b:6100"}, hosts)
+	})
+
+	t.Run("NotConnectionError", func(t *testing.T) {
+		assert := assert.New(t)
+		now := time.Now()
+		p := newPool(&now)
+
+		calls := 0
+		do := p.Do(func(*http.Request) (*http.Response, error) {
+			calls++
+			return nil, errors.New("malformed response")
+		})
+
+		_, err := do(newRequest())
+		assert.Equal("malformed response", err.Error())
+		assert.Equal(1, calls)
+
+		// the target is still healthy
+		do(newRequest())
+		assert.Equal(2, calls)
+	})
+
+	t.Run("SentRequests", func(t *testing.T) {
+		tests := []struct {
+			name          string
+			method        string
+			expectedHosts []string
+		}{
+			{name: "NonIdempotent", method: http.MethodPost, expectedHosts: []string{"xmidt-a:6000"}},
+			{name: "Idempotent", method: http.MethodGet, expectedHosts: []string{"xmidt-a:6000", "xmidt-b:6100"}},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				assert := assert.New(t)
+				now := time.Now()
+				p := newPool(&now)
+
+				var hosts []string
+				do := p.Do(func(r *http.Request) (*http.Response, error) {
+					hosts = append(hosts, r.URL.Host)
+					if r.URL.Host == "xmidt-a:6000" {
+						return nil, reset
+					}
+					return &http.Response{StatusCode: http.StatusOK}, nil
+				})
+
+				r := newRequest()
+				r.Method = test.method
+				do(r)
+				assert.Equal(test.expectedHosts, hosts)
+
+				// the target which dropped the connection cools down either way
+				hosts = nil
+				do(newRequest())
+				assert.Equal([]string{"xmidt-b:6100"}, hosts)
+			})
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package common
+
+import "net/http"
+
+// HeaderFilter selects the XMiDT response headers forwarded to clients. Headers in the deny list are never
+// forwarded. When there's an allow list, only its headers are forwarded. Otherwise, all headers with the
+// "X" prefix are. Header names are matched case insensitively.
+type HeaderFilter struct {
+	allow map[string]bool
+	deny  map[string]bool
+}
+
+// NewHeaderFilter is the constructor for HeaderFilter. It returns nil, which forwards all the headers with
+// the "X" prefix, when both lists are empty
+func NewHeaderFilter(allow, deny []string) *HeaderFilter {
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil
+	}
+
+	return &HeaderFilter{
+		allow: canonicalHeaderSet(allow),
+		deny:  canonicalHeaderSet(deny),
+	}
+}
+
+func canonicalHeaderSet(headers []string) map[string]bool {
+	set := make(map[string]bool, len(headers))
+	for _, h := range headers {
+		set[http.CanonicalHeaderKey(h)] = true
+	}
+	return set
+}
+
+// Forward copies the selected headers from 'from' into 'to'
+func (f *HeaderFilter) Forward(from http.Header, to http.Header) {
+	if f == nil {
+		ForwardHeadersByPrefix("X", from, to)
+		return
+	}
+
+	filtered := make(http.Header, len(from))
+	if len(f.allow) > 0 {
+		for key, values := range from {
+			if f.allow[http.CanonicalHeaderKey(key)] {
+				filtered[key] = values
+			}
+		}
+	} else {
+		ForwardHeadersByPrefix("X", from, filtered)
+	}
+
+	for key, values := range filtered {
+		if f.deny[http.CanonicalHeaderKey(key)] {
+			continue
+		}
+
+		for _, value := range values {
+			to.Add(key, value)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package common
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaderFilter(t *testing.T) {
+	from := http.Header{
+		"X-Webpa-Device-Name": []string{"mac:112233445566"},
+		"X-Internal-Node":     []string{"node-1"},
+		"X-Multi":             []string{"a", "b"},
+		"Retry-After":         []string{"10"},
+		"Content-Type":        []string{"application/json"},
+	}
+
+	tests := []struct {
+		name     string
+		allow    []string
+		deny     []string
+		expected http.Header
+	}{
+		{
+			name: "Default",
+			expected: http.Header{
+				"X-Webpa-Device-Name": []string{"mac:112233445566"},
+				"X-Internal-Node":     []string{"node-1"},
+				"X-Multi":             []string{"a", "b"},
+			},
+		},
+		{
+			name: "DenyOnly",
+			deny: []string{"x-internal-node"},
+			expected: http.Header{
+				"X-Webpa-Device-Name": []string{"mac:112233445566"},
+				"X-Multi":             []string{"a", "b"},
+			},
+		},
+		{
+			name:  "AllowOnly",
+			allow: []string{"retry-after", "X-MULTI"},
+			expected: http.Header{
+				"X-Multi":     []string{"a", "b"},
+				"Retry-After": []string{"10"},
+			},
+		},
+		{
+			name:  "Overlap",
+			allow: []string{"Retry-After", "X-Internal-Node", "X-Webpa-Device-Name"},
+			deny:  []string{"x-internal-NODE", "Content-Type"},
+			expected: http.Header{
+				"X-Webpa-Device-Name": []string{"mac:112233445566"},
+				"Retry-After":         []string{"10"},
+			},
+		},
+		{
+			name:     "AllDenied",
+			allow:    []string{"Retry-After"},
+			deny:     []string{"retry-after"},
+			expected: http.Header{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			to := make(http.Header)
+			NewHeaderFilter(test.allow, test.deny).Forward(from, to)
+			assert.Equal(t, test.expected, to)
+		})
+	}
+}
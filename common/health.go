@@ -0,0 +1,208 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultTargetHealthInterval is used when no positive interval is configured
+const defaultTargetHealthInterval = 30 * time.Second
+
+// TargetHealthOptions are the configuration options for TargetHealth
+type TargetHealthOptions struct {
+	//TargetURLs are the XMiDT base URLs probed by each check, in failover order. XMiDT is
+	//reachable as long as any of them is
+	TargetURLs []string
+
+	//Path is appended to each target URL to build the probe URL
+	//(Optional)
+	Path string
+
+	//Interval is how long a check result is reused before XMiDT is probed again
+	//(Optional) defaults to 30s
+	Interval time.Duration
+
+	//Timeout is the deadline for probing each target
+	Timeout time.Duration
+
+	//Do is the core responsible to perform the actual HTTP request
+	//(Optional) defaults to http.DefaultClient.Do
+	Do func(*http.Request) (*http.Response, error)
+
+	//Now returns the current time
+	//(Optional) defaults to time.Now
+	Now func() time.Time
+}
+
+// TargetHealthStatus is the result of the latest probe against XMiDT
+type TargetHealthStatus struct {
+	Healthy     bool       `json:"healthy"`
+	Target      string     `json:"target,omitempty"`
+	LastChecked time.Time  `json:"lastChecked"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	Latency     string     `json:"latency"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// TargetHealth is an http.Handler which reports whether any of the XMiDT targets is reachable.
+// Probe results are cached for the configured interval so that frequent health checks
+// don't translate into load against XMiDT.
+// Once started, probes run in the background and the handler only reports the latest
+// result. The target is reported unhealthy until the first probe succeeds.
+type TargetHealth struct {
+	o TargetHealthOptions
+
+	mutex      sync.Mutex
+	status     TargetHealthStatus
+	background bool
+}
+
+// NewTargetHealth is the constructor for TargetHealth
+func NewTargetHealth(o TargetHealthOptions) *TargetHealth {
+	if o.Do == nil {
+		o.Do = http.DefaultClient.Do
+	}
+
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+
+	if o.Interval <= 0 {
+		o.Interval = defaultTargetHealthInterval
+	}
+
+	return &TargetHealth{o: o}
+}
+
+// Start probes XMiDT every interval on a background goroutine until shutdown is closed
+func (t *TargetHealth) Start(shutdown <-chan struct{}) {
+	t.mutex.Lock()
+	t.background = true
+	t.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(t.o.Interval)
+		defer ticker.Stop()
+
+		for {
+			t.probe(context.Background())
+
+			select {
+			case <-shutdown:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Status returns the latest probe result. Unless probes run in the background, XMiDT
+// is probed if the cached result is stale
+func (t *TargetHealth) Status(ctx context.Context) TargetHealthStatus {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.background {
+		return t.status
+	}
+
+	if t.status.LastChecked.IsZero() || t.o.Now().Sub(t.status.LastChecked) >= t.o.Interval {
+		t.record(t.check(ctx))
+	}
+
+	return t.status
+}
+
+func (t *TargetHealth) probe(ctx context.Context) {
+	status := t.check(ctx)
+
+	t.mutex.Lock()
+	t.record(status)
+	t.mutex.Unlock()
+}
+
+// record must be called while holding the mutex
+func (t *TargetHealth) record(status TargetHealthStatus) {
+	if !status.Healthy {
+		status.LastSuccess = t.status.LastSuccess
+	}
+	t.status = status
+}
+
+func (t *TargetHealth) check(ctx context.Context) (status TargetHealthStatus) {
+	start := t.o.Now()
+
+	var errs []string
+	for _, target := range t.o.TargetURLs {
+		err := t.ping(ctx, target)
+		if err == nil {
+			status.Target = target
+			break
+		}
+
+		if len(t.o.TargetURLs) > 1 {
+			errs = append(errs, fmt.Sprintf("%s: %v", target, err))
+		} else {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	end := t.o.Now()
+
+	status.LastChecked = end
+	status.Latency = end.Sub(start).String()
+	status.Healthy = status.Target != ""
+
+	if !status.Healthy {
+		status.Error = strings.Join(errs, "; ")
+		return
+	}
+
+	status.LastSuccess = &end
+	return
+}
+
+func (t *TargetHealth) ping(ctx context.Context, target string) error {
+	if t.o.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.o.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, strings.TrimSuffix(target, "/")+t.o.Path, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := t.o.Do(req)
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+
+	// any non server error response means XMiDT is up and answering
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("XMiDT responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (t *TargetHealth) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status := t.Status(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	if status.Healthy {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,167 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargetHealth(t *testing.T) {
+	t.Run("Healthy", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		var method string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method = r.Method
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		h := NewTargetHealth(TargetHealthOptions{TargetURLs: []string{server.URL}, Interval: time.Minute, Timeout: time.Second})
+
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost/health", nil))
+
+		assert.Equal(http.StatusOK, recorder.Code)
+		assert.Equal(http.MethodHead, method)
+		assert.Equal("application/json", recorder.Header().Get("Content-Type"))
+
+		var status TargetHealthStatus
+		require.Nil(json.Unmarshal(recorder.Body.Bytes(), &status))
+		assert.True(status.Healthy)
+		assert.NotNil(status.LastSuccess)
+		assert.NotEmpty(status.Latency)
+		assert.Empty(status.Error)
+	})
+
+	t.Run("ServerError", func(t *testing.T) {
+		assert := assert.New(t)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		h := NewTargetHealth(TargetHealthOptions{TargetURLs: []string{server.URL}, Interval: time.Minute})
+
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost/health", nil))
+		assert.Equal(http.StatusServiceUnavailable, recorder.Code)
+	})
+
+	t.Run("Cached", func(t *testing.T) {
+		assert := assert.New(t)
+
+		var (
+			calls int
+			now   = time.Now()
+			fail  bool
+		)
+
+		h := NewTargetHealth(TargetHealthOptions{
+			TargetURLs: []string{"http://xmidt:6000"},
+			Interval:   time.Minute,
+			Now:        func() time.Time { return now },
+			Do: func(*http.Request) (*http.Response, error) {
+				calls++
+				if fail {
+					return nil, errors.New("connection refused")
+				}
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+			},
+		})
+
+		lastSuccess := now
+		assert.True(h.Status(context.Background()).Healthy)
+
+		fail = true
+		now = now.Add(30 * time.Second)
+		assert.True(h.Status(context.Background()).Healthy)
+		assert.Equal(1, calls)
+
+		now = now.Add(30 * time.Second)
+		status := h.Status(context.Background())
+		assert.Equal(2, calls)
+		assert.False(status.Healthy)
+		assert.Equal("connection refused", status.Error)
+		assert.Equal(now, status.LastChecked)
+		assert.Equal(lastSuccess, *status.LastSuccess)
+	})
+
+	t.Run("Failover", func(t *testing.T) {
+		assert := assert.New(t)
+
+		var reachable bool
+		h := NewTargetHealth(TargetHealthOptions{
+			TargetURLs: []string{"http://xmidt-a:6000", "http://xmidt-b:6000"},
+			Interval:   time.Minute,
+			Do: func(r *http.Request) (*http.Response, error) {
+				if r.URL.Host == "xmidt-b:6000" && reachable {
+					return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+				}
+				return nil, errors.New("connection refused")
+			},
+		})
+
+		status := h.check(context.Background())
+		assert.False(status.Healthy)
+		assert.Equal("http://xmidt-a:6000: connection refused; http://xmidt-b:6000: connection refused", status.Error)
+
+		reachable = true
+		status = h.check(context.Background())
+		assert.True(status.Healthy)
+		assert.Equal("http://xmidt-b:6000", status.Target)
+	})
+
+	t.Run("DefaultInterval", func(t *testing.T) {
+		assert := assert.New(t)
+
+		h := NewTargetHealth(TargetHealthOptions{TargetURLs: []string{"http://xmidt:6000"}})
+		assert.Equal(defaultTargetHealthInterval, h.o.Interval)
+	})
+
+	t.Run("Background", func(t *testing.T) {
+		assert := assert.New(t)
+
+		var (
+			paths   = make(chan string, 10)
+			healthy = make(chan bool, 1)
+		)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths <- r.URL.Path
+			<-healthy
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		h := NewTargetHealth(TargetHealthOptions{TargetURLs: []string{server.URL + "/"}, Path: "/api/v2/health", Interval: time.Hour})
+
+		shutdown := make(chan struct{})
+		defer close(shutdown)
+		h.Start(shutdown)
+
+		// not ready until the first probe succeeds
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost/health", nil))
+		assert.Equal(http.StatusServiceUnavailable, recorder.Code)
+		assert.Equal("/api/v2/health", <-paths)
+
+		healthy <- true
+		assert.Eventually(func() bool {
+			return h.Status(context.Background()).Healthy
+		}, time.Second, 10*time.Millisecond)
+
+		recorder = httptest.NewRecorder()
+		h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost/health", nil))
+		assert.Equal(http.StatusOK, recorder.Code)
+		assert.Empty(paths)
+	})
+}
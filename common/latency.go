@@ -0,0 +1,109 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceIDExemplarLabel is the exemplar label of the trace the observation of a request belongs to
+const TraceIDExemplarLabel = "trace_id"
+
+// exemplarObserver is implemented by histograms which can attach exemplars to their observations
+type exemplarObserver interface {
+	ObserveWithExemplar(value float64, exemplar prometheus.Labels)
+}
+
+// ExemplarHistogram is a metrics.Histogram backed by a Prometheus histogram, which unlike the go-kit one
+// can attach exemplars to observations
+type ExemplarHistogram struct {
+	hv          *prometheus.HistogramVec
+	labelValues []string
+}
+
+// NewExemplarHistogram is the constructor for ExemplarHistogram
+func NewExemplarHistogram(hv *prometheus.HistogramVec) *ExemplarHistogram {
+	return &ExemplarHistogram{hv: hv}
+}
+
+// With implements metrics.Histogram
+func (e *ExemplarHistogram) With(labelValues ...string) metrics.Histogram {
+	return &ExemplarHistogram{
+		hv:          e.hv,
+		labelValues: append(append([]string{}, e.labelValues...), labelValues...),
+	}
+}
+
+func (e *ExemplarHistogram) labels() prometheus.Labels {
+	labels := make(prometheus.Labels, len(e.labelValues)/2)
+	for i := 0; i+1 < len(e.labelValues); i += 2 {
+		labels[e.labelValues[i]] = e.labelValues[i+1]
+	}
+	return labels
+}
+
+// Observe implements metrics.Histogram
+func (e *ExemplarHistogram) Observe(value float64) {
+	e.hv.With(e.labels()).Observe(value)
+}
+
+// ObserveWithExemplar observes value along with the given exemplar
+func (e *ExemplarHistogram) ObserveWithExemplar(value float64, exemplar prometheus.Labels) {
+	e.hv.With(e.labels()).(prometheus.ExemplarObserver).ObserveWithExemplar(value, exemplar)
+}
+
+// traceExemplar returns the exemplar of the sampled trace of ctx, if any
+func traceExemplar(ctx context.Context) (prometheus.Labels, bool) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() || !sc.IsSampled() {
+		return nil, false
+	}
+	return prometheus.Labels{TraceIDExemplarLabel: sc.TraceID().String()}, true
+}
+
+// statusRecorder captures the status code written through a http.ResponseWriter
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.code = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// InstrumentLatency is an Alice-style constructor which observes the end-to-end latency of requests
+// into h labeled by the given service, WRP message type and the class of the response status code (i.e. "2xx").
+// Observations of traced requests carry their trace id as an exemplar when h supports it (see ExemplarHistogram).
+func InstrumentLatency(h metrics.Histogram, service, msgType string) func(http.Handler) http.Handler {
+	return func(delegate http.Handler) http.Handler {
+		if h == nil {
+			return delegate
+		}
+
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				start := time.Now()
+				recorder := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+
+				delegate.ServeHTTP(recorder, r)
+
+				observer := h.With(ServiceLabel, service, MsgTypeLabel, msgType, StatusLabel, fmt.Sprintf("%dxx", recorder.code/100))
+				latency := time.Since(start).Seconds()
+
+				if e, ok := observer.(exemplarObserver); ok {
+					if exemplar, ok := traceExemplar(r.Context()); ok {
+						e.ObserveWithExemplar(latency, exemplar)
+						return
+					}
+				}
+
+				observer.Observe(latency)
+			})
+	}
+}
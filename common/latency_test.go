@@ -0,0 +1,112 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type capturingHistogram struct {
+	labelValues  []string
+	observations []float64
+}
+
+func (c *capturingHistogram) With(labelValues ...string) metrics.Histogram {
+	c.labelValues = append(c.labelValues, labelValues...)
+	return c
+}
+
+func (c *capturingHistogram) Observe(value float64) {
+	c.observations = append(c.observations, value)
+}
+
+func TestInstrumentLatency(t *testing.T) {
+	t.Run("Disabled", func(t *testing.T) {
+		assert := assert.New(t)
+		delegate := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+		handler := InstrumentLatency(nil, "stat", "none")(delegate)
+		assert.NotNil(handler)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+	})
+
+	tests := []struct {
+		name           string
+		code           int
+		expectedStatus string
+	}{
+		{name: "ImplicitOK", expectedStatus: "2xx"},
+		{name: "NotFound", code: http.StatusNotFound, expectedStatus: "4xx"},
+		{name: "Unavailable", code: http.StatusServiceUnavailable, expectedStatus: "5xx"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			h := new(capturingHistogram)
+
+			handler := InstrumentLatency(h, "translation", "SimpleRequestResponse")(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if test.code > 0 {
+						w.WriteHeader(test.code)
+					}
+					w.Write([]byte("ok"))
+				}))
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+
+			assert.Equal([]string{ServiceLabel, "translation", MsgTypeLabel, "SimpleRequestResponse", StatusLabel, test.expectedStatus},
+				h.labelValues)
+			assert.Len(h.observations, 1)
+			assert.Equal("ok", recorder.Body.String())
+		})
+	}
+}
+
+func TestInstrumentLatencyExemplars(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	hv := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_latency_seconds"}, []string{ServiceLabel, MsgTypeLabel, StatusLabel})
+	registry := prometheus.NewPedanticRegistry()
+	require.Nil(registry.Register(hv))
+
+	var traceID string
+	instrumented := InstrumentLatency(NewExemplarHistogram(hv), "stat", "none")(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			traceID = trace.SpanContextFromContext(r.Context()).TraceID().String()
+		}))
+
+	// untraced requests are observed without an exemplar
+	instrumented.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+
+	tracing := newTracing(sdktrace.NewTracerProvider())
+	tracing.Then(instrumented).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+
+	families, err := registry.Gather()
+	require.Nil(err)
+	require.Len(families, 1)
+	require.Len(families[0].GetMetric(), 1)
+
+	histogram := families[0].GetMetric()[0].GetHistogram()
+	assert.EqualValues(2, histogram.GetSampleCount())
+
+	var exemplars []string
+	for _, bucket := range histogram.GetBucket() {
+		if e := bucket.GetExemplar(); e != nil {
+			for _, label := range e.GetLabel() {
+				assert.Equal(TraceIDExemplarLabel, label.GetName())
+				exemplars = append(exemplars, label.GetValue())
+			}
+		}
+	}
+
+	assert.Equal([]string{traceID}, exemplars)
+}
@@ -0,0 +1,42 @@
+package common
+
+import (
+	"sync/atomic"
+
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+// filteredLogger keeps the type stored in LevelLogger consistent regardless of the filter implementation
+type filteredLogger struct {
+	logger kitlog.Logger
+}
+
+// LevelLogger filters log events by level the same way the loggers built by logging.New do, except
+// that the level can be changed at runtime
+type LevelLogger struct {
+	next     kitlog.Logger
+	filtered atomic.Value
+}
+
+// NewLevelLogger is the constructor for LevelLogger. next should not filter log events itself
+func NewLevelLogger(next kitlog.Logger, lvl string) *LevelLogger {
+	l := &LevelLogger{next: next}
+	l.SetLevel(lvl)
+	return l
+}
+
+// SetLevel changes the level (DEBUG, INFO, WARN or ERROR) below which log events are dropped
+func (l *LevelLogger) SetLevel(lvl string) {
+	l.filtered.Store(filteredLogger{logger: logging.NewFilter(l.next, &logging.Options{Level: lvl})})
+}
+
+// Log implements kitlog.Logger
+func (l *LevelLogger) Log(keyvals ...interface{}) error {
+	return l.filtered.Load().(filteredLogger).logger.Log(keyvals...)
+}
+
+// WithLevel returns a logger which filters log events by the given level rather than the current one
+func (l *LevelLogger) WithLevel(lvl string) kitlog.Logger {
+	return logging.NewFilter(l.next, &logging.Options{Level: lvl})
+}
@@ -0,0 +1,28 @@
+package common
+
+import (
+	"bytes"
+	"testing"
+
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+func TestLevelLogger(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	logger := NewLevelLogger(kitlog.NewLogfmtLogger(&buf), "ERROR")
+
+	logging.Info(logger).Log(logging.MessageKey(), "dropped")
+	assert.Empty(buf.String())
+
+	logging.Error(logger).Log(logging.MessageKey(), "kept")
+	assert.Contains(buf.String(), "kept")
+
+	buf.Reset()
+	logger.SetLevel("DEBUG")
+	logging.Debug(logger).Log(logging.MessageKey(), "debugging")
+	assert.Contains(buf.String(), "debugging")
+}
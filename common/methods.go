@@ -0,0 +1,52 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// AllowedMethods returns the methods of supported which are in allowList. All of them are when allowList is empty.
+// Methods are compared case insensitively.
+func AllowedMethods(supported, allowList []string) []string {
+	if len(allowList) == 0 {
+		return supported
+	}
+
+	var allowed []string
+	for _, method := range supported {
+		for _, a := range allowList {
+			if strings.EqualFold(method, a) {
+				allowed = append(allowed, method)
+				break
+			}
+		}
+	}
+	return allowed
+}
+
+// HandleMethods registers handler for the methods of supported which are in allowList at the given path of router.
+// Requests with any other method get a 405 whose Allow header lists the allowed ones. Unlike capability checks,
+// which depend on the caller, the allow list applies to every request.
+func HandleMethods(router *mux.Router, path string, handler http.Handler, supported, allowList []string) {
+	allowed := AllowedMethods(supported, allowList)
+	if len(allowed) > 0 {
+		router.Handle(path, handler).Methods(allowed...)
+	}
+
+	// routes are matched in order so this one only gets the requests the one above doesn't accept
+	router.Handle(path, methodNotAllowed(allowed))
+}
+
+func methodNotAllowed(allowed []string) http.Handler {
+	allow := strings.Join(allowed, ", ")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allow)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("method %s is not allowed", r.Method)})
+	})
+}
@@ -0,0 +1,55 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowedMethods(t *testing.T) {
+	assert := assert.New(t)
+	supported := []string{http.MethodGet, http.MethodPatch, http.MethodPost}
+
+	assert.Equal(supported, AllowedMethods(supported, nil))
+	assert.Equal([]string{http.MethodGet, http.MethodPost}, AllowedMethods(supported, []string{"post", "GET", "DELETE"}))
+	assert.Empty(AllowedMethods(supported, []string{http.MethodDelete}))
+}
+
+func TestHandleMethods(t *testing.T) {
+	tests := []struct {
+		name          string
+		allowList     []string
+		method        string
+		expectedCode  int
+		expectedAllow string
+	}{
+		{name: "NoAllowList", method: http.MethodPatch, expectedCode: http.StatusOK},
+		{name: "Allowed", allowList: []string{"GET"}, method: http.MethodGet, expectedCode: http.StatusOK},
+		{name: "NotAllowed", allowList: []string{"GET"}, method: http.MethodPatch, expectedCode: http.StatusMethodNotAllowed, expectedAllow: "GET"},
+		{name: "NotSupported", method: http.MethodDelete, expectedCode: http.StatusMethodNotAllowed, expectedAllow: "GET, PATCH"},
+		{name: "NoneAllowed", allowList: []string{"DELETE"}, method: http.MethodGet, expectedCode: http.StatusMethodNotAllowed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			router := mux.NewRouter()
+			HandleMethods(router, "/device/{deviceid}/{service}", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}), []string{http.MethodGet, http.MethodPatch}, test.allowList)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(test.method, "/device/mac:112233445566/config", nil))
+
+			assert.Equal(test.expectedCode, w.Code)
+			if test.expectedCode == http.StatusMethodNotAllowed {
+				assert.Equal(test.expectedAllow, w.Header().Get("Allow"))
+				assert.Contains(w.Body.String(), test.method)
+			}
+		})
+	}
+}
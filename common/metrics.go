@@ -0,0 +1,157 @@
+package common
+
+import (
+	"github.com/xmidt-org/webpa-common/xmetrics"
+)
+
+// Names for our metrics
+const (
+	TLSHandshakeFailuresCounter           = "tls_handshake_failures"
+	CircuitBreakerStateTransitionsCounter = "circuit_breaker_state_transitions"
+	RequestLatencyHistogram               = "request_latency_seconds"
+	TransactionLatencyHistogram           = "transaction_latency_seconds"
+	TransactionIDMismatchesCounter        = "transaction_id_mismatches"
+	PartnerRequestsCounter                = "partner_requests"
+	ThrottledRequestsCounter              = "throttled_requests"
+	DeviceThrottledRequestsCounter        = "device_throttled_requests"
+	JWTKeyRefreshesCounter                = "jwt_key_refreshes"
+	AuthTokenCacheLookupsCounter          = "auth_token_cache_lookups"
+	AuthTokenRefreshFailuresCounter       = "auth_token_refresh_failures"
+	StatCacheLookupsCounter               = "stat_cache_lookups"
+	OutboundRequestsInFlightGauge         = "outbound_requests_in_flight"
+	OutboundRequestsQueuedGauge           = "outbound_requests_queued"
+	EndpointRequestsCounter               = "endpoint_requests"
+)
+
+// Labels for our metrics
+const (
+	ServiceLabel  = "service"
+	MsgTypeLabel  = "msg_type"
+	StatusLabel   = "status"
+	EndpointLabel = "endpoint"
+	PartnerLabel  = "partner"
+	OutcomeLabel  = "outcome"
+	RouteLabel    = "route"
+	MethodLabel   = "method"
+	CodeLabel     = "code"
+)
+
+// DefaultLatencyBuckets are the request latency histogram buckets (in seconds) tuned
+// for the default request timeout of 40s
+var DefaultLatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 40, 60}
+
+// DefaultTransactionLatencyBuckets are the XMiDT transaction latency histogram buckets (in seconds)
+var DefaultTransactionLatencyBuckets = []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 40}
+
+// Metrics returns the metrics relevant to the tr1d1um services
+func Metrics() []xmetrics.Metric {
+	return []xmetrics.Metric{
+		{
+			Name: TLSHandshakeFailuresCounter,
+			Type: xmetrics.CounterType,
+			Help: "Count of outbound requests to XMiDT which failed during the TLS handshake",
+		},
+		{
+			Name:       CircuitBreakerStateTransitionsCounter,
+			Type:       xmetrics.CounterType,
+			Help:       "Count of state transitions of the circuit breaker around requests to XMiDT, labeled by the new state",
+			LabelNames: []string{"state"},
+		},
+		{
+			Name: TransactionIDMismatchesCounter,
+			Type: xmetrics.CounterType,
+			Help: "Count of XMiDT responses whose WRP transaction id did not match the request one",
+		},
+		{
+			Name:       PartnerRequestsCounter,
+			Type:       xmetrics.CounterType,
+			Help:       "Count of requests to the device endpoints labeled by partner, service and the class of the response status code",
+			LabelNames: []string{PartnerLabel, ServiceLabel, StatusLabel},
+		},
+		{
+			Name: ThrottledRequestsCounter,
+			Type: xmetrics.CounterType,
+			Help: "Count of requests rejected as their principal was over its rate limit",
+		},
+		{
+			Name: DeviceThrottledRequestsCounter,
+			Type: xmetrics.CounterType,
+			Help: "Count of requests rejected as their device was over its rate limit",
+		},
+		{
+			Name:       JWTKeyRefreshesCounter,
+			Type:       xmetrics.CounterType,
+			Help:       "Count of periodic refreshes of the JWT verification keys labeled by outcome (success or failure)",
+			LabelNames: []string{OutcomeLabel},
+		},
+		{
+			Name:       AuthTokenCacheLookupsCounter,
+			Type:       xmetrics.CounterType,
+			Help:       "Count of lookups of the cached auth token for requests to XMiDT labeled by outcome (hit or miss)",
+			LabelNames: []string{OutcomeLabel},
+		},
+		{
+			Name: AuthTokenRefreshFailuresCounter,
+			Type: xmetrics.CounterType,
+			Help: "Count of failures to acquire a new auth token for requests to XMiDT",
+		},
+		{
+			Name:       StatCacheLookupsCounter,
+			Type:       xmetrics.CounterType,
+			Help:       "Count of lookups of cached device stat responses labeled by outcome (hit or miss)",
+			LabelNames: []string{OutcomeLabel},
+		},
+		{
+			Name: OutboundRequestsInFlightGauge,
+			Type: xmetrics.GaugeType,
+			Help: "Number of requests to XMiDT currently in flight",
+		},
+		{
+			Name: OutboundRequestsQueuedGauge,
+			Type: xmetrics.GaugeType,
+			Help: "Number of requests to XMiDT currently waiting for a slot once the concurrency limit is reached",
+		},
+		{
+			Name:       EndpointRequestsCounter,
+			Type:       xmetrics.CounterType,
+			Help:       "Count of requests to the API labeled by route (stat, translation, hooks or other), HTTP method and response status code",
+			LabelNames: []string{RouteLabel, MethodLabel, CodeLabel},
+		},
+	}
+}
+
+// LatencyMetrics returns the end-to-end request latency metrics with the given histogram buckets (in seconds).
+// DefaultLatencyBuckets are used if none are given
+func LatencyMetrics(buckets []float64) []xmetrics.Metric {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+
+	return []xmetrics.Metric{
+		{
+			Name:       RequestLatencyHistogram,
+			Type:       xmetrics.HistogramType,
+			Help:       "End-to-end latency of requests to the device endpoints, from handler entry to response write",
+			Buckets:    buckets,
+			LabelNames: []string{ServiceLabel, MsgTypeLabel, StatusLabel},
+		},
+	}
+}
+
+// TransactionLatencyMetrics returns the metrics of the latency of requests to XMiDT with the given histogram
+// buckets (in seconds). DefaultTransactionLatencyBuckets are used if none are given
+func TransactionLatencyMetrics(buckets []float64) []xmetrics.Metric {
+	if len(buckets) == 0 {
+		buckets = DefaultTransactionLatencyBuckets
+	}
+
+	return []xmetrics.Metric{
+		{
+			Name:       TransactionLatencyHistogram,
+			Type:       xmetrics.HistogramType,
+			Help:       "Latency of requests to XMiDT, labeled by endpoint type and the class of the response status code",
+			Buckets:    buckets,
+			LabelNames: []string{EndpointLabel, StatusLabel},
+		},
+	}
+}
@@ -0,0 +1,55 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	kitlog "github.com/go-kit/kit/log"
+	kithttp "github.com/go-kit/kit/transport/http"
+)
+
+// OperationLevels overrides the log level for the handling of specific operations (i.e. SET)
+// so that routine operations can log less than high impact ones
+type OperationLevels struct {
+	loggers map[string]kitlog.Logger
+}
+
+// NewOperationLevels builds the loggers for the given operation levels (DEBUG, INFO, WARN or ERROR).
+// Operation names are matched case insensitively. It returns nil when there are no levels.
+func NewOperationLevels(logger *LevelLogger, levels map[string]string) *OperationLevels {
+	if len(levels) == 0 {
+		return nil
+	}
+
+	o := &OperationLevels{loggers: make(map[string]kitlog.Logger, len(levels))}
+	for operation, lvl := range levels {
+		o.loggers[strings.ToUpper(operation)] = logger.WithLevel(lvl)
+	}
+	return o
+}
+
+// CaptureOperationLogger selects the logger for the operation of the request, as reported by operation,
+// if its level is overridden. It must run before Capture for the transaction logs to use it.
+func CaptureOperationLogger(levels *OperationLevels, operation func(*http.Request) string) kithttp.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		if levels == nil {
+			return ctx
+		}
+
+		logger, ok := levels.loggers[strings.ToUpper(operation(r))]
+		if !ok {
+			return ctx
+		}
+
+		return context.WithValue(ctx, ContextKeyOperationLogger, logger)
+	}
+}
+
+// OperationLogger returns the logger selected for the operation of the request, falling back to logger
+func OperationLogger(ctx context.Context, logger kitlog.Logger) kitlog.Logger {
+	if l, ok := ctx.Value(ContextKeyOperationLogger).(kitlog.Logger); ok {
+		return l
+	}
+	return logger
+}
@@ -0,0 +1,59 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperationLevels(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLevelLogger(kitlog.NewLogfmtLogger(&buf), "WARN")
+	levels := NewOperationLevels(logger, map[string]string{"set": "DEBUG", "GET": "ERROR", "DELETE_ROW": "INFO"})
+	settings := NewSettings(Snapshot{})
+
+	tests := []struct {
+		operation      string
+		expectedRecord bool
+		expectedError  bool
+	}{
+		{operation: "SET", expectedRecord: true, expectedError: true},
+		{operation: "DELETE_ROW", expectedRecord: true, expectedError: true},
+		{operation: "GET", expectedRecord: false, expectedError: true},
+		// the global level applies to the rest
+		{operation: "ADD_ROW", expectedRecord: false, expectedError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.operation, func(t *testing.T) {
+			assert := assert.New(t)
+			buf.Reset()
+
+			r := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+			ctx := CaptureOperationLogger(levels, func(*http.Request) string { return test.operation })(context.Background(), r)
+			ctx = Capture(logger)(ctx, r)
+			ctx = context.WithValue(ctx, ContextKeyRequestArrivalTime, time.Now())
+
+			TransactionLogging(settings, logger)(ctx, http.StatusOK, r)
+			assert.Equal(test.expectedRecord, bytes.Contains(buf.Bytes(), []byte("msg=record")), buf.String())
+
+			buf.Reset()
+			ErrorLogEncoder(logger, func(context.Context, error, http.ResponseWriter) {})(ctx, errors.New("failed"), httptest.NewRecorder())
+			assert.Equal(test.expectedError, bytes.Contains(buf.Bytes(), []byte("failed")), buf.String())
+		})
+	}
+
+	t.Run("NilLevels", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+		ctx := CaptureOperationLogger(nil, func(*http.Request) string { return "SET" })(context.Background(), r)
+		assert.Equal(t, logger, OperationLogger(ctx, logger))
+		assert.Nil(t, NewOperationLevels(logger, nil))
+	})
+}
@@ -0,0 +1,65 @@
+package common
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/xmidt-org/bascule"
+	"github.com/xmidt-org/webpa-common/basculechecks"
+)
+
+// HeaderWebpaPartnerID is the header clients without partner information in their tokens may use to identify their partner
+const HeaderWebpaPartnerID = "X-Webpa-Partner-Id"
+
+// Partner label values for requests which don't belong to a known partner
+const (
+	OtherPartner = "other"
+	NoPartner    = "none"
+)
+
+// partnerID returns the first partner id of the request, taken from the authenticated token
+// or, in its absence, the HeaderWebpaPartnerID header
+func partnerID(r *http.Request) string {
+	if auth, ok := bascule.FromContext(r.Context()); ok {
+		if partnerIDs, ok := auth.Token.Attributes().GetStringSlice(basculechecks.PartnerKey); ok && len(partnerIDs) > 0 {
+			return partnerIDs[0]
+		}
+	}
+
+	return strings.TrimSpace(strings.Split(r.Header.Get(HeaderWebpaPartnerID), ",")[0])
+}
+
+// InstrumentPartnerRequests is an Alice-style constructor which counts requests into c labeled by partner,
+// the given service and the class of the response status code. Partners other than knownPartners are
+// counted as OtherPartner to cap the cardinality of the partner label.
+// It must run after authentication so the partners of the token are available.
+func InstrumentPartnerRequests(c metrics.Counter, knownPartners []string, service string) func(http.Handler) http.Handler {
+	known := make(map[string]bool, len(knownPartners))
+	for _, p := range knownPartners {
+		known[p] = true
+	}
+
+	return func(delegate http.Handler) http.Handler {
+		if c == nil {
+			return delegate
+		}
+
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				recorder := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+				delegate.ServeHTTP(recorder, r)
+
+				partner := partnerID(r)
+				switch {
+				case partner == "":
+					partner = NoPartner
+				case !known[partner]:
+					partner = OtherPartner
+				}
+
+				c.With(PartnerLabel, partner, ServiceLabel, service, StatusLabel, fmt.Sprintf("%dxx", recorder.code/100)).Add(1)
+			})
+	}
+}
@@ -0,0 +1,80 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/xmidt-org/bascule"
+)
+
+type capturingCounter struct {
+	labelValues []string
+	value       float64
+}
+
+func (c *capturingCounter) With(labelValues ...string) metrics.Counter {
+	c.labelValues = append(c.labelValues, labelValues...)
+	return c
+}
+
+func (c *capturingCounter) Add(delta float64) {
+	c.value += delta
+}
+
+func TestInstrumentPartnerRequests(t *testing.T) {
+	tests := []struct {
+		name            string
+		tokenPartners   []string
+		header          string
+		code            int
+		expectedPartner string
+	}{
+		{name: "TokenPartner", tokenPartners: []string{"comcast", "sky"}, header: "sky", code: http.StatusOK, expectedPartner: "comcast"},
+		{name: "HeaderPartner", header: "sky, comcast", code: http.StatusNotFound, expectedPartner: "sky"},
+		{name: "UnknownPartner", tokenPartners: []string{"acme"}, code: http.StatusOK, expectedPartner: OtherPartner},
+		{name: "NoPartner", code: http.StatusServiceUnavailable, expectedPartner: NoPartner},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			c := new(capturingCounter)
+
+			handler := InstrumentPartnerRequests(c, []string{"comcast", "sky"}, "stat")(
+				http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					w.WriteHeader(test.code)
+				}))
+
+			r := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+			if test.header != "" {
+				r.Header.Set(HeaderWebpaPartnerID, test.header)
+			}
+
+			if test.tokenPartners != nil {
+				attrs := bascule.NewAttributesFromMap(map[string]interface{}{
+					"allowedResources": map[string]interface{}{
+						"allowedPartners": test.tokenPartners,
+					},
+				})
+				r = r.WithContext(bascule.WithAuthentication(context.Background(), bascule.Authentication{
+					Token: bascule.NewToken("jwt", "client", attrs),
+				}))
+			}
+
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			assert.Equal([]string{PartnerLabel, test.expectedPartner, ServiceLabel, "stat", StatusLabel, fmt.Sprintf("%dxx", test.code/100)}, c.labelValues)
+			assert.Equal(1.0, c.value)
+		})
+	}
+}
+
+func TestInstrumentPartnerRequestsDisabled(t *testing.T) {
+	delegate := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	assert.NotNil(t, InstrumentPartnerRequests(nil, nil, "stat")(delegate))
+}
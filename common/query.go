@@ -0,0 +1,63 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+)
+
+// QueryParams lists the query parameters each HTTP method of an endpoint supports
+type QueryParams map[string][]string
+
+func (q QueryParams) supports(method, param string) bool {
+	for _, p := range q[method] {
+		if p == param {
+			return true
+		}
+	}
+	return false
+}
+
+func newInvalidQueryParamError(format string, args ...interface{}) error {
+	return NewCodedErrorWithErrorCode(fmt.Errorf(format, args...), http.StatusBadRequest, ErrorCodeInvalidQueryParameter)
+}
+
+// StrictQueryParams decorates decoder such that requests with unsupported, repeated or otherwise
+// malformed query parameters are rejected with a 400 identifying the offending parameter.
+// Unless strict is set, decoder is returned as is and such parameters are ignored.
+func StrictQueryParams(strict bool, supported QueryParams, decoder kithttp.DecodeRequestFunc) kithttp.DecodeRequestFunc {
+	if !strict {
+		return decoder
+	}
+
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		query, err := url.ParseQuery(r.URL.RawQuery)
+		if err != nil {
+			return nil, newInvalidQueryParamError("malformed query string: %v", err)
+		}
+
+		params := make([]string, 0, len(query))
+		for param := range query {
+			params = append(params, param)
+		}
+
+		// report the same parameter every time for requests with several invalid ones
+		sort.Strings(params)
+
+		for _, param := range params {
+			if !supported.supports(r.Method, param) {
+				return nil, newInvalidQueryParamError("unsupported query parameter '%s'", param)
+			}
+
+			if len(query[param]) > 1 {
+				return nil, newInvalidQueryParamError("query parameter '%s' must be specified at most once", param)
+			}
+		}
+
+		return decoder(ctx, r)
+	}
+}
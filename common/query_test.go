@@ -0,0 +1,62 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrictQueryParams(t *testing.T) {
+	supported := QueryParams{
+		http.MethodGet: {"names", "attributes"},
+	}
+
+	tests := []struct {
+		name          string
+		strict        bool
+		method        string
+		query         string
+		expectedError string
+	}{
+		{name: "Lenient", method: http.MethodGet, query: "names=a&dryRun=maybe&names=b&bad=%zz"},
+		{name: "NoQuery", strict: true, method: http.MethodGet},
+		{name: "Supported", strict: true, method: http.MethodGet, query: "names=a,b&attributes=notify"},
+		{name: "Unsupported", strict: true, method: http.MethodGet, query: "names=a&format=xml", expectedError: "unsupported query parameter 'format'"},
+		{name: "UnsupportedForMethod", strict: true, method: http.MethodPatch, query: "names=a", expectedError: "unsupported query parameter 'names'"},
+		{name: "Repeated", strict: true, method: http.MethodGet, query: "names=a&names=b", expectedError: "query parameter 'names' must be specified at most once"},
+		{name: "Malformed", strict: true, method: http.MethodGet, query: "names=%zz", expectedError: "malformed query string"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var decoded bool
+			decoder := StrictQueryParams(test.strict, supported, func(_ context.Context, _ *http.Request) (interface{}, error) {
+				decoded = true
+				return nil, nil
+			})
+
+			r := httptest.NewRequest(test.method, "http://localhost/api/v2/device", nil)
+			r.URL.RawQuery = test.query
+
+			_, err := decoder(context.Background(), r)
+
+			if test.expectedError == "" {
+				assert.Nil(err)
+				assert.True(decoded)
+				return
+			}
+
+			assert.False(decoded)
+			if assert.NotNil(err) {
+				assert.Contains(err.Error(), test.expectedError)
+				assert.Equal(http.StatusBadRequest, err.(CodedError).StatusCode())
+				assert.Equal(ErrorCodeInvalidQueryParameter, err.(ErrorCoder).ErrorCode())
+			}
+		})
+	}
+}
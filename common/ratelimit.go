@@ -0,0 +1,290 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/gorilla/mux"
+	"github.com/xmidt-org/bascule"
+	"github.com/xmidt-org/webpa-common/device"
+)
+
+// Rate limiting errors
+var (
+	// ErrRateLimited is the cause of the errors returned for requests of principals over their rate limit
+	ErrRateLimited = errors.New("too many requests. Please slow down")
+
+	// ErrDeviceRateLimited is the cause of the errors returned for requests to devices over their rate limit
+	ErrDeviceRateLimited = errors.New("too many requests to this device. Please slow down")
+)
+
+// rateLimitSweepInterval is how often buckets of idle principals are dropped
+const rateLimitSweepInterval = time.Minute
+
+// RateLimit is a token bucket rate limit
+type RateLimit struct {
+	// RequestsPerSecond is the rate at which the bucket refills. Non-positive values disable the limit
+	RequestsPerSecond float64
+
+	// Burst is the capacity of the bucket. It defaults to RequestsPerSecond (rounded up) when not positive
+	Burst int
+}
+
+func (l RateLimit) burst() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return math.Ceil(l.RequestsPerSecond)
+}
+
+// PrincipalRateLimit overrides the default rate limit for a principal
+type PrincipalRateLimit struct {
+	RateLimit `mapstructure:",squash"`
+
+	// Principal is the identity of the authenticated token (i.e. the JWT subject)
+	Principal string
+}
+
+// RateLimitConfig configures the rate limits applied per principal
+type RateLimitConfig struct {
+	// RateLimit is the default limit of each principal
+	RateLimit `mapstructure:",squash"`
+
+	// Overrides are the limits of specific principals
+	Overrides []PrincipalRateLimit
+}
+
+// DeviceRateLimitConfig configures the rate limit applied per device
+type DeviceRateLimitConfig struct {
+	// Rate is the sustained requests per second each device may receive. Non-positive values disable the limit
+	Rate float64
+
+	// Burst is how many requests a device may receive at once. It defaults to Rate (rounded up) when not positive
+	Burst int
+}
+
+// tokenBucket allows requests as long as it holds tokens, refilling at limit.RequestsPerSecond
+type tokenBucket struct {
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = math.Min(b.limit.burst(), b.tokens+now.Sub(b.last).Seconds()*b.limit.RequestsPerSecond)
+	b.last = now
+}
+
+// take consumes a token if there's one. Otherwise, it returns how long it takes for the next one to be available
+func (b *tokenBucket) take(now time.Time) (bool, time.Duration) {
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	return false, time.Duration((1 - b.tokens) / b.limit.RequestsPerSecond * float64(time.Second))
+}
+
+// untilFull is how long it takes for the bucket to refill completely
+func (b *tokenBucket) untilFull() time.Duration {
+	return time.Duration((b.limit.burst() - b.tokens) / b.limit.RequestsPerSecond * float64(time.Second))
+}
+
+// rateLimitStatus is the state of a bucket right after a request was checked against it
+type rateLimitStatus struct {
+	allowed    bool
+	limit      int
+	remaining  int
+	reset      time.Duration
+	retryAfter time.Duration
+}
+
+// writeHeaders reports the status to the client so it can pace its requests
+func (s rateLimitStatus) writeHeaders(h http.Header) {
+	h.Set("X-RateLimit-Limit", strconv.Itoa(s.limit))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(s.remaining))
+	h.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(s.reset)))
+	if !s.allowed {
+		h.Set("Retry-After", strconv.Itoa(ceilSeconds(s.retryAfter)))
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// RateLimiter throttles requests through token buckets, one per key (i.e. principal)
+type RateLimiter struct {
+	key          func(*http.Request) (string, bool)
+	err          error
+	defaultLimit RateLimit
+	overrides    map[string]RateLimit
+	throttled    metrics.Counter
+	now          func() time.Time
+
+	lock      sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// NewRateLimiter is the constructor for RateLimiter. nil is returned when c limits nobody.
+// throttled counts the rejected requests and is optional.
+func NewRateLimiter(c RateLimitConfig, throttled metrics.Counter) *RateLimiter {
+	overrides := make(map[string]RateLimit, len(c.Overrides))
+	for _, o := range c.Overrides {
+		overrides[o.Principal] = o.RateLimit
+	}
+
+	if c.RequestsPerSecond <= 0 && len(overrides) == 0 {
+		return nil
+	}
+
+	if throttled == nil {
+		throttled = discard.NewCounter()
+	}
+
+	return &RateLimiter{
+		key:          principalKey,
+		err:          ErrRateLimited,
+		defaultLimit: c.RateLimit,
+		overrides:    overrides,
+		throttled:    throttled,
+		now:          time.Now,
+		buckets:      make(map[string]*tokenBucket),
+	}
+}
+
+// NewDeviceRateLimiter is the constructor for RateLimiter throttling requests per device, identified by the
+// "deviceid" path variable. nil is returned when c limits nothing. throttled counts the rejected requests and
+// is optional.
+func NewDeviceRateLimiter(c DeviceRateLimitConfig, throttled metrics.Counter) *RateLimiter {
+	if c.Rate <= 0 {
+		return nil
+	}
+
+	if throttled == nil {
+		throttled = discard.NewCounter()
+	}
+
+	return &RateLimiter{
+		key:          deviceKey,
+		err:          ErrDeviceRateLimited,
+		defaultLimit: RateLimit{RequestsPerSecond: c.Rate, Burst: c.Burst},
+		throttled:    throttled,
+		now:          time.Now,
+		buckets:      make(map[string]*tokenBucket),
+	}
+}
+
+// principalKey keys requests by the principal of their token. Unauthenticated requests have no key
+func principalKey(r *http.Request) (string, bool) {
+	auth, ok := bascule.FromContext(r.Context())
+	if !ok || auth.Token == nil {
+		return "", false
+	}
+	return auth.Token.Principal(), true
+}
+
+// deviceKey keys requests by their canonical device id. Requests with malformed ids have no key
+func deviceKey(r *http.Request) (string, bool) {
+	id, err := device.ParseID(mux.Vars(r)["deviceid"])
+	if err != nil {
+		return "", false
+	}
+	return string(id), true
+}
+
+// allow checks whether the key may make a request right now. ok is false when the key is not limited at all
+func (l *RateLimiter) allow(key string) (rateLimitStatus, bool) {
+	limit, ok := l.overrides[key]
+	if !ok {
+		limit = l.defaultLimit
+	}
+
+	if limit.RequestsPerSecond <= 0 {
+		return rateLimitStatus{}, false
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{limit: limit, tokens: limit.burst(), last: now}
+		l.buckets[key] = b
+	}
+
+	allowed, retryAfter := b.take(now)
+	return rateLimitStatus{
+		allowed:    allowed,
+		limit:      int(limit.burst()),
+		remaining:  int(math.Floor(b.tokens)),
+		reset:      b.untilFull(),
+		retryAfter: retryAfter,
+	}, true
+}
+
+// sweep drops the buckets which are full again as they are no different from new ones.
+// It must be called with the lock held.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if b.refill(now); b.tokens >= b.limit.burst() {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Then decorates delegate such that requests over the rate limit of their key are rejected with a 429 and
+// a Retry-After header. Responses to limited keys carry the X-RateLimit-Limit (bucket capacity),
+// X-RateLimit-Remaining (requests left right now) and X-RateLimit-Reset (seconds until the bucket is full
+// again) headers. Principal limiters must run after authentication so the principal of the request
+// is available. Requests without a key (i.e. unauthenticated ones) are not limited.
+// A nil *RateLimiter returns delegate as is.
+func (l *RateLimiter) Then(delegate http.Handler) http.Handler {
+	if l == nil {
+		return delegate
+	}
+
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			key, ok := l.key(r)
+			if !ok {
+				delegate.ServeHTTP(w, r)
+				return
+			}
+
+			status, limited := l.allow(key)
+			if !limited {
+				delegate.ServeHTTP(w, r)
+				return
+			}
+
+			status.writeHeaders(w.Header())
+			if !status.allowed {
+				l.throttled.Add(1)
+
+				w.Header().Set("Content-Type", "application/json; charset=utf-8")
+				w.WriteHeader(http.StatusTooManyRequests)
+				json.NewEncoder(w).Encode(map[string]string{"message": l.err.Error()})
+				return
+			}
+
+			delegate.ServeHTTP(w, r)
+		})
+}
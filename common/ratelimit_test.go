@@ -0,0 +1,173 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/bascule"
+)
+
+func TestNewRateLimiter(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(NewRateLimiter(RateLimitConfig{}, nil))
+	assert.NotNil(NewRateLimiter(RateLimitConfig{RateLimit: RateLimit{RequestsPerSecond: 1}}, nil))
+	assert.NotNil(NewRateLimiter(RateLimitConfig{Overrides: []PrincipalRateLimit{{Principal: "client", RateLimit: RateLimit{RequestsPerSecond: 1}}}}, nil))
+}
+
+func newPrincipalRequest(principal string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "http://localhost/api/v2/device/mac:112233445566/stat", nil)
+	if principal == "" {
+		return r
+	}
+
+	return r.WithContext(bascule.WithAuthentication(context.Background(), bascule.Authentication{
+		Token: bascule.NewToken("jwt", principal, bascule.NewAttributesFromMap(map[string]interface{}{})),
+	}))
+}
+
+func TestRateLimiter(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var (
+		now       = time.Now()
+		throttled = new(capturingCounter)
+		limiter   = NewRateLimiter(RateLimitConfig{
+			RateLimit: RateLimit{RequestsPerSecond: 2, Burst: 2},
+			Overrides: []PrincipalRateLimit{
+				{Principal: "trusted", RateLimit: RateLimit{}},
+				{Principal: "noisy", RateLimit: RateLimit{RequestsPerSecond: 0.5}},
+			},
+		}, throttled)
+	)
+
+	require.NotNil(limiter)
+	limiter.now = func() time.Time { return now }
+
+	handler := limiter.Then(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(principal string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, newPrincipalRequest(principal))
+		return w
+	}
+
+	// the burst goes through and the bucket of each principal is independent
+	w := serve("client")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal("1", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal("1", w.Header().Get("X-RateLimit-Reset"))
+	assert.Empty(w.Header().Get("Retry-After"))
+
+	w = serve("client")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("0", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal("1", w.Header().Get("X-RateLimit-Reset"))
+	assert.Equal(http.StatusOK, serve("other").Code)
+
+	w = serve("client")
+	assert.Equal(http.StatusTooManyRequests, w.Code)
+	assert.Equal("2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal("0", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal("1", w.Header().Get("X-RateLimit-Reset"))
+	assert.Equal("1", w.Header().Get("Retry-After"))
+	assert.Contains(w.Body.String(), ErrRateLimited.Error())
+	assert.Equal(float64(1), throttled.value)
+
+	// the bucket refills over time
+	now = now.Add(500 * time.Millisecond)
+	assert.Equal(http.StatusOK, serve("client").Code)
+	assert.Equal(http.StatusTooManyRequests, serve("client").Code)
+
+	// overrides take precedence over the default limit and exempt principals get no rate limit headers
+	for i := 0; i < 10; i++ {
+		w = serve("trusted")
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Empty(w.Header().Get("X-RateLimit-Limit"))
+	}
+
+	assert.Equal(http.StatusOK, serve("noisy").Code)
+	w = serve("noisy")
+	assert.Equal(http.StatusTooManyRequests, w.Code)
+	assert.Equal("1", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal("2", w.Header().Get("X-RateLimit-Reset"))
+	assert.Equal("2", w.Header().Get("Retry-After"))
+
+	// unauthenticated requests are left alone
+	for i := 0; i < 10; i++ {
+		assert.Equal(http.StatusOK, serve("").Code)
+	}
+
+	// full buckets are dropped
+	now = now.Add(rateLimitSweepInterval)
+	assert.Equal(http.StatusOK, serve("other").Code)
+	assert.Len(limiter.buckets, 1)
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	var l *RateLimiter
+	delegate := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	assert.NotNil(t, l.Then(delegate))
+}
+
+func TestDeviceRateLimiter(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	assert.Nil(NewDeviceRateLimiter(DeviceRateLimitConfig{}, nil))
+
+	var (
+		now       = time.Now()
+		throttled = new(capturingCounter)
+		limiter   = NewDeviceRateLimiter(DeviceRateLimitConfig{Rate: 1, Burst: 2}, throttled)
+	)
+
+	require.NotNil(limiter)
+	limiter.now = func() time.Time { return now }
+
+	handler := limiter.Then(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(deviceID string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPatch, "http://localhost/api/v2/device/"+deviceID+"/config", nil)
+		r = mux.SetURLVars(r, map[string]string{"deviceid": deviceID, "service": "config"})
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(http.StatusOK, serve("mac:112233445566").Code)
+
+	// different spellings of the same device share its bucket
+	assert.Equal(http.StatusOK, serve("mac:11-22-33-44-55-66").Code)
+
+	w := serve("mac:112233445566")
+	assert.Equal(http.StatusTooManyRequests, w.Code)
+	assert.Equal("2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal("0", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal("2", w.Header().Get("X-RateLimit-Reset"))
+	assert.Equal("1", w.Header().Get("Retry-After"))
+	assert.Contains(w.Body.String(), ErrDeviceRateLimited.Error())
+	assert.Equal(float64(1), throttled.value)
+
+	// other devices are unaffected and malformed ids are left to the handler
+	assert.Equal(http.StatusOK, serve("mac:665544332211").Code)
+	assert.Equal(http.StatusOK, serve("nope").Code)
+	assert.Equal(http.StatusOK, serve("nope").Code)
+	assert.Equal(http.StatusOK, serve("nope").Code)
+
+	now = now.Add(time.Second)
+	assert.Equal(http.StatusOK, serve("mac:112233445566").Code)
+}
@@ -0,0 +1,330 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/xhttp"
+)
+
+// BackoffStrategy describes how the wait interval between retries of a request evolves
+type BackoffStrategy string
+
+// Supported backoff strategies
+const (
+	// BackoffConstant waits the same interval between all retries
+	BackoffConstant BackoffStrategy = "constant"
+
+	// BackoffExponential doubles the wait interval after each retry
+	BackoffExponential BackoffStrategy = "exponential"
+)
+
+// ParseBackoffStrategy converts the given configuration value into a BackoffStrategy.
+// An empty value defaults to BackoffConstant
+func ParseBackoffStrategy(s string) (BackoffStrategy, error) {
+	switch BackoffStrategy(s) {
+	case "", BackoffConstant:
+		return BackoffConstant, nil
+	case BackoffExponential:
+		return BackoffExponential, nil
+	default:
+		return "", fmt.Errorf("unsupported retry backoff strategy '%s'", s)
+	}
+}
+
+// RetryOptions are the configuration options for RetryTransactor
+type RetryOptions struct {
+	//Logger is used to report retried transactions
+	Logger kitlog.Logger
+
+	//Retries is the max number of times a transaction is retried. Values less than 1 disable retries
+	Retries int
+
+	//Interval is the base time to wait between retries
+	Interval time.Duration
+
+	//Backoff is the strategy used to grow Interval between retries
+	//(Optional) defaults to BackoffConstant
+	Backoff BackoffStrategy
+
+	//MaxInterval caps the time to wait between retries
+	//(Optional) a non-positive value disables the cap
+	MaxInterval time.Duration
+
+	//AttemptTimeout is the max duration of each attempt. Attempts never run past the deadline of the
+	//request so later ones get the share of the time budget which is left
+	//(Optional) a non-positive value limits attempts by the request deadline only
+	AttemptTimeout time.Duration
+
+	//Budget is the max duration of a transaction across all of its attempts and the waits between them.
+	//Retries are skipped once the budget left is less than the wait before them
+	//(Optional) a non-positive value limits transactions by the request deadline only
+	Budget time.Duration
+
+	//Jitter randomizes each wait between retries by up to ±Jitter around the computed interval
+	//(Optional) a non-positive value disables jitter
+	Jitter time.Duration
+
+	//Rand is the source of randomness for Jitter. It must return values in [0.0, 1.0)
+	//(Optional) defaults to math/rand.Float64
+	Rand func() float64
+
+	//ShouldRetry decides whether an error from a transaction is worth retrying
+	//(Optional) defaults to retrying temporary errors only
+	ShouldRetry func(error) bool
+
+	//IdempotentOnly restricts retries to idempotent transactions: those whose request context says so through
+	//ContextKeyIdempotent or, without it, those with a GET, HEAD or OPTIONS method
+	IdempotentOnly bool
+
+	//Sleep is the function used to wait between retries
+	//(Optional) defaults to a sleep which is cut short once the request is cancelled
+	Sleep func(time.Duration)
+}
+
+type temporary interface {
+	Temporary() bool
+}
+
+func shouldRetryTemporary(err error) bool {
+	if t, ok := err.(temporary); ok {
+		return t.Temporary()
+	}
+	return false
+}
+
+// isIdempotent reports whether r may be safely sent again after a failed attempt
+func isIdempotent(r *http.Request) bool {
+	if idempotent, ok := r.Context().Value(ContextKeyIdempotent).(bool); ok {
+		return idempotent
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
+// interval returns the time to wait before the given retry attempt (zero based)
+func (o RetryOptions) interval(attempt int) time.Duration {
+	d := o.Interval
+
+	if o.Backoff == BackoffExponential {
+		for i := 0; i < attempt; i++ {
+			if o.MaxInterval > 0 && d >= o.MaxInterval {
+				break
+			}
+
+			// guard against overflowing the duration
+			if d > d<<1 {
+				break
+			}
+			d <<= 1
+		}
+	}
+
+	if o.MaxInterval > 0 && d > o.MaxInterval {
+		d = o.MaxInterval
+	}
+
+	return d
+}
+
+// wait returns the time to sleep before the given retry attempt (zero based) after jitter is applied.
+// The result is never negative.
+func (o RetryOptions) wait(attempt int) time.Duration {
+	d := o.interval(attempt)
+	if o.Jitter <= 0 {
+		return d
+	}
+
+	d += time.Duration((2*o.Rand() - 1) * float64(o.Jitter))
+
+	if d < 0 {
+		d = 0
+	}
+
+	if o.MaxInterval > 0 && d > o.MaxInterval {
+		d = o.MaxInterval
+	}
+
+	return d
+}
+
+// cancelOnClose releases the resources of the context of an attempt once its response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+// sleep waits d before the next attempt. It returns early with the error of ctx once it's cancelled,
+// so that abandoned requests release their resources promptly
+func (o RetryOptions) sleep(ctx context.Context, d time.Duration) error {
+	if o.Sleep != nil {
+		o.Sleep(d)
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// drainResponse releases the connection of a response which comes along an error and won't be used
+func drainResponse(response *http.Response) {
+	if response != nil && response.Body != nil {
+		io.Copy(ioutil.Discard, response.Body)
+		response.Body.Close()
+	}
+}
+
+// remaining returns the time left before the deadline of the request, if any
+func remaining(r *http.Request) (time.Duration, bool) {
+	deadline, ok := r.Context().Deadline()
+	if !ok {
+		return 0, false
+	}
+	return time.Until(deadline), true
+}
+
+// attempt performs a single try of the transaction within min(AttemptTimeout, remaining budget)
+func (o RetryOptions) attempt(r *http.Request, next func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	if o.AttemptTimeout <= 0 {
+		return next(r)
+	}
+
+	timeout := o.AttemptTimeout
+	if left, ok := remaining(r); ok && left < timeout {
+		timeout = left
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	response, err := next(r.WithContext(ctx))
+	return cancelWithResponse(response, err, cancel)
+}
+
+// cancelWithResponse defers cancel until the body of a successful response is closed. It cancels right away otherwise
+func cancelWithResponse(response *http.Response, err error, cancel context.CancelFunc) (*http.Response, error) {
+	if err != nil {
+		drainResponse(response)
+		cancel()
+		return nil, err
+	}
+
+	if response == nil || response.Body == nil {
+		cancel()
+		return response, nil
+	}
+
+	response.Body = &cancelOnClose{ReadCloser: response.Body, cancel: cancel}
+	return response, nil
+}
+
+// RetryTransactor decorates next such that failed transactions are retried per the given options.
+// Unlike xhttp.RetryTransactor, the interval between retries can grow between attempts.
+func RetryTransactor(o RetryOptions, next func(*http.Request) (*http.Response, error)) func(*http.Request) (*http.Response, error) {
+	if o.Retries < 1 {
+		return next
+	}
+
+	if o.Logger == nil {
+		o.Logger = logging.DefaultLogger()
+	}
+
+	if o.ShouldRetry == nil {
+		o.ShouldRetry = shouldRetryTemporary
+	}
+
+	if o.Rand == nil {
+		o.Rand = rand.Float64
+	}
+
+	return func(r *http.Request) (*http.Response, error) {
+		if err := xhttp.EnsureRewindable(r); err != nil {
+			return nil, err
+		}
+
+		// the budget becomes the deadline of the request when it's the tighter one, which the
+		// attempts and waits below are already bound by
+		if o.Budget > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), o.Budget)
+			response, err := o.retry(r.WithContext(ctx), next)
+			return cancelWithResponse(response, err, cancel)
+		}
+
+		return o.retry(r, next)
+	}
+}
+
+// retry performs the attempts of the transaction until one succeeds or retries aren't worth it anymore.
+// A transaction which still fails is logged once along with the number of attempts made.
+func (o RetryOptions) retry(r *http.Request, next func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	response, err := o.attempt(r, next)
+	attempts := 1
+
+	// failed attempts of other transactions may have been partially applied
+	retryable := !o.IdempotentOnly || isIdempotent(r)
+	if err != nil && !retryable {
+		logging.Debug(o.Logger).Log(logging.MessageKey(), "not retrying non idempotent transaction")
+	}
+
+	for ; retryable && attempts <= o.Retries && err != nil && o.ShouldRetry(err); attempts++ {
+		wait := o.wait(attempts - 1)
+
+		// there's no point in retrying if the time budget runs out while waiting
+		if left, ok := remaining(r); ok && left <= wait {
+			logging.Debug(o.Logger).Log(logging.MessageKey(), "request time budget leaves no time for retries", "attempt", attempts, "wait", wait)
+			break
+		}
+
+		if retries, ok := r.Context().Value(ContextKeyRetryCount).(*int32); ok {
+			atomic.AddInt32(retries, 1)
+		}
+
+		logging.Debug(o.Logger).Log(logging.MessageKey(), "retrying transaction", "attempt", attempts, "wait", wait, logging.ErrorKey(), err)
+		drainResponse(response)
+		if err = o.sleep(r.Context(), wait); err != nil {
+			// the request was abandoned while waiting
+			return nil, err
+		}
+
+		if err := xhttp.Rewind(r); err != nil {
+			return nil, err
+		}
+
+		response, err = o.attempt(r, next)
+	}
+
+	if err != nil {
+		if attempts == 1 {
+			logging.Error(o.Logger).Log(logging.MessageKey(), "transaction failed without being retried", logging.ErrorKey(), err)
+		} else {
+			logging.Error(o.Logger).Log(logging.MessageKey(), "all transaction attempts failed", "attempts", attempts, logging.ErrorKey(), err)
+		}
+		drainResponse(response)
+		return nil, err
+	}
+
+	return response, err
+}
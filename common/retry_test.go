@@ -0,0 +1,478 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBackoffStrategy(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    BackoffStrategy
+		expectedErr bool
+	}{
+		{name: "Empty", value: "", expected: BackoffConstant},
+		{name: "Constant", value: "constant", expected: BackoffConstant},
+		{name: "Exponential", value: "exponential", expected: BackoffExponential},
+		{name: "Unsupported", value: "fibonacci", expectedErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			b, err := ParseBackoffStrategy(test.value)
+			assert.Equal(test.expected, b)
+			assert.Equal(test.expectedErr, err != nil)
+		})
+	}
+}
+
+func TestRetryInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  RetryOptions
+		expected []time.Duration
+	}{
+		{
+			name:     "Constant",
+			options:  RetryOptions{Interval: time.Second},
+			expected: []time.Duration{time.Second, time.Second, time.Second},
+		},
+		{
+			name:     "Exponential",
+			options:  RetryOptions{Interval: time.Second, Backoff: BackoffExponential},
+			expected: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second},
+		},
+		{
+			name:     "ExponentialCapped",
+			options:  RetryOptions{Interval: time.Second, Backoff: BackoffExponential, MaxInterval: 5 * time.Second},
+			expected: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second},
+		},
+		{
+			name:     "ConstantCapped",
+			options:  RetryOptions{Interval: 3 * time.Second, MaxInterval: time.Second},
+			expected: []time.Duration{time.Second, time.Second},
+		},
+		{
+			name:     "ExponentialNoOverflow",
+			options:  RetryOptions{Interval: time.Hour, Backoff: BackoffExponential},
+			expected: []time.Duration{time.Hour, 2 * time.Hour},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			for attempt, expected := range test.expected {
+				assert.Equal(expected, test.options.interval(attempt), "attempt %d", attempt)
+			}
+		})
+	}
+
+	t.Run("ExponentialLargeAttempt", func(t *testing.T) {
+		o := RetryOptions{Interval: time.Hour, Backoff: BackoffExponential}
+		assert.True(t, o.interval(100) > 0)
+	})
+}
+
+func TestRetryWait(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  RetryOptions
+		expected time.Duration
+	}{
+		{
+			name:     "NoJitter",
+			options:  RetryOptions{Interval: time.Second, Rand: func() float64 { return 0.9 }},
+			expected: time.Second,
+		},
+		{
+			name:     "LowerBound",
+			options:  RetryOptions{Interval: time.Second, Jitter: 500 * time.Millisecond, Rand: func() float64 { return 0 }},
+			expected: 500 * time.Millisecond,
+		},
+		{
+			name:     "Centered",
+			options:  RetryOptions{Interval: time.Second, Jitter: 500 * time.Millisecond, Rand: func() float64 { return 0.5 }},
+			expected: time.Second,
+		},
+		{
+			name:     "UpperHalf",
+			options:  RetryOptions{Interval: time.Second, Jitter: 500 * time.Millisecond, Rand: func() float64 { return 0.75 }},
+			expected: 1250 * time.Millisecond,
+		},
+		{
+			name:     "NeverNegative",
+			options:  RetryOptions{Interval: time.Second, Jitter: 5 * time.Second, Rand: func() float64 { return 0 }},
+			expected: 0,
+		},
+		{
+			name:     "Capped",
+			options:  RetryOptions{Interval: time.Second, MaxInterval: time.Second, Jitter: time.Second, Rand: func() float64 { return 0.99 }},
+			expected: time.Second,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.options.wait(0))
+		})
+	}
+}
+
+func TestRetryTransactor(t *testing.T) {
+	t.Run("Disabled", func(t *testing.T) {
+		assert := assert.New(t)
+		calls := 0
+		do := RetryTransactor(RetryOptions{}, func(*http.Request) (*http.Response, error) {
+			calls++
+			return nil, &net.DNSError{IsTemporary: true}
+		})
+
+		_, err := do(httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+		assert.NotNil(err)
+		assert.Equal(1, calls)
+	})
+
+	t.Run("ExponentialSleeps", func(t *testing.T) {
+		assert := assert.New(t)
+		var (
+			calls  int
+			sleeps []time.Duration
+		)
+
+		do := RetryTransactor(RetryOptions{
+			Retries:  3,
+			Interval: time.Second,
+			Backoff:  BackoffExponential,
+			Sleep:    func(d time.Duration) { sleeps = append(sleeps, d) },
+		}, func(*http.Request) (*http.Response, error) {
+			calls++
+			return nil, &net.DNSError{IsTemporary: true}
+		})
+
+		_, err := do(httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+		assert.NotNil(err)
+		assert.Equal(4, calls)
+		assert.Equal([]time.Duration{time.Second, 2 * time.Second, 4 * time.Second}, sleeps)
+	})
+
+	t.Run("JitteredSleeps", func(t *testing.T) {
+		assert := assert.New(t)
+		var sleeps []time.Duration
+
+		do := RetryTransactor(RetryOptions{
+			Retries:  2,
+			Interval: time.Second,
+			Jitter:   time.Second,
+			Rand:     func() float64 { return 0.25 },
+			Sleep:    func(d time.Duration) { sleeps = append(sleeps, d) },
+		}, func(*http.Request) (*http.Response, error) {
+			return nil, &net.DNSError{IsTemporary: true}
+		})
+
+		do(httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+		assert.Equal([]time.Duration{500 * time.Millisecond, 500 * time.Millisecond}, sleeps)
+	})
+
+	t.Run("NonTemporaryError", func(t *testing.T) {
+		assert := assert.New(t)
+		calls := 0
+		do := RetryTransactor(RetryOptions{
+			Retries: 3,
+			Sleep:   func(time.Duration) {},
+		}, func(*http.Request) (*http.Response, error) {
+			calls++
+			return nil, errors.New("permanent")
+		})
+
+		_, err := do(httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+		assert.NotNil(err)
+		assert.Equal(1, calls)
+	})
+
+	t.Run("EventualSuccess", func(t *testing.T) {
+		assert := assert.New(t)
+		calls := 0
+		do := RetryTransactor(RetryOptions{
+			Retries: 3,
+			Sleep:   func(time.Duration) {},
+		}, func(*http.Request) (*http.Response, error) {
+			calls++
+			if calls < 2 {
+				return nil, &net.DNSError{IsTemporary: true}
+			}
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		})
+
+		var retries int32
+		r := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+		r = r.WithContext(context.WithValue(r.Context(), ContextKeyRetryCount, &retries))
+
+		resp, err := do(r)
+		assert.Nil(err)
+		assert.Equal(http.StatusOK, resp.StatusCode)
+		assert.Equal(2, calls)
+		assert.EqualValues(1, retries)
+	})
+
+	t.Run("TimeoutBudget", func(t *testing.T) {
+		assert := assert.New(t)
+		var timeouts []time.Duration
+
+		do := RetryTransactor(RetryOptions{
+			Retries:        10,
+			AttemptTimeout: 100 * time.Millisecond,
+			Sleep:          func(time.Duration) {},
+		}, func(r *http.Request) (*http.Response, error) {
+			deadline, _ := r.Context().Deadline()
+			timeouts = append(timeouts, time.Until(deadline))
+			<-r.Context().Done()
+			return nil, &net.DNSError{IsTimeout: true, IsTemporary: true}
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := do(httptest.NewRequest(http.MethodGet, "http://localhost", nil).WithContext(ctx))
+
+		assert.NotNil(err)
+		assert.True(time.Since(start) < 300*time.Millisecond)
+		assert.True(len(timeouts) >= 3)
+		for _, timeout := range timeouts {
+			assert.True(timeout <= 100*time.Millisecond)
+		}
+	})
+
+	t.Run("NoBudgetForRetry", func(t *testing.T) {
+		assert := assert.New(t)
+		calls := 0
+
+		do := RetryTransactor(RetryOptions{
+			Retries:  3,
+			Interval: time.Minute,
+			Sleep:    func(time.Duration) { assert.Fail("retry should be skipped") },
+		}, func(*http.Request) (*http.Response, error) {
+			calls++
+			return nil, &net.DNSError{IsTemporary: true}
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		_, err := do(httptest.NewRequest(http.MethodGet, "http://localhost", nil).WithContext(ctx))
+		assert.NotNil(err)
+		assert.Equal(1, calls)
+	})
+
+	t.Run("RetryBudget", func(t *testing.T) {
+		assert := assert.New(t)
+
+		var waits []time.Duration
+		do := RetryTransactor(RetryOptions{
+			Retries:  3,
+			Interval: 50 * time.Millisecond,
+			Budget:   120 * time.Millisecond,
+			Sleep: func(d time.Duration) {
+				waits = append(waits, d)
+				time.Sleep(d)
+			},
+		}, func(r *http.Request) (*http.Response, error) {
+			deadline, ok := r.Context().Deadline()
+			assert.True(ok)
+			assert.True(time.Until(deadline) <= 120*time.Millisecond)
+			return nil, &net.DNSError{IsTemporary: true}
+		})
+
+		// the request deadline is looser than the budget so the budget is what skips retries
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		_, err := do(httptest.NewRequest(http.MethodGet, "http://localhost", nil).WithContext(ctx))
+		assert.NotNil(err)
+		assert.Equal([]time.Duration{50 * time.Millisecond, 50 * time.Millisecond}, waits)
+	})
+
+	t.Run("RetryBudgetOutlivesResponse", func(t *testing.T) {
+		assert := assert.New(t)
+		var budgetCtx context.Context
+
+		do := RetryTransactor(RetryOptions{
+			Retries: 1,
+			Budget:  time.Minute,
+		}, func(r *http.Request) (*http.Response, error) {
+			budgetCtx = r.Context()
+			return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("body"))}, nil
+		})
+
+		resp, err := do(httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+		assert.Nil(err)
+		assert.Nil(budgetCtx.Err())
+
+		resp.Body.Close()
+		assert.NotNil(budgetCtx.Err())
+	})
+
+	t.Run("AttemptContextOutlivesResponse", func(t *testing.T) {
+		assert := assert.New(t)
+		var attemptCtx context.Context
+
+		do := RetryTransactor(RetryOptions{
+			Retries:        1,
+			AttemptTimeout: time.Minute,
+		}, func(r *http.Request) (*http.Response, error) {
+			attemptCtx = r.Context()
+			return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("body"))}, nil
+		})
+
+		resp, err := do(httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+		assert.Nil(err)
+		assert.Nil(attemptCtx.Err())
+
+		resp.Body.Close()
+		assert.NotNil(attemptCtx.Err())
+	})
+}
+
+func TestRetryCancelledWhileWaiting(t *testing.T) {
+	assert := assert.New(t)
+
+	calls := 0
+	do := RetryTransactor(RetryOptions{
+		Retries:  3,
+		Interval: time.Minute,
+	}, func(*http.Request) (*http.Response, error) {
+		calls++
+		return nil, &net.DNSError{IsTemporary: true}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	response, err := do(httptest.NewRequest(http.MethodGet, "http://localhost", nil).WithContext(ctx))
+
+	assert.Nil(response)
+	assert.Equal(context.Canceled, err)
+	assert.Equal(1, calls)
+	assert.True(time.Since(start) < 5*time.Second)
+}
+
+func TestRetryDrainsFailedResponses(t *testing.T) {
+	assert := assert.New(t)
+
+	var closed int
+	do := RetryTransactor(RetryOptions{
+		Retries: 2,
+		Sleep:   func(time.Duration) {},
+	}, func(*http.Request) (*http.Response, error) {
+		return &http.Response{Body: &closeCounter{ReadCloser: ioutil.NopCloser(strings.NewReader("partial")), closed: &closed}},
+			&net.DNSError{IsTemporary: true}
+	})
+
+	response, err := do(httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+	assert.Nil(response)
+	assert.NotNil(err)
+	assert.Equal(3, closed)
+}
+
+type closeCounter struct {
+	io.ReadCloser
+	closed *int
+}
+
+func (c *closeCounter) Close() error {
+	*c.closed++
+	return c.ReadCloser.Close()
+}
+
+func TestRetryIdempotentOnly(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		idempotent    *bool
+		expectedCalls int
+	}{
+		{name: "Get", method: http.MethodGet, expectedCalls: 3},
+		{name: "Post", method: http.MethodPost, expectedCalls: 1},
+		{name: "IdempotentPost", method: http.MethodPost, idempotent: boolPtr(true), expectedCalls: 3},
+		{name: "NonIdempotentGet", method: http.MethodGet, idempotent: boolPtr(false), expectedCalls: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			calls := 0
+			do := RetryTransactor(RetryOptions{
+				Retries:        2,
+				IdempotentOnly: true,
+				Sleep:          func(time.Duration) {},
+			}, func(*http.Request) (*http.Response, error) {
+				calls++
+				return nil, &net.DNSError{IsTemporary: true}
+			})
+
+			r := httptest.NewRequest(test.method, "http://localhost", strings.NewReader("wrp"))
+			if test.idempotent != nil {
+				r = r.WithContext(context.WithValue(r.Context(), ContextKeyIdempotent, *test.idempotent))
+			}
+
+			response, err := do(r)
+			assert.Nil(response)
+			assert.NotNil(err)
+			assert.Equal(test.expectedCalls, calls)
+		})
+	}
+}
+
+func TestRetryLogsFailure(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		expectedLog string
+	}{
+		{name: "Retried", err: &net.DNSError{IsTemporary: true}, expectedLog: `msg="all transaction attempts failed" attempts=3`},
+		{name: "NotRetryable", err: errors.New("invalid request"), expectedLog: `msg="transaction failed without being retried"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var buf bytes.Buffer
+			do := RetryTransactor(RetryOptions{
+				Logger:  kitlog.NewLogfmtLogger(&buf),
+				Retries: 2,
+				Sleep:   func(time.Duration) {},
+			}, func(*http.Request) (*http.Response, error) {
+				return nil, test.err
+			})
+
+			_, err := do(httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+			assert.NotNil(err)
+
+			// the final error is logged once
+			assert.Equal(1, strings.Count(buf.String(), "level=error"))
+			assert.Contains(buf.String(), test.expectedLog)
+		})
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,90 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"github.com/xmidt-org/webpa-common/device"
+)
+
+// DeviceIDSchemes restricts the schemes (i.e. "mac", "uuid") of the device ids requests may address
+type DeviceIDSchemes struct {
+	allowed  map[string]bool
+	partners map[string]map[string]bool
+}
+
+func schemeSet(schemes []string) map[string]bool {
+	set := make(map[string]bool, len(schemes))
+	for _, s := range schemes {
+		set[strings.ToLower(s)] = true
+	}
+	return set
+}
+
+// NewDeviceIDSchemes is the constructor for DeviceIDSchemes. allowed are the schemes all partners may use
+// and an empty list allows them all. partners lists the schemes specific partners (case-insensitive) may use
+// instead of the allowed ones. nil is returned when there's nothing to restrict.
+func NewDeviceIDSchemes(allowed []string, partners map[string][]string) *DeviceIDSchemes {
+	if len(allowed) == 0 && len(partners) == 0 {
+		return nil
+	}
+
+	d := &DeviceIDSchemes{partners: make(map[string]map[string]bool, len(partners))}
+	if len(allowed) > 0 {
+		d.allowed = schemeSet(allowed)
+	}
+
+	for partner, schemes := range partners {
+		d.partners[strings.ToLower(partner)] = schemeSet(schemes)
+	}
+
+	return d
+}
+
+// allows reports whether partner may address devices through scheme. The schemes of the partner
+// take precedence over the globally allowed ones.
+func (d *DeviceIDSchemes) allows(partner, scheme string) bool {
+	if schemes, ok := d.partners[strings.ToLower(partner)]; ok {
+		return schemes[scheme]
+	}
+
+	return d.allowed == nil || d.allowed[scheme]
+}
+
+// Check fails with a 403 if the partner of r isn't allowed to address the device with the given id. It's a no-op
+// for nil schemes
+func (d *DeviceIDSchemes) Check(r *http.Request, id device.ID) error {
+	if d == nil {
+		return nil
+	}
+
+	scheme := strings.SplitN(string(id), ":", 2)[0]
+	if !d.allows(partnerID(r), scheme) {
+		return NewCodedError(fmt.Errorf("device id scheme '%s' is not allowed", scheme), http.StatusForbidden)
+	}
+
+	return nil
+}
+
+// RestrictDeviceIDSchemes decorates decoder such that requests addressing devices (the "deviceid" path variable)
+// through a scheme their partner isn't allowed to use are rejected with a 403. Requests with malformed device
+// ids are left to decoder. A nil schemes returns decoder as is.
+func RestrictDeviceIDSchemes(schemes *DeviceIDSchemes, decoder kithttp.DecodeRequestFunc) kithttp.DecodeRequestFunc {
+	if schemes == nil {
+		return decoder
+	}
+
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		if id, err := device.ParseID(mux.Vars(r)["deviceid"]); err == nil {
+			if err := schemes.Check(r, id); err != nil {
+				return nil, err
+			}
+		}
+
+		return decoder(ctx, r)
+	}
+}
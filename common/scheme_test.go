@@ -0,0 +1,69 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDeviceIDSchemes(t *testing.T) {
+	assert.Nil(t, NewDeviceIDSchemes(nil, nil))
+	assert.NotNil(t, NewDeviceIDSchemes(nil, map[string][]string{"comcast": {"mac"}}))
+}
+
+func TestRestrictDeviceIDSchemes(t *testing.T) {
+	schemes := NewDeviceIDSchemes([]string{"mac", "uuid"}, map[string][]string{
+		"Comcast": {"MAC"},
+		"sky":     {"mac", "serial"},
+	})
+
+	tests := []struct {
+		name         string
+		schemes      *DeviceIDSchemes
+		partner      string
+		deviceID     string
+		expectedCode int
+	}{
+		{name: "Unrestricted", deviceID: "serial:1234"},
+		{name: "GlobalAllowed", schemes: schemes, partner: "acme", deviceID: "uuid:1234"},
+		{name: "GlobalDisallowed", schemes: schemes, partner: "acme", deviceID: "serial:1234", expectedCode: http.StatusForbidden},
+		{name: "NoPartner", schemes: schemes, deviceID: "mac:112233445566"},
+		{name: "PartnerAllowed", schemes: schemes, partner: "comcast", deviceID: "MAC:11:22:33:44:55:66"},
+		{name: "PartnerDisallowed", schemes: schemes, partner: "comcast", deviceID: "uuid:1234", expectedCode: http.StatusForbidden},
+		{name: "PartnerOverridesGlobal", schemes: schemes, partner: "sky", deviceID: "serial:1234"},
+		{name: "MalformedID", schemes: schemes, partner: "comcast", deviceID: "nope"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			r := httptest.NewRequest(http.MethodGet, "http://localhost/api/v2/device", nil)
+			r = mux.SetURLVars(r, map[string]string{"deviceid": test.deviceID})
+			if test.partner != "" {
+				r.Header.Set(HeaderWebpaPartnerID, test.partner)
+			}
+
+			var decoded bool
+			_, err := RestrictDeviceIDSchemes(test.schemes, func(_ context.Context, _ *http.Request) (interface{}, error) {
+				decoded = true
+				return nil, nil
+			})(context.Background(), r)
+
+			if test.expectedCode == 0 {
+				assert.Nil(err)
+				assert.True(decoded)
+				return
+			}
+
+			if assert.NotNil(err) {
+				assert.Equal(test.expectedCode, err.(CodedError).StatusCode())
+			}
+			assert.False(decoded)
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Snapshot holds the settings which can be reloaded at runtime (i.e. on SIGHUP)
+type Snapshot struct {
+	// ValidServices are the services the translation endpoints accept
+	ValidServices []string
+
+	// ReducedLoggingResponseCodes are the response codes for which transactions are logged without headers
+	ReducedLoggingResponseCodes []int
+
+	// ReducedLoggingPaths are the patterns of request paths for which transactions are logged without headers,
+	// regardless of the response code
+	ReducedLoggingPaths []*regexp.Regexp
+
+	// ParameterAllowList are the patterns TR-181 parameter names must match for translation requests to go through.
+	// All parameters are allowed when empty
+	ParameterAllowList []*regexp.Regexp
+}
+
+// CompileReducedLoggingPaths compiles the given regular expressions for Snapshot.ReducedLoggingPaths
+func CompileReducedLoggingPaths(patterns []string) ([]*regexp.Regexp, error) {
+	var paths []*regexp.Regexp
+	for _, pattern := range patterns {
+		r, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reduced logging path pattern '%s': %v", pattern, err)
+		}
+		paths = append(paths, r)
+	}
+	return paths, nil
+}
+
+// ParseResponseCodes expands the given entries into the sorted, deduplicated response codes of
+// Snapshot.ReducedLoggingResponseCodes. Entries are either single codes (i.e. "200"), classes (i.e. "2xx")
+// or inclusive ranges (i.e. "200-299").
+func ParseResponseCodes(entries []string) ([]int, error) {
+	set := make(map[int]bool)
+	for _, entry := range entries {
+		first, last, err := parseResponseCodeRange(strings.TrimSpace(entry))
+		if err != nil {
+			return nil, fmt.Errorf("invalid response code entry '%s': %v", entry, err)
+		}
+
+		for code := first; code <= last; code++ {
+			set[code] = true
+		}
+	}
+
+	var codes []int
+	for code := range set {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	return codes, nil
+}
+
+func parseResponseCodeRange(entry string) (first, last int, err error) {
+	lower := strings.ToLower(entry)
+	switch {
+	case len(lower) == 3 && strings.HasSuffix(lower, "xx"):
+		if lower[0] < '1' || lower[0] > '5' {
+			return 0, 0, fmt.Errorf("unknown response code class")
+		}
+		first = int(lower[0]-'0') * 100
+		return first, first + 99, nil
+
+	case strings.Contains(entry, "-"):
+		bounds := strings.SplitN(entry, "-", 2)
+		if first, err = parseResponseCode(bounds[0]); err != nil {
+			return
+		}
+		if last, err = parseResponseCode(bounds[1]); err != nil {
+			return
+		}
+		if first > last {
+			err = fmt.Errorf("range start is greater than its end")
+		}
+		return
+
+	default:
+		first, err = parseResponseCode(entry)
+		return first, first, err
+	}
+}
+
+func parseResponseCode(s string) (int, error) {
+	code, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("'%s' is not a number", s)
+	}
+	if code < 100 || code > 599 {
+		return 0, fmt.Errorf("%d is not between 100 and 599", code)
+	}
+	return code, nil
+}
+
+// Settings hands out the current Snapshot. Reloads swap the Snapshot as a whole so that each request
+// observes a consistent set of settings. A nil *Settings hands out an empty Snapshot.
+type Settings struct {
+	current atomic.Value
+}
+
+// NewSettings is the constructor for Settings
+func NewSettings(s Snapshot) *Settings {
+	settings := new(Settings)
+	settings.Store(s)
+	return settings
+}
+
+// Load returns the current Snapshot
+func (s *Settings) Load() Snapshot {
+	if s == nil {
+		return Snapshot{}
+	}
+	return s.current.Load().(Snapshot)
+}
+
+// Store makes snapshot the current Snapshot
+func (s *Settings) Store(snapshot Snapshot) {
+	s.current.Store(snapshot)
+}
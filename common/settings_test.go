@@ -0,0 +1,106 @@
+package common
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSettings(t *testing.T) {
+	t.Run("Nil", func(t *testing.T) {
+		var s *Settings
+		assert.Equal(t, Snapshot{}, s.Load())
+	})
+
+	t.Run("Store", func(t *testing.T) {
+		assert := assert.New(t)
+
+		s := NewSettings(Snapshot{ValidServices: []string{"config"}, ReducedLoggingResponseCodes: []int{200}})
+		assert.Equal([]string{"config"}, s.Load().ValidServices)
+
+		s.Store(Snapshot{ValidServices: []string{"config", "stat"}})
+		assert.Equal(Snapshot{ValidServices: []string{"config", "stat"}}, s.Load())
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		var (
+			a  = Snapshot{ValidServices: []string{"a"}, ReducedLoggingResponseCodes: []int{1}}
+			b  = Snapshot{ValidServices: []string{"b"}, ReducedLoggingResponseCodes: []int{2}}
+			s  = NewSettings(a)
+			wg sync.WaitGroup
+		)
+
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				if i%2 == 0 {
+					s.Store(b)
+				} else {
+					s.Store(a)
+				}
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				snapshot := s.Load()
+				if snapshot.ValidServices[0] == "a" {
+					assert.Equal(t, a, snapshot)
+				} else {
+					assert.Equal(t, b, snapshot)
+				}
+			}
+		}()
+
+		wg.Wait()
+	})
+}
+
+func TestParseResponseCodes(t *testing.T) {
+	tests := []struct {
+		name          string
+		entries       []string
+		expected      []int
+		expectedError string
+	}{
+		{name: "Empty"},
+		{name: "Codes", entries: []string{"504", "200"}, expected: []int{200, 504}},
+		{name: "Class", entries: []string{"1xx"}, expected: rangeOf(100, 199)},
+		{name: "UpperCaseClass", entries: []string{"3XX"}, expected: rangeOf(300, 399)},
+		{name: "Range", entries: []string{"200-204"}, expected: rangeOf(200, 204)},
+		{name: "Overlaps", entries: []string{"2xx", "204", "250-301", " 302 "}, expected: rangeOf(200, 302)},
+		{name: "NotANumber", entries: []string{"ok"}, expectedError: "invalid response code entry 'ok': 'ok' is not a number"},
+		{name: "UnknownClass", entries: []string{"6xx"}, expectedError: "invalid response code entry '6xx': unknown response code class"},
+		{name: "OutOfBounds", entries: []string{"200-600"}, expectedError: "invalid response code entry '200-600': 600 is not between 100 and 599"},
+		{name: "Reversed", entries: []string{"299-200"}, expectedError: "invalid response code entry '299-200': range start is greater than its end"},
+		{name: "OpenRange", entries: []string{"200-"}, expectedError: "invalid response code entry '200-': '' is not a number"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			codes, err := ParseResponseCodes(test.entries)
+			if test.expectedError != "" {
+				if assert.NotNil(err) {
+					assert.Equal(test.expectedError, err.Error())
+				}
+				return
+			}
+
+			assert.Nil(err)
+			assert.Equal(test.expected, codes)
+		})
+	}
+}
+
+func rangeOf(first, last int) []int {
+	var codes []int
+	for code := first; code <= last; code++ {
+		codes = append(codes, code)
+	}
+	return codes
+}
@@ -0,0 +1,81 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+// Headers to override the timeout of requests to the XMiDT API
+const (
+	// HeaderTr1d1umTimeout is the request header through which clients ask for a specific timeout (a Go duration string)
+	HeaderTr1d1umTimeout = "X-Tr1d1um-Timeout"
+
+	// HeaderTr1d1umTimeoutClamped is the response header which reports the timeout applied
+	// when the requested one was out of the allowed range
+	HeaderTr1d1umTimeoutClamped = "X-Tr1d1um-Timeout-Clamped"
+)
+
+// RequestTimeoutOptions are the configuration options for RequestTimeout
+type RequestTimeoutOptions struct {
+	//Min is the lowest timeout clients can ask for
+	Min time.Duration
+
+	//Max is the highest timeout clients can ask for. A non-positive value disables the override
+	Max time.Duration
+
+	//Logger is used to report clamped timeouts
+	//(Optional) defaults to the webpa-common default logger
+	Logger kitlog.Logger
+}
+
+// RequestTimeout is an Alice-style constructor which honors client requested timeouts through
+// the X-Tr1d1um-Timeout header. Requested timeouts out of [Min, Max] are clamped and the applied
+// value is reported through the X-Tr1d1um-Timeout-Clamped response header.
+// Missing or unparsable values leave the default timeout in place.
+func RequestTimeout(o RequestTimeoutOptions) func(http.Handler) http.Handler {
+	if o.Logger == nil {
+		o.Logger = logging.DefaultLogger()
+	}
+
+	return func(delegate http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				value := r.Header.Get(HeaderTr1d1umTimeout)
+				if o.Max <= 0 || value == "" {
+					delegate.ServeHTTP(w, r)
+					return
+				}
+
+				timeout, err := time.ParseDuration(value)
+				if err != nil || timeout <= 0 {
+					delegate.ServeHTTP(w, r)
+					return
+				}
+
+				if clamped := clamp(timeout, o.Min, o.Max); clamped != timeout {
+					logging.Warn(o.Logger).Log(logging.MessageKey(), "requested timeout is out of range. Clamping it",
+						"requested", timeout, "min", o.Min, "max", o.Max, "applied", clamped)
+					w.Header().Set(HeaderTr1d1umTimeoutClamped, clamped.String())
+					timeout = clamped
+				}
+
+				delegate.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ContextKeyRequestTimeout, timeout)))
+			})
+	}
+}
+
+func clamp(d, min, max time.Duration) time.Duration {
+	if d < min {
+		return min
+	}
+
+	if d > max {
+		return max
+	}
+
+	return d
+}
@@ -0,0 +1,52 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	tests := []struct {
+		name            string
+		min, max        time.Duration
+		header          string
+		expectedTimeout interface{}
+		expectedClamped string
+	}{
+		{name: "NoHeader", min: time.Second, max: time.Minute},
+		{name: "Disabled", header: "10s"},
+		{name: "Unparsable", min: time.Second, max: time.Minute, header: "ten seconds"},
+		{name: "Negative", min: time.Second, max: time.Minute, header: "-10s"},
+		{name: "InRange", min: time.Second, max: time.Minute, header: "10s", expectedTimeout: 10 * time.Second},
+		{name: "ClampedToMax", min: time.Second, max: time.Minute, header: "10m", expectedTimeout: time.Minute, expectedClamped: "1m0s"},
+		{name: "ClampedToMin", min: time.Second, max: time.Minute, header: "1ms", expectedTimeout: time.Second, expectedClamped: "1s"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var actualTimeout interface{}
+			handler := RequestTimeout(RequestTimeoutOptions{Min: test.min, Max: test.max, Logger: logging.NewTestLogger(nil, t)})(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					actualTimeout = r.Context().Value(ContextKeyRequestTimeout)
+				}))
+
+			r := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+			if test.header != "" {
+				r.Header.Set(HeaderTr1d1umTimeout, test.header)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, r)
+
+			assert.Equal(test.expectedTimeout, actualTimeout)
+			assert.Equal(test.expectedClamped, recorder.Header().Get(HeaderTr1d1umTimeoutClamped))
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/xmidt-org/webpa-common/logging"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/stdout"
+	"go.opentelemetry.io/otel/exporters/trace/zipkin"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Supported tracing exporters
+const (
+	TracingExporterZipkin = "zipkin"
+	TracingExporterStdout = "stdout"
+)
+
+// defaultTracingServiceName is the default name tr1d1um is known by in traces
+const defaultTracingServiceName = "tr1d1um"
+
+// tracerName identifies the spans created by tr1d1um itself, as opposed to those of the HTTP instrumentation
+const tracerName = "github.com/xmidt-org/tr1d1um"
+
+// TracingConfig configures the OpenTelemetry tracing of requests
+type TracingConfig struct {
+	// Exporter is where spans are sent: "zipkin" or "stdout". Tracing is disabled when empty
+	Exporter string
+
+	// Endpoint is the URL of the collector spans are sent to. Required by the zipkin exporter
+	Endpoint string
+
+	// SampleRate is the fraction of the traces started by tr1d1um which are sampled. Traces started upstream
+	// follow the sampling decision of their parent. It defaults to 1 when not positive
+	SampleRate float64
+
+	// ServiceName is the name tr1d1um is known by in traces. It defaults to "tr1d1um"
+	ServiceName string
+}
+
+// Tracing traces requests through tr1d1um and into XMiDT. A nil *Tracing traces nothing so tracing
+// can be left out of request paths at no cost.
+type Tracing struct {
+	provider   *sdktrace.TracerProvider
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// tracingErrorHandler logs the errors OpenTelemetry runs into in the background, such as failed exports
+type tracingErrorHandler struct {
+	logger kitlog.Logger
+}
+
+func (h tracingErrorHandler) Handle(err error) {
+	// some resource detectors report nil errors
+	if err != nil {
+		logging.Error(h.logger).Log(logging.MessageKey(), "tracing error", logging.ErrorKey(), err)
+	}
+}
+
+// NewTracing is the constructor for Tracing. nil is returned when no exporter is configured.
+// logger reports the errors of the exporter and is optional.
+func NewTracing(c TracingConfig, logger kitlog.Logger) (*Tracing, error) {
+	var exporter sdktrace.SpanExporter
+	switch c.Exporter {
+	case "":
+		return nil, nil
+	case TracingExporterZipkin:
+		e, err := zipkin.NewRawExporter(c.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		exporter = e
+	case TracingExporterStdout:
+		e, err := stdout.NewExporter(stdout.WithWriter(os.Stdout), stdout.WithoutMetricExport())
+		if err != nil {
+			return nil, err
+		}
+		exporter = e
+	default:
+		return nil, fmt.Errorf("unsupported tracing exporter '%s'", c.Exporter)
+	}
+
+	if logger == nil {
+		logger = logging.DefaultLogger()
+	}
+
+	otel.SetErrorHandler(tracingErrorHandler{logger: logger})
+
+	sampleRate := c.SampleRate
+	if sampleRate <= 0 {
+		sampleRate = 1
+	}
+
+	serviceName := c.ServiceName
+	if serviceName == "" {
+		serviceName = defaultTracingServiceName
+	}
+
+	return newTracing(sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRate))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.ServiceNameKey.String(serviceName))),
+	)), nil
+}
+
+func newTracing(provider *sdktrace.TracerProvider) *Tracing {
+	return &Tracing{
+		provider:   provider,
+		tracer:     provider.Tracer(tracerName),
+		propagator: propagation.TraceContext{},
+	}
+}
+
+func (t *Tracing) options() []otelhttp.Option {
+	return []otelhttp.Option{
+		otelhttp.WithTracerProvider(t.provider),
+		otelhttp.WithPropagators(t.propagator),
+	}
+}
+
+// Then decorates delegate such that each request is covered by a server span, which continues the trace
+// of the traceparent header of the request if there's one.
+func (t *Tracing) Then(delegate http.Handler) http.Handler {
+	if t == nil {
+		return delegate
+	}
+
+	return otelhttp.NewHandler(delegate, defaultTracingServiceName, t.options()...)
+}
+
+// Transport decorates base such that each outbound request is covered by a client span and carries
+// the trace context in its traceparent header
+func (t *Tracing) Transport(base http.RoundTripper) http.RoundTripper {
+	if t == nil {
+		return base
+	}
+
+	return otelhttp.NewTransport(base, t.options()...)
+}
+
+// Span is an Alice-style constructor which covers the rest of the request by a span with the given name
+func (t *Tracing) Span(name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if t == nil {
+			return next
+		}
+
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				ctx, span := t.tracer.Start(r.Context(), name)
+				defer span.End()
+				next.ServeHTTP(w, r.WithContext(ctx))
+			})
+	}
+}
+
+type stageParentContextKey struct{}
+
+// Stage decorates the constructor such that the work it does before calling its delegate (i.e. authentication)
+// is covered by a span with the given name. The span ends as soon as the delegate is called so the handlers which
+// follow the stage aren't part of it.
+func (t *Tracing) Stage(name string, stage func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	if t == nil {
+		return stage
+	}
+
+	return func(next http.Handler) http.Handler {
+		staged := stage(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				trace.SpanFromContext(r.Context()).End()
+
+				// what follows the stage is a sibling of its span rather than a child
+				parent, _ := r.Context().Value(stageParentContextKey{}).(trace.Span)
+				next.ServeHTTP(w, r.WithContext(trace.ContextWithSpan(r.Context(), parent)))
+			}))
+
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				ctx := context.WithValue(r.Context(), stageParentContextKey{}, trace.SpanFromContext(r.Context()))
+				ctx, span := t.tracer.Start(ctx, name)
+
+				// ending the span again is a no-op when the stage called its delegate
+				defer span.End()
+				staged.ServeHTTP(w, r.WithContext(ctx))
+			})
+	}
+}
+
+// Shutdown exports the pending spans
+func (t *Tracing) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+
+	return t.provider.Shutdown(ctx)
+}
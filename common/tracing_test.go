@@ -0,0 +1,112 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestNewTracing(t *testing.T) {
+	assert := assert.New(t)
+
+	tracing, err := NewTracing(TracingConfig{}, nil)
+	assert.Nil(tracing)
+	assert.Nil(err)
+
+	tracing, err = NewTracing(TracingConfig{Exporter: TracingExporterZipkin, Endpoint: "http://zipkin:9411/api/v2/spans"}, nil)
+	assert.NotNil(tracing)
+	assert.Nil(err)
+
+	_, err = NewTracing(TracingConfig{Exporter: TracingExporterZipkin}, nil)
+	assert.NotNil(err)
+
+	_, err = NewTracing(TracingConfig{Exporter: "jaeger"}, nil)
+	assert.NotNil(err)
+}
+
+func TestTracingDisabled(t *testing.T) {
+	assert := assert.New(t)
+
+	var tracing *Tracing
+	delegate := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	assert.NotNil(tracing.Then(delegate))
+	assert.NotNil(tracing.Span("handle")(delegate))
+	assert.NotNil(tracing.Stage("authenticate", func(next http.Handler) http.Handler { return next })(delegate))
+	assert.Equal(http.DefaultTransport, tracing.Transport(http.DefaultTransport))
+	assert.Nil(tracing.Shutdown(context.Background()))
+}
+
+func TestTracing(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var (
+		exporter = tracetest.NewInMemoryExporter()
+		tracing  = newTracing(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	)
+
+	var traceparent string
+	xmidt := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer xmidt.Close()
+
+	client := &http.Client{Transport: tracing.Transport(http.DefaultTransport)}
+
+	authenticate := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	handler := tracing.Then(tracing.Stage("authenticate", authenticate)(tracing.Span("handle")(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, xmidt.URL, nil)
+			require.Nil(err)
+
+			resp, err := client.Do(req)
+			require.Nil(err)
+			resp.Body.Close()
+		}))))
+
+	r := httptest.NewRequest(http.MethodGet, "http://localhost/api/v2/device/mac:112233445566/stat", nil)
+	r.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := make(map[string]*sdktrace.SpanSnapshot)
+	for _, s := range exporter.GetSpans() {
+		spans[s.Name] = s
+	}
+
+	require.Len(spans, 4)
+	server, auth, handle, outbound := spans["tr1d1um"], spans["authenticate"], spans["handle"], spans["GET"]
+	require.NotNil(server)
+	require.NotNil(auth)
+	require.NotNil(handle)
+	require.NotNil(outbound)
+
+	// authentication and the service handling are separate spans of the request, which covers the outbound call
+	assert.Equal(server.SpanContext.SpanID(), auth.Parent.SpanID())
+	assert.Equal(server.SpanContext.SpanID(), handle.Parent.SpanID())
+	assert.Equal(handle.SpanContext.SpanID(), outbound.Parent.SpanID())
+	assert.True(auth.EndTime.Before(handle.StartTime) || auth.EndTime.Equal(handle.StartTime))
+
+	// XMiDT continues the trace
+	assert.Contains(traceparent, outbound.SpanContext.TraceID().String())
+	assert.Contains(traceparent, outbound.SpanContext.SpanID().String())
+
+	// rejected requests still end the stage span
+	exporter.Reset()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost/api/v2/device/mac:112233445566/stat", nil))
+	assert.Len(exporter.GetSpans(), 2)
+}
@@ -0,0 +1,86 @@
+package common
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+// HeaderTr1d1umTransactionID is the header key for the transaction id which correlates the logs
+// of a request across Tr1d1um and XMiDT
+const HeaderTr1d1umTransactionID = "X-Tr1d1um-Transaction-Id"
+
+// HeaderTr1d1umTransactionUUID is an alias of HeaderTr1d1umTransactionID for clients which name the id
+// after the WRP transaction uuid it becomes
+const HeaderTr1d1umTransactionUUID = "X-Tr1d1um-Transaction-Uuid"
+
+// maxTransactionIDLength is the length beyond which transaction ids sent by clients are replaced
+const maxTransactionIDLength = 128
+
+// validTransactionID reports whether a transaction id sent by a client may be echoed back, logged and used as
+// the WRP transaction uuid as is. Only ids of up to maxTransactionIDLength characters in [A-Za-z0-9-_.:] are.
+func validTransactionID(id string) bool {
+	if id == "" || len(id) > maxTransactionIDLength {
+		return false
+	}
+
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// TransactionID is an Alice-style constructor which reads the transaction id of incoming requests from
+// the HeaderTr1d1umTransactionID header or, in its absence, the HeaderTr1d1umTransactionUUID one. Without
+// either, the legacy HeaderWPATID header is used and a new id is generated when none is present. Ids which
+// are too long or have characters other than letters, digits, '-', '_', '.' and ':' are replaced by a new one. The id
+// is stored in the request context, where it doubles as the WRP transaction uuid, and echoed back in both
+// the HeaderTr1d1umTransactionID and HeaderTr1d1umTransactionUUID response headers.
+func TransactionID(delegate http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(HeaderTr1d1umTransactionID)
+			if id == "" {
+				id = r.Header.Get(HeaderTr1d1umTransactionUUID)
+			}
+
+			if id == "" {
+				id = r.Header.Get(HeaderWPATID)
+			}
+
+			if !validTransactionID(id) {
+				id = genUUID()
+			}
+
+			w.Header().Set(HeaderTr1d1umTransactionID, id)
+			w.Header().Set(HeaderTr1d1umTransactionUUID, id)
+			delegate.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ContextKeyTransactionID, id)))
+		})
+}
+
+// TransactionIDFromContext returns the transaction id of the request, if any
+func TransactionIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(ContextKeyTransactionID).(string)
+	return id, ok
+}
+
+// genUUID generates a random (version 4) UUID
+// it returns "N/A" in the extreme case the random bytes could not be generated
+func genUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "N/A"
+	}
+
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
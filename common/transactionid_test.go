@@ -0,0 +1,65 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionID(t *testing.T) {
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	tests := []struct {
+		name     string
+		id       string
+		uuid     string
+		legacy   string
+		expected string
+	}{
+		{name: "Propagated", id: "client-id", uuid: "client-uuid", legacy: "legacy-tid", expected: "client-id"},
+		{name: "UUID", uuid: "client-uuid", legacy: "legacy-tid", expected: "client-uuid"},
+		{name: "Legacy", legacy: "legacy-tid", expected: "legacy-tid"},
+		{name: "Generated"},
+		{name: "Characters", id: "client-id\nlevel=error msg=injected"},
+		{name: "TooLong", id: strings.Repeat("a", maxTransactionIDLength+1)},
+		{name: "InvalidLegacy", legacy: "legacy tid"},
+		{name: "AllowedCharacters", id: "Client_ID.v2:0-1", expected: "Client_ID.v2:0-1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var actual string
+			handler := TransactionID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				actual, _ = TransactionIDFromContext(r.Context())
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+			if test.id != "" {
+				r.Header.Set(HeaderTr1d1umTransactionID, test.id)
+			}
+			if test.uuid != "" {
+				r.Header.Set(HeaderTr1d1umTransactionUUID, test.uuid)
+			}
+			if test.legacy != "" {
+				r.Header.Set(HeaderWPATID, test.legacy)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, r)
+
+			if test.expected == "" {
+				assert.Regexp(uuidPattern, actual)
+			} else {
+				assert.Equal(test.expected, actual)
+			}
+			assert.Equal(actual, recorder.Header().Get(HeaderTr1d1umTransactionID))
+			assert.Equal(actual, recorder.Header().Get(HeaderTr1d1umTransactionUUID))
+		})
+	}
+}
@@ -1,12 +1,33 @@
 package common
 
 import (
+	"compress/gzip"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
+
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/xmidt-org/webpa-common/logging"
 )
 
+// ErrSlowResponse is returned when the XMiDT API response body trickles in below the configured minimum throughput
+var ErrSlowResponse = NewCodedError(errors.New("response from XMiDT API was too slow"), http.StatusGatewayTimeout)
+
+// ErrGatewayTLS is shown to API consumers when tr1d1um could not establish a TLS connection to the XMiDT API
+var ErrGatewayTLS = errors.New("TLS handshake with XMiDT API failed")
+
 // XmidtResponse represents the data that a tr1d1um transactor keeps from an HTTP request to
 // the XMiDT API
 type XmidtResponse struct {
@@ -17,6 +38,9 @@ type XmidtResponse struct {
 	//ForwardedHeaders contains all the headers tr1d1um keeps from the transaction
 	ForwardedHeaders http.Header
 
+	//ContentType is the Content-Type of the XMiDT response
+	ContentType string
+
 	//Body represents the full data off the XMiDT http.Response body
 	Body []byte
 }
@@ -36,41 +60,285 @@ type Tr1d1umTransactorOptions struct {
 
 	//Do is the core responsible to perform the actual HTTP request
 	Do func(*http.Request) (*http.Response, error)
+
+	//MinThroughput is the minimum rate (in bytes per second) at which the response body must be
+	//received. Reading is aborted if the rate stays below it for a full ThroughputWindow.
+	//(Optional) a non-positive value disables the guard
+	MinThroughput int64
+
+	//ThroughputWindow is the period over which MinThroughput is measured
+	ThroughputWindow time.Duration
+
+	//Logger is used to report operational problems such as TLS handshake failures against XMiDT
+	//(Optional) defaults to the webpa-common default logger
+	Logger kitlog.Logger
+
+	//TLSHandshakeFailures counts failed TLS handshakes against XMiDT
+	//(Optional)
+	TLSHandshakeFailures metrics.Counter
+
+	//DecompressResponses enables transparent decompression of gzip encoded response bodies
+	DecompressResponses bool
+
+	//Endpoint is the type of XMiDT endpoint requests are sent to (i.e. "stat") used to label metrics
+	Endpoint string
+
+	//TransactionLatency observes the duration of requests to XMiDT
+	//(Optional)
+	TransactionLatency metrics.Histogram
+
+	//ResponseHeaders selects the XMiDT response headers forwarded to clients
+	//(Optional) defaults to the headers with the "X" prefix
+	ResponseHeaders *HeaderFilter
+
+	//ConcurrencyLimiter caps the number of requests to XMiDT in flight. It may be shared across transactors
+	//(Optional)
+	ConcurrencyLimiter *ConcurrencyLimiter
+
+	//UserAgent is the User-Agent header of requests to XMiDT
+	//(Optional) requests keep the Go default one when empty
+	UserAgent string
 }
 
 func NewTr1d1umTransactor(o *Tr1d1umTransactorOptions) Tr1d1umTransactor {
-	return &tr1d1umTransactor{
-		Do:             o.Do,
-		RequestTimeout: o.RequestTimeout,
+	t := &tr1d1umTransactor{
+		Do:                   o.Do,
+		RequestTimeout:       o.RequestTimeout,
+		MinThroughput:        o.MinThroughput,
+		ThroughputWindow:     o.ThroughputWindow,
+		Logger:               o.Logger,
+		TLSHandshakeFailures: o.TLSHandshakeFailures,
+		DecompressResponses:  o.DecompressResponses,
+		Endpoint:             o.Endpoint,
+		TransactionLatency:   o.TransactionLatency,
+		ResponseHeaders:      o.ResponseHeaders,
+		ConcurrencyLimiter:   o.ConcurrencyLimiter,
+		UserAgent:            o.UserAgent,
+	}
+
+	if t.Logger == nil {
+		t.Logger = logging.DefaultLogger()
+	}
+
+	if t.TLSHandshakeFailures == nil {
+		t.TLSHandshakeFailures = discard.NewCounter()
+	}
+
+	if t.TransactionLatency == nil {
+		t.TransactionLatency = discard.NewHistogram()
 	}
+
+	return t
 }
 
 type tr1d1umTransactor struct {
-	RequestTimeout time.Duration
-	Do             func(*http.Request) (*http.Response, error)
+	RequestTimeout       time.Duration
+	Do                   func(*http.Request) (*http.Response, error)
+	MinThroughput        int64
+	ThroughputWindow     time.Duration
+	Logger               kitlog.Logger
+	TLSHandshakeFailures metrics.Counter
+	DecompressResponses  bool
+	Endpoint             string
+	TransactionLatency   metrics.Histogram
+	ResponseHeaders      *HeaderFilter
+	ConcurrencyLimiter   *ConcurrencyLimiter
+	UserAgent            string
 }
 
 func (t *tr1d1umTransactor) Transact(req *http.Request) (result *XmidtResponse, err error) {
-	ctx, cancel := context.WithTimeout(req.Context(), t.RequestTimeout)
+	timeout := t.RequestTimeout
+
+	// callers may override the request timeout on a per-request basis
+	if d, ok := req.Context().Value(ContextKeyRequestTimeout).(time.Duration); ok && d > 0 {
+		timeout = d
+	}
+
+	// let XMiDT tell which tr1d1um version and instance is calling
+	if t.UserAgent != "" {
+		req.Header.Set("User-Agent", t.UserAgent)
+	}
+
+	// let XMiDT correlate its logs with ours
+	if id, ok := TransactionIDFromContext(req.Context()); ok {
+		req.Header.Set(HeaderTr1d1umTransactionID, id)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
 	defer cancel()
 
-	var resp *http.Response
-	if resp, err = t.Do(req.WithContext(ctx)); err == nil {
+	// the slot is held until the response body is read as the connection is busy until then
+	release, err := t.ConcurrencyLimiter.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	start := time.Now()
+	resp, err := t.Do(req.WithContext(ctx))
+	t.observeLatency(start, resp, err)
+
+	if err == nil {
 		result = &XmidtResponse{
 			ForwardedHeaders: make(http.Header),
 			Body:             []byte{},
 		}
 
-		ForwardHeadersByPrefix("X", resp.Header, result.ForwardedHeaders)
+		t.ResponseHeaders.Forward(resp.Header, result.ForwardedHeaders)
 		result.Code = resp.StatusCode
+		result.ContentType = resp.Header.Get("Content-Type")
 
 		defer resp.Body.Close()
 
-		result.Body, err = ioutil.ReadAll(resp.Body)
+		var (
+			body  io.Reader = resp.Body
+			guard *throughputGuard
+		)
+
+		if t.MinThroughput > 0 && t.ThroughputWindow > 0 {
+			guard = newThroughputGuard(resp.Body, t.MinThroughput, t.ThroughputWindow, cancel)
+			defer guard.stop()
+			body = guard
+		}
+
+		if t.DecompressResponses && strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+			if body, err = gzip.NewReader(body); err != nil {
+				result = nil
+				err = NewCodedError(fmt.Errorf("invalid gzip response from XMiDT API: %v", err), http.StatusBadGateway)
+				return
+			}
+		}
+
+		if result.Body, err = ioutil.ReadAll(body); err != nil && guard != nil && guard.tripped() {
+			err = ErrSlowResponse
+		}
+		return
+	}
+
+	if isTLSHandshakeError(err) {
+		// not a client issue but a misconfiguration between tr1d1um and XMiDT
+		t.TLSHandshakeFailures.Add(1)
+		logging.Error(t.Logger).Log(logging.MessageKey(), "TLS handshake with XMiDT failed. Check certificates and TLS settings",
+			"url", req.URL.String(), logging.ErrorKey(), err)
+		err = NewCodedErrorWithErrorCode(ErrGatewayTLS, http.StatusBadGateway, ErrorCodeGatewayTLS)
 		return
 	}
 
 	//Timeout, network errors, etc.
-	err = NewCodedError(err, http.StatusServiceUnavailable)
+	if _, ok := err.(CodedError); !ok {
+		err = NewCodedError(err, http.StatusServiceUnavailable)
+	}
 	return
 }
+
+// observeLatency records the duration of a request to XMiDT labeled by the class of the response status code.
+// Requests which got no response are labeled as "error"
+func (t *tr1d1umTransactor) observeLatency(start time.Time, resp *http.Response, err error) {
+	status := "error"
+	if err == nil {
+		status = fmt.Sprintf("%dxx", resp.StatusCode/100)
+	}
+
+	t.TransactionLatency.With(EndpointLabel, t.Endpoint, StatusLabel, status).Observe(time.Since(start).Seconds())
+}
+
+// errHTTPResponseToHTTPSClient is the message of the untyped error net/http returns
+// when a TLS client handshake is answered with a plain HTTP response
+const errHTTPResponseToHTTPSClient = "http: server gave HTTP response to HTTPS client"
+
+// isTLSHandshakeError reports whether err was caused by a failed TLS handshake
+// (untrusted or invalid certificates, protocol or cipher mismatches, non-TLS peers, etc.)
+func isTLSHandshakeError(err error) bool {
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		invalidCert      x509.CertificateInvalidError
+		hostname         x509.HostnameError
+		recordHeader     tls.RecordHeaderError
+		opErr            *net.OpError
+		urlErr           *url.Error
+	)
+
+	if errors.As(err, &unknownAuthority) || errors.As(err, &invalidCert) ||
+		errors.As(err, &hostname) || errors.As(err, &recordHeader) {
+		return true
+	}
+
+	// alerts sent by the server (i.e. "remote error: tls: handshake failure")
+	if errors.As(err, &opErr) && opErr.Op == "remote error" {
+		return true
+	}
+
+	// net/http consumes the RecordHeaderError of a plain HTTP peer and replaces it
+	return errors.As(err, &urlErr) && urlErr.Err != nil && urlErr.Err.Error() == errHTTPResponseToHTTPSClient
+}
+
+// throughputGuard aborts the reading of a response body which is received at
+// a rate lower than the configured minimum for a whole window
+type throughputGuard struct {
+	reader   io.Reader
+	minBytes int64
+	window   time.Duration
+	abort    func()
+
+	lock    sync.Mutex
+	read    int64
+	slow    bool
+	stopped bool
+	timer   *time.Timer
+}
+
+func newThroughputGuard(r io.Reader, minThroughput int64, window time.Duration, abort func()) *throughputGuard {
+	g := &throughputGuard{
+		reader:   r,
+		minBytes: int64(float64(minThroughput) * window.Seconds()),
+		window:   window,
+		abort:    abort,
+	}
+
+	g.lock.Lock()
+	g.timer = time.AfterFunc(window, g.check)
+	g.lock.Unlock()
+
+	return g
+}
+
+func (g *throughputGuard) Read(p []byte) (int, error) {
+	n, err := g.reader.Read(p)
+
+	g.lock.Lock()
+	g.read += int64(n)
+	g.lock.Unlock()
+
+	return n, err
+}
+
+func (g *throughputGuard) check() {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if g.stopped {
+		return
+	}
+
+	if g.read < g.minBytes {
+		g.slow = true
+		g.abort()
+		return
+	}
+
+	g.read = 0
+	g.timer.Reset(g.window)
+}
+
+func (g *throughputGuard) tripped() bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.slow
+}
+
+func (g *throughputGuard) stop() {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.stopped = true
+	g.timer.Stop()
+}
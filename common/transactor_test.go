@@ -2,12 +2,20 @@ package common
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
 	"errors"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"runtime"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/go-kit/kit/metrics/generic"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -36,14 +44,16 @@ func TestTransactIdeal(t *testing.T) {
 		Code:             404,
 		Body:             []byte("not found"),
 		ForwardedHeaders: http.Header{"X-A": []string{"a", "b"}},
+		ContentType:      "text/plain",
 	}
 
 	rawXmidtResponse := &http.Response{
 		StatusCode: 404,
 		Body:       ioutil.NopCloser(bytes.NewBufferString("not found")),
 		Header: http.Header{
-			"X-A": []string{"a", "b"}, //should be forwarded
-			"Y-A": []string{"c", "d"}, //should be ignored
+			"X-A":          []string{"a", "b"}, //should be forwarded
+			"Y-A":          []string{"c", "d"}, //should be ignored
+			"Content-Type": []string{"text/plain"},
 		},
 	}
 
@@ -58,3 +68,346 @@ func TestTransactIdeal(t *testing.T) {
 	assert.Nil(e)
 	assert.EqualValues(expected, actual)
 }
+
+func TestTransactGzipResponse(t *testing.T) {
+	var compressed bytes.Buffer
+	gw := gzip.NewWriter(&compressed)
+	gw.Write([]byte(`{"statusCode": 200}`))
+	gw.Close()
+
+	tests := []struct {
+		name         string
+		decompress   bool
+		body         []byte
+		expectedBody []byte
+		expectedErr  bool
+	}{
+		{name: "Disabled", body: compressed.Bytes(), expectedBody: compressed.Bytes()},
+		{name: "Enabled", decompress: true, body: compressed.Bytes(), expectedBody: []byte(`{"statusCode": 200}`)},
+		{name: "Invalid", decompress: true, body: []byte("not gzip"), expectedErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			transactor := NewTr1d1umTransactor(&Tr1d1umTransactorOptions{
+				Do: func(_ *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       ioutil.NopCloser(bytes.NewReader(test.body)),
+						Header:     http.Header{"Content-Encoding": []string{"gzip"}},
+					}, nil
+				},
+				RequestTimeout:      time.Second,
+				DecompressResponses: test.decompress,
+			})
+
+			actual, err := transactor.Transact(httptest.NewRequest(http.MethodGet, "localhost:6003/test", nil))
+			if test.expectedErr {
+				assert.Nil(actual)
+				assert.EqualValues(http.StatusBadGateway, err.(CodedError).StatusCode())
+				return
+			}
+
+			assert.Nil(err)
+			assert.Equal(test.expectedBody, actual.Body)
+		})
+	}
+}
+
+func TestTransactSlowResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		for i := 0; i < 10; i++ {
+			w.Write([]byte("a"))
+			w.(http.Flusher).Flush()
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	t.Run("BelowMinThroughput", func(t *testing.T) {
+		assert := assert.New(t)
+		transactor := NewTr1d1umTransactor(&Tr1d1umTransactorOptions{
+			Do:               http.DefaultClient.Do,
+			RequestTimeout:   5 * time.Second,
+			MinThroughput:    1024,
+			ThroughputWindow: 100 * time.Millisecond,
+		})
+
+		r := httptest.NewRequest(http.MethodGet, server.URL, nil)
+		r.RequestURI = ""
+		_, e := transactor.Transact(r)
+		assert.Equal(ErrSlowResponse, e)
+	})
+
+	t.Run("AboveMinThroughput", func(t *testing.T) {
+		assert := assert.New(t)
+		transactor := NewTr1d1umTransactor(&Tr1d1umTransactorOptions{
+			Do:               http.DefaultClient.Do,
+			RequestTimeout:   5 * time.Second,
+			MinThroughput:    1,
+			ThroughputWindow: 200 * time.Millisecond,
+		})
+
+		r := httptest.NewRequest(http.MethodGet, server.URL, nil)
+		r.RequestURI = ""
+		result, e := transactor.Transact(r)
+		assert.Nil(e)
+		assert.Equal("aaaaaaaaaa", string(result.Body))
+	})
+}
+
+func TestTransactRequestTimeoutOverride(t *testing.T) {
+	assert := assert.New(t)
+
+	var deadline time.Duration
+	transactor := NewTr1d1umTransactor(&Tr1d1umTransactorOptions{
+		RequestTimeout: time.Hour,
+		Do: func(r *http.Request) (*http.Response, error) {
+			d, ok := r.Context().Deadline()
+			assert.True(ok)
+			deadline = time.Until(d)
+			return nil, errors.New("done")
+		},
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "localhost:6003/test", nil)
+	r = r.WithContext(context.WithValue(r.Context(), ContextKeyRequestTimeout, time.Second))
+	transactor.Transact(r)
+	assert.True(deadline <= time.Second)
+}
+
+func TestTransactTLSHandshakeFailure(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tlsServer := httptest.NewTLSServer(handler)
+	defer tlsServer.Close()
+
+	plainServer := httptest.NewServer(handler)
+	defer plainServer.Close()
+
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{
+			name: "UntrustedCertificate",
+			url:  tlsServer.URL,
+		},
+		{
+			name: "NonTLSServer",
+			url:  "https://" + plainServer.Listener.Addr().String(),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			counter := generic.NewCounter("tls_handshake_failures")
+
+			transactor := NewTr1d1umTransactor(&Tr1d1umTransactorOptions{
+				Do:                   new(http.Client).Do,
+				RequestTimeout:       time.Second,
+				TLSHandshakeFailures: counter,
+			})
+
+			r := httptest.NewRequest(http.MethodGet, test.url, nil)
+			r.RequestURI = ""
+
+			_, err := transactor.Transact(r)
+			assert.NotNil(err)
+
+			ce, ok := err.(CodedError)
+			assert.True(ok)
+			assert.EqualValues(http.StatusBadGateway, ce.StatusCode())
+			assert.EqualValues(ErrorCodeGatewayTLS, ce.(ErrorCoder).ErrorCode())
+			assert.EqualValues(1, counter.Value())
+		})
+	}
+
+	t.Run("NetworkErrorNotCounted", func(t *testing.T) {
+		assert := assert.New(t)
+		counter := generic.NewCounter("tls_handshake_failures")
+
+		transactor := NewTr1d1umTransactor(&Tr1d1umTransactorOptions{
+			Do: func(*http.Request) (*http.Response, error) {
+				return nil, errors.New("connection refused")
+			},
+			RequestTimeout:       time.Second,
+			TLSHandshakeFailures: counter,
+		})
+
+		_, err := transactor.Transact(httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+		assert.EqualValues(http.StatusServiceUnavailable, err.(CodedError).StatusCode())
+		assert.EqualValues(0, counter.Value())
+	})
+}
+
+func TestTransactLatency(t *testing.T) {
+	tests := []struct {
+		name           string
+		resp           *http.Response
+		err            error
+		expectedStatus string
+	}{
+		{
+			name:           "Response",
+			resp:           &http.Response{StatusCode: 404, Body: ioutil.NopCloser(bytes.NewBufferString("not found"))},
+			expectedStatus: "4xx",
+		},
+		{
+			name:           "NoResponse",
+			err:            errors.New("network test error"),
+			expectedStatus: "error",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			h := new(capturingHistogram)
+
+			transactor := NewTr1d1umTransactor(&Tr1d1umTransactorOptions{
+				Do: func(_ *http.Request) (*http.Response, error) {
+					return test.resp, test.err
+				},
+				Endpoint:           "stat",
+				TransactionLatency: h,
+			})
+
+			transactor.Transact(httptest.NewRequest(http.MethodGet, "localhost:6003/test", nil))
+
+			assert.Equal([]string{EndpointLabel, "stat", StatusLabel, test.expectedStatus}, h.labelValues)
+			assert.Len(h.observations, 1)
+		})
+	}
+}
+
+func TestTransactTransactionID(t *testing.T) {
+	assert := assert.New(t)
+
+	var actual string
+	transactor := NewTr1d1umTransactor(&Tr1d1umTransactorOptions{
+		Do: func(r *http.Request) (*http.Response, error) {
+			actual = r.Header.Get(HeaderTr1d1umTransactionID)
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewBufferString(""))}, nil
+		},
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "localhost:6003/test", nil)
+	r = r.WithContext(context.WithValue(r.Context(), ContextKeyTransactionID, "transaction-id"))
+
+	_, e := transactor.Transact(r)
+	assert.Nil(e)
+	assert.Equal("transaction-id", actual)
+}
+
+// waitFor polls condition until it holds or timeout elapses, reporting whether it held
+func waitFor(timeout time.Duration, condition func() bool) bool {
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if condition() {
+			return true
+		}
+	}
+	return condition()
+}
+
+func TestTransactTimeoutsDoNotLeak(t *testing.T) {
+	assert := assert.New(t)
+
+	var (
+		open    int64
+		release = make(chan struct{})
+	)
+
+	// XMiDT never answers in time, holding on to each request until tr1d1um gives up or the test ends
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		switch state {
+		case http.StateNew:
+			atomic.AddInt64(&open, 1)
+		case http.StateClosed, http.StateHijacked:
+			atomic.AddInt64(&open, -1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+	defer close(release)
+
+	client := &http.Client{Transport: &http.Transport{}}
+	defer client.Transport.(*http.Transport).CloseIdleConnections()
+
+	transactor := NewTr1d1umTransactor(&Tr1d1umTransactorOptions{
+		RequestTimeout: 50 * time.Millisecond,
+		Do: RetryTransactor(RetryOptions{
+			Retries:        2,
+			Interval:       time.Millisecond,
+			AttemptTimeout: 20 * time.Millisecond,
+			ShouldRetry:    func(error) bool { return true },
+		}, client.Do),
+	})
+
+	baseline := runtime.NumGoroutine()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := httptest.NewRequest(http.MethodGet, server.URL, nil)
+			r.RequestURI = ""
+			_, err := transactor.Transact(r)
+			assert.NotNil(err)
+		}()
+	}
+	wg.Wait()
+
+	client.Transport.(*http.Transport).CloseIdleConnections()
+
+	assert.True(waitFor(5*time.Second, func() bool { return atomic.LoadInt64(&open) == 0 }),
+		"connections left open: %d", atomic.LoadInt64(&open))
+	assert.True(waitFor(5*time.Second, func() bool { return runtime.NumGoroutine() <= baseline }),
+		"goroutines: baseline %d, now %d", baseline, runtime.NumGoroutine())
+}
+
+func TestTransactUserAgent(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		expected  string
+	}{
+		{name: "Configured", userAgent: "tr1d1um/0.5.0", expected: "tr1d1um/0.5.0"},
+		{name: "Unset", expected: "Go-http-client/1.1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var actual string
+			transactor := NewTr1d1umTransactor(&Tr1d1umTransactorOptions{
+				UserAgent: test.userAgent,
+				Do: func(r *http.Request) (*http.Response, error) {
+					actual = r.Header.Get("User-Agent")
+					return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewBufferString(""))}, nil
+				},
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "localhost:6003/test", nil)
+			r.Header.Set("User-Agent", "Go-http-client/1.1")
+
+			_, e := transactor.Transact(r)
+			assert.Nil(e)
+			assert.Equal(test.expected, actual)
+		})
+	}
+}
@@ -36,14 +36,25 @@ func (rs *transactionResponse) MarshalJSON() ([]byte, error) {
 	return json.Marshal(rs)
 }
 
+// CallerCredentials identifies the caller of a request by the principal it was authenticated as, if any,
+// and its Authorization header value, which requests authenticated by client certificate don't have.
+// Responses cached on behalf of a caller must only be shared with callers with the same credentials.
+func CallerCredentials(ctx context.Context, authHeaderValue string) string {
+	if auth, ok := bascule.FromContext(ctx); ok && auth.Token != nil {
+		return string(auth.Authorization) + " " + auth.Token.Principal() + " " + authHeaderValue
+	}
+	return authHeaderValue
+}
+
 // HeaderWPATID is the header key for the WebPA transaction UUID
 const HeaderWPATID = "X-WebPA-Transaction-Id"
 
 // TransactionLogging is used by the different Tr1d1um services to
-// keep track of incoming requests and their corresponding responses
-func TransactionLogging(reducedLoggingResponseCodes []int, logger kitlog.Logger) kithttp.ServerFinalizerFunc {
-	errorLogger := logging.Error(logger)
+// keep track of incoming requests and their corresponding responses.
+// The reduced logging response codes and paths are taken from the current settings snapshot
+func TransactionLogging(settings *Settings, logger kitlog.Logger) kithttp.ServerFinalizerFunc {
 	return func(ctx context.Context, code int, r *http.Request) {
+		errorLogger := logging.Error(OperationLogger(ctx, logger))
 		tid, _ := ctx.Value(ContextKeyRequestTID).(string)
 		transactionInfoLogger, ok := ctx.Value(ContextKeyTransactionInfoLogger).(kitlog.Logger)
 
@@ -60,16 +71,26 @@ func TransactionLogging(reducedLoggingResponseCodes []int, logger kitlog.Logger)
 			errorLogger.Log(logging.ErrorKey(), "Request arrival not capture for transaction logger", "tid", tid)
 		}
 
+		snapshot := settings.Load()
 		includeHeaders := true
 		response := transactionResponse{Code: code}
 
-		for _, responseCode := range reducedLoggingResponseCodes {
+		for _, responseCode := range snapshot.ReducedLoggingResponseCodes {
 			if responseCode == code {
 				includeHeaders = false
 				break
 			}
 		}
 
+		if includeHeaders {
+			for _, path := range snapshot.ReducedLoggingPaths {
+				if path.MatchString(r.URL.Path) {
+					includeHeaders = false
+					break
+				}
+			}
+		}
+
 		if includeHeaders {
 			response.Headers = ctx.Value(kithttp.ContextKeyResponseHeaders)
 		}
@@ -92,9 +113,8 @@ func ForwardHeadersByPrefix(p string, from http.Header, to http.Header) {
 // ErrorLogEncoder decorates the errorEncoder in such a way that
 // errors are logged with their corresponding unique request identifier
 func ErrorLogEncoder(logger kitlog.Logger, ee kithttp.ErrorEncoder) kithttp.ErrorEncoder {
-	var errorLogger = logging.Error(logger)
 	return func(ctx context.Context, e error, w http.ResponseWriter) {
-		errorLogger.Log(logging.ErrorKey(), e.Error(), "tid", ctx.Value(ContextKeyRequestTID).(string))
+		logging.Error(OperationLogger(ctx, logger)).Log(logging.ErrorKey(), e.Error(), "tid", ctx.Value(ContextKeyRequestTID).(string))
 		ee(ctx, e, w)
 	}
 }
@@ -115,13 +135,13 @@ func Welcome(delegate http.Handler) http.Handler {
 // from the incoming request. Unlike Welcome, values captured here are
 // intended to be used only throughout the gokit server flow: (request decoding, business logic,  response encoding)
 func Capture(logger kitlog.Logger) kithttp.RequestFunc {
-	var transactionInfoLogger = logging.Info(logger)
-
 	return func(ctx context.Context, r *http.Request) (nctx context.Context) {
-		var tid string
-
-		if tid = r.Header.Get(HeaderWPATID); tid == "" {
-			tid = genTID()
+		// the transaction id doubles as the WRP transaction uuid
+		tid, ok := TransactionIDFromContext(ctx)
+		if !ok {
+			if tid = r.Header.Get(HeaderWPATID); !validTransactionID(tid) {
+				tid = genTID()
+			}
 		}
 
 		nctx = context.WithValue(ctx, ContextKeyRequestTID, tid)
@@ -133,7 +153,7 @@ func Capture(logger kitlog.Logger) kithttp.RequestFunc {
 			satClientID = auth.Token.Principal()
 		}
 
-		transactionInfoLogger := kitlog.WithPrefix(transactionInfoLogger,
+		transactionInfoLogger := kitlog.WithPrefix(logging.Info(OperationLogger(ctx, logger)),
 			logging.MessageKey(), "record",
 			"request", transactionRequest{
 				Address: r.RemoteAddr,
@@ -5,8 +5,13 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
 	"testing"
+	"time"
 
+	kitlog "github.com/go-kit/kit/log"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/xmidt-org/bascule"
 	"github.com/xmidt-org/webpa-common/logging"
 
 	"github.com/stretchr/testify/assert"
@@ -99,6 +104,28 @@ func TestCapture(t *testing.T) {
 		ctx := Capture(logging.NewTestLogger(nil, t))(context.TODO(), r)
 		assert.NotEmpty(ctx.Value(ContextKeyRequestTID).(string))
 	})
+
+	t.Run("TransactionID", func(t *testing.T) {
+		assert := assert.New(t)
+		r := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+		r.Header.Set(HeaderWPATID, "tid01")
+		ctx := context.WithValue(context.TODO(), ContextKeyTransactionID, "id01")
+		ctx = Capture(logging.NewTestLogger(nil, t))(ctx, r)
+		assert.EqualValues("id01", ctx.Value(ContextKeyRequestTID).(string))
+	})
+}
+
+func TestCallerCredentials(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("Bearer token", CallerCredentials(context.Background(), "Bearer token"))
+
+	ctx := bascule.WithAuthentication(context.Background(), bascule.Authentication{
+		Authorization: "Cert",
+		Token:         bascule.NewToken("cert", "client0", bascule.NewAttributes()),
+	})
+	assert.Equal("Cert client0 ", CallerCredentials(ctx, ""))
+	assert.NotEqual(CallerCredentials(ctx, ""), CallerCredentials(context.Background(), ""))
 }
 
 func TestGenTID(t *testing.T) {
@@ -106,3 +133,51 @@ func TestGenTID(t *testing.T) {
 	tid := genTID()
 	assert.NotEmpty(tid)
 }
+
+func TestTransactionLogging(t *testing.T) {
+	settings := NewSettings(Snapshot{
+		ReducedLoggingResponseCodes: []int{http.StatusOK},
+		ReducedLoggingPaths:         []*regexp.Regexp{regexp.MustCompile("/stat$")},
+	})
+
+	tests := []struct {
+		name            string
+		url             string
+		code            int
+		expectedHeaders bool
+	}{
+		{name: "Full", url: "http://localhost/api/v2/device/mac:112233445566/config", code: http.StatusNotFound, expectedHeaders: true},
+		{name: "ReducedByCode", url: "http://localhost/api/v2/device/mac:112233445566/config", code: http.StatusOK},
+		{name: "ReducedByPath", url: "http://localhost/api/v2/device/mac:112233445566/stat", code: http.StatusNotFound},
+		{name: "ReducedByBoth", url: "http://localhost/api/v2/device/mac:112233445566/stat", code: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var response *transactionResponse
+			logger := kitlog.LoggerFunc(func(keyvals ...interface{}) error {
+				for i := 0; i+1 < len(keyvals); i += 2 {
+					if keyvals[i] == "response" {
+						r := keyvals[i+1].(transactionResponse)
+						response = &r
+					}
+				}
+				return nil
+			})
+
+			r := httptest.NewRequest(http.MethodGet, test.url, nil)
+			ctx := context.WithValue(context.Background(), ContextKeyTransactionInfoLogger, kitlog.Logger(logger))
+			ctx = context.WithValue(ctx, ContextKeyRequestArrivalTime, time.Now())
+			ctx = context.WithValue(ctx, kithttp.ContextKeyResponseHeaders, http.Header{"X-Test": []string{"1"}})
+
+			TransactionLogging(settings, logger)(ctx, test.code, r)
+
+			if assert.NotNil(response) {
+				assert.Equal(test.code, response.Code)
+				assert.Equal(test.expectedHeaders, response.Headers != nil)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+// PoolWarmerOptions are the configuration options for PoolWarmer
+type PoolWarmerOptions struct {
+	//TargetURLs are the XMiDT base URLs whose connections are kept warm
+	TargetURLs []string
+
+	//Path is appended to each target URL to build the ping URL
+	//(Optional)
+	Path string
+
+	//Interval is the time between pings. Pings are skipped while requests flow through the pool
+	Interval time.Duration
+
+	//Timeout is the deadline for each ping
+	//(Optional)
+	Timeout time.Duration
+
+	//Connections is the number of concurrent pings per target, which is the number of connections kept warm
+	//(Optional) defaults to 1
+	Connections int
+
+	//Do is the client whose connection pool is kept warm
+	//(Optional) defaults to http.DefaultClient.Do
+	Do func(*http.Request) (*http.Response, error)
+
+	//Logger reports failed pings at the debug level
+	//(Optional)
+	Logger kitlog.Logger
+}
+
+// PoolWarmer keeps the connections to XMiDT from going cold during traffic troughs by exercising them
+// with lightweight requests whenever the pool has been idle for an interval. It complements TCP keep-alives,
+// which keep connections open but don't prevent them from being closed for being idle.
+type PoolWarmer struct {
+	o        PoolWarmerOptions
+	lastUsed int64
+}
+
+// NewPoolWarmer is the constructor for PoolWarmer. It returns nil, a no-op PoolWarmer, when the interval isn't positive
+func NewPoolWarmer(o PoolWarmerOptions) *PoolWarmer {
+	if o.Interval <= 0 {
+		return nil
+	}
+
+	if o.Connections < 1 {
+		o.Connections = 1
+	}
+
+	if o.Do == nil {
+		o.Do = http.DefaultClient.Do
+	}
+
+	if o.Logger == nil {
+		o.Logger = logging.DefaultLogger()
+	}
+
+	return &PoolWarmer{o: o}
+}
+
+// Track decorates next, which should share the connection pool of the pings, such that requests
+// through it postpone pings
+func (p *PoolWarmer) Track(next func(*http.Request) (*http.Response, error)) func(*http.Request) (*http.Response, error) {
+	if p == nil {
+		return next
+	}
+
+	return func(r *http.Request) (*http.Response, error) {
+		p.touch()
+		return next(r)
+	}
+}
+
+func (p *PoolWarmer) touch() {
+	atomic.StoreInt64(&p.lastUsed, time.Now().UnixNano())
+}
+
+// idle reports whether no requests went through the pool within the last interval
+func (p *PoolWarmer) idle() bool {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&p.lastUsed))) >= p.o.Interval
+}
+
+// Start pings XMiDT every interval on a background goroutine until shutdown is closed
+func (p *PoolWarmer) Start(shutdown <-chan struct{}) {
+	if p == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(p.o.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-shutdown:
+				return
+			case <-ticker.C:
+				if p.idle() {
+					p.warm()
+				}
+			}
+		}
+	}()
+}
+
+// warm pings every target over as many connections as configured
+func (p *PoolWarmer) warm() {
+	var wg sync.WaitGroup
+	for _, target := range p.o.TargetURLs {
+		url := strings.TrimSuffix(target, "/") + p.o.Path
+		for i := 0; i < p.o.Connections; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := p.ping(url); err != nil {
+					logging.Debug(p.o.Logger).Log(logging.MessageKey(), "failed to ping XMiDT to keep connections warm", "url", url, logging.ErrorKey(), err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+func (p *PoolWarmer) ping(url string) error {
+	ctx := context.Background()
+	if p.o.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.o.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.o.Do(req)
+	if err != nil {
+		return err
+	}
+
+	// the connection only goes back to the pool once the body is drained
+	drainResponse(resp)
+	return nil
+}
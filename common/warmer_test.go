@@ -0,0 +1,125 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pingServer counts the pings it gets and reports their path
+func pingServer() (*httptest.Server, *int32, *sync.Map) {
+	var (
+		pings int32
+		paths sync.Map
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			atomic.AddInt32(&pings, 1)
+			paths.Store(r.URL.Path, true)
+		}
+	}))
+
+	return server, &pings, &paths
+}
+
+func TestPoolWarmer(t *testing.T) {
+	const interval = 50 * time.Millisecond
+
+	t.Run("Interval", func(t *testing.T) {
+		assert := assert.New(t)
+
+		server, pings, paths := pingServer()
+		defer server.Close()
+
+		p := NewPoolWarmer(PoolWarmerOptions{
+			TargetURLs: []string{server.URL + "/"},
+			Path:       "/api/v2/health",
+			Interval:   interval,
+			Timeout:    time.Second,
+			Do:         server.Client().Do,
+		})
+
+		shutdown := make(chan struct{})
+		p.Start(shutdown)
+
+		time.Sleep(interval/2 + 5*interval)
+		close(shutdown)
+
+		// 5 ticks fit in the time window, allowing for scheduling delays
+		count := atomic.LoadInt32(pings)
+		assert.True(count >= 3 && count <= 5, "pings: %d", count)
+
+		_, ok := paths.Load("/api/v2/health")
+		assert.True(ok)
+
+		// no pings after shutdown
+		time.Sleep(2 * interval)
+		assert.Equal(count, atomic.LoadInt32(pings))
+	})
+
+	t.Run("Connections", func(t *testing.T) {
+		server, pings, _ := pingServer()
+		defer server.Close()
+
+		p := NewPoolWarmer(PoolWarmerOptions{
+			TargetURLs:  []string{server.URL, server.URL},
+			Interval:    interval,
+			Connections: 3,
+			Do:          server.Client().Do,
+		})
+
+		p.warm()
+		assert.Equal(t, int32(6), atomic.LoadInt32(pings))
+	})
+
+	t.Run("Busy", func(t *testing.T) {
+		server, pings, _ := pingServer()
+		defer server.Close()
+
+		p := NewPoolWarmer(PoolWarmerOptions{
+			TargetURLs: []string{server.URL},
+			Interval:   interval,
+			Do:         server.Client().Do,
+		})
+
+		do := p.Track(func(*http.Request) (*http.Response, error) {
+			return nil, nil
+		})
+
+		shutdown := make(chan struct{})
+		p.Start(shutdown)
+		defer close(shutdown)
+
+		// traffic flowing through the pool makes pings unnecessary
+		for i := 0; i < 20; i++ {
+			do(httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+			time.Sleep(interval / 5)
+		}
+
+		assert.Zero(t, atomic.LoadInt32(pings))
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		assert := assert.New(t)
+
+		p := NewPoolWarmer(PoolWarmerOptions{TargetURLs: []string{"http://localhost"}})
+		assert.Nil(p)
+
+		var called bool
+		do := p.Track(func(*http.Request) (*http.Response, error) {
+			called = true
+			return nil, nil
+		})
+
+		do(httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+		assert.True(called)
+
+		p.Start(make(chan struct{}))
+	})
+}
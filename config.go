@@ -0,0 +1,355 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/spf13/viper"
+	"github.com/xmidt-org/tr1d1um/common"
+	"github.com/xmidt-org/tr1d1um/stat"
+	"github.com/xmidt-org/tr1d1um/translation"
+	"github.com/xmidt-org/webpa-common/device"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+// envPrefix is the prefix of the environment variables which override config keys
+const envPrefix = "TR1D1UM"
+
+// envKeyReplacer maps config keys to environment variable names. Nested keys are joined with underscores
+// (i.e. TR1D1UM_JWTVALIDATOR_KEYS_FACTORY_URI overrides jwtValidator.keys.factory.uri)
+var envKeyReplacer = strings.NewReplacer(".", "_")
+
+func envName(key string) string {
+	return envPrefix + "_" + strings.ToUpper(envKeyReplacer.Replace(key))
+}
+
+// configure registers the clientHTTP2 alias, the defaults and the environment variable overrides
+func configure(v *viper.Viper) error {
+	// clientHTTP2 is the name the HTTP/2 flag was introduced with
+	v.RegisterAlias(clientHTTP2Key, clientForceAttemptHTTP2Key)
+
+	for k, va := range defaults {
+		v.SetDefault(k, va)
+	}
+
+	return bindEnv(v)
+}
+
+// bindEnv makes environment variables take precedence over both the config file and the defaults.
+// It must be called once the defaults are set as only the keys known by then can be overridden.
+func bindEnv(v *viper.Viper) error {
+	v.SetEnvPrefix(envPrefix)
+	v.SetEnvKeyReplacer(envKeyReplacer)
+	v.AutomaticEnv()
+
+	// AutomaticEnv only applies to the exact key looked up. Overrides of nested keys are merged into the config
+	// as well for them to show up when their parent key is unmarshaled
+	overrides := make(map[string]interface{})
+	for _, key := range v.AllKeys() {
+		if value, ok := os.LookupEnv(envName(key)); ok {
+			setNested(overrides, strings.Split(key, "."), value)
+		}
+	}
+
+	return v.MergeConfigMap(overrides)
+}
+
+func setNested(m map[string]interface{}, path []string, value interface{}) {
+	for _, p := range path[:len(path)-1] {
+		sub, ok := m[p].(map[string]interface{})
+		if !ok {
+			sub = make(map[string]interface{})
+			m[p] = sub
+		}
+		m = sub
+	}
+	m[path[len(path)-1]] = value
+}
+
+// durationKeys are the config keys whose values must be parseable durations
+var durationKeys = []string{
+	targetCooldownKey,
+	netDialerTimeoutKey,
+	clientTimeoutKey,
+	clientIdleConnTimeoutKey,
+	reqTimeoutKey,
+	reqMinTimeoutKey,
+	reqMaxTimeoutKey,
+	reqRetryIntervalKey,
+	reqRetryMaxIntervalKey,
+	reqRetryBudgetKey,
+	reqRetryJitterKey,
+	reqAttemptTimeoutKey,
+	respMinThroughputWindowKey,
+	circuitBreakerCooldownKey,
+	readinessIntervalKey,
+	readinessTimeoutKey,
+	shutdownDrainTimeoutKey,
+	keepWarmIntervalKey,
+	keepWarmTimeoutKey,
+	onlineStatusTTLKey,
+	dedupWindowKey,
+	authAcquirerCacheTTLKey,
+	statCacheTTLKey,
+	clientOverLimitQueueTimeoutKey,
+	serverReadTimeoutKey,
+	serverReadHeaderTimeoutKey,
+	serverWriteTimeoutKey,
+	serverIdleTimeoutKey,
+}
+
+// configErrors lists every problem found in the configuration
+type configErrors []error
+
+func (c configErrors) Error() string {
+	messages := make([]string, len(c))
+	for i, err := range c {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("invalid configuration:\n\t%s", strings.Join(messages, "\n\t"))
+}
+
+// validateConfig checks the configuration so that tr1d1um fails fast at startup rather than
+// misbehaving later. All problems are reported at once.
+func validateConfig(v *viper.Viper) error {
+	var errs configErrors
+
+	if urls := targetURLs(v); len(urls) == 0 || urls[0] == "" {
+		errs = append(errs, fmt.Errorf("%s must not be empty", targetURLKey))
+	}
+
+	for _, key := range durationKeys {
+		if value := v.GetString(key); value != "" {
+			if _, err := time.ParseDuration(value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid duration '%s'", key, value))
+			}
+		}
+	}
+
+	// the readiness probe ticks at this interval
+	if interval, err := time.ParseDuration(v.GetString(readinessIntervalKey)); err == nil && interval <= 0 {
+		errs = append(errs, fmt.Errorf("%s must be positive", readinessIntervalKey))
+	}
+
+	// the client timeout would cut requests off before the respWaitTimeoutMax clients may ask for
+	if max, client := v.GetDuration(reqMaxTimeoutKey), v.GetDuration(clientTimeoutKey); max > 0 && client > 0 && max > client {
+		errs = append(errs, fmt.Errorf("%s (%v) must not exceed %s (%v)", reqMaxTimeoutKey, max, clientTimeoutKey, client))
+	}
+
+	if _, err := device.ParseID(v.GetString(WRPSourcekey)); err != nil {
+		errs = append(errs, fmt.Errorf("%s: invalid WRP source '%s': %v", WRPSourcekey, v.GetString(WRPSourcekey), err))
+	}
+
+	if _, err := common.ParseBackoffStrategy(v.GetString(reqRetryBackoffKey)); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %v", reqRetryBackoffKey, err))
+	}
+
+	if _, err := common.ParseOverLimitBehavior(v.GetString(clientOverLimitBehaviorKey)); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %v", clientOverLimitBehaviorKey, err))
+	}
+
+	if v.GetBool(metricsRequireAuthKey) && len(v.GetStringSlice(metricsAuthorizationsKey)) == 0 {
+		errs = append(errs, fmt.Errorf("%s: %v", metricsAuthorizationsKey, errNoMetricsAuthorizations))
+	}
+
+	if v.GetBool(wrpSinkKey+".enabled") && v.GetString(wrpSinkKey+".target") == "" {
+		errs = append(errs, fmt.Errorf("%s.target is required when %s.enabled is set", wrpSinkKey, wrpSinkKey))
+	}
+
+	// the online pre-check goes through the stat service, which isn't built when it's disabled
+	if v.GetBool(translationEnabledKey) && v.GetBool(onlinePreCheckKey) && !v.GetBool(statEnabledKey) {
+		errs = append(errs, fmt.Errorf("%s requires %s to be set", onlinePreCheckKey, statEnabledKey))
+	}
+
+	if hooksEnabled(v) && !v.IsSet(webhookStoreKey) {
+		errs = append(errs, fmt.Errorf("%s is required when %s is set", webhookStoreKey, hooksEnabledKey))
+	}
+
+	var capabilityCheck CapabilityConfig
+	if err := v.UnmarshalKey("capabilityCheck", &capabilityCheck); err != nil {
+		errs = append(errs, fmt.Errorf("capabilityCheck: %v", err))
+	}
+
+	for _, e := range capabilityCheck.EndpointBuckets {
+		if _, err := regexp.Compile(e); err != nil {
+			errs = append(errs, fmt.Errorf("capabilityCheck.endpointBuckets: invalid regular expression '%s': %v", e, err))
+		}
+	}
+
+	if _, err := translation.CompileParameterAllowList(v.GetStringSlice(parameterAllowListKey)); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %v", parameterAllowListKey, err))
+	}
+
+	if _, err := translation.ParseChecksumAlgorithms(v.GetStringSlice(checksumAlgorithmsKey)); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %v", checksumAlgorithmsKey, err))
+	}
+
+	if _, err := stat.ParseFormats(v.GetStringSlice(statSupportedFormatsKey)); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %v", statSupportedFormatsKey, err))
+	}
+
+	if _, err := translation.ParseDeviceIDFormats(v.GetStringSlice(deviceIDFormatsKey)); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %v", deviceIDFormatsKey, err))
+	}
+
+	if _, err := common.ParseResponseCodes(v.GetStringSlice(reducedTransactionLoggingCodesKey)); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %v", reducedTransactionLoggingCodesKey, err))
+	}
+
+	if _, err := common.CompileReducedLoggingPaths(v.GetStringSlice(reducedTransactionLoggingPathsKey)); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %v", reducedTransactionLoggingPathsKey, err))
+	}
+
+	for operation, lvl := range v.GetStringMapString(logOperationLevelsKey) {
+		switch strings.ToUpper(lvl) {
+		case "DEBUG", "INFO", "WARN", "ERROR":
+		default:
+			errs = append(errs, fmt.Errorf("%s.%s: invalid log level '%s'", logOperationLevelsKey, operation, lvl))
+		}
+	}
+
+	var localization translation.LocalizationConfig
+	if err := v.UnmarshalKey(localizationKey, &localization); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %v", localizationKey, err))
+	}
+
+	var jwtVal JWTValidator
+	if err := v.UnmarshalKey("jwtValidator", &jwtVal); err != nil {
+		errs = append(errs, fmt.Errorf("jwtValidator: %v", err))
+	} else if _, err := newJWTParser(jwtVal.AllowedAlgorithms); err != nil {
+		errs = append(errs, fmt.Errorf("jwtValidator.allowedAlgorithms: %v", err))
+	}
+
+	var rules []ClaimRule
+	if err := v.UnmarshalKey(claimRulesKey, &rules); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %v", claimRulesKey, err))
+	} else if _, err := newClaimRules(rules); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %v", claimRulesKey, err))
+	}
+
+	// client certificates carry no capabilities or claims to check
+	var clientTLS ClientTLSConfig
+	if err := v.UnmarshalKey(clientTLSKey, &clientTLS); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %v", clientTLSKey, err))
+	} else if err := clientTLS.validate(); err != nil {
+		errs = append(errs, fmt.Errorf("%s.mode: %v", clientTLSKey, err))
+	} else if clientTLS.CAFile != "" {
+		if capabilityCheck.Type == "enforce" {
+			errs = append(errs, fmt.Errorf("%s can't be combined with an enforced capabilityCheck", clientTLSKey))
+		}
+
+		if len(rules) > 0 {
+			errs = append(errs, fmt.Errorf("%s can't be combined with %s", clientTLSKey, claimRulesKey))
+		}
+	}
+
+	if _, err := common.NewReadDuringWrite(v.GetString(readDuringWriteKey)); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %v", readDuringWriteKey, err))
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+var errNoSupportedServices = errors.New("no supportedServices are configured so all translation requests will be rejected")
+
+// checkSupportedServices calls out an empty list of supported services as the translation endpoints
+// then reject every request. It's a warning unless requireSupportedServices is set.
+func checkSupportedServices(v *viper.Viper, logger kitlog.Logger) error {
+	if len(v.GetStringSlice(translationServicesKey)) > 0 {
+		return nil
+	}
+
+	if v.GetBool(requireSupportedServicesKey) {
+		return errNoSupportedServices
+	}
+
+	logging.Warn(logger).Log(logging.MessageKey(), errNoSupportedServices.Error())
+	return nil
+}
+
+// reloadableKeys are the config keys (lower cased as viper reports them) reloadConfig applies.
+// Changes to any other key, such as listen addresses, require a restart.
+var reloadableKeys = map[string]bool{
+	strings.ToLower(translationServicesKey):            true,
+	strings.ToLower(reducedTransactionLoggingCodesKey): true,
+	strings.ToLower(reducedTransactionLoggingPathsKey): true,
+	strings.ToLower(logLevelKey):                       true,
+	strings.ToLower(parameterAllowListKey):             true,
+}
+
+// newSnapshot builds the reloadable settings of v, which must have been validated
+func newSnapshot(v *viper.Viper) common.Snapshot {
+	reducedLoggingCodes, _ := common.ParseResponseCodes(v.GetStringSlice(reducedTransactionLoggingCodesKey))
+	reducedLoggingPaths, _ := common.CompileReducedLoggingPaths(v.GetStringSlice(reducedTransactionLoggingPathsKey))
+	parameterAllowList, _ := translation.CompileParameterAllowList(v.GetStringSlice(parameterAllowListKey))
+
+	return common.Snapshot{
+		ValidServices:               v.GetStringSlice(translationServicesKey),
+		ReducedLoggingResponseCodes: reducedLoggingCodes,
+		ReducedLoggingPaths:         reducedLoggingPaths,
+		ParameterAllowList:          parameterAllowList,
+	}
+}
+
+// reloadConfig re-reads the config file the current configuration v came from and swaps in the settings
+// which can safely change at runtime. Nothing changes unless the new configuration is valid.
+func reloadConfig(v *viper.Viper, settings *common.Settings, logger *common.LevelLogger) error {
+	next := viper.New()
+	next.SetConfigFile(v.ConfigFileUsed())
+	if err := next.ReadInConfig(); err != nil {
+		return err
+	}
+
+	if err := configure(next); err != nil {
+		return err
+	}
+
+	if err := validateConfig(next); err != nil {
+		return err
+	}
+
+	if err := checkSupportedServices(next, logger); err != nil {
+		return err
+	}
+
+	settings.Store(newSnapshot(next))
+	logger.SetLevel(next.GetString(logLevelKey))
+
+	for _, key := range restartRequired(v, next) {
+		logging.Warn(logger).Log(logging.MessageKey(), "ignoring config change which requires a restart", "key", key)
+	}
+	logging.Info(logger).Log(logging.MessageKey(), "configuration reloaded", "configurationFile", next.ConfigFileUsed())
+	return nil
+}
+
+// restartRequired lists the keys which are not reloadable and differ between the current and next configurations
+func restartRequired(current, next *viper.Viper) []string {
+	keys := make(map[string]bool)
+	for _, key := range current.AllKeys() {
+		keys[key] = true
+	}
+	for _, key := range next.AllKeys() {
+		keys[key] = true
+	}
+
+	var changed []string
+	for key := range keys {
+		if !reloadableKeys[key] && !reflect.DeepEqual(current.Get(key), next.Get(key)) {
+			changed = append(changed, key)
+		}
+	}
+
+	sort.Strings(changed)
+	return changed
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/tr1d1um/common"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+func newDefaultViper() *viper.Viper {
+	v := viper.New()
+	for k, va := range defaults {
+		v.SetDefault(k, va)
+	}
+	return v
+}
+
+func TestValidateConfig(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		assert.Nil(t, validateConfig(newDefaultViper()))
+	})
+
+	t.Run("AllProblems", func(t *testing.T) {
+		assert := assert.New(t)
+
+		v := newDefaultViper()
+		v.Set(targetURLKey, "")
+		v.Set(clientTimeoutKey, "fifty seconds")
+		v.Set(readinessTimeoutKey, "2")
+		v.Set(WRPSourcekey, "tr1d1um")
+		v.Set("capabilityCheck", map[string]interface{}{"type": "enforce", "endpointBuckets": []string{"hook\\b", "device/(.*"}})
+		v.Set(readDuringWriteKey, "sometimes")
+		v.Set(checksumAlgorithmsKey, []string{"sha256", "crc32"})
+		v.Set(reducedTransactionLoggingPathsKey, []string{"/stat$", "/device/(.*"})
+		v.Set(logOperationLevelsKey, map[string]string{"SET": "debug", "GET": "quiet"})
+		v.Set("jwtValidator.allowedAlgorithms", []string{"RS256", "none"})
+		v.Set(claimRulesKey, []map[string]interface{}{{"claim": "sub", "values": []string{"a"}, "match": "most"}})
+		v.Set(deviceIDFormatsKey, []string{"mac", "imei"})
+		v.Set(clientOverLimitBehaviorKey, "drop")
+		v.Set(metricsRequireAuthKey, true)
+		v.Set(wrpSinkKey+".enabled", true)
+		v.Set(reducedTransactionLoggingCodesKey, []string{"2xx", "504-500"})
+		v.Set(statSupportedFormatsKey, []string{"application/json", "text/xml"})
+		v.Set(statEnabledKey, false)
+		v.Set(onlinePreCheckKey, true)
+		v.Set(hooksEnabledKey, true)
+		v.Set(localizationKey, map[string]interface{}{"mappings": "none"})
+
+		err := validateConfig(v)
+		if assert.NotNil(err) {
+			assert.Len(err.(configErrors), 20)
+			assert.Contains(err.Error(), targetURLKey)
+			assert.Contains(err.Error(), clientTimeoutKey)
+			assert.Contains(err.Error(), readinessTimeoutKey)
+			assert.Contains(err.Error(), WRPSourcekey)
+			assert.Contains(err.Error(), "device/(.*")
+			assert.Contains(err.Error(), readDuringWriteKey)
+			assert.Contains(err.Error(), "crc32")
+			assert.Contains(err.Error(), claimRulesKey)
+			assert.Contains(err.Error(), "jwtValidator.allowedAlgorithms")
+			assert.Contains(err.Error(), "quiet")
+			assert.Contains(err.Error(), reducedTransactionLoggingPathsKey)
+			assert.Contains(err.Error(), "imei")
+			assert.Contains(err.Error(), clientOverLimitBehaviorKey)
+			assert.Contains(err.Error(), metricsAuthorizationsKey)
+			assert.Contains(err.Error(), wrpSinkKey)
+			assert.Contains(err.Error(), "504-500")
+			assert.Contains(err.Error(), "text/xml")
+			assert.Contains(err.Error(), onlinePreCheckKey)
+			assert.Contains(err.Error(), webhookStoreKey)
+			assert.Contains(err.Error(), localizationKey)
+		}
+	})
+
+	t.Run("NonPositiveReadinessInterval", func(t *testing.T) {
+		assert := assert.New(t)
+
+		v := newDefaultViper()
+		v.Set(readinessIntervalKey, "0s")
+		err := validateConfig(v)
+		if assert.NotNil(err) {
+			assert.Len(err.(configErrors), 1)
+			assert.Contains(err.Error(), readinessIntervalKey)
+		}
+	})
+
+	t.Run("RespWaitTimeoutMaxOverClientTimeout", func(t *testing.T) {
+		assert := assert.New(t)
+
+		v := newDefaultViper()
+		v.Set(reqMaxTimeoutKey, "50s")
+		assert.Nil(validateConfig(v))
+
+		v.Set(reqMaxTimeoutKey, "51s")
+		err := validateConfig(v)
+		if assert.NotNil(err) {
+			assert.Len(err.(configErrors), 1)
+			assert.Contains(err.Error(), reqMaxTimeoutKey)
+		}
+	})
+
+	t.Run("ClientTLS", func(t *testing.T) {
+		assert := assert.New(t)
+
+		v := newDefaultViper()
+		v.Set(clientTLSKey, map[string]interface{}{"caFile": "/etc/tr1d1um/client_ca.pem"})
+		v.Set("capabilityCheck", map[string]interface{}{"type": "monitor"})
+		assert.Nil(validateConfig(v))
+
+		v.Set(clientTLSKey, map[string]interface{}{"caFile": "/etc/tr1d1um/client_ca.pem", "mode": clientTLSOptional})
+		err := validateConfig(v)
+		if assert.NotNil(err) {
+			assert.Len(err.(configErrors), 1)
+			assert.Contains(err.Error(), errOptionalClientTLS.Error())
+		}
+
+		v.Set(clientTLSKey, map[string]interface{}{"caFile": "/etc/tr1d1um/client_ca.pem"})
+		v.Set("capabilityCheck", map[string]interface{}{"type": "enforce"})
+		v.Set(claimRulesKey, []map[string]interface{}{{"claim": "sub", "values": []string{"a"}}})
+		err = validateConfig(v)
+		if assert.NotNil(err) {
+			assert.Len(err.(configErrors), 2)
+			assert.Contains(err.Error(), "capabilityCheck")
+			assert.Contains(err.Error(), claimRulesKey)
+		}
+	})
+}
+
+func TestBindEnv(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	v := newDefaultViper()
+	v.SetConfigType("yaml")
+	require.Nil(v.ReadConfig(strings.NewReader(`
+targetURL: "http://localhost:6000"
+jwtValidator:
+  keys:
+    factory:
+      uri: "http://localhost/{keyId}"
+    purpose: 0
+`)))
+
+	overrides := map[string]string{
+		"TR1D1UM_TARGETURL":                     "http://xmidt:6000",
+		"TR1D1UM_RESPWAITTIMEOUT":               "10s",
+		"TR1D1UM_JWTVALIDATOR_KEYS_FACTORY_URI": "http://keys/{keyId}",
+	}
+	for name, value := range overrides {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+
+	require.Nil(bindEnv(v))
+
+	// env vars take precedence over both the config file and the defaults
+	assert.Equal("http://xmidt:6000", v.GetString(targetURLKey))
+	assert.Equal(10*time.Second, v.GetDuration(reqTimeoutKey))
+
+	// overrides of nested keys show up when their parent is unmarshaled, without dropping its other keys
+	var jwtValidator map[string]interface{}
+	require.Nil(v.UnmarshalKey("jwtValidator", &jwtValidator))
+	assert.Equal("http://keys/{keyId}", v.GetString("jwtValidator.keys.factory.uri"))
+	assert.Equal(map[string]interface{}{
+		"keys": map[string]interface{}{
+			"factory": map[string]interface{}{"uri": "http://keys/{keyId}"},
+			"purpose": 0,
+		},
+	}, jwtValidator)
+
+	// keys without an env var are left alone
+	assert.Equal(time.Second, v.GetDuration(reqMinTimeoutKey))
+}
+
+func TestCheckSupportedServices(t *testing.T) {
+	tests := []struct {
+		name            string
+		services        []string
+		required        bool
+		expectedErr     error
+		expectedWarning bool
+	}{
+		{name: "Configured", services: []string{"config"}},
+		{name: "ConfiguredRequired", services: []string{"config"}, required: true},
+		{name: "EmptyWarning", expectedWarning: true},
+		{name: "EmptyRequired", required: true, expectedErr: errNoSupportedServices},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			v := newDefaultViper()
+			if test.services != nil {
+				v.Set(translationServicesKey, test.services)
+			}
+			v.Set(requireSupportedServicesKey, test.required)
+
+			var buf bytes.Buffer
+			assert.Equal(test.expectedErr, checkSupportedServices(v, log.NewLogfmtLogger(&buf)))
+
+			if test.expectedWarning {
+				assert.Contains(buf.String(), "level=warn")
+				assert.Contains(buf.String(), "supportedServices")
+			} else {
+				assert.Empty(buf.String())
+			}
+		})
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "tr1d1um")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "tr1d1um.yaml")
+	writeConfig := func(config string) {
+		require.Nil(ioutil.WriteFile(file, []byte(config), 0644))
+	}
+
+	writeConfig(`
+primary:
+  address: ":6100"
+log:
+  level: "ERROR"
+supportedServices:
+  - "config"
+`)
+
+	v := viper.New()
+	v.SetConfigFile(file)
+	require.Nil(v.ReadInConfig())
+	require.Nil(configure(v))
+
+	var (
+		buf      bytes.Buffer
+		logger   = common.NewLevelLogger(log.NewLogfmtLogger(&buf), v.GetString(logLevelKey))
+		settings = common.NewSettings(newSnapshot(v))
+	)
+
+	writeConfig(`
+primary:
+  address: ":7100"
+log:
+  level: "DEBUG"
+  reducedLoggingResponseCodes: [200, "201-202"]
+supportedServices:
+  - "config"
+  - "stat"
+translation:
+  parameterAllowList:
+    - "^Device\\.WiFi\\."
+`)
+
+	require.Nil(reloadConfig(v, settings, logger))
+	snapshot := settings.Load()
+	assert.Equal([]string{"config", "stat"}, snapshot.ValidServices)
+	assert.Equal([]int{200, 201, 202}, snapshot.ReducedLoggingResponseCodes)
+	assert.Empty(snapshot.ReducedLoggingPaths)
+	if assert.Len(snapshot.ParameterAllowList, 1) {
+		assert.Equal(`^Device\.WiFi\.`, snapshot.ParameterAllowList[0].String())
+	}
+	assert.Contains(buf.String(), "key=primary.address")
+
+	buf.Reset()
+	logging.Debug(logger).Log(logging.MessageKey(), "debugging")
+	assert.Contains(buf.String(), "debugging")
+
+	// invalid configurations leave the current settings alone
+	writeConfig(`
+targetURL: ""
+supportedServices:
+  - "stat"
+`)
+
+	assert.NotNil(reloadConfig(v, settings, logger))
+	assert.Equal([]string{"config", "stat"}, settings.Load().ValidServices)
+}
@@ -4,17 +4,27 @@ go 1.14
 
 require (
 	github.com/c9s/goprocinfo v0.0.0-20190309065803-0b2ad9ac246b // indirect
+	github.com/dgrijalva/jwt-go v3.2.0+incompatible
 	github.com/go-kit/kit v0.9.0
 	github.com/goph/emperror v0.17.3-0.20190703203600-60a8d9faa17b
 	github.com/gorilla/mux v1.7.3
 	github.com/justinas/alice v1.2.0
+	github.com/prometheus/client_golang v1.9.0
 	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.6.2
-	github.com/stretchr/testify v1.5.1
+	github.com/stretchr/testify v1.7.0
+	github.com/ugorji/go/codec v1.1.7
 	github.com/xmidt-org/argus v0.3.3
 	github.com/xmidt-org/bascule v0.8.1
 	github.com/xmidt-org/webpa-common v1.10.2
 	github.com/xmidt-org/wrp-go v1.3.3
-	golang.org/x/crypto v0.0.0-20190701094942-4def268fd1a4 // indirect
+	go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp v0.20.0
+	go.opentelemetry.io/otel v0.20.0
+	go.opentelemetry.io/otel/exporters/stdout v0.20.0
+	go.opentelemetry.io/otel/exporters/trace/zipkin v0.20.0
+	go.opentelemetry.io/otel/sdk v0.20.0
+	go.opentelemetry.io/otel/trace v0.20.0
+	golang.org/x/crypto v0.0.0-20190701094942-4def268fd1a4
+	golang.org/x/net v0.0.0-20200202094626-16171245cfb2
 	golang.org/x/text v0.3.1-0.20180807135948-17ff2d5776d2 // indirect
 )
@@ -0,0 +1,147 @@
+package hooks
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/xmidt-org/webpa-common/webhook"
+)
+
+// privateNetworks are the private address ranges, which net.IP can't tell apart before go 1.17
+var privateNetworks = mustParseCIDRs("10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7")
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks, err := parseCIDRs(cidrs)
+	if err != nil {
+		panic(err)
+	}
+	return networks
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR '%s': %v", cidr, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// CallbackURLConfig restricts where webhook events may be delivered so that tr1d1um can't be used to
+// reach internal services
+type CallbackURLConfig struct {
+	// AllowedSchemes are the schemes callback URLs may use
+	AllowedSchemes []string
+
+	// AllowedCIDRs are the private, loopback or link-local address ranges callback URLs may resolve to anyway
+	// (Optional)
+	AllowedCIDRs []string
+}
+
+// CallbackValidator rejects webhooks whose callback URLs use a scheme which isn't allowed or resolve to
+// private, loopback or link-local addresses
+type CallbackValidator struct {
+	schemes  map[string]bool
+	allowed  []*net.IPNet
+	lookupIP func(host string) ([]net.IP, error)
+}
+
+// NewCallbackValidator is the constructor for CallbackValidator
+func NewCallbackValidator(c CallbackURLConfig) (*CallbackValidator, error) {
+	allowed, err := parseCIDRs(c.AllowedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &CallbackValidator{
+		schemes:  make(map[string]bool, len(c.AllowedSchemes)),
+		allowed:  allowed,
+		lookupIP: net.LookupIP,
+	}
+
+	for _, scheme := range c.AllowedSchemes {
+		v.schemes[strings.ToLower(scheme)] = true
+	}
+
+	return v, nil
+}
+
+// Validate checks all the URLs events of w may be delivered to. It's a no-op for nil validators.
+// Addresses are only checked at registration so DNS changes made afterwards go unnoticed.
+func (v *CallbackValidator) Validate(w *webhook.W) error {
+	if v == nil {
+		return nil
+	}
+
+	urls := append([]string{w.Config.URL}, w.Config.AlternativeURLs...)
+	if w.FailureURL != "" {
+		urls = append(urls, w.FailureURL)
+	}
+
+	for _, u := range urls {
+		if err := v.validateURL(u); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (v *CallbackValidator) validateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid callback URL '%s': %v", rawURL, err)
+	}
+
+	if !v.schemes[strings.ToLower(u.Scheme)] {
+		return fmt.Errorf("callback URL '%s' must use one of the schemes %v", rawURL, keys(v.schemes))
+	}
+
+	host := u.Hostname()
+	if host == "" {
+		return fmt.Errorf("callback URL '%s' has no host", rawURL)
+	}
+
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		if ips, err = v.lookupIP(host); err != nil {
+			return fmt.Errorf("unable to resolve the host of callback URL '%s': %v", rawURL, err)
+		}
+	}
+
+	for _, ip := range ips {
+		if internal(ip) && !contains(v.allowed, ip) {
+			return fmt.Errorf("callback URL '%s' resolves to the internal address %s", rawURL, ip)
+		}
+	}
+
+	return nil
+}
+
+func internal(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() || contains(privateNetworks, ip)
+}
+
+func contains(networks []*net.IPNet, ip net.IP) bool {
+	for _, n := range networks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func keys(set map[string]bool) []string {
+	list := make([]string, 0, len(set))
+	for k := range set {
+		list = append(list, k)
+	}
+	sort.Strings(list)
+	return list
+}
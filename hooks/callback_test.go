@@ -0,0 +1,87 @@
+package hooks
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/webhook"
+)
+
+func TestNewCallbackValidator(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := NewCallbackValidator(CallbackURLConfig{AllowedSchemes: []string{"https"}, AllowedCIDRs: []string{"10.0.0.0/33"}})
+	assert.NotNil(err)
+
+	v, err := NewCallbackValidator(CallbackURLConfig{AllowedSchemes: []string{"HTTPS"}, AllowedCIDRs: []string{"10.20.0.0/16"}})
+	assert.Nil(err)
+	assert.True(v.schemes["https"])
+	assert.Len(v.allowed, 1)
+}
+
+func TestCallbackValidator(t *testing.T) {
+	tests := []struct {
+		name          string
+		url           string
+		altURL        string
+		failureURL    string
+		expectedError string
+	}{
+		{name: "Public", url: "https://events.example.com/hook"},
+		{name: "PublicIP", url: "https://203.0.113.10/hook"},
+		{name: "AllowedCIDR", url: "https://10.20.1.1/hook"},
+		{name: "Scheme", url: "http://events.example.com/hook", expectedError: "callback URL 'http://events.example.com/hook' must use one of the schemes [https]"},
+		{name: "Loopback", url: "https://localhost:8080/hook", expectedError: "callback URL 'https://localhost:8080/hook' resolves to the internal address 127.0.0.1"},
+		{name: "Private", url: "https://192.168.1.1/hook", expectedError: "callback URL 'https://192.168.1.1/hook' resolves to the internal address 192.168.1.1"},
+		{name: "LinkLocal", url: "https://169.254.169.254/latest/meta-data", expectedError: "callback URL 'https://169.254.169.254/latest/meta-data' resolves to the internal address 169.254.169.254"},
+		{name: "IPv6Loopback", url: "https://[::1]/hook", expectedError: "callback URL 'https://[::1]/hook' resolves to the internal address ::1"},
+		{name: "AlternativeURL", url: "https://events.example.com/hook", altURL: "https://10.0.0.1/hook", expectedError: "callback URL 'https://10.0.0.1/hook' resolves to the internal address 10.0.0.1"},
+		{name: "FailureURL", url: "https://events.example.com/hook", failureURL: "https://internal.example.com/failed", expectedError: "callback URL 'https://internal.example.com/failed' resolves to the internal address 172.16.0.5"},
+		{name: "Unresolvable", url: "https://nowhere.example.com/hook", expectedError: "unable to resolve the host of callback URL 'https://nowhere.example.com/hook': no such host"},
+		{name: "NoHost", url: "https:///hook", expectedError: "callback URL 'https:///hook' has no host"},
+	}
+
+	v, err := NewCallbackValidator(CallbackURLConfig{AllowedSchemes: []string{"https"}, AllowedCIDRs: []string{"10.20.0.0/16"}})
+	require.Nil(t, err)
+
+	v.lookupIP = func(host string) ([]net.IP, error) {
+		switch host {
+		case "events.example.com":
+			return []net.IP{net.ParseIP("203.0.113.10")}, nil
+		case "localhost":
+			return []net.IP{net.ParseIP("127.0.0.1")}, nil
+		case "internal.example.com":
+			return []net.IP{net.ParseIP("203.0.113.11"), net.ParseIP("172.16.0.5")}, nil
+		default:
+			return nil, errors.New("no such host")
+		}
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			w := new(webhook.W)
+			w.Config.URL = test.url
+			w.FailureURL = test.failureURL
+			if test.altURL != "" {
+				w.Config.AlternativeURLs = []string{test.altURL}
+			}
+
+			err := v.Validate(w)
+			if test.expectedError == "" {
+				assert.Nil(err)
+			} else if assert.NotNil(err) {
+				assert.Equal(test.expectedError, err.Error())
+			}
+		})
+	}
+}
+
+func TestCallbackValidatorDisabled(t *testing.T) {
+	var v *CallbackValidator
+	assert.Nil(t, v.Validate(&webhook.W{}))
+}
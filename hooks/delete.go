@@ -0,0 +1,51 @@
+package hooks
+
+import (
+	"net/http"
+
+	"github.com/xmidt-org/bascule"
+)
+
+// RemoveRegistry is an api call which removes the webhook whose identifier (as listed by ListRegistry) is
+// given by the id query parameter. Only the webhooks registered by the authenticated principal can be
+// removed. Others are reported as unknown, just like identifiers which don't exist.
+func (r *Registry) RemoveRegistry(rw http.ResponseWriter, req *http.Request) {
+	id := req.URL.Query().Get("id")
+	if id == "" {
+		jsonResponse(rw, http.StatusBadRequest, "id query parameter is required")
+		return
+	}
+
+	owner := ""
+	// get Owner
+	if auth, ok := bascule.FromContext(req.Context()); ok {
+		owner = auth.Token.Principal()
+	}
+
+	// argus scopes items by owner and doesn't tell missing items apart from other failures on removal
+	items, err := r.hookStore.GetItems(owner)
+	if err != nil {
+		jsonResponse(rw, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	found := false
+	for _, item := range items {
+		if item.Identifier == id {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		jsonResponse(rw, http.StatusNotFound, "webhook not found")
+		return
+	}
+
+	if _, err := r.hookStore.Remove(id, owner); err != nil {
+		jsonResponse(rw, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,61 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/xmidt-org/argus/model"
+	"github.com/xmidt-org/bascule"
+)
+
+func TestDeleteWebhookHandler(t *testing.T) {
+	items := []model.Item{newHookItem("a", "http://localhost/a", time.Now())}
+
+	tests := []struct {
+		name           string
+		query          string
+		getErr         error
+		removeErr      error
+		expectedRemove bool
+		expectedStatus int
+	}{
+		{name: "Success", query: "?id=a", expectedRemove: true, expectedStatus: http.StatusNoContent},
+		{name: "MissingID", expectedStatus: http.StatusBadRequest},
+		{name: "Unknown", query: "?id=b", expectedStatus: http.StatusNotFound},
+		{name: "GetFailure", query: "?id=a", getErr: errors.New("failed to get items, non 200 statuscode"), expectedStatus: http.StatusInternalServerError},
+		{name: "RemoveFailure", query: "?id=a", removeErr: errors.New("failed to delete item"), expectedRemove: true, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			mockStore := &MockHookPusherStore{}
+			mockStore.On("GetItems", "owner").Return(items, test.getErr)
+			mockStore.On("Remove", mock.Anything, "owner").Return(model.Item{}, test.removeErr)
+
+			registry := Registry{hookStore: mockStore}
+
+			req := httptest.NewRequest(http.MethodDelete, "/hook"+test.query, nil)
+			req = req.WithContext(bascule.WithAuthentication(context.Background(), bascule.Authentication{
+				Token: bascule.NewToken("jwt", "owner", bascule.NewAttributesFromMap(map[string]interface{}{})),
+			}))
+
+			response := httptest.NewRecorder()
+			registry.RemoveRegistry(response, req)
+			assert.Equal(test.expectedStatus, response.Code)
+
+			if test.expectedRemove {
+				mockStore.AssertCalled(t, "Remove", "a", "owner")
+			} else {
+				mockStore.AssertNotCalled(t, "Remove", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
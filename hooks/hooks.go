@@ -8,6 +8,7 @@ import (
 	"github.com/xmidt-org/argus/chrysom"
 	"github.com/xmidt-org/argus/model"
 	"github.com/xmidt-org/bascule"
+	"github.com/xmidt-org/tr1d1um/common"
 	"github.com/xmidt-org/webpa-common/webhook"
 	"io/ioutil"
 	"net/http"
@@ -22,18 +23,29 @@ type Options struct {
 
 	Log                kitlog.Logger
 	WebhookStoreConfig chrysom.ClientConfig
+
+	// MaxRequestBodyBytes is the size limit of webhook registration payloads. Larger requests fail with 413
+	// (Optional) no limit is enforced when it's not positive
+	MaxRequestBodyBytes int64
+
+	// CallbackValidator rejects registrations whose callback URLs may reach internal services
+	// (Optional) callback URLs aren't checked when nil
+	CallbackValidator *CallbackValidator
 }
 
 // ConfigHandler configures a given handler with webhook endpoints
 func ConfigHandler(o *Options) {
 	r, _ := NewRegistry(RegistryConfig{
-		Logger:   o.Log,
-		Listener: nil,
-		Config:   o.WebhookStoreConfig,
+		Logger:            o.Log,
+		Listener:          nil,
+		Config:            o.WebhookStoreConfig,
+		CallbackValidator: o.CallbackValidator,
 	})
 
-	o.APIRouter.Handle("/hook", o.Authenticate.ThenFunc(r.UpdateRegistry)).Methods(http.MethodPost)
+	o.APIRouter.Handle("/hook", o.Authenticate.Append(common.LimitRequestBody(o.MaxRequestBodyBytes)).ThenFunc(r.UpdateRegistry)).Methods(http.MethodPost)
+	o.APIRouter.Handle("/hook", o.Authenticate.ThenFunc(r.RemoveRegistry)).Methods(http.MethodDelete)
 	o.APIRouter.Handle("/hooks", o.Authenticate.ThenFunc(r.GetRegistry)).Methods(http.MethodGet)
+	o.APIRouter.Handle("/hooks/summary", o.Authenticate.ThenFunc(r.ListRegistry)).Methods(http.MethodGet)
 
 }
 
@@ -43,9 +55,10 @@ type Registry struct {
 }
 
 type RegistryConfig struct {
-	Logger   kitlog.Logger
-	Listener chrysom.ListenerFunc
-	Config   chrysom.ClientConfig
+	Logger            kitlog.Logger
+	Listener          chrysom.ListenerFunc
+	Config            chrysom.ClientConfig
+	CallbackValidator *CallbackValidator
 }
 
 func NewRegistry(config RegistryConfig) (*Registry, error) {
@@ -67,9 +80,9 @@ func jsonResponse(rw http.ResponseWriter, code int, msg string) {
 	rw.Header().Set("Content-Type", "application/json")
 	rw.WriteHeader(code)
 	type responseMessage struct {
-		msg string `json:"message"`
+		Message string `json:"message"`
 	}
-	data, _ := json.Marshal(&responseMessage{msg: msg})
+	data, _ := json.Marshal(&responseMessage{Message: msg})
 	rw.Write(data)
 }
 
@@ -109,12 +122,21 @@ func (r *Registry) GetRegistry(rw http.ResponseWriter, req *http.Request) {
 // update is an api call to processes a listener registration for adding and updating
 func (r *Registry) UpdateRegistry(rw http.ResponseWriter, req *http.Request) {
 	payload, err := ioutil.ReadAll(req.Body)
+	if err == common.ErrRequestBodyTooLarge {
+		jsonResponse(rw, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
 
 	w, err := webhook.NewW(payload, req.RemoteAddr)
 	if err != nil {
 		jsonResponse(rw, http.StatusBadRequest, err.Error())
 		return
 	}
+
+	if err := r.config.CallbackValidator.Validate(w); err != nil {
+		jsonResponse(rw, http.StatusBadRequest, err.Error())
+		return
+	}
 	webhook := map[string]interface{}{}
 	data, err := json.Marshal(&w)
 	if err != nil {
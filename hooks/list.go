@@ -0,0 +1,126 @@
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/xmidt-org/bascule"
+)
+
+// Page sizes of webhook summaries
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+// webhookSummary is what operators need to audit a webhook registration
+type webhookSummary struct {
+	ID     string    `json:"id"`
+	URL    string    `json:"url"`
+	Events []string  `json:"events"`
+	Until  time.Time `json:"until"`
+}
+
+type webhookSummaries struct {
+	Webhooks []webhookSummary `json:"webhooks"`
+	Total    int              `json:"total"`
+	Offset   int              `json:"offset"`
+	Limit    int              `json:"limit"`
+}
+
+// ListRegistry is an api call which lists a page of the registered webhooks, sorted by URL, with just
+// their callback URLs, events and expiration times. The page is selected through the offset and limit
+// query parameters. Pages hold at most 500 webhooks and default to 50
+func (r *Registry) ListRegistry(rw http.ResponseWriter, req *http.Request) {
+	offset, err := pageParam(req, "offset", 0)
+	if err != nil {
+		jsonResponse(rw, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit, err := pageParam(req, "limit", defaultListLimit)
+	if err != nil {
+		jsonResponse(rw, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if limit == 0 {
+		limit = defaultListLimit
+	} else if limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	owner := ""
+	// get Owner
+	if auth, ok := bascule.FromContext(req.Context()); ok {
+		owner = auth.Token.Principal()
+	}
+
+	items, err := r.hookStore.GetItems(owner)
+	if err != nil {
+		jsonResponse(rw, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	summaries := make([]webhookSummary, 0, len(items))
+	for _, item := range items {
+		hook, err := convertItemToWebhook(item)
+		if err != nil {
+			continue
+		}
+
+		summaries = append(summaries, webhookSummary{
+			ID:     item.Identifier,
+			URL:    hook.Config.URL,
+			Events: hook.Events,
+			Until:  hook.Until,
+		})
+	}
+
+	// argus doesn't guarantee any order, which paging needs
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].URL != summaries[j].URL {
+			return summaries[i].URL < summaries[j].URL
+		}
+		return summaries[i].ID < summaries[j].ID
+	})
+
+	page := webhookSummaries{Webhooks: []webhookSummary{}, Total: len(summaries), Offset: offset, Limit: limit}
+	if offset < len(summaries) {
+		end := offset + limit
+		if end > len(summaries) {
+			end = len(summaries)
+		}
+		page.Webhooks = summaries[offset:end]
+	}
+
+	data, err := json.Marshal(&page)
+	if err != nil {
+		// this should never happen
+		jsonResponse(rw, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	rw.Write(data)
+}
+
+// pageParam returns the value of the given non-negative integer query parameter, or def when it's missing
+func pageParam(req *http.Request, name string, def int) (int, error) {
+	value := req.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", name)
+	}
+
+	return n, nil
+}
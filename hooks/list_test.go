@@ -0,0 +1,86 @@
+package hooks
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/argus/model"
+)
+
+func newHookItem(id, url string, until time.Time) model.Item {
+	return model.Item{
+		Identifier: id,
+		Data: map[string]interface{}{
+			"config": map[string]interface{}{"url": url, "content_type": "application/json"},
+			"events": []interface{}{"device-status.*"},
+			"until":  until.Format(time.RFC3339),
+		},
+	}
+}
+
+func TestListWebhookHandler(t *testing.T) {
+	until := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	items := []model.Item{
+		newHookItem("c", "http://localhost/c", until),
+		newHookItem("a", "http://localhost/a", until),
+		newHookItem("b", "http://localhost/b", until),
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		storeErr       error
+		expectedStatus int
+		expectedIDs    []string
+		expectedLimit  int
+	}{
+		{name: "All", expectedStatus: http.StatusOK, expectedIDs: []string{"a", "b", "c"}, expectedLimit: defaultListLimit},
+		{name: "Page", query: "?offset=1&limit=1", expectedStatus: http.StatusOK, expectedIDs: []string{"b"}, expectedLimit: 1},
+		{name: "PastTheEnd", query: "?offset=5", expectedStatus: http.StatusOK, expectedIDs: []string{}, expectedLimit: defaultListLimit},
+		{name: "CappedLimit", query: "?limit=10000", expectedStatus: http.StatusOK, expectedIDs: []string{"a", "b", "c"}, expectedLimit: maxListLimit},
+		{name: "BadOffset", query: "?offset=-1", expectedStatus: http.StatusBadRequest},
+		{name: "BadLimit", query: "?limit=ten", expectedStatus: http.StatusBadRequest},
+		{name: "StoreFailure", storeErr: errors.New("failed to get items, non 200 statuscode"), expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mockStore := &MockHookPusherStore{}
+			mockStore.On("GetItems", mock.Anything).Return(items, test.storeErr)
+
+			registry := Registry{hookStore: mockStore}
+
+			response := httptest.NewRecorder()
+			registry.ListRegistry(response, httptest.NewRequest(http.MethodGet, "/hooks/summary"+test.query, nil))
+			require.Equal(test.expectedStatus, response.Code)
+
+			if test.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var page webhookSummaries
+			require.Nil(json.Unmarshal(response.Body.Bytes(), &page))
+			assert.Equal(len(items), page.Total)
+			assert.Equal(test.expectedLimit, page.Limit)
+
+			ids := []string{}
+			for _, w := range page.Webhooks {
+				ids = append(ids, w.ID)
+				assert.Equal("http://localhost/"+w.ID, w.URL)
+				assert.Equal([]string{"device-status.*"}, w.Events)
+				assert.True(until.Equal(w.Until))
+			}
+			assert.Equal(test.expectedIDs, ids)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net/http"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// HTTP2Config configures HTTP/2 on the primary (API) listener
+type HTTP2Config struct {
+	// Enabled accepts HTTP/2 over cleartext connections (h2c), either with prior knowledge or through the
+	// Upgrade header. Listeners with a certificate negotiate HTTP/2 through ALPN regardless. HTTP/1.1 clients
+	// are served as usual either way.
+	Enabled bool
+
+	// MaxConcurrentStreams is the number of requests each h2c client connection may have in flight
+	// (Optional) defaults to 250
+	MaxConcurrentStreams uint32
+}
+
+// Then decorates handler such that h2c connections are served. It's a no-op unless HTTP/2 is enabled.
+// The handlers webpa-common wraps the primary handler in pass the connection preface of h2c clients through,
+// but the requests of h2c connections are then served by handler directly: they don't get the static
+// X-Tr1d1um-* headers and aren't counted by the webpa-common request metrics.
+func (c HTTP2Config) Then(handler http.Handler) http.Handler {
+	if !c.Enabled {
+		return handler
+	}
+
+	return h2c.NewHandler(handler, &http2.Server{MaxConcurrentStreams: c.MaxConcurrentStreams})
+}
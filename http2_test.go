@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/justinas/alice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/tr1d1um/common"
+	"github.com/xmidt-org/tr1d1um/translation"
+	"github.com/xmidt-org/webpa-common/concurrent"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/server"
+	"github.com/xmidt-org/webpa-common/xmetrics"
+	"github.com/xmidt-org/wrp-go/wrp"
+	"golang.org/x/net/http2"
+)
+
+type deviceResponder struct {
+	payload string
+}
+
+func (d deviceResponder) SendWRP(_ context.Context, msg *wrp.Message, _ string) (*common.XmidtResponse, error) {
+	return &common.XmidtResponse{
+		Code:             http.StatusOK,
+		ForwardedHeaders: make(http.Header),
+		ContentType:      wrp.Msgpack.ContentType(),
+		Body: wrp.MustEncode(&wrp.Message{
+			Type:            wrp.SimpleRequestResponseMessageType,
+			TransactionUUID: msg.TransactionUUID,
+			Payload:         []byte(d.payload),
+		}, wrp.Msgpack),
+	}, nil
+}
+
+func newH2CClient() *http.Client {
+	return &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+				return net.Dial(network, addr)
+			},
+		},
+	}
+}
+
+func TestHTTP2Translation(t *testing.T) {
+	const payload = `{"statusCode":200,"parameters":[{"name":"Device.DeviceInfo.SerialNumber","value":"abc"}]}`
+
+	r := mux.NewRouter()
+	translation.ConfigHandler(&translation.Options{
+		S:            deviceResponder{payload: payload},
+		APIRouter:    r.PathPrefix("/api/v2").Subrouter(),
+		Authenticate: new(alice.Chain),
+		Log:          logging.DefaultLogger(),
+		Settings:     common.NewSettings(common.Snapshot{ValidServices: []string{"config"}}),
+	})
+
+	t.Run("H2C", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		server := httptest.NewServer(HTTP2Config{Enabled: true}.Then(r))
+		defer server.Close()
+
+		resp, err := newH2CClient().Get(server.URL + "/api/v2/device/mac:112233445566/config?names=Device.DeviceInfo.SerialNumber")
+		require.Nil(err)
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		require.Nil(err)
+		assert.Equal(2, resp.ProtoMajor)
+		assert.Equal(http.StatusOK, resp.StatusCode)
+		assert.JSONEq(payload, string(body))
+	})
+
+	t.Run("HTTP1Fallback", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		server := httptest.NewServer(HTTP2Config{Enabled: true}.Then(r))
+		defer server.Close()
+
+		resp, err := http.Get(server.URL + "/api/v2/device/mac:112233445566/config?names=Device.DeviceInfo.SerialNumber")
+		require.Nil(err)
+		defer resp.Body.Close()
+
+		assert.Equal(1, resp.ProtoMajor)
+		assert.Equal(http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		server := httptest.NewServer(HTTP2Config{}.Then(r))
+		defer server.Close()
+
+		_, err := newH2CClient().Get(server.URL + "/api/v2/device/mac:112233445566/config?names=Device.DeviceInfo.SerialNumber")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestHTTP2PreparedServer(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(err)
+	address := l.Addr().String()
+	l.Close()
+
+	registry, err := xmetrics.NewRegistry(nil, server.Metrics)
+	require.Nil(err)
+
+	// the h2c handler isn't the outermost one once webpa-common prepared the primary server
+	webPA := &server.WebPA{ApplicationName: applicationName, Primary: server.Basic{Name: "primary", Address: address}}
+	_, runnable, _ := webPA.Prepare(logging.DefaultLogger(), nil, registry, HTTP2Config{Enabled: true}.Then(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})))
+
+	_, shutdown, err := concurrent.Execute(runnable)
+	require.Nil(err)
+	defer close(shutdown)
+
+	var resp *http.Response
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if resp, err = newH2CClient().Get("http://" + address + "/"); err == nil {
+			break
+		}
+	}
+	require.Nil(err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.Nil(err)
+	assert.Equal(2, resp.ProtoMajor)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal("ok", string(body))
+}
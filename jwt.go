@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/xmidt-org/bascule"
+)
+
+const (
+	noneAlgorithm = "none"
+
+	audienceClaim = "aud"
+	issuerClaim   = "iss"
+)
+
+var (
+	errNoneAlgorithm       = errors.New(`tokens signed with the "none" algorithm are not accepted`)
+	errAlgorithmNotAllowed = errors.New("token signing algorithm is not allowed")
+	errUnexpectedAudience  = errors.New("token is not meant for this audience")
+	errUnexpectedIssuer    = errors.New("token is not issued by the expected issuer")
+)
+
+// algorithmParser is a bascule.JWTParser which only accepts tokens signed with the allowed algorithms.
+// Tokens are checked before their signature is verified. The "none" algorithm is never accepted.
+type algorithmParser struct {
+	allowed map[string]bool
+}
+
+// newJWTParser returns a parser accepting tokens signed with any of the given algorithms (i.e. "RS256").
+// All algorithms but "none" are accepted when allowed is empty.
+func newJWTParser(allowed []string) (bascule.JWTParser, error) {
+	p := algorithmParser{allowed: make(map[string]bool, len(allowed))}
+	for _, alg := range allowed {
+		if strings.EqualFold(alg, noneAlgorithm) {
+			return nil, errNoneAlgorithm
+		}
+
+		if jwt.GetSigningMethod(alg) == nil {
+			return nil, fmt.Errorf("unsupported signing algorithm '%s'", alg)
+		}
+
+		p.allowed[alg] = true
+	}
+
+	return p, nil
+}
+
+// ParseJWT implements bascule.JWTParser
+func (p algorithmParser) ParseJWT(token string, claims jwt.Claims, keyFunc jwt.Keyfunc) (*jwt.Token, error) {
+	var parser jwt.Parser
+
+	unverified, _, err := parser.ParseUnverified(token, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	alg := unverified.Method.Alg()
+	if alg == noneAlgorithm {
+		return nil, errNoneAlgorithm
+	}
+
+	if len(p.allowed) > 0 && !p.allowed[alg] {
+		return nil, fmt.Errorf("%v: %s", errAlgorithmNotAllowed, alg)
+	}
+
+	return parser.ParseWithClaims(token, claims, keyFunc)
+}
+
+// audienceCheck validates tokens are meant for any of the expected audiences. Per the JWT spec,
+// the aud claim may either be a single string or a list of them.
+func audienceCheck(expected []string) bascule.Validator {
+	return bascule.ValidatorFunc(func(_ context.Context, token bascule.Token) error {
+		if token.Attributes() == nil {
+			return errUnexpectedAudience
+		}
+
+		aud, ok := token.Attributes().Get(audienceClaim)
+		if !ok {
+			return errUnexpectedAudience
+		}
+
+		audiences := claimValues(aud)
+		for _, e := range expected {
+			if audiences[e] {
+				return nil
+			}
+		}
+
+		return errUnexpectedAudience
+	})
+}
+
+// issuerCheck validates tokens are issued by the expected issuer
+func issuerCheck(expected string) bascule.Validator {
+	return bascule.ValidatorFunc(func(_ context.Context, token bascule.Token) error {
+		if token.Attributes() == nil {
+			return errUnexpectedIssuer
+		}
+
+		if iss, ok := token.Attributes().GetString(issuerClaim); !ok || iss != expected {
+			return errUnexpectedIssuer
+		}
+
+		return nil
+	})
+}
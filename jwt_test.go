@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/bascule"
+)
+
+func TestNewJWTParser(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := newJWTParser(nil)
+	assert.Nil(err)
+
+	_, err = newJWTParser([]string{"RS256", "ES256"})
+	assert.Nil(err)
+
+	_, err = newJWTParser([]string{"RS256", "None"})
+	assert.Equal(errNoneAlgorithm, err)
+
+	_, err = newJWTParser([]string{"XY999"})
+	assert.NotNil(err)
+}
+
+func TestAlgorithmParser(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+
+	hmacKey := []byte("secret")
+
+	sign := func(method jwt.SigningMethod, key interface{}) string {
+		token, err := jwt.NewWithClaims(method, jwt.MapClaims{"sub": "client0"}).SignedString(key)
+		require.Nil(t, err)
+		return token
+	}
+
+	keys := map[string]interface{}{
+		"RS256": &rsaKey.PublicKey,
+		"ES256": &ecKey.PublicKey,
+		"HS256": hmacKey,
+		"none":  jwt.UnsafeAllowNoneSignatureType,
+	}
+
+	tests := []struct {
+		name        string
+		allowed     []string
+		token       string
+		expectedErr error
+	}{
+		{
+			name:    "RS256Allowed",
+			allowed: []string{"RS256", "ES256"},
+			token:   sign(jwt.SigningMethodRS256, rsaKey),
+		},
+		{
+			name:    "ES256Allowed",
+			allowed: []string{"RS256", "ES256"},
+			token:   sign(jwt.SigningMethodES256, ecKey),
+		},
+		{
+			name:        "HS256NotAllowed",
+			allowed:     []string{"RS256", "ES256"},
+			token:       sign(jwt.SigningMethodHS256, hmacKey),
+			expectedErr: errAlgorithmNotAllowed,
+		},
+		{
+			name:  "AnyAllowed",
+			token: sign(jwt.SigningMethodHS256, hmacKey),
+		},
+		{
+			name:        "None",
+			token:       sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType),
+			expectedErr: errNoneAlgorithm,
+		},
+		{
+			name:        "NoneWithAllowList",
+			allowed:     []string{"RS256"},
+			token:       sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType),
+			expectedErr: errNoneAlgorithm,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			parser, err := newJWTParser(test.allowed)
+			require.Nil(err)
+
+			var verified bool
+			token, err := parser.ParseJWT(test.token, jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
+				verified = true
+				return keys[token.Method.Alg()], nil
+			})
+
+			if test.expectedErr == nil {
+				assert.Nil(err)
+				if assert.NotNil(token) {
+					assert.True(token.Valid)
+				}
+				return
+			}
+
+			if assert.NotNil(err) {
+				assert.Contains(err.Error(), test.expectedErr.Error())
+			}
+			assert.False(verified, "signatures of rejected tokens must not be verified")
+		})
+	}
+}
+
+func TestAudienceCheck(t *testing.T) {
+	check := audienceCheck([]string{"tr1d1um", "xmidt"})
+
+	tests := []struct {
+		name        string
+		attrs       map[string]interface{}
+		expectedErr error
+	}{
+		{name: "String", attrs: map[string]interface{}{"aud": "xmidt"}},
+		{name: "List", attrs: map[string]interface{}{"aud": []interface{}{"other", "tr1d1um"}}},
+		{name: "StringMismatch", attrs: map[string]interface{}{"aud": "other"}, expectedErr: errUnexpectedAudience},
+		{name: "ListMismatch", attrs: map[string]interface{}{"aud": []interface{}{"other", "another"}}, expectedErr: errUnexpectedAudience},
+		{name: "Missing", attrs: map[string]interface{}{}, expectedErr: errUnexpectedAudience},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			token := bascule.NewToken("jwt", "client0", bascule.NewAttributesFromMap(test.attrs))
+			assert.Equal(t, test.expectedErr, check.Check(context.Background(), token))
+		})
+	}
+}
+
+func TestIssuerCheck(t *testing.T) {
+	check := issuerCheck("https://issuer.example.com")
+
+	tests := []struct {
+		name        string
+		attrs       map[string]interface{}
+		expectedErr error
+	}{
+		{name: "Match", attrs: map[string]interface{}{"iss": "https://issuer.example.com"}},
+		{name: "Mismatch", attrs: map[string]interface{}{"iss": "https://other.example.com"}, expectedErr: errUnexpectedIssuer},
+		{name: "NotAString", attrs: map[string]interface{}{"iss": 42}, expectedErr: errUnexpectedIssuer},
+		{name: "Missing", attrs: map[string]interface{}{}, expectedErr: errUnexpectedIssuer},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			token := bascule.NewToken("jwt", "client0", bascule.NewAttributesFromMap(test.attrs))
+			assert.Equal(t, test.expectedErr, check.Check(context.Background(), token))
+		})
+	}
+}
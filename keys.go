@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
+	"github.com/xmidt-org/bascule/key"
+	"github.com/xmidt-org/tr1d1um/common"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+const (
+	refreshSuccess = "success"
+	refreshFailure = "failure"
+
+	// keyIDParameter is replaced by the key id of the token in key URIs templated per key
+	keyIDParameter = "{keyId}"
+)
+
+// probeKeyID returns the key id refreshed resolvers of keys fetched from uri are checked with. URIs templated
+// per key id aren't checked, as only the key ids of incoming tokens are known to exist.
+func probeKeyID(uri string) string {
+	if strings.Contains(uri, keyIDParameter) {
+		return ""
+	}
+	return DefaultKeyID
+}
+
+// resolverHolder keeps the type stored in keyRefresher consistent regardless of the resolver implementation
+type resolverHolder struct {
+	resolver key.Resolver
+}
+
+// keyRefresher is a key.Resolver which periodically replaces its underlying resolver so that rotated JWT
+// verification keys are picked up without a restart. Failed refreshes leave the current keys in place.
+type keyRefresher struct {
+	newResolver func() (key.Resolver, error)
+	probeKeyID  string
+	interval    time.Duration
+	refreshes   metrics.Counter
+	logger      log.Logger
+	current     atomic.Value
+}
+
+// newKeyRefresher is the constructor for keyRefresher. It fails if the initial resolver can't be created.
+// Refreshed resolvers must be able to fetch probeKeyID, unless it's empty, to replace the current one.
+func newKeyRefresher(newResolver func() (key.Resolver, error), probeKeyID string, interval time.Duration, refreshes metrics.Counter, logger log.Logger) (*keyRefresher, error) {
+	resolver, err := newResolver()
+	if err != nil {
+		return nil, err
+	}
+
+	k := &keyRefresher{
+		newResolver: newResolver,
+		probeKeyID:  probeKeyID,
+		interval:    interval,
+		refreshes:   refreshes,
+		logger:      logger,
+	}
+
+	k.current.Store(resolverHolder{resolver: resolver})
+	return k, nil
+}
+
+// ResolveKey implements key.Resolver
+func (k *keyRefresher) ResolveKey(ctx context.Context, keyID string) (key.Pair, error) {
+	return k.current.Load().(resolverHolder).resolver.ResolveKey(ctx, keyID)
+}
+
+// refresh swaps in a new resolver once it proves able to fetch the probe key
+func (k *keyRefresher) refresh() error {
+	resolver, err := k.newResolver()
+	if err == nil && k.probeKeyID != "" {
+		_, err = resolver.ResolveKey(context.Background(), k.probeKeyID)
+	}
+
+	if err != nil {
+		k.refreshes.With(common.OutcomeLabel, refreshFailure).Add(1)
+		logging.Error(k.logger).Log(logging.MessageKey(), "failed to refresh JWT verification keys, keeping the current ones", logging.ErrorKey(), err)
+		return err
+	}
+
+	k.current.Store(resolverHolder{resolver: resolver})
+	k.refreshes.With(common.OutcomeLabel, refreshSuccess).Add(1)
+	return nil
+}
+
+// Start refreshes the keys every interval on a background goroutine until shutdown is closed.
+// It's a no-op unless the interval is positive.
+func (k *keyRefresher) Start(shutdown <-chan struct{}) {
+	if k == nil || k.interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(k.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-shutdown:
+				return
+			case <-ticker.C:
+				k.refresh()
+			}
+		}
+	}()
+}
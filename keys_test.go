@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/bascule/key"
+	"github.com/xmidt-org/tr1d1um/common"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+// outcomeCounter counts by the value of the outcome label
+type outcomeCounter struct {
+	counts  map[string]float64
+	outcome string
+}
+
+func (c *outcomeCounter) With(labelValues ...string) metrics.Counter {
+	next := &outcomeCounter{counts: c.counts}
+	for i := 0; i+1 < len(labelValues); i += 2 {
+		if labelValues[i] == common.OutcomeLabel {
+			next.outcome = labelValues[i+1]
+		}
+	}
+	return next
+}
+
+func (c *outcomeCounter) Add(delta float64) {
+	c.counts[c.outcome] += delta
+}
+
+// generationResolver tells resolvers apart by the order in which they were created
+type generationResolver struct {
+	generation int
+	err        error
+}
+
+func (g generationResolver) ResolveKey(_ context.Context, _ string) (key.Pair, error) {
+	return nil, g.err
+}
+
+func (g generationResolver) String() string {
+	return "generationResolver"
+}
+
+func TestKeyRefresher(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var (
+		generation  int
+		fetchErr    error
+		resolverErr error
+		counter     = &outcomeCounter{counts: make(map[string]float64)}
+	)
+
+	newResolver := func() (key.Resolver, error) {
+		if resolverErr != nil {
+			return nil, resolverErr
+		}
+		generation++
+		return generationResolver{generation: generation, err: fetchErr}, nil
+	}
+
+	current := func(k *keyRefresher) int {
+		return k.current.Load().(resolverHolder).resolver.(generationResolver).generation
+	}
+
+	k, err := newKeyRefresher(newResolver, DefaultKeyID, time.Hour, counter, logging.NewTestLogger(nil, t))
+	require.Nil(err)
+	assert.Equal(1, current(k))
+
+	_, err = k.ResolveKey(context.Background(), DefaultKeyID)
+	assert.Nil(err)
+
+	// successful refreshes swap the resolver
+	assert.Nil(k.refresh())
+	assert.Equal(2, current(k))
+	assert.Equal(float64(1), counter.counts[refreshSuccess])
+
+	// resolvers which can't be created are discarded
+	resolverErr = errors.New("bad config")
+	assert.NotNil(k.refresh())
+	assert.Equal(2, current(k))
+
+	// so are resolvers which can't fetch the default key
+	resolverErr = nil
+	fetchErr = errors.New("JWKS unreachable")
+	assert.NotNil(k.refresh())
+	assert.Equal(2, current(k))
+	assert.Equal(float64(2), counter.counts[refreshFailure])
+
+	_, err = k.ResolveKey(context.Background(), DefaultKeyID)
+	assert.Nil(err)
+}
+
+func TestKeyRefresherTemplatedURI(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	assert.Equal(DefaultKeyID, probeKeyID("http://keys/jwks"))
+	assert.Equal("", probeKeyID("http://keys/{keyId}"))
+
+	generation := 0
+	newResolver := func() (key.Resolver, error) {
+		generation++
+		return generationResolver{generation: generation, err: errors.New("no such key")}, nil
+	}
+
+	k, err := newKeyRefresher(newResolver, probeKeyID("http://keys/{keyId}"), time.Hour, &outcomeCounter{counts: make(map[string]float64)}, logging.NewTestLogger(nil, t))
+	require.Nil(err)
+
+	// keys are fetched per key id, so resolvers aren't expected to know the default one
+	assert.Nil(k.refresh())
+	assert.Equal(2, k.current.Load().(resolverHolder).resolver.(generationResolver).generation)
+}
+
+func TestKeyRefresherStart(t *testing.T) {
+	counter := &outcomeCounter{counts: make(map[string]float64)}
+	refreshes := make(chan struct{}, 10)
+
+	newResolver := func() (key.Resolver, error) {
+		refreshes <- struct{}{}
+		return generationResolver{}, nil
+	}
+
+	k, err := newKeyRefresher(newResolver, DefaultKeyID, 10*time.Millisecond, counter, logging.NewTestLogger(nil, t))
+	require.Nil(t, err)
+	<-refreshes
+
+	shutdown := make(chan struct{})
+	k.Start(shutdown)
+	defer close(shutdown)
+
+	select {
+	case <-refreshes:
+	case <-time.After(time.Second):
+		assert.Fail(t, "keys were not refreshed")
+	}
+
+	// a non positive interval disables refreshes
+	var disabled *keyRefresher
+	disabled.Start(shutdown)
+}
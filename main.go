@@ -27,11 +27,11 @@ import (
 	"io"
 	"net"
 	"net/http"
-	_ "net/http/pprof"
 	"os"
 	"os/signal"
 	"regexp"
 	"runtime"
+	"syscall"
 	"time"
 
 	"github.com/xmidt-org/tr1d1um/common"
@@ -57,8 +57,8 @@ import (
 	"github.com/xmidt-org/webpa-common/server"
 	"github.com/xmidt-org/webpa-common/webhook"
 	"github.com/xmidt-org/webpa-common/webhook/aws"
-	"github.com/xmidt-org/webpa-common/xhttp"
 	"github.com/xmidt-org/webpa-common/xmetrics"
+	"golang.org/x/net/http2"
 )
 
 // convenient global values
@@ -68,15 +68,120 @@ const (
 
 	translationServicesKey            = "supportedServices"
 	targetURLKey                      = "targetURL"
+	targetURLsKey                     = "targetURLs"
+	targetCooldownKey                 = "targetCooldown"
 	netDialerTimeoutKey               = "netDialerTimeout"
 	clientTimeoutKey                  = "clientTimeout"
+	clientMaxIdleConnsKey             = "client.maxIdleConns"
+	clientMaxIdleConnsPerHostKey      = "client.maxIdleConnsPerHost"
+	clientIdleConnTimeoutKey          = "client.idleConnTimeout"
+	clientMaxConnsPerHostKey          = "client.maxConnsPerHost"
+	clientForceAttemptHTTP2Key        = "client.forceAttemptHTTP2"
+	clientHTTP2Key                    = "clientHTTP2"
+	clientMaxConcurrentRequestsKey    = "client.maxConcurrentRequests"
+	clientOverLimitBehaviorKey        = "client.overLimitBehavior"
+	clientOverLimitQueueTimeoutKey    = "client.overLimitQueueTimeout"
+	clientOverLimitQueueLengthKey     = "client.overLimitQueueLength"
+	keepWarmIntervalKey               = "client.keepWarm.interval"
+	responseHeaderAllowListKey        = "response.headerAllowList"
+	responseHeaderDenyListKey         = "response.headerDenyList"
+	keepWarmPathKey                   = "client.keepWarm.path"
+	keepWarmTimeoutKey                = "client.keepWarm.timeout"
+	keepWarmConnectionsKey            = "client.keepWarm.connections"
 	reqTimeoutKey                     = "respWaitTimeout"
+	reqMinTimeoutKey                  = "respWaitTimeoutMin"
+	reqMaxTimeoutKey                  = "respWaitTimeoutMax"
 	reqRetryIntervalKey               = "requestRetryInterval"
+	reqRetryBackoffKey                = "requestRetryBackoff"
+	reqRetryMaxIntervalKey            = "requestRetryMaxInterval"
+	reqRetryJitterKey                 = "requestRetryJitter"
+	reqRetryIdempotentOnlyKey         = "requestRetryIdempotentOnly"
+	reqAttemptTimeoutKey              = "requestAttemptTimeout"
+	reqRetryBudgetKey                 = "requestRetryBudget"
 	reqMaxRetriesKey                  = "requestMaxRetries"
+	respMinThroughputKey              = "responseMinThroughput"
+	respMinThroughputWindowKey        = "responseMinThroughputWindow"
 	WRPSourcekey                      = "WRPSource"
 	hooksSchemeKey                    = "hooksScheme"
 	reducedTransactionLoggingCodesKey = "log.reducedLoggingResponseCodes"
+	reducedTransactionLoggingPathsKey = "log.reducedLoggingPaths"
+	logKey                            = "log"
+	logFormatKey                      = "log.format"
+	logLevelKey                       = "log.level"
+	logOperationLevelsKey             = "log.operationLevels"
+	redactedHeadersKey                = "log.redactedHeaders"
 	authAcquirerKey                   = "authAcquirer"
+	authAcquirerBufferKey             = "authAcquirer.JWT.buffer"
+	authAcquirerCacheTTLKey           = "authAcquirer.cacheTTL"
+	localizationKey                   = "translation.localization"
+	tokenMaxAgeKey                    = "translation.tokenMaxAge"
+	analyticsKey                      = "translation.analytics"
+	latencyBucketsKey                 = "metrics.latencyBuckets"
+	knownPartnersKey                  = "metrics.knownPartners"
+	metricsRequireAuthKey             = "metrics.requireAuth"
+	metricsAuthorizationsKey          = "metrics.authorizations"
+	transactionLatencyBucketsKey      = "transactionLatencyBuckets"
+	statExpectedFieldsKey             = "stat.expectedFields"
+	statAllowPartialKey               = "stat.allowPartial"
+	statBatchWorkersKey               = "stat.batchWorkers"
+	statCacheTTLKey                   = "stat.cacheTTL"
+	statCacheSizeKey                  = "stat.cacheSize"
+	statAllowedMethodsKey             = "stat.allowedMethods"
+	statSupportedFormatsKey           = "stat.supportedFormats"
+	circuitBreakerFailureThresholdKey = "circuitBreaker.failureThreshold"
+	circuitBreakerCooldownKey         = "circuitBreaker.cooldown"
+	circuitBreakerHalfOpenProbesKey   = "circuitBreaker.halfOpenProbes"
+	readinessPathKey                  = "readiness.path"
+	readinessIntervalKey              = "readiness.interval"
+	readinessTimeoutKey               = "readiness.timeout"
+	wrpCompressionKey                 = "wrp.compression.enabled"
+	wrpDefaultContentTypeKey          = "wrp.defaultContentType"
+	wrpNegotiateEncodingKey           = "wrp.negotiateEncoding"
+	wrpAllowedContentTypesKey         = "wrp.allowedContentTypes"
+	wrpDefaultQOSKey                  = "wrp.defaultQos"
+	strictQueryParamsKey              = "strictQueryParams"
+	allowTransactionIDMismatchKey     = "translation.allowTransactionIDMismatch"
+	allowWildcardGetKey               = "translation.allowWildcardGet"
+	parameterAllowListKey             = "translation.parameterAllowList"
+	checksumAlgorithmsKey             = "translation.checksumAlgorithms"
+	onlinePreCheckKey                 = "translation.onlinePreCheck"
+	onlineStatusTTLKey                = "translation.onlineStatusTTL"
+	dedupEnabledKey                   = "translation.dedup.enabled"
+	dedupWindowKey                    = "translation.dedup.window"
+	deviceIDFormatsKey                = "translation.deviceIdSchemes"
+	partnerIDsKey                     = "translation.partnerIds"
+	requirePartnerIDKey               = "translation.requirePartnerID"
+	strictPayloadValidationKey        = "translation.strictPayloadValidation"
+	translationAllowedMethodsKey      = "translation.allowedMethods"
+	claimRulesKey                     = "claimRules"
+	basicAuthHashedKey                = "basicAuthHashed"
+	clientTLSKey                      = "clientTLS"
+	maxRequestBodyBytesKey            = "maxRequestBodyBytes"
+	tracingKey                        = "tracing"
+	requireContentLengthKey           = "requireContentLength"
+	shutdownDrainTimeoutKey           = "shutdownDrainTimeout"
+	readDuringWriteKey                = "readDuringWrite"
+	requireSupportedServicesKey       = "requireSupportedServices"
+	deadlineHeaderKey                 = "deadlineHeader"
+	allowedDeviceIDSchemesKey         = "allowedDeviceIdSchemes"
+	partnerDeviceIDSchemesKey         = "partnerDeviceIdSchemes"
+	rateLimitKey                      = "rateLimit"
+	basicAuthFileKey                  = "basicAuthFile"
+	perDeviceRateLimitKey             = "rateLimit.perDevice"
+	corsKey                           = "cors"
+	pprofKey                          = "pprof"
+	wrpSinkKey                        = "debug.wrpSink"
+	statEnabledKey                    = "services.stat.enabled"
+	translationEnabledKey             = "services.translation.enabled"
+	hooksEnabledKey                   = "services.hooks.enabled"
+	webhookStoreKey                   = "webhookStore"
+	http2Key                          = "server.http2"
+	serverReadTimeoutKey              = "server.readTimeout"
+	serverReadHeaderTimeoutKey        = "server.readHeaderTimeout"
+	serverWriteTimeoutKey             = "server.writeTimeout"
+	serverIdleTimeoutKey              = "server.idleTimeout"
+	clientUserAgentKey                = "clientUserAgent"
+	clientUserAgentHostnameKey        = "clientUserAgentHostname"
 )
 
 var (
@@ -87,22 +192,49 @@ var (
 )
 
 var defaults = map[string]interface{}{
-	translationServicesKey: []string{}, // no services allowed by the default
-	targetURLKey:           "localhost:6000",
-	netDialerTimeoutKey:    "5s",
-	clientTimeoutKey:       "50s",
-	reqTimeoutKey:          "40s",
-	reqRetryIntervalKey:    "2s",
-	reqMaxRetriesKey:       2,
-	WRPSourcekey:           "dns:localhost",
-	hooksSchemeKey:         "https",
+	translationServicesKey:       []string{}, // no services allowed by the default
+	targetURLKey:                 "localhost:6000",
+	netDialerTimeoutKey:          "5s",
+	clientTimeoutKey:             "50s",
+	clientMaxIdleConnsKey:        200,
+	clientMaxIdleConnsPerHostKey: 100,
+	clientIdleConnTimeoutKey:     "90s",
+	reqTimeoutKey:                "40s",
+	reqMinTimeoutKey:             "1s",
+	serverReadTimeoutKey:         "60s",
+	serverReadHeaderTimeoutKey:   "10s",
+	serverIdleTimeoutKey:         "120s",
+	reqRetryIntervalKey:          "2s",
+	reqRetryBackoffKey:           string(common.BackoffConstant),
+	reqRetryIdempotentOnlyKey:    true,
+	reqMaxRetriesKey:             2,
+	respMinThroughputKey:         0,
+	respMinThroughputWindowKey:   "10s",
+	WRPSourcekey:                 "dns:localhost",
+	hooksSchemeKey:               "https",
+	statEnabledKey:               true,
+	translationEnabledKey:        true,
+	readinessIntervalKey:         "30s",
+	readinessTimeoutKey:          "2s",
+	keepWarmTimeoutKey:           "2s",
+	keepWarmConnectionsKey:       1,
+	targetCooldownKey:            "30s",
+	circuitBreakerCooldownKey:    "30s",
+	maxRequestBodyBytesKey:       1 << 20,
+	allowWildcardGetKey:          true,
+	onlineStatusTTLKey:           "5s",
+	dedupWindowKey:               "10s",
+	wrpDefaultContentTypeKey:     "application/json",
+	wrpDefaultQOSKey:             -1,
+	authAcquirerCacheTTLKey:      "1m",
+	redactedHeadersKey:           []string{"Authorization"},
 }
 
 func tr1d1um(arguments []string) (exitCode int) {
 
 	var (
 		f, v                                = pflag.NewFlagSet(applicationName, pflag.ContinueOnError), viper.New()
-		logger, metricsRegistry, webPA, err = server.Initialize(applicationName, arguments, f, v, webhook.Metrics, aws.Metrics, basculechecks.Metrics, basculemetrics.Metrics)
+		logger, metricsRegistry, webPA, err = server.Initialize(applicationName, arguments, f, v, common.Metrics, latencyMetrics(v), transactionLatencyMetrics(v), webhook.Metrics, aws.Metrics, basculechecks.Metrics, basculemetrics.Metrics)
 	)
 
 	// This allows us to communicate the version of the binary upon request.
@@ -117,13 +249,22 @@ func tr1d1um(arguments []string) (exitCode int) {
 		return 1
 	}
 
+	levelLogger, err := loadConfig(v)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		return 1
+	}
+
+	logger = levelLogger
+
 	var (
 		infoLogger, errorLogger = logging.Info(logger), logging.Error(logger)
 		authenticate            *alice.Chain
 	)
 
-	for k, va := range defaults {
-		v.SetDefault(k, va)
+	if err := checkSupportedServices(v, logger); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		return 1
 	}
 
 	infoLogger.Log("configurationFile", v.ConfigFileUsed())
@@ -132,7 +273,35 @@ func tr1d1um(arguments []string) (exitCode int) {
 
 	APIRouter := r.PathPrefix(fmt.Sprintf("/%s/", apiBase)).Subrouter()
 
-	authenticate, err = authenticationHandler(v, logger, metricsRegistry)
+	// router middleware runs once a route is matched so requests can be counted per route
+	APIRouter.Use(common.InstrumentEndpointRequests(metricsRegistry.NewCounter(common.EndpointRequestsCounter)))
+
+	var tracingConfig common.TracingConfig
+	if err := v.UnmarshalKey(tracingKey, &tracingConfig); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to parse tracing config: %s\n", err.Error())
+		return 1
+	}
+
+	tracing, err := common.NewTracing(tracingConfig, logger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to set up tracing: %s\n", err.Error())
+		return 1
+	}
+
+	var clientTLS ClientTLSConfig
+	if err := v.UnmarshalKey(clientTLSKey, &clientTLS); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to parse client TLS config: %s\n", err.Error())
+		return 1
+	}
+
+	// the TLS handshake of the primary server then fails without a client certificate issued by the CAs
+	if clientTLS.CAFile != "" {
+		webPA.Primary.ClientCACertFile = clientTLS.CAFile
+	}
+
+	setServerTimeouts(v, &webPA.Primary)
+
+	authenticate, basicAuth, jwtKeys, err := authenticationHandler(v, logger, metricsRegistry, tracing)
 
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Unable to build authentication handler: %s\n", err.Error())
@@ -146,99 +315,435 @@ func tr1d1um(arguments []string) (exitCode int) {
 		return 1
 	}
 
+	pConfigs, err := newPoolConfigs(v)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to parse connection pool configuration values: %s \n", err.Error())
+		return 1
+	}
+
+	retryOptions, err := newRetryOptions(v, logger, tConfigs)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to parse retry configuration values: %s \n", err.Error())
+		return 1
+	}
+
+	targets, err := common.NewTargetPool(common.TargetPoolOptions{
+		TargetURLs: targetURLs(v),
+		Cooldown:   v.GetDuration(targetCooldownKey),
+		Logger:     logger,
+	})
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to parse target URLs: %s \n", err.Error())
+		return 1
+	}
+
+	// health of the XMiDT target is public so that load balancers can probe it without credentials
+	targetHealth := common.NewTargetHealth(common.TargetHealthOptions{
+		TargetURLs: targetURLs(v),
+		Path:       v.GetString(readinessPathKey),
+		Interval:   v.GetDuration(readinessIntervalKey),
+		Timeout:    v.GetDuration(readinessTimeoutKey),
+	})
+
+	r.Handle("/health", targetHealth).Methods(http.MethodGet)
+
+	// disabled services don't get a client, and hence a warmer, of their own
+	var statWarmer, translationWarmer *common.PoolWarmer
+
+	tlsHandshakeFailures := metricsRegistry.NewCounter(common.TLSHandshakeFailuresCounter)
+	latencyHistogram := metricsRegistry.NewHistogram(common.RequestLatencyHistogram, 0)
+	if tracing != nil {
+		// traced requests point to their trace from the latency histogram
+		latencyHistogram = common.NewExemplarHistogram(metricsRegistry.NewHistogramVec(common.RequestLatencyHistogram))
+	}
+	transactionLatency := metricsRegistry.NewHistogram(common.TransactionLatencyHistogram, 0)
+	partnerRequests := metricsRegistry.NewCounter(common.PartnerRequestsCounter)
+
+	circuitBreaker := common.NewCircuitBreaker(common.CircuitBreakerOptions{
+		FailureThreshold: v.GetInt(circuitBreakerFailureThresholdKey),
+		Cooldown:         v.GetDuration(circuitBreakerCooldownKey),
+		HalfOpenProbes:   v.GetInt(circuitBreakerHalfOpenProbesKey),
+		Logger:           logger,
+		StateTransitions: metricsRegistry.NewCounter(common.CircuitBreakerStateTransitionsCounter),
+	})
+
 	//
-	// Webhooks (if not configured, handler for webhooks is not set up)
+	// Webhooks (if not enabled, handler for webhooks is not set up)
 	//
-	var webhookStoreConfig chrysom.ClientConfig
+	if hooksEnabled(v) {
+		var webhookStoreConfig chrysom.ClientConfig
+		if err := v.UnmarshalKey(webhookStoreKey, &webhookStoreConfig); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to parse webhook store config: %s\n", err.Error())
+			return 1
+		}
 
-	if err := v.UnmarshalKey("webhookStore", &webhookStoreConfig); err == nil {
+		var callbackURLs hooks.CallbackURLConfig
+		if err := v.UnmarshalKey(webhookStoreKey, &callbackURLs); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to parse webhook callback URL config: %s\n", err.Error())
+			return 1
+		}
+
+		if len(callbackURLs.AllowedSchemes) == 0 {
+			callbackURLs.AllowedSchemes = []string{v.GetString(hooksSchemeKey)}
+		}
+
+		callbackValidator, err := hooks.NewCallbackValidator(callbackURLs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to build webhook callback URL validator: %s\n", err.Error())
+			return 1
+		}
 
 		hooks.ConfigHandler(&hooks.Options{
-			APIRouter:          APIRouter,
-			Authenticate:       authenticate,
-			Log:                logger,
-			WebhookStoreConfig: webhookStoreConfig,
+			APIRouter:           APIRouter,
+			Authenticate:        authenticate,
+			Log:                 logger,
+			WebhookStoreConfig:  webhookStoreConfig,
+			MaxRequestBodyBytes: v.GetInt64(maxRequestBodyBytesKey),
+			CallbackValidator:   callbackValidator,
 		})
 
 	} else {
-		infoLogger.Log(logging.MessageKey(), "webhookStore disabled")
+		infoLogger.Log(logging.MessageKey(), "webhooks disabled")
 	}
 
+	responseHeaders := common.NewHeaderFilter(v.GetStringSlice(responseHeaderAllowListKey), v.GetStringSlice(responseHeaderDenyListKey))
+
+	// the limit is shared by the stat and WRP transactors as both open connections to XMiDT
+	overLimitBehavior, _ := common.ParseOverLimitBehavior(v.GetString(clientOverLimitBehaviorKey))
+	concurrencyLimiter := common.NewConcurrencyLimiter(common.ConcurrencyLimiterOptions{
+		MaxConcurrentRequests: v.GetInt(clientMaxConcurrentRequestsKey),
+		OverLimitBehavior:     overLimitBehavior,
+		QueueTimeout:          v.GetDuration(clientOverLimitQueueTimeoutKey),
+		QueueLength:           v.GetInt(clientOverLimitQueueLengthKey),
+		InFlight:              metricsRegistry.NewGauge(common.OutboundRequestsInFlightGauge),
+		QueueDepth:            metricsRegistry.NewGauge(common.OutboundRequestsQueuedGauge),
+	})
+
+	clientUserAgent := userAgent(v)
+
+	settings := common.NewSettings(newSnapshot(v))
+
+	var acquirer acquire.Acquirer
+	if v.IsSet(authAcquirerKey) {
+		if a, err := createAuthAcquirer(v, logger); err != nil {
+			errorLogger.Log(logging.MessageKey(), "Could not configure auth acquirer", logging.ErrorKey(), err)
+		} else {
+			acquirer = common.NewCachingAcquirer(common.CachingAcquirerOptions{
+				Acquirer:        a,
+				Buffer:          v.GetDuration(authAcquirerBufferKey),
+				TTL:             v.GetDuration(authAcquirerCacheTTLKey),
+				Lookups:         metricsRegistry.NewCounter(common.AuthTokenCacheLookupsCounter),
+				RefreshFailures: metricsRegistry.NewCounter(common.AuthTokenRefreshFailuresCounter),
+			})
+
+			infoLogger.Log(logging.MessageKey(), "Outbound request authentication token acquirer enabled")
+		}
+	}
+
+	// clients of the device endpoints may override respWaitTimeout within the configured bounds
+	deviceAuthenticate := authenticate.Append(common.RequestTimeout(common.RequestTimeoutOptions{
+		Min:    v.GetDuration(reqMinTimeoutKey),
+		Max:    v.GetDuration(reqMaxTimeoutKey),
+		Logger: logger,
+	}))
+
+	statEnabled, translationEnabled := v.GetBool(statEnabledKey), v.GetBool(translationEnabledKey)
+
 	//
 	// Stat Service configs
 	//
-	statServiceOptions := &stat.ServiceOptions{
-		HTTPTransactor: common.NewTr1d1umTransactor(
-			&common.Tr1d1umTransactorOptions{
-				Do: xhttp.RetryTransactor(
-					xhttp.RetryOptions{
-						Logger:   logger,
-						Retries:  v.GetInt(reqMaxRetriesKey),
-						Interval: v.GetDuration(reqRetryIntervalKey),
-					},
-					newClient(v, tConfigs).Do),
-				RequestTimeout: tConfigs.rTimeout,
-			}),
-		XmidtStatURL: fmt.Sprintf("%s/%s/device/${device}/stat", v.GetString(targetURLKey), apiBase),
+	var ss stat.Service
+	if statEnabled {
+		statClient, err := newClient(v, tConfigs, pConfigs, tracing)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to build HTTP client: %s \n", err.Error())
+			return 1
+		}
+
+		statWarmer = newPoolWarmer(v, statClient, logger)
+
+		ss = stat.NewService(&stat.ServiceOptions{
+			HTTPTransactor: common.NewTr1d1umTransactor(
+				&common.Tr1d1umTransactorOptions{
+					Do:                   circuitBreaker.Do(common.RetryTransactor(retryOptions, targets.Do(common.DeadlineHeader(v.GetString(deadlineHeaderKey), statWarmer.Track(statClient.Do))))),
+					Endpoint:             "stat",
+					RequestTimeout:       tConfigs.rTimeout,
+					MinThroughput:        v.GetInt64(respMinThroughputKey),
+					ThroughputWindow:     v.GetDuration(respMinThroughputWindowKey),
+					Logger:               logger,
+					TLSHandshakeFailures: tlsHandshakeFailures,
+					TransactionLatency:   transactionLatency,
+					ResponseHeaders:      responseHeaders,
+					ConcurrencyLimiter:   concurrencyLimiter,
+					UserAgent:            clientUserAgent,
+				}),
+			XmidtStatURL:     fmt.Sprintf("%s/%s/device/${device}/stat", targets.Primary(), apiBase),
+			AuthAcquirer:     acquirer,
+			ExpectedFields:   v.GetStringSlice(statExpectedFieldsKey),
+			AllowPartial:     v.GetBool(statAllowPartialKey),
+			StatCacheTTL:     v.GetDuration(statCacheTTLKey),
+			StatCacheSize:    v.GetInt(statCacheSizeKey),
+			StatCacheLookups: metricsRegistry.NewCounter(common.StatCacheLookupsCounter),
+		})
+	} else {
+		infoLogger.Log(logging.MessageKey(), "stat service disabled")
 	}
 
 	//
 	// WRP Service configs
 	//
-	translationOptions := &translation.ServiceOptions{
-		XmidtWrpURL: fmt.Sprintf("%s/%s/device", v.GetString(targetURLKey), apiBase),
+	var (
+		ts      translation.Service
+		wrpSink *translation.WRPSink
+	)
 
-		WRPSource: v.GetString(WRPSourcekey),
+	if translationEnabled {
+		translationClient, err := newClient(v, tConfigs, pConfigs, tracing)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to build HTTP client: %s \n", err.Error())
+			return 1
+		}
 
-		Tr1d1umTransactor: common.NewTr1d1umTransactor(
-			&common.Tr1d1umTransactorOptions{
-				RequestTimeout: tConfigs.rTimeout,
-				Do: xhttp.RetryTransactor(
-					xhttp.RetryOptions{
-						Logger:   logger,
-						Retries:  v.GetInt(reqMaxRetriesKey),
-						Interval: v.GetDuration(reqRetryIntervalKey),
-					},
-					newClient(v, tConfigs).Do),
-			}),
+		translationWarmer = newPoolWarmer(v, translationClient, logger)
+
+		var wrpSinkConfig translation.WRPSinkConfig
+		if err := v.UnmarshalKey(wrpSinkKey, &wrpSinkConfig); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to parse WRP sink config: %s\n", err.Error())
+			return 1
+		}
+
+		if wrpSink, err = translation.NewWRPSink(wrpSinkConfig, logger); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to start WRP sink: %s\n", err.Error())
+			return 1
+		}
+
+		if wrpSink != nil {
+			infoLogger.Log(logging.MessageKey(), "mirroring outbound WRP messages", "target", wrpSinkConfig.Target)
+		}
+
+		translationOptions := &translation.ServiceOptions{
+			XmidtWrpURL: fmt.Sprintf("%s/%s/device", targets.Primary(), apiBase),
+
+			WRPSource: v.GetString(WRPSourcekey),
+
+			AuthAcquirer: acquirer,
+
+			Tr1d1umTransactor: common.NewTr1d1umTransactor(
+				&common.Tr1d1umTransactorOptions{
+					RequestTimeout:       tConfigs.rTimeout,
+					Do:                   circuitBreaker.Do(common.RetryTransactor(retryOptions, targets.Do(common.DeadlineHeader(v.GetString(deadlineHeaderKey), translationWarmer.Track(translationClient.Do))))),
+					Endpoint:             "translation",
+					MinThroughput:        v.GetInt64(respMinThroughputKey),
+					ThroughputWindow:     v.GetDuration(respMinThroughputWindowKey),
+					Logger:               logger,
+					TLSHandshakeFailures: tlsHandshakeFailures,
+					TransactionLatency:   transactionLatency,
+					DecompressResponses:  v.GetBool(wrpCompressionKey),
+					ResponseHeaders:      responseHeaders,
+					ConcurrencyLimiter:   concurrencyLimiter,
+					UserAgent:            clientUserAgent,
+				}),
+
+			Logger:                     logger,
+			TransactionIDMismatches:    metricsRegistry.NewCounter(common.TransactionIDMismatchesCounter),
+			AllowTransactionIDMismatch: v.GetBool(allowTransactionIDMismatchKey),
+			WRPSink:                    wrpSink,
+		}
+
+		// validateConfig makes sure the stat service is enabled for the pre-check
+		var onlinePreCheck *translation.OnlinePreCheck
+		if v.GetBool(onlinePreCheckKey) {
+			onlinePreCheck = translation.NewOnlinePreCheck(ss.RequestStat, v.GetDuration(onlineStatusTTLKey))
+		}
+
+		var dedup *translation.Dedup
+		if v.GetBool(dedupEnabledKey) {
+			dedup = translation.NewDedup(v.GetDuration(dedupWindowKey))
+		}
+
+		// duplicates are caught before they cost an online check
+		ts = dedup.Then(onlinePreCheck.Then(translation.NewService(translationOptions)))
+	} else {
+		infoLogger.Log(logging.MessageKey(), "translation service disabled")
 	}
 
-	reducedLoggingResponseCodes := v.GetIntSlice(reducedTransactionLoggingCodesKey)
+	readDuringWrite, err := common.NewReadDuringWrite(v.GetString(readDuringWriteKey))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to configure read during write handling: %s \n", err.Error())
+		return 1
+	}
 
-	if v.IsSet(authAcquirerKey) {
-		acquirer, err := createAuthAcquirer(v)
-		if err != nil {
-			errorLogger.Log(logging.MessageKey(), "Could not configure auth acquirer", logging.ErrorKey(), err)
-		} else {
-			translationOptions.AuthAcquirer = acquirer
-			statServiceOptions.AuthAcquirer = acquirer
-			infoLogger.Log(logging.MessageKey(), "Outbound request authentication token acquirer enabled")
+	var deviceRateLimiter *common.RateLimiter
+	if v.IsSet(perDeviceRateLimitKey) {
+		var perDevice common.DeviceRateLimitConfig
+		if err := v.UnmarshalKey(perDeviceRateLimitKey, &perDevice); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to parse per device rate limit: %s \n", err.Error())
+			return 1
 		}
+
+		deviceRateLimiter = common.NewDeviceRateLimiter(perDevice, metricsRegistry.NewCounter(common.DeviceThrottledRequestsCounter))
 	}
 
-	ss := stat.NewService(statServiceOptions)
-	ts := translation.NewService(translationOptions)
+	deviceIDSchemes := common.NewDeviceIDSchemes(v.GetStringSlice(allowedDeviceIDSchemesKey), v.GetStringMapStringSlice(partnerDeviceIDSchemesKey))
 
 	// Must be called before translation.ConfigHandler due to mux path specificity (https://github.com/gorilla/mux#matching-routes).
-	stat.ConfigHandler(&stat.Options{
-		S:                           ss,
-		APIRouter:                   APIRouter,
-		Authenticate:                authenticate,
-		Log:                         logger,
-		ReducedLoggingResponseCodes: reducedLoggingResponseCodes,
-	})
+	operationLevels := common.NewOperationLevels(levelLogger, v.GetStringMapString(logOperationLevelsKey))
+
+	statFormats, _ := stat.ParseFormats(v.GetStringSlice(statSupportedFormatsKey))
+
+	if statEnabled {
+		stat.ConfigHandler(&stat.Options{
+			S:                   ss,
+			APIRouter:           APIRouter,
+			Authenticate:        &deviceAuthenticate,
+			Log:                 logger,
+			Settings:            settings,
+			LatencyHistogram:    latencyHistogram,
+			PartnerRequests:     partnerRequests,
+			KnownPartners:       v.GetStringSlice(knownPartnersKey),
+			StrictQueryParams:   v.GetBool(strictQueryParamsKey),
+			ReadDuringWrite:     readDuringWrite,
+			DeviceIDSchemes:     deviceIDSchemes,
+			OperationLevels:     operationLevels,
+			BatchWorkers:        v.GetInt(statBatchWorkersKey),
+			MaxRequestBodyBytes: v.GetInt64(maxRequestBodyBytesKey),
+			AllowedMethods:      v.GetStringSlice(statAllowedMethodsKey),
+			Formats:             statFormats,
+		})
+	}
 
-	translation.ConfigHandler(&translation.Options{
-		S:                           ts,
-		APIRouter:                   APIRouter,
-		Authenticate:                authenticate,
-		Log:                         logger,
-		ValidServices:               v.GetStringSlice(translationServicesKey),
-		ReducedLoggingResponseCodes: reducedLoggingResponseCodes,
-	})
+	var localization translation.LocalizationConfig
+	if err := v.UnmarshalKey(localizationKey, &localization); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to parse localization config: %s\n", err.Error())
+		return 1
+	}
+
+	var tokenMaxAge map[string]time.Duration
+	if err := v.UnmarshalKey(tokenMaxAgeKey, &tokenMaxAge); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to parse token max age configuration values: %s \n", err.Error())
+		return 1
+	}
+
+	var analyticsConfig common.AnalyticsConfig
+	if err := v.UnmarshalKey(analyticsKey, &analyticsConfig); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to parse analytics configuration values: %s \n", err.Error())
+		return 1
+	}
+
+	analyticsLogger, err := common.NewAnalyticsLogger(analyticsConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to build analytics logger: %s \n", err.Error())
+		return 1
+	}
+
+	checksumAlgorithms, err := translation.ParseChecksumAlgorithms(v.GetStringSlice(checksumAlgorithmsKey))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to parse checksum algorithms: %s \n", err.Error())
+		return 1
+	}
+
+	var partnerIDs translation.PartnerIDsConfig
+	if err := v.UnmarshalKey(partnerIDsKey, &partnerIDs); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to parse partner ids config: %s \n", err.Error())
+		return 1
+	}
+
+	deviceIDFormats, err := translation.ParseDeviceIDFormats(v.GetStringSlice(deviceIDFormatsKey))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to parse device id schemes: %s \n", err.Error())
+		return 1
+	}
+
+	if translationEnabled {
+		translation.ConfigHandler(&translation.Options{
+			S:                       ts,
+			APIRouter:               APIRouter,
+			Authenticate:            &deviceAuthenticate,
+			Log:                     logger,
+			Settings:                settings,
+			Localization:            &localization,
+			TokenMaxAge:             tokenMaxAge,
+			AnalyticsLogger:         analyticsLogger,
+			LatencyHistogram:        latencyHistogram,
+			PartnerRequests:         partnerRequests,
+			KnownPartners:           v.GetStringSlice(knownPartnersKey),
+			Compression:             v.GetBool(wrpCompressionKey),
+			NegotiateEncoding:       v.GetBool(wrpNegotiateEncodingKey),
+			DefaultContentType:      v.GetString(wrpDefaultContentTypeKey),
+			AllowedContentTypes:     v.GetStringSlice(wrpAllowedContentTypesKey),
+			DefaultQOS:              v.GetInt(wrpDefaultQOSKey),
+			AllowWildcardGet:        v.GetBool(allowWildcardGetKey),
+			ChecksumAlgorithms:      checksumAlgorithms,
+			StrictQueryParams:       v.GetBool(strictQueryParamsKey),
+			MaxRequestBodyBytes:     v.GetInt64(maxRequestBodyBytesKey),
+			RequireContentLength:    v.GetBool(requireContentLengthKey),
+			ReadDuringWrite:         readDuringWrite,
+			DeviceIDSchemes:         deviceIDSchemes,
+			DeviceIDFormats:         deviceIDFormats,
+			PartnerIDs:              &partnerIDs,
+			RequirePartnerID:        v.GetBool(requirePartnerIDKey),
+			StrictPayloadValidation: v.GetBool(strictPayloadValidationKey),
+			AllowedMethods:          v.GetStringSlice(translationAllowedMethodsKey),
+			DeviceRateLimiter:       deviceRateLimiter,
+			OperationLevels:         operationLevels,
+		})
+	}
+
+	var corsConfig common.CORSConfig
+	if err := v.UnmarshalKey(corsKey, &corsConfig); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to parse CORS config: %s\n", err.Error())
+		return 1
+	}
+
+	// preflight requests are answered ahead of the router so they skip the authentication of the API routes
+	cors := common.NewCORS(corsConfig, fmt.Sprintf("/%s/", apiBase))
+
+	drainer := common.NewDrainer(logger)
+
+	// the latency exemplars of traced requests are only exposed by the tr1d1um metrics server
+	var metricsServer *http.Server
+	if requireAuth := v.GetBool(metricsRequireAuthKey); requireAuth || (tracing != nil && webPA.Metric.Address != "") {
+		var auth *metricsAuth
+		if requireAuth {
+			auth, err = newMetricsAuth(v.GetStringSlice(metricsAuthorizationsKey))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Unable to set up metrics authentication: %s\n", err.Error())
+				return 1
+			}
+		}
+
+		// webpa-common doesn't start its own metrics server without an address
+		metricsServer, err = newMetricsServer(logger, webPA.Metric, webPA.Primary, metricsRegistry, auth)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to set up metrics server: %s\n", err.Error())
+			return 1
+		}
+
+		webPA.Metric.Address = ""
+	}
+
+	var pprofConfig PprofConfig
+	if err := v.UnmarshalKey(pprofKey, &pprofConfig); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to parse pprof config: %s\n", err.Error())
+		return 1
+	}
+
+	// webpa-common would serve the default mux, where net/http/pprof registers itself, at pprof.address
+	// whether or not pprof is enabled
+	pprofServer := newPprofServer(pprofConfig)
+	webPA.Pprof.Address = ""
+
+	var http2Config HTTP2Config
+	if err := v.UnmarshalKey(http2Key, &http2Config); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to parse HTTP/2 config: %s\n", err.Error())
+		return 1
+	}
 
 	var (
-		_, tr1d1umServer, done = webPA.Prepare(logger, nil, metricsRegistry, r)
+		_, tr1d1umServer, done = webPA.Prepare(logger, nil, metricsRegistry, http2Config.Then(tracing.Then(drainer.Then(cors.Then(r)))))
 		signals                = make(chan os.Signal, 10)
 	)
 
@@ -252,10 +757,29 @@ func tr1d1um(arguments []string) (exitCode int) {
 		return 4
 	}
 
-	signal.Notify(signals, os.Kill, os.Interrupt)
+	serve(metricsServer, "metrics", errorLogger)
+	serve(pprofServer, "pprof", errorLogger)
+
+	targetHealth.Start(shutdown)
+	statWarmer.Start(shutdown)
+	translationWarmer.Start(shutdown)
+	jwtKeys.Start(shutdown)
+
+	signal.Notify(signals, os.Kill, os.Interrupt, syscall.SIGHUP)
 	for exit := false; !exit; {
 		select {
 		case s := <-signals:
+			if s == syscall.SIGHUP {
+				if err := reloadConfig(v, settings, levelLogger); err != nil {
+					errorLogger.Log(logging.MessageKey(), "Unable to reload configuration. Keeping the current one", logging.ErrorKey(), err)
+				}
+
+				if err := basicAuth.load(); err != nil {
+					errorLogger.Log(logging.MessageKey(), "Unable to reload basic auth file. Keeping the current credentials", logging.ErrorKey(), err)
+				}
+				continue
+			}
+
 			logger.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "exiting due to signal", "signal", s)
 			exit = true
 		case <-done:
@@ -264,12 +788,55 @@ func tr1d1um(arguments []string) (exitCode int) {
 		}
 	}
 
+	// reject new requests and give in-flight ones a chance to complete before servers are closed, see Drainer.Drain
+	if drainTimeout := v.GetDuration(shutdownDrainTimeoutKey); drainTimeout > 0 {
+		infoLogger.Log(logging.MessageKey(), "draining in-flight requests", "inFlight", drainer.InFlight(), "timeout", drainTimeout)
+		drainer.Drain(drainTimeout)
+	}
+
 	close(shutdown)
 	waitGroup.Wait()
 
+	for _, server := range []*http.Server{metricsServer, pprofServer} {
+		if server != nil {
+			server.Close()
+		}
+	}
+
+	wrpSink.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+	defer cancel()
+	if err := tracing.Shutdown(ctx); err != nil {
+		errorLogger.Log(logging.MessageKey(), "Unable to export pending spans", logging.ErrorKey(), err)
+	}
+
 	return 0
 }
 
+// tracingShutdownTimeout bounds the export of the pending spans at exit
+const tracingShutdownTimeout = 5 * time.Second
+
+// serve runs server, which tr1d1um manages rather than webpa-common, in the background until it's closed.
+// It's a no-op for nil servers
+func serve(server *http.Server, name string, errorLogger log.Logger) {
+	if server == nil {
+		return
+	}
+
+	listenAndServe := server.ListenAndServe
+	if server.TLSConfig != nil {
+		// the certificates are part of the TLS config already
+		listenAndServe = func() error { return server.ListenAndServeTLS("", "") }
+	}
+
+	go func() {
+		if err := listenAndServe(); err != nil && err != http.ErrServerClosed {
+			errorLogger.Log(logging.MessageKey(), "Server exited", "server", name, logging.ErrorKey(), err)
+		}
+	}()
+}
+
 // timeoutConfigs holds parsable config values for HTTP transactions
 type timeoutConfigs struct {
 	// HTTP client timeout
@@ -282,7 +849,123 @@ type timeoutConfigs struct {
 	dTimeout time.Duration
 }
 
-func createAuthAcquirer(v *viper.Viper) (acquire.Acquirer, error) {
+// poolConfigs holds parsable config values for the connection pool of HTTP clients
+type poolConfigs struct {
+	// max idle connections across all hosts
+	maxIdleConns int
+
+	// max idle connections per host
+	maxIdleConnsPerHost int
+
+	// time idle connections are kept open
+	idleConnTimeout time.Duration
+
+	// max connections per host, whether idle or not
+	maxConnsPerHost int
+}
+
+// latencyMetrics defers reading the latency histogram buckets until the configuration is loaded
+func latencyMetrics(v *viper.Viper) func() []xmetrics.Metric {
+	return func() []xmetrics.Metric {
+		var buckets []float64
+		v.UnmarshalKey(latencyBucketsKey, &buckets)
+		return common.LatencyMetrics(buckets)
+	}
+}
+
+// loadConfig applies the defaults and environment variable overrides to v, validates it and builds the logger.
+// Nothing may read v before, the logger included, for it to be configured the same way reloadConfig does.
+func loadConfig(v *viper.Viper) (*common.LevelLogger, error) {
+	if err := configure(v); err != nil {
+		return nil, fmt.Errorf("Unable to apply environment variable overrides: %s", err)
+	}
+
+	if err := validateConfig(v); err != nil {
+		return nil, err
+	}
+
+	logger, err := newLogger(v)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to configure logging: %s", err)
+	}
+
+	return logger, nil
+}
+
+// Supported values of the log.format config
+const (
+	logFormatLogfmt = "logfmt"
+	logFormatJSON   = "json"
+)
+
+// newLogger rebuilds the logger created by server.Initialize such that its level can be reloaded at runtime.
+// log.format picks the encoding when set. Otherwise, the webpa-common log.json flag decides it.
+func newLogger(v *viper.Viper) (*common.LevelLogger, error) {
+	o := new(logging.Options)
+	if err := v.UnmarshalKey(logKey, o); err != nil {
+		return nil, err
+	}
+
+	switch format := v.GetString(logFormatKey); format {
+	case "":
+	case logFormatLogfmt, logFormatJSON:
+		o.JSON = format == logFormatJSON
+	default:
+		return nil, fmt.Errorf("unsupported log format '%s'", format)
+	}
+
+	lvl := o.Level
+
+	// filtering is left to the LevelLogger
+	o.Level = "DEBUG"
+	return common.NewLevelLogger(logging.New(o), lvl), nil
+}
+
+func transactionLatencyMetrics(v *viper.Viper) func() []xmetrics.Metric {
+	return func() []xmetrics.Metric {
+		var buckets []float64
+		v.UnmarshalKey(transactionLatencyBucketsKey, &buckets)
+		return common.TransactionLatencyMetrics(buckets)
+	}
+}
+
+// newRetryOptions builds the retry configuration for outbound requests to the XMiDT API.
+// The interval between retries is never allowed to exceed the request timeout.
+func newRetryOptions(v *viper.Viper, logger log.Logger, t *timeoutConfigs) (o common.RetryOptions, err error) {
+	var backoff common.BackoffStrategy
+	if backoff, err = common.ParseBackoffStrategy(v.GetString(reqRetryBackoffKey)); err != nil {
+		return
+	}
+
+	maxInterval := v.GetDuration(reqRetryMaxIntervalKey)
+	if maxInterval <= 0 || maxInterval > t.rTimeout {
+		maxInterval = t.rTimeout
+	}
+
+	o = common.RetryOptions{
+		Logger:         logger,
+		Retries:        v.GetInt(reqMaxRetriesKey),
+		Interval:       v.GetDuration(reqRetryIntervalKey),
+		Backoff:        backoff,
+		MaxInterval:    maxInterval,
+		Jitter:         v.GetDuration(reqRetryJitterKey),
+		AttemptTimeout: v.GetDuration(reqAttemptTimeoutKey),
+		Budget:         v.GetDuration(reqRetryBudgetKey),
+		IdempotentOnly: v.GetBool(reqRetryIdempotentOnlyKey),
+	}
+	return
+}
+
+// targetURLs returns the configured XMiDT base URLs. The singular targetURL is used
+// when no list is configured
+func targetURLs(v *viper.Viper) []string {
+	if urls := v.GetStringSlice(targetURLsKey); len(urls) > 0 {
+		return urls
+	}
+	return []string{v.GetString(targetURLKey)}
+}
+
+func createAuthAcquirer(v *viper.Viper, logger log.Logger) (acquire.Acquirer, error) {
 	var options authAcquirerConfig
 	err := v.UnmarshalKey(authAcquirerKey, &options)
 
@@ -291,7 +974,17 @@ func createAuthAcquirer(v *viper.Viper) (acquire.Acquirer, error) {
 	}
 
 	if options.JWT.AuthURL != "" && options.JWT.Buffer != 0 && options.JWT.Timeout != 0 {
-		return acquire.NewRemoteBearerTokenAcquirer(options.JWT)
+		jwt, err := acquire.NewRemoteBearerTokenAcquirer(options.JWT)
+		if err != nil || options.Fallback.Token == "" {
+			return jwt, err
+		}
+
+		fallback, err := acquire.NewFixedAuthAcquirer(options.Fallback.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		return &fallbackAcquirer{primary: jwt, fallback: fallback, logger: logger}, nil
 	}
 
 	if options.Basic != "" {
@@ -317,22 +1010,161 @@ func newTimeoutConfigs(v *viper.Viper) (t *timeoutConfigs, err error) {
 	return
 }
 
-func newClient(v *viper.Viper, t *timeoutConfigs) *http.Client {
+// serverWriteTimeoutMargin is the time the primary server has left to write the responses of the slowest requests
+const serverWriteTimeoutMargin = 15 * time.Second
+
+// setServerTimeouts applies the configured timeouts to the primary server so that slow clients can't hold its
+// connections indefinitely. Timeouts already set in the webpa primary config take precedence. The write timeout
+// defaults to the longest time a request may take, which clients of the device endpoints may raise up to
+// respWaitTimeoutMax, plus serverWriteTimeoutMargin.
+func setServerTimeouts(v *viper.Viper, primary *server.Basic) {
+	if primary.ReadTimeout <= 0 {
+		primary.ReadTimeout = v.GetDuration(serverReadTimeoutKey)
+	}
+
+	if primary.ReadHeaderTimeout <= 0 {
+		primary.ReadHeaderTimeout = v.GetDuration(serverReadHeaderTimeoutKey)
+	}
+
+	if primary.IdleTimeout <= 0 {
+		primary.IdleTimeout = v.GetDuration(serverIdleTimeoutKey)
+	}
+
+	if primary.WriteTimeout > 0 {
+		return
+	}
+
+	if primary.WriteTimeout = v.GetDuration(serverWriteTimeoutKey); primary.WriteTimeout <= 0 {
+		longest := v.GetDuration(reqTimeoutKey)
+		if max := v.GetDuration(reqMaxTimeoutKey); max > longest {
+			longest = max
+		}
+		primary.WriteTimeout = longest + serverWriteTimeoutMargin
+	}
+}
+
+// userAgent returns the User-Agent header of requests to XMiDT. Unless configured, it identifies this tr1d1um
+// build and, when enabled, the host it runs on.
+func userAgent(v *viper.Viper) string {
+	if ua := v.GetString(clientUserAgentKey); ua != "" {
+		return ua
+	}
+
+	version := Version
+	if version == "" {
+		version = "dev"
+	}
+
+	ua := applicationName + "/" + version
+	if v.GetBool(clientUserAgentHostnameKey) {
+		if hostname, err := os.Hostname(); err == nil && hostname != "" {
+			ua += " (" + hostname + ")"
+		}
+	}
+	return ua
+}
+
+// hooksEnabled reports whether the webhook endpoints are served. Unless set explicitly, they are
+// whenever a webhook store is configured
+func hooksEnabled(v *viper.Viper) bool {
+	if v.IsSet(hooksEnabledKey) {
+		return v.GetBool(hooksEnabledKey)
+	}
+	return v.IsSet(webhookStoreKey)
+}
+
+func newPoolConfigs(v *viper.Viper) (p *poolConfigs, err error) {
+	p = &poolConfigs{
+		maxIdleConns:        v.GetInt(clientMaxIdleConnsKey),
+		maxIdleConnsPerHost: v.GetInt(clientMaxIdleConnsPerHostKey),
+		maxConnsPerHost:     v.GetInt(clientMaxConnsPerHostKey),
+	}
+
+	if p.idleConnTimeout, err = time.ParseDuration(v.GetString(clientIdleConnTimeoutKey)); err != nil {
+		return nil, err
+	}
+
+	if p.maxIdleConns < 0 || p.maxIdleConnsPerHost < 0 || p.idleConnTimeout < 0 || p.maxConnsPerHost < 0 {
+		return nil, errors.New("connection pool values must not be negative")
+	}
+
+	return
+}
+
+// newPoolWarmer keeps the connection pool of client warm if client.keepWarm.interval is set
+func newPoolWarmer(v *viper.Viper, client *http.Client, logger log.Logger) *common.PoolWarmer {
+	return common.NewPoolWarmer(common.PoolWarmerOptions{
+		TargetURLs:  targetURLs(v),
+		Path:        v.GetString(keepWarmPathKey),
+		Interval:    v.GetDuration(keepWarmIntervalKey),
+		Timeout:     v.GetDuration(keepWarmTimeoutKey),
+		Connections: v.GetInt(keepWarmConnectionsKey),
+		Do:          client.Do,
+		Logger:      logger,
+	})
+}
+
+func newClient(v *viper.Viper, t *timeoutConfigs, p *poolConfigs, tracing *common.Tracing) (*http.Client, error) {
+	transport := &http.Transport{
+		Dial: (&net.Dialer{
+			Timeout: t.dTimeout,
+		}).Dial,
+		MaxIdleConns:        p.maxIdleConns,
+		MaxIdleConnsPerHost: p.maxIdleConnsPerHost,
+		IdleConnTimeout:     p.idleConnTimeout,
+		MaxConnsPerHost:     p.maxConnsPerHost,
+		ForceAttemptHTTP2:   v.GetBool(clientForceAttemptHTTP2Key),
+	}
+
+	// HTTP/2 is negotiated through ALPN so XMiDT servers without support keep getting HTTP/1.1
+	if transport.ForceAttemptHTTP2 {
+		if err := http2.ConfigureTransport(transport); err != nil {
+			return nil, err
+		}
+	}
+
+	// outbound requests carry the trace context so XMiDT can continue the trace
 	return &http.Client{
-		Timeout: t.cTimeout,
-		Transport: &http.Transport{
-			Dial: (&net.Dialer{
-				Timeout: t.dTimeout,
-			}).Dial},
+		Timeout:   t.cTimeout,
+		Transport: tracing.Transport(transport),
+	}, nil
+}
+
+// redactedPlaceholder replaces the values of redacted headers in logs
+const redactedPlaceholder = "[REDACTED]"
+
+// redactHeaders returns a copy of h where the values of the redacted headers (canonical keys) are replaced
+func redactHeaders(h http.Header, redacted map[string]bool) http.Header {
+	if len(redacted) == 0 {
+		return h
 	}
+
+	c := make(http.Header, len(h))
+	for k, values := range h {
+		if redacted[http.CanonicalHeaderKey(k)] {
+			c[k] = []string{redactedPlaceholder}
+		} else {
+			c[k] = values
+		}
+	}
+	return c
 }
 
-func SetLogger(logger log.Logger) func(delegate http.Handler) http.Handler {
+// SetLogger attaches a request specific logger to the request context. The values of redactedHeaders
+// (case-insensitive) never make it into the logs.
+func SetLogger(logger log.Logger, redactedHeaders ...string) func(delegate http.Handler) http.Handler {
+	redacted := make(map[string]bool, len(redactedHeaders))
+	for _, h := range redactedHeaders {
+		redacted[http.CanonicalHeaderKey(h)] = true
+	}
+
 	return func(delegate http.Handler) http.Handler {
 		return http.HandlerFunc(
 			func(w http.ResponseWriter, r *http.Request) {
+				transactionID, _ := common.TransactionIDFromContext(r.Context())
 				ctx := r.WithContext(logging.WithLogger(r.Context(),
-					log.With(logger, "requestHeaders", r.Header, "requestURL", r.URL.EscapedPath(), "method", r.Method)))
+					log.With(logger, "requestHeaders", redactHeaders(r.Header, redacted), "requestURL", r.URL.EscapedPath(), "method", r.Method,
+						"transactionID", transactionID)))
 				delegate.ServeHTTP(w, ctx)
 			})
 	}
@@ -351,11 +1183,28 @@ type JWTValidator struct {
 	// Leeway is used to set the amount of time buffer should be given to JWT
 	// time values, such as nbf
 	Leeway bascule.Leeway
+
+	// AllowedAlgorithms are the signing algorithms tokens may use (i.e. RS256).
+	// All but "none" are accepted when empty
+	AllowedAlgorithms []string
+
+	// ExpectedAudience are the audiences tokens may be meant for (aud claim).
+	// Any audience is accepted when empty
+	ExpectedAudience []string
+
+	// ExpectedIssuer is the issuer tokens must come from (iss claim).
+	// Any issuer is accepted when empty
+	ExpectedIssuer string
+
+	// RefreshInterval is the time between refreshes of the verification keys.
+	// Keys are only fetched once when it's not positive
+	RefreshInterval time.Duration
 }
 
 type authAcquirerConfig struct {
-	JWT   acquire.RemoteBearerTokenAcquirerOptions
-	Basic string
+	JWT      acquire.RemoteBearerTokenAcquirerOptions
+	Basic    string
+	Fallback FallbackAcquirerConfig
 }
 
 type CapabilityConfig struct {
@@ -365,10 +1214,12 @@ type CapabilityConfig struct {
 	EndpointBuckets []string
 }
 
-// authenticationHandler configures the authorization requirements for requests to reach the main handler
-func authenticationHandler(v *viper.Viper, logger log.Logger, registry xmetrics.Registry) (*alice.Chain, error) {
+// authenticationHandler configures the authorization requirements for requests to reach the main handler.
+// It also returns the basic auth credentials so that they can be reloaded and the JWT verification keys
+// so that they can be refreshed.
+func authenticationHandler(v *viper.Viper, logger log.Logger, registry xmetrics.Registry, tracing *common.Tracing) (*alice.Chain, *basicCredentials, *keyRefresher, error) {
 	if registry == nil {
-		return nil, errors.New("nil registry")
+		return nil, nil, nil, errors.New("nil registry")
 	}
 
 	basculeMeasures := basculemetrics.NewAuthValidationMeasures(registry)
@@ -391,27 +1242,43 @@ func authenticationHandler(v *viper.Viper, logger log.Logger, registry xmetrics.
 	}
 	logging.Debug(logger).Log(logging.MessageKey(), "Created list of allowed basic auths", "allowed", basicAllowed, "config", basicAuth)
 
+	parseURL := basculehttp.CreateRemovePrefixURLFunc("/"+apiBase+"/", basculehttp.DefaultParseURLFunc)
+
 	options := []basculehttp.COption{
 		basculehttp.WithCLogger(GetLogger),
 		basculehttp.WithCErrorResponseFunc(listener.OnErrorResponse),
-		basculehttp.WithParseURLFunc(basculehttp.CreateRemovePrefixURLFunc("/"+apiBase+"/", basculehttp.DefaultParseURLFunc)),
+		basculehttp.WithParseURLFunc(parseURL),
 	}
-	if len(basicAllowed) > 0 {
-		options = append(options, basculehttp.WithTokenFactory("Basic", basculehttp.BasicTokenFactory(basicAllowed)))
+
+	credentials, err := newBasicCredentials(basicAllowed, v.GetString(basicAuthFileKey), v.GetBool(basicAuthHashedKey))
+	if err != nil {
+		return nil, nil, nil, emperror.With(err, "failed to load basic auth file")
 	}
-	var jwtVal JWTValidator
+
+	if credentials.enabled() {
+		options = append(options, basculehttp.WithTokenFactory("Basic", credentials))
+	}
+	var (
+		jwtVal JWTValidator
+		keys   *keyRefresher
+	)
 
 	v.UnmarshalKey("jwtValidator", &jwtVal)
 	if jwtVal.Keys.URI != "" {
-		resolver, err := jwtVal.Keys.NewResolver()
+		keys, err = newKeyRefresher(jwtVal.Keys.NewResolver, probeKeyID(jwtVal.Keys.URI), jwtVal.RefreshInterval, registry.NewCounter(common.JWTKeyRefreshesCounter), logger)
 		if err != nil {
-			return &alice.Chain{}, emperror.With(err, "failed to create resolver")
+			return &alice.Chain{}, nil, nil, emperror.With(err, "failed to create resolver")
+		}
+
+		parser, err := newJWTParser(jwtVal.AllowedAlgorithms)
+		if err != nil {
+			return nil, nil, nil, emperror.With(err, "failed to create JWT parser")
 		}
 
 		options = append(options, basculehttp.WithTokenFactory("Bearer", basculehttp.BearerTokenFactory{
 			DefaultKeyId: DefaultKeyID,
-			Resolver:     resolver,
-			Parser:       bascule.DefaultJWTParser,
+			Resolver:     keys,
+			Parser:       parser,
 			Leeway:       jwtVal.Leeway,
 		}))
 	}
@@ -424,6 +1291,14 @@ func authenticationHandler(v *viper.Viper, logger log.Logger, registry xmetrics.
 		bascule.CreateValidTypeCheck([]string{"jwt"}),
 	}
 
+	if len(jwtVal.ExpectedAudience) > 0 {
+		bearerRules = append(bearerRules, audienceCheck(jwtVal.ExpectedAudience))
+	}
+
+	if jwtVal.ExpectedIssuer != "" {
+		bearerRules = append(bearerRules, issuerCheck(jwtVal.ExpectedIssuer))
+	}
+
 	// only add capability check if the configuration is set
 	var capabilityCheck CapabilityConfig
 	v.UnmarshalKey("capabilityCheck", &capabilityCheck)
@@ -439,24 +1314,69 @@ func authenticationHandler(v *viper.Viper, logger log.Logger, registry xmetrics.
 		}
 		checker, err := basculechecks.NewCapabilityChecker(capabilityCheckMeasures, capabilityCheck.Prefix, capabilityCheck.AcceptAllMethod, endpoints)
 		if err != nil {
-			return nil, emperror.With(err, "failed to create capability check")
+			return nil, nil, nil, emperror.With(err, "failed to create capability check")
+		}
+		// listing the supported services requires no capability
+		bearerRules = append(bearerRules, exemptPaths(checker.CreateBasculeCheck(capabilityCheck.Type == "enforce"), translation.ServicesPath))
+	}
+
+	var rules []ClaimRule
+	if err := v.UnmarshalKey(claimRulesKey, &rules); err != nil {
+		return nil, nil, nil, emperror.With(err, "failed to parse claim rules")
+	}
+
+	if len(rules) > 0 {
+		claimCheck, err := newClaimRules(rules)
+		if err != nil {
+			return nil, nil, nil, emperror.With(err, "failed to create claim rules")
 		}
-		bearerRules = append(bearerRules, checker.CreateBasculeCheck(capabilityCheck.Type == "enforce"))
+		bearerRules = append(bearerRules, claimCheck)
+	}
+
+	var clientTLS ClientTLSConfig
+	if err := v.UnmarshalKey(clientTLSKey, &clientTLS); err != nil {
+		return nil, nil, nil, emperror.With(err, "failed to parse client TLS config")
+	}
+
+	certs, err := newClientCerts(clientTLS, parseURL)
+	if err != nil {
+		return nil, nil, nil, emperror.With(err, "failed to load client CAs")
 	}
 
-	authEnforcer := basculehttp.NewEnforcer(
+	enforcerOptions := []basculehttp.EOption{
 		basculehttp.WithELogger(GetLogger),
 		basculehttp.WithRules("Basic", bascule.Validators{
 			bascule.CreateAllowAllCheck(),
 		}),
 		basculehttp.WithRules("Bearer", bearerRules),
 		basculehttp.WithEErrorResponseFunc(listener.OnErrorResponse),
-	)
+	}
+
+	if certs != nil {
+		// certificates carry no capabilities or claims, validateConfig rejects clientTLS along with
+		// an enforced capabilityCheck or claimRules
+		enforcerOptions = append(enforcerOptions, basculehttp.WithRules(certAuthorization, bascule.Validators{
+			bascule.CreateNonEmptyPrincipalCheck(),
+			bascule.CreateNonEmptyTypeCheck(),
+			bascule.CreateValidTypeCheck([]string{certTokenType}),
+		}))
+	}
+
+	authEnforcer := basculehttp.NewEnforcer(enforcerOptions...)
+
+	var rateLimit common.RateLimitConfig
+	if err := v.UnmarshalKey(rateLimitKey, &rateLimit); err != nil {
+		return nil, nil, nil, emperror.With(err, "failed to parse rate limit config")
+	}
+
+	rateLimiter := common.NewRateLimiter(rateLimit, registry.NewCounter(common.ThrottledRequestsCounter))
 
-	constructors := []alice.Constructor{SetLogger(logger), authConstructor, authEnforcer, basculehttp.NewListenerDecorator(listener)}
+	// authentication and the handling of the request by its service are traced separately
+	authentication := alice.New(certs.Then(authConstructor), authEnforcer, basculehttp.NewListenerDecorator(listener))
+	constructors := []alice.Constructor{common.TransactionID, SetLogger(logger, v.GetStringSlice(redactedHeadersKey)...), tracing.Stage("authenticate", authentication.Then), rateLimiter.Then, tracing.Span("handle")}
 
 	chain := alice.New(constructors...)
-	return &chain, nil
+	return &chain, credentials, keys, nil
 }
 
 func printVersion(f *pflag.FlagSet, arguments []string) (error, bool) {
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/xmidt-org/webpa-common/server"
+)
+
+var (
+	errNoMetricsAuthorizations = errors.New("at least one authorization is required when metrics.requireAuth is set")
+	errMetricsCertificates     = errors.New("metric.certificateFile and metric.keyFile must list the same number of files")
+)
+
+// metricsAuth guards the metrics endpoint with fixed Authorization header values (i.e. "Bearer xyz" or
+// "Basic xyz=="), independently of the authentication of the API
+type metricsAuth struct {
+	authorizations [][]byte
+	challenge      string
+}
+
+// newMetricsAuth is the constructor for metricsAuth
+func newMetricsAuth(authorizations []string) (*metricsAuth, error) {
+	if len(authorizations) == 0 {
+		return nil, errNoMetricsAuthorizations
+	}
+
+	m := &metricsAuth{challenge: `Bearer realm="metrics"`}
+	for _, a := range authorizations {
+		m.authorizations = append(m.authorizations, []byte(a))
+		if strings.HasPrefix(a, "Basic ") {
+			m.challenge = `Basic realm="metrics"`
+		}
+	}
+
+	return m, nil
+}
+
+// Then decorates delegate such that requests without one of the authorizations fail with a 401.
+// A nil metricsAuth lets every request through.
+func (m *metricsAuth) Then(delegate http.Handler) http.Handler {
+	if m == nil {
+		return delegate
+	}
+
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if !m.allowed(r.Header.Get("Authorization")) {
+				w.Header().Set("WWW-Authenticate", m.challenge)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			delegate.ServeHTTP(w, r)
+		})
+}
+
+func (m *metricsAuth) allowed(authorization string) bool {
+	allowed := false
+	for _, a := range m.authorizations {
+		// all values are compared so that timing doesn't tell which one came close
+		if subtle.ConstantTimeCompare([]byte(authorization), a) == 1 {
+			allowed = true
+		}
+	}
+	return allowed
+}
+
+// newMetricsServer builds the server which replaces the webpa-common metrics one when metrics require
+// authentication or carry exemplars, as the latter can't be decorated and doesn't negotiate the OpenMetrics
+// format exemplars are only exposed in. auth is optional. It serves TLS with the certificates of the metric config,
+// if any, and shares the timeouts of the primary server so that slow clients can't hold its connections either.
+func newMetricsServer(logger log.Logger, metric server.Metric, primary server.Basic, gatherer prometheus.Gatherer, auth *metricsAuth) (*http.Server, error) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", auth.Then(promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError, EnableOpenMetrics: true})))
+
+	s := &http.Server{
+		Addr:              metric.Address,
+		Handler:           mux,
+		ReadHeaderTimeout: durationOrDefault(primary.ReadHeaderTimeout, server.DefaultReadHeaderTimeout),
+		ReadTimeout:       durationOrDefault(primary.ReadTimeout, server.DefaultReadTimeout),
+		WriteTimeout:      durationOrDefault(primary.WriteTimeout, server.DefaultWriteTimeout),
+		IdleTimeout:       durationOrDefault(primary.IdleTimeout, server.DefaultIdleTimeout),
+		MaxHeaderBytes:    server.DefaultMaxHeaderBytes,
+		ErrorLog:          server.NewErrorLog(metric.Name, logger),
+	}
+
+	if len(metric.CertificateFile) == 0 && len(metric.KeyFile) == 0 {
+		return s, nil
+	}
+
+	if len(metric.CertificateFile) != len(metric.KeyFile) {
+		return nil, errMetricsCertificates
+	}
+
+	s.TLSConfig = new(tls.Config)
+	for i := range metric.CertificateFile {
+		cert, err := tls.LoadX509KeyPair(metric.CertificateFile[i], metric.KeyFile[i])
+		if err != nil {
+			return nil, err
+		}
+		s.TLSConfig.Certificates = append(s.TLSConfig.Certificates, cert)
+	}
+
+	return s, nil
+}
+
+// durationOrDefault defaults unset server timeouts the way webpa-common does
+func durationOrDefault(value, defaultValue time.Duration) time.Duration {
+	if value > 0 {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/server"
+)
+
+func TestNewMetricsAuth(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := newMetricsAuth(nil)
+	assert.Equal(errNoMetricsAuthorizations, err)
+
+	auth, err := newMetricsAuth([]string{"Bearer xyz"})
+	assert.Nil(err)
+	assert.Equal(`Bearer realm="metrics"`, auth.challenge)
+
+	auth, err = newMetricsAuth([]string{"Bearer xyz", "Basic dXNlcjpwYXNz"})
+	assert.Nil(err)
+	assert.Equal(`Basic realm="metrics"`, auth.challenge)
+}
+
+func TestMetricsServer(t *testing.T) {
+	auth, err := newMetricsAuth([]string{"Bearer xyz", "Basic dXNlcjpwYXNz"})
+	require.Nil(t, err)
+
+	metricsServer, err := newMetricsServer(log.NewNopLogger(), server.Metric{Address: ":0"}, server.Basic{ReadTimeout: time.Minute}, prometheus.NewRegistry(), auth)
+	require.Nil(t, err)
+
+	// the timeouts of the primary server apply, with the webpa-common defaults where they're unset
+	assert.Equal(t, time.Minute, metricsServer.ReadTimeout)
+	assert.Equal(t, server.DefaultIdleTimeout, metricsServer.IdleTimeout)
+	assert.Nil(t, metricsServer.TLSConfig)
+
+	tests := []struct {
+		name          string
+		authorization string
+		expectedCode  int
+	}{
+		{name: "Bearer", authorization: "Bearer xyz", expectedCode: http.StatusOK},
+		{name: "Basic", authorization: "Basic dXNlcjpwYXNz", expectedCode: http.StatusOK},
+		{name: "Missing", expectedCode: http.StatusUnauthorized},
+		{name: "Wrong", authorization: "Bearer abc", expectedCode: http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			r := httptest.NewRequest(http.MethodGet, "http://localhost/metrics", nil)
+			if test.authorization != "" {
+				r.Header.Set("Authorization", test.authorization)
+			}
+
+			rw := httptest.NewRecorder()
+			metricsServer.Handler.ServeHTTP(rw, r)
+			assert.Equal(test.expectedCode, rw.Code)
+
+			if test.expectedCode == http.StatusUnauthorized {
+				assert.Equal(`Basic realm="metrics"`, rw.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestMetricsServerOpenMetrics(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// without authentication the server only replaces the webpa-common one to expose exemplars
+	metricsServer, err := newMetricsServer(log.NewNopLogger(), server.Metric{Address: ":0"}, server.Basic{}, prometheus.NewRegistry(), nil)
+	require.Nil(err)
+
+	r := httptest.NewRequest(http.MethodGet, "http://localhost/metrics", nil)
+	r.Header.Set("Accept", "application/openmetrics-text; version=0.0.1")
+
+	rw := httptest.NewRecorder()
+	metricsServer.Handler.ServeHTTP(rw, r)
+	assert.Equal(http.StatusOK, rw.Code)
+	assert.True(strings.HasPrefix(rw.Header().Get("Content-Type"), "application/openmetrics-text"))
+}
+
+func TestMetricsServerTLS(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "tr1d1um")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issueKeyPair(t, "tr1d1um", x509.ExtKeyUsageServerAuth)
+	certFile, keyFile := writeKeyPair(t, dir, "metrics", serverCert, serverKey)
+
+	auth, err := newMetricsAuth([]string{"Bearer xyz"})
+	require.Nil(err)
+
+	_, err = newMetricsServer(log.NewNopLogger(), server.Metric{CertificateFile: []string{certFile}}, server.Basic{}, prometheus.NewRegistry(), auth)
+	assert.Equal(errMetricsCertificates, err)
+
+	_, err = newMetricsServer(log.NewNopLogger(), server.Metric{CertificateFile: []string{certFile}, KeyFile: []string{certFile}}, server.Basic{}, prometheus.NewRegistry(), auth)
+	assert.NotNil(err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	metricsServer, err := newMetricsServer(log.NewNopLogger(), server.Metric{Address: address, CertificateFile: []string{certFile}, KeyFile: []string{keyFile}}, server.Basic{}, prometheus.NewRegistry(), auth)
+	require.Nil(err)
+	require.NotNil(metricsServer.TLSConfig)
+
+	serve(metricsServer, "metrics", log.NewNopLogger())
+	defer metricsServer.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+
+	r, err := http.NewRequest(http.MethodGet, "https://"+address+"/metrics", nil)
+	require.Nil(err)
+	r.Header.Set("Authorization", "Bearer xyz")
+
+	var resp *http.Response
+	for i := 0; i < 50; i++ {
+		if resp, err = client.Do(r); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	require.Nil(err)
+	resp.Body.Close()
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.NotNil(resp.TLS)
+}
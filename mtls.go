@@ -0,0 +1,167 @@
+package main
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/justinas/alice"
+	"github.com/xmidt-org/bascule"
+)
+
+const (
+	clientTLSRequire  = "require"
+	clientTLSOptional = "optional"
+
+	// certAuthorization is the bascule authorization type of requests authenticated by client certificate
+	certAuthorization bascule.Authorization = "Cert"
+	certTokenType                           = "cert"
+)
+
+var (
+	errNoClientCAs = errors.New("no certificates found in client CA file")
+
+	// webpa-common builds the TLS config of the primary server, which requires a client certificate once a
+	// client CA is configured, within the runnable WebPA.Prepare returns
+	errOptionalClientTLS = errors.New("optional client certificates aren't supported by the primary server, use the require mode")
+)
+
+// ClientTLSConfig configures mutual TLS authentication of API clients
+type ClientTLSConfig struct {
+	// CAFile is the PEM bundle of the CAs client certificates must be issued by
+	CAFile string
+
+	// Mode is "require" (default), for the TLS handshake to fail without a valid client certificate.
+	// "optional" is rejected, see errOptionalClientTLS
+	Mode string
+}
+
+// validate checks the mode of an enabled ClientTLSConfig
+func (c ClientTLSConfig) validate() error {
+	if c.CAFile == "" {
+		return nil
+	}
+
+	switch c.Mode {
+	case "", clientTLSRequire:
+		return nil
+	case clientTLSOptional:
+		return errOptionalClientTLS
+	default:
+		return fmt.Errorf("unsupported client TLS mode '%s'", c.Mode)
+	}
+}
+
+// clientCerts authenticates requests by the client certificate of their TLS connection. The common name
+// of the certificate subject is the principal.
+type clientCerts struct {
+	roots    *x509.CertPool
+	parseURL func(*url.URL) (*url.URL, error)
+}
+
+// newClientCerts is the constructor for clientCerts. It returns nil, which disables client certificate
+// authentication, when no CA file is configured
+func newClientCerts(c ClientTLSConfig, parseURL func(*url.URL) (*url.URL, error)) (*clientCerts, error) {
+	if c.CAFile == "" {
+		return nil, nil
+	}
+
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+
+	pem, err := ioutil.ReadFile(c.CAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(pem) {
+		return nil, errNoClientCAs
+	}
+
+	return &clientCerts{roots: roots, parseURL: parseURL}, nil
+}
+
+// authenticate verifies the client certificate of the request, if any, and returns its token
+func (c *clientCerts) authenticate(r *http.Request) (bascule.Token, bool, error) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil, false, nil
+	}
+
+	leaf := r.TLS.PeerCertificates[0]
+	intermediates := x509.NewCertPool()
+	for _, cert := range r.TLS.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         c.roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return nil, true, err
+	}
+
+	if leaf.Subject.CommonName == "" {
+		return nil, true, errors.New("client certificate has no subject common name")
+	}
+
+	attributes := bascule.NewAttributesFromMap(map[string]interface{}{
+		"subject":  leaf.Subject.String(),
+		"dnsNames": leaf.DNSNames,
+	})
+
+	return bascule.NewToken(certTokenType, leaf.Subject.CommonName, attributes), true, nil
+}
+
+// Then decorates constructor, the Authorization header based authentication, such that requests
+// with a client certificate and no Authorization header are authenticated by their certificate instead.
+// Requests whose certificate can't be verified fail with 401.
+func (c *clientCerts) Then(constructor alice.Constructor) alice.Constructor {
+	if c == nil {
+		return constructor
+	}
+
+	return func(delegate http.Handler) http.Handler {
+		byHeader := constructor(delegate)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "" {
+				byHeader.ServeHTTP(w, r)
+				return
+			}
+
+			token, ok, err := c.authenticate(r)
+			if !ok {
+				byHeader.ServeHTTP(w, r)
+				return
+			}
+
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid client certificate: %v", err), http.StatusUnauthorized)
+				return
+			}
+
+			u, err := c.parseURL(r.URL)
+			if err != nil {
+				http.Error(w, "invalid request URL", http.StatusBadRequest)
+				return
+			}
+
+			ctx := bascule.WithAuthentication(r.Context(), bascule.Authentication{
+				Authorization: certAuthorization,
+				Token:         token,
+				Request: bascule.Request{
+					URL:    u,
+					Method: r.Method,
+				},
+			})
+
+			delegate.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
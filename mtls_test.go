@@ -0,0 +1,313 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/bascule"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.Nil(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.Nil(t, err)
+
+	return testCA{cert: cert, key: key}
+}
+
+func (ca testCA) issue(t *testing.T, commonName string) *x509.Certificate {
+	cert, _ := ca.issueKeyPair(t, commonName, x509.ExtKeyUsageClientAuth)
+	return cert
+}
+
+func (ca testCA) issueKeyPair(t *testing.T, commonName string, usage x509.ExtKeyUsage) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"partner"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.Nil(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.Nil(t, err)
+	return cert, key
+}
+
+// writeKeyPair writes the PEM encoded cert and key to dir and returns their paths
+func writeKeyPair(t *testing.T, dir, name string, cert *x509.Certificate, key *ecdsa.PrivateKey) (string, string) {
+	der, err := x509.MarshalECPrivateKey(key)
+	require.Nil(t, err)
+
+	certFile, keyFile := filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem")
+	require.Nil(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0600))
+	require.Nil(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600))
+	return certFile, keyFile
+}
+
+func TestNewClientCerts(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "tr1d1um")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t)
+	caFile := filepath.Join(dir, "ca.pem")
+	require.Nil(ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), 0600))
+
+	emptyFile := filepath.Join(dir, "empty.pem")
+	require.Nil(ioutil.WriteFile(emptyFile, []byte("nothing here"), 0600))
+
+	certs, err := newClientCerts(ClientTLSConfig{}, nil)
+	assert.Nil(certs)
+	assert.Nil(err)
+
+	certs, err = newClientCerts(ClientTLSConfig{CAFile: caFile, Mode: clientTLSRequire}, nil)
+	assert.NotNil(certs)
+	assert.Nil(err)
+
+	_, err = newClientCerts(ClientTLSConfig{CAFile: caFile, Mode: clientTLSOptional}, nil)
+	assert.Equal(errOptionalClientTLS, err)
+
+	_, err = newClientCerts(ClientTLSConfig{CAFile: caFile, Mode: "sometimes"}, nil)
+	assert.NotNil(err)
+
+	_, err = newClientCerts(ClientTLSConfig{CAFile: emptyFile}, nil)
+	assert.Equal(errNoClientCAs, err)
+
+	_, err = newClientCerts(ClientTLSConfig{CAFile: filepath.Join(dir, "missing.pem")}, nil)
+	assert.NotNil(err)
+}
+
+func TestClientCerts(t *testing.T) {
+	ca, otherCA := newTestCA(t), newTestCA(t)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	certs := &clientCerts{
+		roots: roots,
+		parseURL: func(u *url.URL) (*url.URL, error) {
+			return u, nil
+		},
+	}
+
+	tests := []struct {
+		name              string
+		peerCertificates  []*x509.Certificate
+		authorization     string
+		expectedCode      int
+		expectedByHeader  bool
+		expectedPrincipal string
+	}{
+		{
+			name:              "Verified",
+			peerCertificates:  []*x509.Certificate{ca.issue(t, "client0")},
+			expectedCode:      http.StatusOK,
+			expectedPrincipal: "client0",
+		},
+		{
+			name:             "Untrusted",
+			peerCertificates: []*x509.Certificate{otherCA.issue(t, "client0")},
+			expectedCode:     http.StatusUnauthorized,
+		},
+		{
+			name:             "NoCommonName",
+			peerCertificates: []*x509.Certificate{ca.issue(t, "")},
+			expectedCode:     http.StatusUnauthorized,
+		},
+		{
+			name:             "NoCertificate",
+			expectedCode:     http.StatusOK,
+			expectedByHeader: true,
+		},
+		{
+			name:             "AuthorizationHeader",
+			peerCertificates: []*x509.Certificate{ca.issue(t, "client0")},
+			authorization:    "Bearer token",
+			expectedCode:     http.StatusOK,
+			expectedByHeader: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var (
+				byHeader bool
+				auth     bascule.Authentication
+				found    bool
+			)
+
+			constructor := func(delegate http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					byHeader = true
+					delegate.ServeHTTP(w, r)
+				})
+			}
+
+			handler := certs.Then(constructor)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				auth, found = bascule.FromContext(r.Context())
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "https://localhost/api/v2/device/mac:112233445566/stat", nil)
+			r.TLS = &tls.ConnectionState{PeerCertificates: test.peerCertificates}
+			if test.authorization != "" {
+				r.Header.Set("Authorization", test.authorization)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			assert.Equal(test.expectedCode, w.Code)
+			assert.Equal(test.expectedByHeader, byHeader)
+
+			if test.expectedPrincipal != "" && assert.True(found) {
+				assert.Equal(certAuthorization, auth.Authorization)
+				assert.Equal(test.expectedPrincipal, auth.Token.Principal())
+				assert.Equal(certTokenType, auth.Token.Type())
+			}
+		})
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		var disabled *clientCerts
+		called := false
+		constructor := func(delegate http.Handler) http.Handler {
+			called = true
+			return delegate
+		}
+		disabled.Then(constructor)(http.NotFoundHandler())
+		assert.True(t, called)
+	})
+}
+
+func TestClientCertsOverTLS(t *testing.T) {
+	require := require.New(t)
+
+	ca, otherCA := newTestCA(t), newTestCA(t)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	certs := &clientCerts{roots: roots, parseURL: func(u *url.URL) (*url.URL, error) { return u, nil }}
+	serverCert, serverKey := ca.issueKeyPair(t, "tr1d1um", x509.ExtKeyUsageServerAuth)
+
+	// requests not authenticated by certificate get the principal "header"
+	byHeader := func(delegate http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("header"))
+		})
+	}
+
+	s := httptest.NewUnstartedServer(certs.Then(byHeader)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth, _ := bascule.FromContext(r.Context())
+		w.Write([]byte(auth.Token.Principal()))
+	})))
+
+	// the TLS config webpa-common gives the primary server once its client CA file is set
+	s.TLS = &tls.Config{
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    roots,
+		Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.Raw}, PrivateKey: serverKey}},
+	}
+	s.StartTLS()
+	defer s.Close()
+
+	clientKeyPair := func(issuer testCA) []tls.Certificate {
+		cert, key := issuer.issueKeyPair(t, "client0", x509.ExtKeyUsageClientAuth)
+		return []tls.Certificate{{Certificate: [][]byte{cert.Raw}, PrivateKey: key}}
+	}
+
+	tests := []struct {
+		name              string
+		certificates      []tls.Certificate
+		authorization     string
+		expectedErr       bool
+		expectedPrincipal string
+	}{
+		{name: "Verified", certificates: clientKeyPair(ca), expectedPrincipal: "client0"},
+		{name: "AuthorizationHeader", certificates: clientKeyPair(ca), authorization: "Bearer token", expectedPrincipal: "header"},
+		{name: "NoCertificate", expectedErr: true},
+		{name: "Untrusted", certificates: clientKeyPair(otherCA), expectedErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			client := &http.Client{Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: test.certificates},
+			}}
+
+			req, err := http.NewRequest(http.MethodGet, s.URL, nil)
+			require.Nil(err)
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+
+			resp, err := client.Do(req)
+			if test.expectedErr {
+				assert.NotNil(err)
+				return
+			}
+
+			if !assert.Nil(err) {
+				return
+			}
+			defer resp.Body.Close()
+
+			body, err := ioutil.ReadAll(resp.Body)
+			assert.Nil(err)
+			assert.Equal(http.StatusOK, resp.StatusCode)
+			assert.Equal(test.expectedPrincipal, string(body))
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+// PprofConfig configures the listener of the pprof debug endpoints
+type PprofConfig struct {
+	// Enabled serves the pprof endpoints. They aren't reachable at all otherwise
+	Enabled bool
+
+	// Address is the bind address of the pprof listener (i.e. "localhost:6102"), separate from the API one
+	Address string
+}
+
+// newPprofServer builds the server of the pprof endpoints. It returns nil when pprof is disabled
+func newPprofServer(c PprofConfig) *http.Server {
+	if !c.Enabled || c.Address == "" {
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return &http.Server{
+		Addr:    c.Address,
+		Handler: mux,
+	}
+}
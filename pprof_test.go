@@ -0,0 +1,29 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPprofServer(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(newPprofServer(PprofConfig{Address: ":6102"}))
+	assert.Nil(newPprofServer(PprofConfig{Enabled: true}))
+
+	server := newPprofServer(PprofConfig{Enabled: true, Address: ":6102"})
+	if assert.NotNil(server) {
+		assert.Equal(":6102", server.Addr)
+
+		rw := httptest.NewRecorder()
+		server.Handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "http://localhost:6102/debug/pprof/", nil))
+		assert.Equal(http.StatusOK, rw.Code)
+
+		rw = httptest.NewRecorder()
+		server.Handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "http://localhost:6102/api/v2/device", nil))
+		assert.Equal(http.StatusNotFound, rw.Code)
+	}
+}
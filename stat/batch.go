@@ -0,0 +1,167 @@
+package stat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/xmidt-org/tr1d1um/common"
+	"github.com/xmidt-org/webpa-common/device"
+)
+
+// BatchPath is the path, relative to the API prefix, of the endpoint which fetches the statistics of many devices at once
+const BatchPath = "/device/stat"
+
+// defaultBatchWorkers is the number of concurrent XMiDT requests of a batch when none is configured
+const defaultBatchWorkers = 10
+
+var errEmptyDeviceIDs = common.NewBadRequestError(errors.New("request body must be a non-empty JSON array of device ids"))
+
+type batchStatRequest struct {
+	AuthHeaderValue string
+
+	//DeviceIDs are the device ids as requested, which may not be valid
+	DeviceIDs []string
+
+	//Errors are the reasons requested device ids can't be fetched, by requested device id
+	Errors map[string]error
+}
+
+// batchStatResult is the outcome of a batch stat request for a single device. Stat is the XMiDT response when
+// it's JSON and Message explains failures otherwise
+type batchStatResult struct {
+	StatusCode int             `json:"statusCode"`
+	Stat       json.RawMessage `json:"stat,omitempty"`
+	Message    string          `json:"message,omitempty"`
+}
+
+type batchStatResponse struct {
+	Devices map[string]batchStatResult `json:"devices"`
+}
+
+// decodeBatchRequest returns a decoder for the JSON array of device ids of batch stat requests. Malformed
+// ids, and ids with a scheme the partner may not use, are reported per device rather than failing the request
+func decodeBatchRequest(schemes *common.DeviceIDSchemes) kithttp.DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (interface{}, error) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err == common.ErrRequestBodyTooLarge {
+			return nil, err
+		} else if err != nil {
+			return nil, common.NewBadRequestError(err)
+		}
+
+		var deviceIDs []string
+		if err := json.Unmarshal(body, &deviceIDs); err != nil || len(deviceIDs) == 0 {
+			return nil, errEmptyDeviceIDs
+		}
+
+		req := &batchStatRequest{
+			AuthHeaderValue: r.Header.Get("Authorization"),
+			Errors:          make(map[string]error),
+		}
+
+		seen := make(map[string]bool, len(deviceIDs))
+		for _, requested := range deviceIDs {
+			if seen[requested] {
+				continue
+			}
+			seen[requested] = true
+
+			id, err := device.ParseID(requested)
+			if err != nil {
+				req.Errors[requested] = common.NewBadRequestError(err)
+			} else if err := schemes.Check(r, id); err != nil {
+				req.Errors[requested] = err
+			}
+
+			req.DeviceIDs = append(req.DeviceIDs, requested)
+		}
+
+		return req, nil
+	}
+}
+
+// makeBatchStatEndpoint fetches the statistics of all the devices of a batch with at most workers
+// concurrent XMiDT requests
+func makeBatchStatEndpoint(s Service, workers int) endpoint.Endpoint {
+	if workers < 1 {
+		workers = defaultBatchWorkers
+	}
+
+	return func(ctx context.Context, r interface{}) (interface{}, error) {
+		req := r.(*batchStatRequest)
+
+		var (
+			lock    sync.Mutex
+			wg      sync.WaitGroup
+			tokens  = make(chan struct{}, workers)
+			devices = make(map[string]batchStatResult, len(req.DeviceIDs))
+		)
+
+		// invalid ids are reported before any worker starts writing results
+		for requested, err := range req.Errors {
+			devices[requested] = newBatchStatError(err)
+		}
+
+		for _, requested := range req.DeviceIDs {
+			if req.Errors[requested] != nil {
+				continue
+			}
+
+			wg.Add(1)
+			tokens <- struct{}{}
+			go func(requested string) {
+				defer func() {
+					<-tokens
+					wg.Done()
+				}()
+
+				var result batchStatResult
+
+				id, _ := device.ParseID(requested)
+				if resp, err := s.RequestStat(ctx, req.AuthHeaderValue, string(id)); err != nil {
+					result = newBatchStatError(err)
+				} else {
+					result = newBatchStatResult(resp)
+				}
+
+				lock.Lock()
+				devices[requested] = result
+				lock.Unlock()
+			}(requested)
+		}
+
+		wg.Wait()
+		return &batchStatResponse{Devices: devices}, nil
+	}
+}
+
+func newBatchStatResult(resp *common.XmidtResponse) batchStatResult {
+	result := batchStatResult{StatusCode: resp.Code}
+	if json.Valid(resp.Body) {
+		result.Stat = resp.Body
+	} else {
+		result.Message = string(resp.Body)
+	}
+	return result
+}
+
+// newBatchStatError reports err the way encodeError would, masking errors which aren't meant for clients
+func newBatchStatError(err error) batchStatResult {
+	if ce, ok := err.(common.CodedError); ok {
+		return batchStatResult{StatusCode: ce.StatusCode(), Message: err.Error()}
+	}
+
+	return batchStatResult{StatusCode: http.StatusInternalServerError, Message: common.ErrTr1d1umInternal.Error()}
+}
+
+func encodeBatchResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(common.HeaderWPATID, ctx.Value(common.ContextKeyRequestTID).(string))
+	return json.NewEncoder(w).Encode(response)
+}
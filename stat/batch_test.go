@@ -0,0 +1,130 @@
+package stat
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/tr1d1um/common"
+)
+
+func TestDecodeBatchRequest(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		for _, body := range []string{"", "[]", `{"devices": ["mac:112233445566"]}`} {
+			r := httptest.NewRequest(http.MethodPost, "http://localhost/api/v2/device/stat", strings.NewReader(body))
+			_, err := decodeBatchRequest(nil)(context.Background(), r)
+			assert.Equal(t, errEmptyDeviceIDs, err)
+		}
+	})
+
+	t.Run("Devices", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+
+		schemes := common.NewDeviceIDSchemes([]string{"mac"}, nil)
+		r := httptest.NewRequest(http.MethodPost, "http://localhost/api/v2/device/stat",
+			strings.NewReader(`["mac:112233445566", "mac:112233445566", "serial:1234", "mac:12"]`))
+		r.Header.Set("Authorization", "Basic xyz")
+
+		req, err := decodeBatchRequest(schemes)(context.Background(), r)
+		require.Nil(err)
+
+		batch := req.(*batchStatRequest)
+		assert.Equal("Basic xyz", batch.AuthHeaderValue)
+		assert.Equal([]string{"mac:112233445566", "serial:1234", "mac:12"}, batch.DeviceIDs)
+		assert.Len(batch.Errors, 2)
+		assert.Equal(http.StatusForbidden, batch.Errors["serial:1234"].(common.CodedError).StatusCode())
+		assert.Equal(http.StatusBadRequest, batch.Errors["mac:12"].(common.CodedError).StatusCode())
+	})
+
+	t.Run("BodyLimit", func(t *testing.T) {
+		body := `["mac:112233445566", "mac:665544332211"]`
+
+		tests := []struct {
+			name        string
+			max         int64
+			expectedErr error
+		}{
+			{name: "Below", max: int64(len(body)) + 1},
+			{name: "Above", max: int64(len(body)) - 1, expectedErr: common.ErrRequestBodyTooLarge},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				assert := assert.New(t)
+
+				var err error
+				handler := common.LimitRequestBody(test.max)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+					_, err = decodeBatchRequest(nil)(context.Background(), r)
+				}))
+
+				handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "http://localhost/api/v2/device/stat", strings.NewReader(body)))
+				assert.Equal(test.expectedErr, err)
+			})
+		}
+	})
+}
+
+func TestMakeBatchStatEndpoint(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s := new(MockService)
+	s.On("RequestStat", mock.Anything, "a0", "mac:112233445566").Return(&common.XmidtResponse{Code: http.StatusOK, Body: []byte(`{"statistics":{}}`)}, nil)
+	s.On("RequestStat", mock.Anything, "a0", "mac:665544332211").Return(&common.XmidtResponse{Code: http.StatusNotFound, Body: []byte("device not found")}, nil)
+	s.On("RequestStat", mock.Anything, "a0", "mac:aabbccddeeff").Return(nil, common.NewCodedError(errors.New("XMiDT is unavailable"), http.StatusServiceUnavailable))
+	s.On("RequestStat", mock.Anything, "a0", "mac:ffeeddccbbaa").Return(nil, errors.New("internal details"))
+
+	response, err := makeBatchStatEndpoint(s, 2)(context.Background(), &batchStatRequest{
+		AuthHeaderValue: "a0",
+		DeviceIDs:       []string{"mac:112233445566", "mac:665544332211", "mac:AABBCCDDEEFF", "mac:ffeeddccbbaa", "mac:12"},
+		Errors:          map[string]error{"mac:12": common.NewBadRequestError(errors.New("invalid mac"))},
+	})
+	require.Nil(err)
+
+	assert.Equal(map[string]batchStatResult{
+		"mac:112233445566": {StatusCode: http.StatusOK, Stat: []byte(`{"statistics":{}}`)},
+		"mac:665544332211": {StatusCode: http.StatusNotFound, Message: "device not found"},
+		"mac:AABBCCDDEEFF": {StatusCode: http.StatusServiceUnavailable, Message: "XMiDT is unavailable"},
+		"mac:ffeeddccbbaa": {StatusCode: http.StatusInternalServerError, Message: common.ErrTr1d1umInternal.Error()},
+		"mac:12":           {StatusCode: http.StatusBadRequest, Message: "invalid mac"},
+	}, response.(*batchStatResponse).Devices)
+}
+
+type concurrencyService struct {
+	current, max int32
+}
+
+func (c *concurrencyService) RequestStat(context.Context, string, string) (*common.XmidtResponse, error) {
+	n := atomic.AddInt32(&c.current, 1)
+	defer atomic.AddInt32(&c.current, -1)
+
+	for {
+		max := atomic.LoadInt32(&c.max)
+		if n <= max || atomic.CompareAndSwapInt32(&c.max, max, n) {
+			break
+		}
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	return &common.XmidtResponse{Code: http.StatusOK, Body: []byte(`{}`)}, nil
+}
+
+func TestMakeBatchStatEndpointWorkers(t *testing.T) {
+	s := new(concurrencyService)
+
+	deviceIDs := []string{"mac:000000000001", "mac:000000000002", "mac:000000000003", "mac:000000000004", "mac:000000000005", "mac:000000000006"}
+	response, err := makeBatchStatEndpoint(s, 3)(context.Background(), &batchStatRequest{DeviceIDs: deviceIDs})
+
+	assert.Nil(t, err)
+	assert.Len(t, response.(*batchStatResponse).Devices, len(deviceIDs))
+	assert.True(t, atomic.LoadInt32(&s.max) <= 3)
+}
@@ -0,0 +1,138 @@
+package stat
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/xmidt-org/tr1d1um/common"
+)
+
+// defaultStatCacheSize is the max number of devices whose stat responses are cached when none is configured
+const defaultStatCacheSize = 10000
+
+// Outcomes of stat cache lookups
+const (
+	statCacheHit  = "hit"
+	statCacheMiss = "miss"
+)
+
+type cachedStat struct {
+	key     string
+	resp    *common.XmidtResponse
+	fetched time.Time
+}
+
+// statCache keeps the latest complete stat responses of the most recently requested devices. The least
+// recently used devices are evicted once size is reached. Responses are only shared by requests with the
+// same credentials so that XMiDT keeps authorizing every caller, see statCacheKey.
+type statCache struct {
+	ttl     time.Duration
+	size    int
+	lookups metrics.Counter
+	now     func() time.Time
+
+	lock    sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+// newStatCache is the constructor for statCache. It returns nil, which disables caching, when ttl isn't positive
+func newStatCache(ttl time.Duration, size int, lookups metrics.Counter) *statCache {
+	if ttl <= 0 {
+		return nil
+	}
+
+	if size < 1 {
+		size = defaultStatCacheSize
+	}
+
+	if lookups == nil {
+		lookups = discard.NewCounter()
+	}
+
+	return &statCache{
+		ttl:     ttl,
+		size:    size,
+		lookups: lookups,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// statCacheKey identifies the stat response of a device fetched on behalf of a caller with the given
+// credentials, which are hashed so that they aren't kept in memory
+func statCacheKey(deviceID, credentials string) string {
+	sum := sha256.Sum256([]byte(credentials))
+	return deviceID + " " + hex.EncodeToString(sum[:])
+}
+
+// get returns the cached response under key, with an Age header, if it's still fresh
+func (c *statCache) get(key string) (*common.XmidtResponse, bool) {
+	now := c.now()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		entry := e.Value.(*cachedStat)
+		if age := now.Sub(entry.fetched); age < c.ttl {
+			c.lru.MoveToFront(e)
+			c.lookups.With(common.OutcomeLabel, statCacheHit).Add(1)
+			return withAge(entry.resp, age), true
+		}
+
+		c.lru.Remove(e)
+		delete(c.entries, key)
+	}
+
+	c.lookups.With(common.OutcomeLabel, statCacheMiss).Add(1)
+	return nil, false
+}
+
+// put caches the response under key. Only complete and partial stats are cached so that
+// devices which just came online aren't reported offline for a whole TTL
+func (c *statCache) put(key string, resp *common.XmidtResponse) {
+	if resp.Code != http.StatusOK && resp.Code != http.StatusPartialContent {
+		return
+	}
+
+	entry := &cachedStat{key: key, resp: resp, fetched: c.now()}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		e.Value = entry
+		c.lru.MoveToFront(e)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(entry)
+
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedStat).key)
+	}
+}
+
+// withAge returns a copy of resp whose forwarded headers report its age, in seconds
+func withAge(resp *common.XmidtResponse, age time.Duration) *common.XmidtResponse {
+	headers := make(http.Header, len(resp.ForwardedHeaders)+1)
+	for k, values := range resp.ForwardedHeaders {
+		headers[k] = append([]string(nil), values...)
+	}
+	headers.Set("Age", strconv.Itoa(int(age/time.Second)))
+
+	cached := *resp
+	cached.ForwardedHeaders = headers
+	return &cached
+}
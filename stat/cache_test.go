@@ -0,0 +1,113 @@
+package stat
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/xmidt-org/tr1d1um/common"
+)
+
+func TestNewStatCache(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(newStatCache(0, 0, nil))
+
+	c := newStatCache(time.Second, 0, nil)
+	assert.NotNil(c)
+	assert.Equal(defaultStatCacheSize, c.size)
+}
+
+func TestStatCache(t *testing.T) {
+	assert := assert.New(t)
+
+	c := newStatCache(time.Minute, 2, nil)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	ok := &common.XmidtResponse{Code: http.StatusOK, Body: []byte(`{}`), ForwardedHeaders: http.Header{"X-Xmidt": []string{"a"}}}
+
+	_, hit := c.get("mac:112233445566")
+	assert.False(hit)
+
+	c.put("mac:112233445566", ok)
+	c.put("mac:665544332211", &common.XmidtResponse{Code: http.StatusNotFound})
+
+	now = now.Add(5 * time.Second)
+	resp, hit := c.get("mac:112233445566")
+	assert.True(hit)
+	assert.Equal("5", resp.ForwardedHeaders.Get("Age"))
+	assert.Equal("a", resp.ForwardedHeaders.Get("X-Xmidt"))
+	assert.Empty(ok.ForwardedHeaders.Get("Age"))
+
+	// 404s aren't cached
+	_, hit = c.get("mac:665544332211")
+	assert.False(hit)
+
+	// the least recently used device is evicted
+	c.put("mac:aabbccddeeff", ok)
+	c.put("mac:ffeeddccbbaa", ok)
+	_, hit = c.get("mac:112233445566")
+	assert.False(hit)
+	_, hit = c.get("mac:ffeeddccbbaa")
+	assert.True(hit)
+
+	// entries expire after the ttl
+	now = now.Add(time.Minute)
+	_, hit = c.get("mac:ffeeddccbbaa")
+	assert.False(hit)
+}
+
+func TestRequestStatCached(t *testing.T) {
+	assert := assert.New(t)
+
+	m := new(common.MockTr1d1umTransactor)
+	m.On("Transact", mock.Anything).Return(&common.XmidtResponse{Code: http.StatusOK, Body: []byte(`{}`)}, nil).Once()
+
+	s := NewService(&ServiceOptions{
+		XmidtStatURL:   "http://localhost/stat/${device}",
+		HTTPTransactor: m,
+		StatCacheTTL:   time.Minute,
+	})
+
+	resp, err := s.RequestStat(context.Background(), "auth", "mac:112233445566")
+	assert.Nil(err)
+	assert.Empty(resp.ForwardedHeaders.Get("Age"))
+
+	resp, err = s.RequestStat(context.Background(), "auth", "mac:112233445566")
+	assert.Nil(err)
+	assert.Equal("0", resp.ForwardedHeaders.Get("Age"))
+
+	m.AssertNumberOfCalls(t, "Transact", 1)
+}
+
+func TestRequestStatCachedPerCredentials(t *testing.T) {
+	assert := assert.New(t)
+
+	m := new(common.MockTr1d1umTransactor)
+	m.On("Transact", mock.MatchedBy(func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "auth0"
+	})).Return(&common.XmidtResponse{Code: http.StatusOK, Body: []byte(`{}`)}, nil).Once()
+	m.On("Transact", mock.MatchedBy(func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "auth1"
+	})).Return(nil, common.NewCodedError(errors.New("forbidden"), http.StatusForbidden)).Once()
+
+	s := NewService(&ServiceOptions{
+		XmidtStatURL:   "http://localhost/stat/${device}",
+		HTTPTransactor: m,
+		StatCacheTTL:   time.Minute,
+	})
+
+	_, err := s.RequestStat(context.Background(), "auth0", "mac:112233445566")
+	assert.Nil(err)
+
+	// XMiDT must authorize other callers rather than them getting the cached response
+	_, err = s.RequestStat(context.Background(), "auth1", "mac:112233445566")
+	assert.NotNil(err)
+
+	m.AssertExpectations(t)
+}
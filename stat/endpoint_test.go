@@ -14,7 +14,7 @@ func TestMakeStatEndpoint(t *testing.T) {
 		AuthHeaderValue: "a0",
 	}
 
-	s.On("RequestStat", "a0", "mac:1122334455").Return(nil, nil)
+	s.On("RequestStat", context.TODO(), "a0", "mac:1122334455").Return(nil, nil)
 
 	endpoint(context.TODO(), sr)
 	s.AssertExpectations(t)
@@ -0,0 +1,149 @@
+package stat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xmidt-org/tr1d1um/common"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/ugorji/go/codec"
+)
+
+// Media types stat responses can be encoded in
+const (
+	FormatJSON    = "application/json"
+	FormatMsgpack = "application/msgpack"
+)
+
+// DefaultFormats are the formats of stat responses when none are configured. JSON is the one clients get
+// unless they ask otherwise
+var DefaultFormats = []string{FormatJSON, FormatMsgpack}
+
+var errNotAcceptable = common.NewCodedError(errors.New("none of the accepted media types is supported"), http.StatusNotAcceptable)
+
+// ParseFormats validates the given stat response formats. DefaultFormats are returned if none are given
+func ParseFormats(formats []string) ([]string, error) {
+	if len(formats) == 0 {
+		return DefaultFormats, nil
+	}
+
+	parsed := make([]string, 0, len(formats))
+	for _, f := range formats {
+		switch strings.ToLower(f) {
+		case FormatJSON, FormatMsgpack:
+			parsed = append(parsed, strings.ToLower(f))
+		default:
+			return nil, fmt.Errorf("unsupported stat response format '%s'", f)
+		}
+	}
+	return parsed, nil
+}
+
+type formatContextKey struct{}
+
+type acceptedMediaType struct {
+	mediaType string
+	q         float64
+}
+
+// negotiateFormat picks the format of formats which the Accept header value prefers. JSON, or the first of
+// formats if JSON isn't one of them, wins when the client accepts any.
+func negotiateFormat(accept string, formats []string) (string, error) {
+	preferred := formats[0]
+	for _, f := range formats {
+		if f == FormatJSON {
+			preferred = f
+		}
+	}
+
+	if strings.TrimSpace(accept) == "" {
+		return preferred, nil
+	}
+
+	var accepted []acceptedMediaType
+	for _, value := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+
+		if q > 0 {
+			accepted = append(accepted, acceptedMediaType{mediaType: strings.ToLower(mediaType), q: q})
+		}
+	}
+
+	sort.SliceStable(accepted, func(i, j int) bool { return accepted[i].q > accepted[j].q })
+
+	for _, a := range accepted {
+		switch a.mediaType {
+		case "*/*", "application/*":
+			return preferred, nil
+		}
+
+		for _, f := range formats {
+			if a.mediaType == f {
+				return f, nil
+			}
+		}
+	}
+
+	return "", errNotAcceptable
+}
+
+// captureFormat negotiates the format of the stat response of the request. Failures are reported by
+// decodeFormatRequest so that requests nobody can accept aren't sent to XMiDT.
+func captureFormat(formats []string) kithttp.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		format, err := negotiateFormat(r.Header.Get("Accept"), formats)
+		if err != nil {
+			return context.WithValue(ctx, formatContextKey{}, err)
+		}
+		return context.WithValue(ctx, formatContextKey{}, format)
+	}
+}
+
+// decodeFormatRequest decorates decoder such that requests whose response format couldn't be negotiated
+// fail with a 406
+func decodeFormatRequest(decoder kithttp.DecodeRequestFunc) kithttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		if err, ok := ctx.Value(formatContextKey{}).(error); ok {
+			return nil, err
+		}
+		return decoder(ctx, r)
+	}
+}
+
+// formatFromContext returns the negotiated format of the stat response, JSON if there's none
+func formatFromContext(ctx context.Context) string {
+	if format, ok := ctx.Value(formatContextKey{}).(string); ok {
+		return format
+	}
+	return FormatJSON
+}
+
+// jsonToMsgpack re-encodes the JSON stat of a device as msgpack
+func jsonToMsgpack(body []byte) ([]byte, error) {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return nil, err
+	}
+
+	var encoded []byte
+	err := codec.NewEncoderBytes(&encoded, &codec.MsgpackHandle{WriteExt: true}).Encode(v)
+	return encoded, err
+}
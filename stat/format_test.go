@@ -0,0 +1,102 @@
+package stat
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugorji/go/codec"
+	"github.com/xmidt-org/tr1d1um/common"
+)
+
+func TestParseFormats(t *testing.T) {
+	assert := assert.New(t)
+
+	formats, err := ParseFormats(nil)
+	assert.Nil(err)
+	assert.Equal(DefaultFormats, formats)
+
+	formats, err = ParseFormats([]string{"Application/Msgpack"})
+	assert.Nil(err)
+	assert.Equal([]string{FormatMsgpack}, formats)
+
+	_, err = ParseFormats([]string{FormatJSON, "text/xml"})
+	assert.NotNil(err)
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		name           string
+		accept         string
+		formats        []string
+		expectedFormat string
+		expectedErr    error
+	}{
+		{name: "NoAccept", formats: DefaultFormats, expectedFormat: FormatJSON},
+		{name: "Any", accept: "*/*", formats: DefaultFormats, expectedFormat: FormatJSON},
+		{name: "Msgpack", accept: "application/msgpack", formats: DefaultFormats, expectedFormat: FormatMsgpack},
+		{name: "Quality", accept: "application/json;q=0.5, application/msgpack", formats: DefaultFormats, expectedFormat: FormatMsgpack},
+		{name: "Refused", accept: "application/msgpack;q=0, */*", formats: DefaultFormats, expectedFormat: FormatJSON},
+		{name: "AnyWithoutJSON", accept: "application/*", formats: []string{FormatMsgpack}, expectedFormat: FormatMsgpack},
+		{name: "Unsupported", accept: "text/xml", formats: DefaultFormats, expectedErr: errNotAcceptable},
+		{name: "Restricted", accept: "application/msgpack", formats: []string{FormatJSON}, expectedErr: errNotAcceptable},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			format, err := negotiateFormat(test.accept, test.formats)
+			assert.Equal(test.expectedFormat, format)
+			assert.Equal(test.expectedErr, err)
+		})
+	}
+}
+
+func TestDecodeFormatRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	r := httptest.NewRequest(http.MethodGet, "http://localhost/api/v2/device/mac:112233445566/stat", nil)
+	r.Header.Set("Accept", "text/xml")
+
+	decoder := decodeFormatRequest(func(context.Context, *http.Request) (interface{}, error) {
+		assert.Fail("requests which can't be accepted should not be decoded")
+		return nil, nil
+	})
+
+	_, err := decoder(captureFormat(DefaultFormats)(context.Background(), r), r)
+
+	if assert.NotNil(err) {
+		assert.Equal(http.StatusNotAcceptable, err.(common.CodedError).StatusCode())
+	}
+}
+
+func TestEncodeMsgpackResponse(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	r := httptest.NewRequest(http.MethodGet, "http://localhost/api/v2/device/mac:112233445566/stat", nil)
+	r.Header.Set("Accept", FormatMsgpack)
+	ctx := captureFormat(DefaultFormats)(ctxTID, r)
+
+	w := httptest.NewRecorder()
+	require.Nil(encodeResponse(ctx, w, &common.XmidtResponse{
+		Code:             http.StatusOK,
+		ForwardedHeaders: http.Header{},
+		Body:             []byte(`{"dBytesSent": "1024", "online": true}`),
+	}))
+
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal(FormatMsgpack, w.Header().Get("Content-Type"))
+
+	h := &codec.MsgpackHandle{}
+	h.RawToString = true
+
+	var decoded map[string]interface{}
+	require.Nil(codec.NewDecoderBytes(w.Body.Bytes(), h).Decode(&decoded))
+	assert.Equal("1024", decoded["dBytesSent"])
+	assert.Equal(true, decoded["online"])
+}
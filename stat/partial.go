@@ -0,0 +1,74 @@
+package stat
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/xmidt-org/tr1d1um/common"
+)
+
+// missingFieldsKey is the field of partial stat responses which lists the expected fields the device did not report
+const missingFieldsKey = "missingFields"
+
+// checkCompleteness verifies the successful stat response resp reports all the expected fields.
+// Fields are dot separated paths into the JSON object (i.e. "statistics.bytesSent").
+// Fields with null values are considered missing as well.
+// Incomplete responses are returned as 206 Partial Content with the list of missing fields when
+// allowPartial is set. Otherwise, they fail with a 502.
+func checkCompleteness(resp *common.XmidtResponse, expectedFields []string, allowPartial bool) (*common.XmidtResponse, error) {
+	if resp == nil || resp.Code != http.StatusOK || len(expectedFields) == 0 {
+		return resp, nil
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(resp.Body, &body); err != nil {
+		return resp, nil
+	}
+
+	var missing []string
+	for _, field := range expectedFields {
+		if !hasField(body, field) {
+			missing = append(missing, field)
+		}
+	}
+
+	if len(missing) == 0 {
+		return resp, nil
+	}
+
+	if !allowPartial {
+		return nil, common.NewCodedError(fmt.Errorf("device stat response is missing fields: %s", strings.Join(missing, ", ")), http.StatusBadGateway)
+	}
+
+	body[missingFieldsKey] = missing
+
+	partialBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &common.XmidtResponse{
+		Code:             http.StatusPartialContent,
+		ForwardedHeaders: resp.ForwardedHeaders,
+		Body:             partialBody,
+	}, nil
+}
+
+func hasField(body map[string]interface{}, field string) bool {
+	var value interface{} = body
+
+	for _, key := range strings.Split(field, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+
+		if value, ok = object[key]; !ok {
+			return false
+		}
+	}
+
+	return value != nil
+}
@@ -0,0 +1,111 @@
+package stat
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/tr1d1um/common"
+)
+
+func TestCheckCompleteness(t *testing.T) {
+	expectedFields := []string{"id", "statistics.bytesSent", "statistics.upTime"}
+
+	tests := []struct {
+		name            string
+		code            int
+		body            string
+		expectedFields  []string
+		allowPartial    bool
+		expectedCode    int
+		expectedMissing []interface{}
+		expectedErrCode int
+		unchanged       bool
+	}{
+		{
+			name:      "NoExpectedFields",
+			code:      http.StatusOK,
+			body:      `{"id": "mac:112233445566"}`,
+			unchanged: true,
+		},
+		{
+			name:           "Complete",
+			code:           http.StatusOK,
+			body:           `{"id": "mac:112233445566", "statistics": {"bytesSent": 10, "upTime": "1h"}}`,
+			expectedFields: expectedFields,
+			unchanged:      true,
+		},
+		{
+			name:           "NotOK",
+			code:           http.StatusNotFound,
+			body:           `{}`,
+			expectedFields: expectedFields,
+			unchanged:      true,
+		},
+		{
+			name:           "NotJSON",
+			code:           http.StatusOK,
+			body:           `stats`,
+			expectedFields: expectedFields,
+			unchanged:      true,
+		},
+		{
+			name:            "Partial",
+			code:            http.StatusOK,
+			body:            `{"id": "mac:112233445566", "statistics": {"bytesSent": 10, "upTime": null}}`,
+			expectedFields:  expectedFields,
+			allowPartial:    true,
+			expectedCode:    http.StatusPartialContent,
+			expectedMissing: []interface{}{"statistics.upTime"},
+		},
+		{
+			name:            "PartialMissingSection",
+			code:            http.StatusOK,
+			body:            `{"id": "mac:112233445566"}`,
+			expectedFields:  expectedFields,
+			allowPartial:    true,
+			expectedCode:    http.StatusPartialContent,
+			expectedMissing: []interface{}{"statistics.bytesSent", "statistics.upTime"},
+		},
+		{
+			name:            "Strict",
+			code:            http.StatusOK,
+			body:            `{"id": "mac:112233445566"}`,
+			expectedFields:  expectedFields,
+			expectedErrCode: http.StatusBadGateway,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			resp := &common.XmidtResponse{Code: test.code, Body: []byte(test.body), ForwardedHeaders: http.Header{}}
+			actual, err := checkCompleteness(resp, test.expectedFields, test.allowPartial)
+
+			if test.unchanged {
+				assert.Nil(err)
+				assert.Equal(resp, actual)
+				return
+			}
+
+			if test.expectedErrCode > 0 {
+				require.NotNil(err)
+				assert.Nil(actual)
+				assert.Equal(test.expectedErrCode, err.(common.CodedError).StatusCode())
+				return
+			}
+
+			require.Nil(err)
+			assert.Equal(test.expectedCode, actual.Code)
+
+			var body map[string]interface{}
+			require.Nil(json.Unmarshal(actual.Body, &body))
+			assert.Equal(test.expectedMissing, body[missingFieldsKey])
+			assert.Equal("mac:112233445566", body["id"])
+		})
+	}
+}
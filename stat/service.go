@@ -1,24 +1,30 @@
 package stat
 
 import (
+	"context"
 	"github.com/xmidt-org/bascule/acquire"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/go-kit/kit/metrics"
 	"github.com/xmidt-org/tr1d1um/common"
 )
 
 // Service defines the behavior of the device statistics Tr1d1um Service.
 type Service interface {
-	RequestStat(authHeaderValue, deviceID string) (*common.XmidtResponse, error)
+	RequestStat(ctx context.Context, authHeaderValue, deviceID string) (*common.XmidtResponse, error)
 }
 
 // NewService constructs a new stat service instance given some options.
 func NewService(o *ServiceOptions) Service {
 	return &service{
-		transactor:   o.HTTPTransactor,
-		authAcquirer: o.AuthAcquirer,
-		xmidtStatURL: o.XmidtStatURL,
+		transactor:     o.HTTPTransactor,
+		authAcquirer:   o.AuthAcquirer,
+		xmidtStatURL:   o.XmidtStatURL,
+		expectedFields: o.ExpectedFields,
+		allowPartial:   o.AllowPartial,
+		cache:          newStatCache(o.StatCacheTTL, o.StatCacheSize, o.StatCacheLookups),
 	}
 }
 
@@ -36,6 +42,29 @@ type ServiceOptions struct {
 	//Tr1d1umTransactor is the component that's responsible to make the HTTP
 	//request to the XMiDT API and return only data we care about.
 	HTTPTransactor common.Tr1d1umTransactor
+
+	//ExpectedFields are the dot separated paths (i.e. "statistics.bytesSent") of the fields
+	//a complete device stat response must report
+	//(Optional)
+	ExpectedFields []string
+
+	//AllowPartial makes responses missing some of the ExpectedFields be returned as 206 Partial Content
+	//rather than failing with 502
+	AllowPartial bool
+
+	//StatCacheTTL is how long the stat responses of devices are served from memory rather than
+	//fetched from XMiDT again. Caching is disabled when not positive
+	//(Optional)
+	StatCacheTTL time.Duration
+
+	//StatCacheSize is the max number of devices whose stat responses are cached. The least recently
+	//requested devices are evicted first
+	//(Optional) defaults to 10000
+	StatCacheSize int
+
+	//StatCacheLookups counts stat cache lookups labeled by outcome (hit or miss)
+	//(Optional)
+	StatCacheLookups metrics.Counter
 }
 
 type service struct {
@@ -44,11 +73,35 @@ type service struct {
 	authAcquirer acquire.Acquirer
 
 	xmidtStatURL string
+
+	expectedFields []string
+
+	allowPartial bool
+
+	cache *statCache
 }
 
 // RequestStat contacts the XMiDT cluster for device statistics.
-func (s *service) RequestStat(authHeaderValue, deviceID string) (*common.XmidtResponse, error) {
-	r, err := http.NewRequest(http.MethodGet, strings.Replace(s.xmidtStatURL, "${device}", deviceID, 1), nil)
+func (s *service) RequestStat(ctx context.Context, authHeaderValue, deviceID string) (*common.XmidtResponse, error) {
+	if s.cache == nil {
+		return s.fetchStat(ctx, authHeaderValue, deviceID)
+	}
+
+	key := statCacheKey(deviceID, common.CallerCredentials(ctx, authHeaderValue))
+	if resp, ok := s.cache.get(key); ok {
+		return resp, nil
+	}
+
+	resp, err := s.fetchStat(ctx, authHeaderValue, deviceID)
+	if err == nil {
+		s.cache.put(key, resp)
+	}
+
+	return resp, err
+}
+
+func (s *service) fetchStat(ctx context.Context, authHeaderValue, deviceID string) (*common.XmidtResponse, error) {
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.Replace(s.xmidtStatURL, "${device}", deviceID, 1), nil)
 
 	if err != nil {
 		return nil, err
@@ -62,5 +115,11 @@ func (s *service) RequestStat(authHeaderValue, deviceID string) (*common.XmidtRe
 	}
 
 	r.Header.Set("Authorization", authHeaderValue)
-	return s.transactor.Transact(r)
+
+	resp, err := s.transactor.Transact(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return checkCompleteness(resp, s.expectedFields, s.allowPartial)
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/xmidt-org/webpa-common/device"
 
 	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
 	kithttp "github.com/go-kit/kit/transport/http"
 	"github.com/gorilla/mux"
 	"github.com/justinas/alice"
@@ -20,30 +21,107 @@ type Options struct {
 	S Service
 
 	//APIRouter is assumed to be a subrouter with the API prefix path (i.e. 'api/v2')
-	APIRouter                   *mux.Router
-	Authenticate                *alice.Chain
-	Log                         kitlog.Logger
-	ReducedLoggingResponseCodes []int
+	APIRouter    *mux.Router
+	Authenticate *alice.Chain
+	Log          kitlog.Logger
+
+	//Settings provides the reduced logging response codes, which can be reloaded at runtime
+	Settings *common.Settings
+
+	//LatencyHistogram observes the end-to-end latency of requests
+	//(Optional)
+	LatencyHistogram metrics.Histogram
+
+	//PartnerRequests counts requests per partner
+	//(Optional)
+	PartnerRequests metrics.Counter
+
+	//KnownPartners are the partners PartnerRequests reports individually. Others are grouped together
+	KnownPartners []string
+
+	//StrictQueryParams makes requests with query parameters fail with 400 rather than having them ignored
+	StrictQueryParams bool
+
+	//DeviceIDSchemes restricts the device id schemes partners may use
+	//(Optional)
+	DeviceIDSchemes *common.DeviceIDSchemes
+
+	//ReadDuringWrite handles stat requests issued while a write to the same device is in flight
+	//(Optional)
+	ReadDuringWrite *common.ReadDuringWrite
+
+	//OperationLevels overrides the log level of stat requests through the STAT operation
+	//(Optional)
+	OperationLevels *common.OperationLevels
+
+	//BatchWorkers is the max number of concurrent XMiDT requests of a batch stat request
+	//(Optional) defaults to 10
+	BatchWorkers int
+
+	//MaxRequestBodyBytes is the size limit of request bodies (i.e. the device ids of batch requests).
+	//Larger requests fail with 413
+	//(Optional) no limit is enforced when it's not positive
+	MaxRequestBodyBytes int64
+
+	//Formats are the media types stat responses may be encoded in, as the Accept header asks. Requests accepting
+	//none of them fail with a 406
+	//(Optional) defaults to DefaultFormats
+	Formats []string
+
+	//AllowedMethods restricts the HTTP methods of the stat endpoints. Others are rejected with a 405
+	//whether or not capabilities are checked
+	//(Optional) all supported methods are allowed when empty
+	AllowedMethods []string
 }
 
+// Operation is the name stat requests go by in OperationLevels
+const Operation = "STAT"
+
 // ConfigHandler sets up the server that powers the stat service
 // That is, it configures the mux paths to access the service
 func ConfigHandler(c *Options) {
 	opts := []kithttp.ServerOption{
-		kithttp.ServerBefore(common.Capture(c.Log)),
+		kithttp.ServerBefore(common.CaptureOperationLogger(c.OperationLevels, statOperation), common.Capture(c.Log)),
 		kithttp.ServerErrorEncoder(common.ErrorLogEncoder(c.Log, encodeError)),
-		kithttp.ServerFinalizer(common.TransactionLogging(c.ReducedLoggingResponseCodes, c.Log)),
+		kithttp.ServerFinalizer(common.TransactionLogging(c.Settings, c.Log)),
+	}
+
+	formats := c.Formats
+	if len(formats) == 0 {
+		formats = DefaultFormats
 	}
 
+	// only the stat of single devices is available in other formats than JSON
+	statOpts := append([]kithttp.ServerOption{kithttp.ServerBefore(captureFormat(formats))}, opts...)
+
 	statHandler := kithttp.NewServer(
 		makeStatEndpoint(c.S),
-		decodeRequest,
+		decodeFormatRequest(common.RestrictDeviceIDSchemes(c.DeviceIDSchemes, common.StrictQueryParams(c.StrictQueryParams, nil, decodeRequest))),
 		encodeResponse,
+		statOpts...,
+	)
+
+	// stat requests don't produce WRP messages
+	instrument := common.InstrumentLatency(c.LatencyHistogram, "stat", "none")
+	countPartner := common.InstrumentPartnerRequests(c.PartnerRequests, c.KnownPartners, "stat")
+	limitBody := common.LimitRequestBody(c.MaxRequestBodyBytes)
+
+	common.HandleMethods(c.APIRouter, "/device/{deviceid}/stat", instrument(c.Authenticate.Then(countPartner(common.Welcome(c.ReadDuringWrite.Then(limitBody(statHandler)))))),
+		[]string{http.MethodGet}, c.AllowedMethods)
+
+	batchHandler := kithttp.NewServer(
+		makeBatchStatEndpoint(c.S, c.BatchWorkers),
+		common.StrictQueryParams(c.StrictQueryParams, nil, decodeBatchRequest(c.DeviceIDSchemes)),
+		encodeBatchResponse,
 		opts...,
 	)
 
-	c.APIRouter.Handle("/device/{deviceid}/stat", c.Authenticate.Then(common.Welcome(statHandler))).
-		Methods(http.MethodGet)
+	common.HandleMethods(c.APIRouter, BatchPath, instrument(c.Authenticate.Then(countPartner(common.Welcome(limitBody(batchHandler))))),
+		[]string{http.MethodPost}, c.AllowedMethods)
+}
+
+func statOperation(*http.Request) string {
+	return Operation
 }
 
 func decodeRequest(_ context.Context, r *http.Request) (req interface{}, err error) {
@@ -64,6 +142,14 @@ func encodeError(ctx context.Context, err error, w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.Header().Set(common.HeaderWPATID, ctx.Value(common.ContextKeyRequestTID).(string))
 
+	if h, ok := err.(kithttp.Headerer); ok {
+		for k, values := range h.Headers() {
+			for _, v := range values {
+				w.Header().Add(k, v)
+			}
+		}
+	}
+
 	if ce, ok := err.(common.CodedError); ok {
 		w.WriteHeader(ce.StatusCode())
 	} else {
@@ -71,9 +157,15 @@ func encodeError(ctx context.Context, err error, w http.ResponseWriter) {
 		err = common.ErrTr1d1umInternal
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{
+	body := map[string]string{
 		"message": err.Error(),
-	})
+	}
+
+	if ec, ok := err.(common.ErrorCoder); ok && ec.ErrorCode() != "" {
+		body["code"] = ec.ErrorCode()
+	}
+
+	json.NewEncoder(w).Encode(body)
 }
 
 // encodeResponse simply forwards the response Tr1d1um got from the XMiDT API
@@ -82,9 +174,16 @@ func encodeError(ctx context.Context, err error, w http.ResponseWriter) {
 // do we care to make that distinction?
 func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
 	resp := response.(*common.XmidtResponse)
-
-	if resp.Code == http.StatusOK {
-		w.Header().Set("Content-Type", "application/json")
+	body := resp.Body
+
+	if resp.Code == http.StatusOK || resp.Code == http.StatusPartialContent {
+		format := formatFromContext(ctx)
+		if format == FormatMsgpack {
+			if body, err = jsonToMsgpack(resp.Body); err != nil {
+				return
+			}
+		}
+		w.Header().Set("Content-Type", format)
 	} else {
 		w.Header().Del("Content-Type")
 	}
@@ -93,6 +192,6 @@ func encodeResponse(ctx context.Context, w http.ResponseWriter, response interfa
 	common.ForwardHeadersByPrefix("", resp.ForwardedHeaders, w.Header())
 
 	w.WriteHeader(resp.Code)
-	_, err = w.Write(resp.Body)
+	_, err = w.Write(body)
 	return
 }
@@ -82,6 +82,35 @@ func TestEncodeError(t *testing.T) {
 		assert.EqualValues(http.StatusInternalServerError, w.Code)
 		assert.EqualValues(expected.String(), w.Body.String())
 	})
+
+	t.Run("ErrorCode", func(t *testing.T) {
+		assert := assert.New(t)
+		expected := bytes.NewBufferString("")
+
+		json.NewEncoder(expected).Encode(
+			map[string]string{
+				"message": common.ErrGatewayTLS.Error(),
+				"code":    common.ErrorCodeGatewayTLS,
+			},
+		)
+
+		w := httptest.NewRecorder()
+		encodeError(ctxTID, common.NewCodedErrorWithErrorCode(common.ErrGatewayTLS, http.StatusBadGateway, common.ErrorCodeGatewayTLS), w)
+
+		assert.EqualValues(http.StatusBadGateway, w.Code)
+		assert.EqualValues(expected.String(), w.Body.String())
+	})
+
+	t.Run("Headers", func(t *testing.T) {
+		assert := assert.New(t)
+
+		w := httptest.NewRecorder()
+		encodeError(ctxTID, common.NewCodedErrorWithHeaders(common.ErrCircuitOpen, http.StatusServiceUnavailable,
+			http.Header{"Retry-After": []string{"30"}}), w)
+
+		assert.EqualValues(http.StatusServiceUnavailable, w.Code)
+		assert.EqualValues("30", w.Header().Get("Retry-After"))
+	})
 }
 
 func testErrorEncode(t *testing.T, expectedCode int, es []error) {
@@ -0,0 +1,94 @@
+package translation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/xmidt-org/tr1d1um/common"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+)
+
+// CompileParameterAllowList compiles the given regular expressions TR-181 parameter names must match
+func CompileParameterAllowList(patterns []string) ([]*regexp.Regexp, error) {
+	allowList := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		r, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameter allow list pattern '%s': %v", pattern, err)
+		}
+		allowList = append(allowList, r)
+	}
+	return allowList, nil
+}
+
+// parameterNames returns the names of all the TR-181 parameters (including tables and rows) the given WDMP touches
+func parameterNames(wdmp []byte) []string {
+	var w struct {
+		Names      []string        `json:"names"`
+		Parameters []setParam      `json:"parameters"`
+		Table      string          `json:"table"`
+		Row        json.RawMessage `json:"row"`
+	}
+
+	if err := json.Unmarshal(wdmp, &w); err != nil {
+		return nil
+	}
+
+	names := w.Names
+	for _, p := range w.Parameters {
+		if p.Name != nil {
+			names = append(names, *p.Name)
+		}
+	}
+
+	if w.Table != "" {
+		names = append(names, w.Table)
+	}
+
+	// rows are only parameter names for DELETE_ROW. Otherwise, they're the values of a new row
+	var row string
+	if json.Unmarshal(w.Row, &row) == nil && row != "" {
+		names = append(names, row)
+	}
+
+	return names
+}
+
+func allowed(name string, allowList []*regexp.Regexp) bool {
+	for _, r := range allowList {
+		if r.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeAllowedParametersRequest decorates decoder such that requests touching parameters which match none
+// of the patterns of the current allow list of settings are rejected with a 403. All parameters are allowed
+// when the allow list is empty.
+func decodeAllowedParametersRequest(settings *common.Settings, decoder kithttp.DecodeRequestFunc) kithttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		request, err := decoder(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+
+		// the allow list is read once so that reloads don't change it halfway through a request
+		allowList := settings.Load().ParameterAllowList
+		if len(allowList) == 0 {
+			return request, nil
+		}
+
+		for _, name := range parameterNames(request.(*wrpRequest).WRPMessage.Payload) {
+			if !allowed(name, allowList) {
+				return nil, common.NewCodedError(fmt.Errorf("parameter '%s' is not allowed", name), http.StatusForbidden)
+			}
+		}
+
+		return request, nil
+	}
+}
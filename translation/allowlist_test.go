@@ -0,0 +1,77 @@
+package translation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/tr1d1um/common"
+	"github.com/xmidt-org/wrp-go/wrp"
+)
+
+func TestCompileParameterAllowList(t *testing.T) {
+	assert := assert.New(t)
+
+	allowList, err := CompileParameterAllowList([]string{`^Device\.WiFi\.`})
+	assert.Nil(err)
+	assert.Len(allowList, 1)
+
+	_, err = CompileParameterAllowList([]string{`^Device\.(`})
+	assert.NotNil(err)
+}
+
+func TestDecodeAllowedParametersRequest(t *testing.T) {
+	allowList, err := CompileParameterAllowList([]string{`^Device\.WiFi\.`, `^Device\.DeviceInfo\.`})
+	require.Nil(t, err)
+
+	tests := []struct {
+		name          string
+		allowList     bool
+		wdmp          string
+		expectedError string
+	}{
+		{name: "EmptyAllowList", wdmp: `{"command":"GET","names":["Device.Hosts.HostNumberOfEntries"]}`},
+		{name: "Get", allowList: true, wdmp: `{"command":"GET","names":["Device.WiFi.SSID.1.Enable","Device.DeviceInfo.Manufacturer"]}`},
+		{name: "GetDisallowed", allowList: true, wdmp: `{"command":"GET","names":["Device.WiFi.SSID.1.Enable","Device.Hosts.HostNumberOfEntries"]}`,
+			expectedError: "parameter 'Device.Hosts.HostNumberOfEntries' is not allowed"},
+		{name: "Set", allowList: true, wdmp: `{"command":"SET","parameters":[{"name":"Device.WiFi.SSID.1.Enable","value":"true","dataType":3}]}`},
+		{name: "SetDisallowed", allowList: true,
+			wdmp:          `{"command":"SET","parameters":[{"name":"Device.WiFi.SSID.1.Enable","value":"true","dataType":3},{"name":"Device.X_CISCO.Reboot","value":"true","dataType":3}]}`,
+			expectedError: "parameter 'Device.X_CISCO.Reboot' is not allowed"},
+		{name: "AddRow", allowList: true, wdmp: `{"command":"ADD_ROW","table":"Device.WiFi.AccessPoint.","row":{"MACAddress":"12:34:56:78:90:AB"}}`},
+		{name: "DeleteRowDisallowed", allowList: true, wdmp: `{"command":"DELETE_ROW","row":"Device.Hosts.Host.1."}`,
+			expectedError: "parameter 'Device.Hosts.Host.1.' is not allowed"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var patterns = allowList
+			if !test.allowList {
+				patterns = nil
+			}
+
+			decoder := decodeAllowedParametersRequest(common.NewSettings(common.Snapshot{ParameterAllowList: patterns}), func(_ context.Context, _ *http.Request) (interface{}, error) {
+				return &wrpRequest{WRPMessage: &wrp.Message{Payload: []byte(test.wdmp)}}, nil
+			})
+
+			request, err := decoder(context.Background(), httptest.NewRequest(http.MethodGet, "http://localhost:8090/api", nil))
+
+			if test.expectedError == "" {
+				assert.Nil(err)
+				assert.NotNil(request)
+				return
+			}
+
+			assert.Nil(request)
+			if assert.NotNil(err) {
+				assert.Equal(test.expectedError, err.Error())
+				assert.Equal(http.StatusForbidden, err.(common.CodedError).StatusCode())
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package translation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	kitlog "github.com/go-kit/kit/log"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"github.com/xmidt-org/tr1d1um/common"
+	"github.com/xmidt-org/wrp-go/wrp"
+)
+
+type analyticsContextKey struct{}
+
+// transactionAnalytics gathers the details of a device transaction as it moves through the gokit server flow
+type transactionAnalytics struct {
+	device         string
+	partners       []string
+	operation      string
+	parameterCount int
+	retries        int32
+}
+
+// captureAnalytics prepares the collection of analytics for the transaction. It's a no-op
+// when analytics are disabled (nil logger)
+func captureAnalytics(logger kitlog.Logger) kithttp.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		if logger == nil {
+			return ctx
+		}
+
+		a := &transactionAnalytics{device: mux.Vars(r)["deviceid"]}
+		ctx = context.WithValue(ctx, analyticsContextKey{}, a)
+		return context.WithValue(ctx, common.ContextKeyRetryCount, &a.retries)
+	}
+}
+
+// recordWRPAnalytics adds the details of the decoded WRP message to the analytics of the transaction, if any
+func recordWRPAnalytics(ctx context.Context, m *wrp.Message) {
+	a, ok := ctx.Value(analyticsContextKey{}).(*transactionAnalytics)
+	if !ok {
+		return
+	}
+
+	var wdmp struct {
+		Command    string                     `json:"command"`
+		Names      []string                   `json:"names"`
+		Parameters []json.RawMessage          `json:"parameters"`
+		Row        json.RawMessage            `json:"row"`
+		Rows       map[string]json.RawMessage `json:"rows"`
+	}
+
+	a.partners = m.PartnerIDs
+
+	if err := json.Unmarshal(m.Payload, &wdmp); err != nil {
+		return
+	}
+
+	a.operation = wdmp.Command
+	a.parameterCount = len(wdmp.Names) + len(wdmp.Parameters) + len(wdmp.Rows)
+	if len(wdmp.Row) > 0 {
+		a.parameterCount++
+	}
+}
+
+// analyticsLogging emits a single analytics record per transaction regardless of the reduced transaction logging settings
+func analyticsLogging(logger kitlog.Logger) kithttp.ServerFinalizerFunc {
+	return func(ctx context.Context, code int, r *http.Request) {
+		a, ok := ctx.Value(analyticsContextKey{}).(*transactionAnalytics)
+		if !ok {
+			return
+		}
+
+		var duration time.Duration
+		if requestArrival, ok := ctx.Value(common.ContextKeyRequestArrivalTime).(time.Time); ok {
+			duration = time.Since(requestArrival)
+		}
+
+		tid, _ := ctx.Value(common.ContextKeyRequestTID).(string)
+
+		logger.Log(
+			"msg", "transaction completed",
+			"device", a.device,
+			"partners", a.partners,
+			"operation", a.operation,
+			"parameterCount", a.parameterCount,
+			"status", code,
+			"duration", duration.String(),
+			"retries", atomic.LoadInt32(&a.retries),
+			"tid", tid,
+		)
+	}
+}
@@ -0,0 +1,99 @@
+package translation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/tr1d1um/common"
+	"github.com/xmidt-org/wrp-go/wrp"
+)
+
+type recordingLogger struct {
+	records []map[string]interface{}
+}
+
+func (l *recordingLogger) Log(keyvals ...interface{}) error {
+	record := make(map[string]interface{})
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		record[keyvals[i].(string)] = keyvals[i+1]
+	}
+	l.records = append(l.records, record)
+	return nil
+}
+
+func TestAnalytics(t *testing.T) {
+	tests := []struct {
+		name                   string
+		payload                string
+		expectedOperation      string
+		expectedParameterCount int
+	}{
+		{
+			name:                   "Get",
+			payload:                `{"command": "GET", "names": ["p1", "p2"]}`,
+			expectedOperation:      CommandGet,
+			expectedParameterCount: 2,
+		},
+		{
+			name:                   "Set",
+			payload:                `{"command": "SET", "parameters": [{"name": "p1", "value": "v1", "dataType": 0}]}`,
+			expectedOperation:      CommandSet,
+			expectedParameterCount: 1,
+		},
+		{
+			name:                   "ReplaceRows",
+			payload:                `{"command": "REPLACE_ROWS", "table": "t", "rows": {"0": {"a": "b"}, "1": {"a": "c"}}}`,
+			expectedOperation:      CommandReplaceRows,
+			expectedParameterCount: 2,
+		},
+		{
+			name:                   "DeleteRow",
+			payload:                `{"command": "DELETE_ROW", "row": "t.1."}`,
+			expectedOperation:      CommandDeleteRow,
+			expectedParameterCount: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			logger := new(recordingLogger)
+
+			r := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+			r = mux.SetURLVars(r, map[string]string{"deviceid": "mac:112233445566"})
+
+			ctx := context.WithValue(ctxTID, common.ContextKeyRequestArrivalTime, time.Now())
+			ctx = captureAnalytics(logger)(ctx, r)
+
+			recordWRPAnalytics(ctx, &wrp.Message{Payload: []byte(test.payload), PartnerIDs: []string{"comcast"}})
+			*(ctx.Value(common.ContextKeyRetryCount).(*int32)) = 2
+
+			analyticsLogging(logger)(ctx, http.StatusOK, r)
+
+			require.Len(logger.records, 1)
+			record := logger.records[0]
+			assert.Equal("mac:112233445566", record["device"])
+			assert.Equal([]string{"comcast"}, record["partners"])
+			assert.Equal(test.expectedOperation, record["operation"])
+			assert.Equal(test.expectedParameterCount, record["parameterCount"])
+			assert.Equal(http.StatusOK, record["status"])
+			assert.NotEmpty(record["duration"])
+			assert.EqualValues(2, record["retries"])
+			assert.Equal("test-tid", record["tid"])
+		})
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		assert := assert.New(t)
+		r := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+		ctx := captureAnalytics(nil)(ctxTID, r)
+		assert.Equal(ctxTID, ctx)
+	})
+}
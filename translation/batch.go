@@ -0,0 +1,157 @@
+package translation
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/xmidt-org/tr1d1um/common"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+)
+
+type batchGetContextKey struct{}
+
+// batchGet is a GET for the list of TR-181 parameter names in the body of a POST request
+type batchGet struct {
+	names []string
+	err   error
+}
+
+// batchParameter is the outcome of a batch GET for a single parameter. Parameter is the device
+// result for the parameter when it was fetched and Message explains why it wasn't otherwise.
+type batchParameter struct {
+	StatusCode int             `json:"statusCode"`
+	Parameter  json.RawMessage `json:"parameter,omitempty"`
+	Message    string          `json:"message,omitempty"`
+}
+
+// isBatchGetRequest reports whether r is a POST to a device service rather than to one of its tables
+func isBatchGetRequest(r *http.Request) bool {
+	return r.Method == http.MethodPost && mux.Vars(r)["parameter"] == ""
+}
+
+// captureBatchGet reads the JSON array of parameter names of batch GET requests. It must run after the
+// body is decompressed.
+func captureBatchGet(ctx context.Context, r *http.Request) context.Context {
+	if !isBatchGetRequest(r) {
+		return ctx
+	}
+
+	var b batchGet
+
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+
+	if err == common.ErrRequestBodyTooLarge {
+		b.err = err
+	} else if err != nil || json.Unmarshal(body, &b.names) != nil {
+		b.err = ErrInvalidBatchNames
+	} else if len(b.names) == 0 {
+		b.err = ErrEmptyNames
+	} else {
+		for _, name := range b.names {
+			if name == "" {
+				b.err = ErrInvalidBatchNames
+				break
+			}
+		}
+	}
+
+	return context.WithValue(ctx, batchGetContextKey{}, &b)
+}
+
+// decodeBatchGetRequest decorates decoder such that batch GET requests with an invalid list of names are rejected
+func decodeBatchGetRequest(decoder kithttp.DecodeRequestFunc) kithttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		if b, ok := ctx.Value(batchGetContextKey{}).(*batchGet); ok && b.err != nil {
+			return nil, b.err
+		}
+
+		return decoder(ctx, r)
+	}
+}
+
+// batchGetFromContext returns the parameter names of batch GET requests
+func batchGetFromContext(ctx context.Context) ([]string, bool) {
+	b, ok := ctx.Value(batchGetContextKey{}).(*batchGet)
+	if !ok || b.err != nil {
+		return nil, false
+	}
+
+	return b.names, true
+}
+
+// requestBatchGetPayload builds a single GET WDMP for all the given names
+func requestBatchGetPayload(names []string) ([]byte, error) {
+	if len(names) < 1 {
+		return nil, ErrEmptyNames
+	}
+
+	return json.Marshal(&getWDMP{Command: CommandGet, Names: names})
+}
+
+// encodeBatchGetResponse writes the device results of a batch GET keyed by the requested parameter names.
+// Parameters the device returned no result for carry the device status code and message. The response
+// code is 200 when all parameters were fetched and 207 when only some of them were.
+func encodeBatchGetResponse(w http.ResponseWriter, names []string, payload []byte) error {
+	var deviceResponse struct {
+		StatusCode int               `json:"statusCode"`
+		Message    string            `json:"message"`
+		Parameters []json.RawMessage `json:"parameters"`
+	}
+
+	if err := json.Unmarshal(payload, &deviceResponse); err != nil {
+		_, err = w.Write(payload)
+		return err
+	}
+
+	results := make(map[string]json.RawMessage, len(deviceResponse.Parameters))
+	for _, p := range deviceResponse.Parameters {
+		var named struct {
+			Name string `json:"name"`
+		}
+
+		if json.Unmarshal(p, &named) == nil && named.Name != "" {
+			results[named.Name] = p
+		}
+	}
+
+	failureCode := deviceResponse.StatusCode
+	if failureCode == 0 || failureCode == http.StatusOK {
+		failureCode = http.StatusNotFound
+	}
+
+	parameters := make(map[string]batchParameter, len(names))
+	fetched := 0
+	for _, name := range names {
+		if p, ok := results[name]; ok {
+			parameters[name] = batchParameter{StatusCode: http.StatusOK, Parameter: p}
+			fetched++
+			continue
+		}
+
+		message := deviceResponse.Message
+		if message == "" {
+			message = "parameter not returned by device"
+		}
+
+		parameters[name] = batchParameter{StatusCode: failureCode, Message: message}
+	}
+
+	statusCode := http.StatusOK
+	switch {
+	case fetched == 0:
+		statusCode = failureCode
+	case fetched < len(names):
+		statusCode = http.StatusMultiStatus
+	}
+
+	w.WriteHeader(statusCode)
+	return json.NewEncoder(w).Encode(map[string]interface{}{
+		"statusCode": statusCode,
+		"parameters": parameters,
+	})
+}
@@ -0,0 +1,147 @@
+package translation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/tr1d1um/common"
+	"github.com/xmidt-org/wrp-go/wrp"
+)
+
+func TestCaptureBatchGet(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		vars          map[string]string
+		body          string
+		expectedBatch bool
+		expectedNames []string
+		expectedErr   error
+	}{
+		{name: "Get", method: http.MethodGet, vars: map[string]string{"service": "config"}},
+		{name: "AddRow", method: http.MethodPost, vars: map[string]string{"service": "config", "parameter": "Device.NAT.PortMapping."}, body: `{}`},
+		{name: "Batch", method: http.MethodPost, vars: map[string]string{"service": "config"}, body: `["Device.A", "Device.B"]`, expectedBatch: true, expectedNames: []string{"Device.A", "Device.B"}},
+		{name: "Empty", method: http.MethodPost, vars: map[string]string{"service": "config"}, body: `[]`, expectedErr: ErrEmptyNames},
+		{name: "EmptyName", method: http.MethodPost, vars: map[string]string{"service": "config"}, body: `["Device.A", ""]`, expectedErr: ErrInvalidBatchNames},
+		{name: "NotArray", method: http.MethodPost, vars: map[string]string{"service": "config"}, body: `{"names": ["Device.A"]}`, expectedErr: ErrInvalidBatchNames},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			r := httptest.NewRequest(test.method, "http://localhost:8090/api", strings.NewReader(test.body))
+			r = mux.SetURLVars(r, test.vars)
+			ctx := captureBatchGet(context.Background(), r)
+
+			_, err := decodeBatchGetRequest(func(_ context.Context, _ *http.Request) (interface{}, error) {
+				return nil, nil
+			})(ctx, r)
+			assert.Equal(test.expectedErr, err)
+
+			names, ok := batchGetFromContext(ctx)
+			assert.Equal(test.expectedBatch, ok)
+			assert.Equal(test.expectedNames, names)
+		})
+	}
+}
+
+func TestDecodeBatchGetRequest(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	r := httptest.NewRequest(http.MethodPost, "http://localhost:8090/api", strings.NewReader(`["Device.A", "Device.B"]`))
+	r = mux.SetURLVars(r, map[string]string{"deviceid": "mac:112233445566", "service": "config"})
+
+	request, err := decodeRequest(captureBatchGet(ctxTID, r), r)
+	require.Nil(err)
+
+	var wdmp getWDMP
+	require.Nil(json.Unmarshal(request.(*wrpRequest).WRPMessage.Payload, &wdmp))
+	assert.Equal(getWDMP{Command: CommandGet, Names: []string{"Device.A", "Device.B"}}, wdmp)
+}
+
+func TestEncodeBatchGetResponse(t *testing.T) {
+	tests := []struct {
+		name               string
+		payload            string
+		expectedCode       int
+		expectedParameters map[string]batchParameter
+	}{
+		{
+			name:         "AllFetched",
+			payload:      `{"statusCode":200,"parameters":[{"name":"Device.A","value":"a","dataType":0},{"name":"Device.B","value":"b","dataType":0}]}`,
+			expectedCode: http.StatusOK,
+			expectedParameters: map[string]batchParameter{
+				"Device.A": {StatusCode: http.StatusOK, Parameter: json.RawMessage(`{"name":"Device.A","value":"a","dataType":0}`)},
+				"Device.B": {StatusCode: http.StatusOK, Parameter: json.RawMessage(`{"name":"Device.B","value":"b","dataType":0}`)},
+			},
+		},
+		{
+			name:         "Partial",
+			payload:      `{"statusCode":520,"message":"Invalid parameter name","parameters":[{"name":"Device.A","value":"a","dataType":0}]}`,
+			expectedCode: http.StatusMultiStatus,
+			expectedParameters: map[string]batchParameter{
+				"Device.A": {StatusCode: http.StatusOK, Parameter: json.RawMessage(`{"name":"Device.A","value":"a","dataType":0}`)},
+				"Device.B": {StatusCode: 520, Message: "Invalid parameter name"},
+			},
+		},
+		{
+			name:         "NoneFetched",
+			payload:      `{"statusCode":520,"message":"Invalid parameter name"}`,
+			expectedCode: 520,
+			expectedParameters: map[string]batchParameter{
+				"Device.A": {StatusCode: 520, Message: "Invalid parameter name"},
+				"Device.B": {StatusCode: 520, Message: "Invalid parameter name"},
+			},
+		},
+		{
+			name:         "MissingWithSuccess",
+			payload:      `{"statusCode":200,"parameters":[{"name":"Device.A","value":"a","dataType":0}]}`,
+			expectedCode: http.StatusMultiStatus,
+			expectedParameters: map[string]batchParameter{
+				"Device.A": {StatusCode: http.StatusOK, Parameter: json.RawMessage(`{"name":"Device.A","value":"a","dataType":0}`)},
+				"Device.B": {StatusCode: http.StatusNotFound, Message: "parameter not returned by device"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			r := httptest.NewRequest(http.MethodPost, "http://localhost:8090/api", strings.NewReader(`["Device.A", "Device.B"]`))
+			r = mux.SetURLVars(r, map[string]string{"service": "config"})
+			ctx := captureBatchGet(ctxTID, r)
+
+			response := &common.XmidtResponse{
+				Code: http.StatusOK,
+				Body: wrp.MustEncode(&wrp.Message{
+					Type:    wrp.SimpleRequestResponseMessageType,
+					Payload: []byte(test.payload),
+				}, wrp.Msgpack),
+			}
+
+			recorder := httptest.NewRecorder()
+			require.Nil(encodeResponse(ctx, recorder, response))
+			assert.Equal(test.expectedCode, recorder.Code)
+
+			var body struct {
+				StatusCode int                       `json:"statusCode"`
+				Parameters map[string]batchParameter `json:"parameters"`
+			}
+
+			require.Nil(json.Unmarshal(recorder.Body.Bytes(), &body))
+			assert.Equal(test.expectedCode, body.StatusCode)
+			assert.Equal(test.expectedParameters, body.Parameters)
+		})
+	}
+}
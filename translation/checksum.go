@@ -0,0 +1,133 @@
+package translation
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/xmidt-org/tr1d1um/common"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+)
+
+const (
+	contentMD5HeaderKey = "Content-MD5"
+	bodySHA256HeaderKey = "X-Tr1d1um-Body-SHA256"
+)
+
+// ChecksumAlgorithm describes how a request body checksum is sent and verified
+type ChecksumAlgorithm struct {
+	name    string
+	header  string
+	newHash func() hash.Hash
+	decode  func(string) ([]byte, error)
+}
+
+// checksumAlgorithms are the supported algorithms by name
+var checksumAlgorithms = map[string]ChecksumAlgorithm{
+	"md5": {
+		name:    "md5",
+		header:  contentMD5HeaderKey,
+		newHash: md5.New,
+		decode:  base64.StdEncoding.DecodeString,
+	},
+	"sha256": {
+		name:    "sha256",
+		header:  bodySHA256HeaderKey,
+		newHash: sha256.New,
+		decode:  hex.DecodeString,
+	},
+}
+
+// ParseChecksumAlgorithms returns the checksum algorithms with the given names (md5 or sha256)
+func ParseChecksumAlgorithms(names []string) ([]ChecksumAlgorithm, error) {
+	algorithms := make([]ChecksumAlgorithm, 0, len(names))
+	for _, name := range names {
+		a, ok := checksumAlgorithms[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported checksum algorithm '%s'", name)
+		}
+		algorithms = append(algorithms, a)
+	}
+	return algorithms, nil
+}
+
+type checksumContextKey struct{}
+
+// checksum is the outcome of the body checksum verification for a request
+type checksum struct {
+	err error
+}
+
+// captureChecksum verifies request bodies against the checksums clients send for any of the given algorithms.
+// It must run before the body is decompressed as checksums are computed over the bytes sent over the wire.
+// It's a no-op when no algorithms are enabled or the request carries no checksum.
+func captureChecksum(algorithms []ChecksumAlgorithm) kithttp.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		var expected []ChecksumAlgorithm
+		for _, a := range algorithms {
+			if r.Header.Get(a.header) != "" {
+				expected = append(expected, a)
+			}
+		}
+
+		if len(expected) == 0 {
+			return ctx
+		}
+
+		var c checksum
+
+		body, err := ioutil.ReadAll(r.Body)
+		r.Body.Close()
+
+		if err != nil {
+			c.err = err
+			if err != common.ErrRequestBodyTooLarge {
+				c.err = common.NewBadRequestError(err)
+			}
+			body = nil
+		} else {
+			for _, a := range expected {
+				if !a.verify(body, r.Header.Get(a.header)) {
+					c.err = ErrChecksumMismatch
+					break
+				}
+			}
+		}
+
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		return context.WithValue(ctx, checksumContextKey{}, &c)
+	}
+}
+
+// verify reports whether the given encoded value is the checksum of body
+func (a ChecksumAlgorithm) verify(body []byte, value string) bool {
+	expected, err := a.decode(strings.TrimSpace(value))
+	if err != nil {
+		return false
+	}
+
+	h := a.newHash()
+	h.Write(body)
+	return bytes.Equal(h.Sum(nil), expected)
+}
+
+// decodeChecksumRequest decorates decoder such that requests whose body doesn't match their checksum are rejected
+func decodeChecksumRequest(decoder kithttp.DecodeRequestFunc) kithttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		if c, ok := ctx.Value(checksumContextKey{}).(*checksum); ok && c.err != nil {
+			return nil, c.err
+		}
+
+		return decoder(ctx, r)
+	}
+}
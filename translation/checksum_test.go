@@ -0,0 +1,143 @@
+package translation
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChecksumAlgorithms(t *testing.T) {
+	assert := assert.New(t)
+
+	algorithms, err := ParseChecksumAlgorithms([]string{"MD5", "sha256"})
+	assert.Nil(err)
+	if assert.Len(algorithms, 2) {
+		assert.Equal(contentMD5HeaderKey, algorithms[0].header)
+		assert.Equal(bodySHA256HeaderKey, algorithms[1].header)
+	}
+
+	algorithms, err = ParseChecksumAlgorithms(nil)
+	assert.Nil(err)
+	assert.Empty(algorithms)
+
+	_, err = ParseChecksumAlgorithms([]string{"md5", "crc32"})
+	assert.NotNil(err)
+}
+
+func TestCaptureChecksum(t *testing.T) {
+	body := []byte(`{"parameters":[{"name":"deviceName","value":"newName","dataType":0}]}`)
+	md5Sum := md5.Sum(body)
+	sha256Sum := sha256.Sum256(body)
+	otherSum := sha256.Sum256([]byte("other"))
+
+	all, err := ParseChecksumAlgorithms([]string{"md5", "sha256"})
+	require.Nil(t, err)
+
+	md5Only, err := ParseChecksumAlgorithms([]string{"md5"})
+	require.Nil(t, err)
+
+	tests := []struct {
+		name         string
+		algorithms   []ChecksumAlgorithm
+		headers      map[string]string
+		expectedBody []byte
+		expectedErr  error
+	}{
+		{
+			name:         "NoChecksum",
+			algorithms:   all,
+			expectedBody: body,
+		},
+		{
+			name:         "MD5",
+			algorithms:   all,
+			headers:      map[string]string{contentMD5HeaderKey: base64.StdEncoding.EncodeToString(md5Sum[:])},
+			expectedBody: body,
+		},
+		{
+			name:         "MD5Mismatch",
+			algorithms:   all,
+			headers:      map[string]string{contentMD5HeaderKey: base64.StdEncoding.EncodeToString(otherSum[:16])},
+			expectedBody: body,
+			expectedErr:  ErrChecksumMismatch,
+		},
+		{
+			name:         "MD5Malformed",
+			algorithms:   all,
+			headers:      map[string]string{contentMD5HeaderKey: "not base64!"},
+			expectedBody: body,
+			expectedErr:  ErrChecksumMismatch,
+		},
+		{
+			name:         "SHA256",
+			algorithms:   all,
+			headers:      map[string]string{bodySHA256HeaderKey: strings.ToUpper(hex.EncodeToString(sha256Sum[:]))},
+			expectedBody: body,
+		},
+		{
+			name:         "SHA256Mismatch",
+			algorithms:   all,
+			headers:      map[string]string{bodySHA256HeaderKey: hex.EncodeToString(otherSum[:])},
+			expectedBody: body,
+			expectedErr:  ErrChecksumMismatch,
+		},
+		{
+			name:       "BothOneMismatch",
+			algorithms: all,
+			headers: map[string]string{
+				contentMD5HeaderKey: base64.StdEncoding.EncodeToString(md5Sum[:]),
+				bodySHA256HeaderKey: hex.EncodeToString(otherSum[:]),
+			},
+			expectedBody: body,
+			expectedErr:  ErrChecksumMismatch,
+		},
+		{
+			name:         "AlgorithmDisabled",
+			algorithms:   md5Only,
+			headers:      map[string]string{bodySHA256HeaderKey: hex.EncodeToString(otherSum[:])},
+			expectedBody: body,
+		},
+		{
+			name:         "Disabled",
+			headers:      map[string]string{contentMD5HeaderKey: base64.StdEncoding.EncodeToString(otherSum[:16])},
+			expectedBody: body,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			r := httptest.NewRequest(http.MethodPatch, "http://localhost:8080", bytes.NewReader(body))
+			for k, v := range test.headers {
+				r.Header.Set(k, v)
+			}
+
+			ctx := captureChecksum(test.algorithms)(context.Background(), r)
+
+			data, err := ioutil.ReadAll(r.Body)
+			assert.Nil(err)
+			assert.Equal(test.expectedBody, data)
+
+			var decoded bool
+			_, err = decodeChecksumRequest(func(_ context.Context, _ *http.Request) (interface{}, error) {
+				decoded = true
+				return nil, nil
+			})(ctx, r)
+
+			assert.Equal(test.expectedErr, err)
+			assert.Equal(test.expectedErr == nil, decoded)
+		})
+	}
+}
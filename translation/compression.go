@@ -0,0 +1,98 @@
+package translation
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/xmidt-org/tr1d1um/common"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+)
+
+const (
+	contentEncodingHeaderKey = "Content-Encoding"
+	gzipEncoding             = "gzip"
+)
+
+type compressionContextKey struct{}
+
+// compression is the outcome of the compression negotiation for a request
+type compression struct {
+	err error
+}
+
+// captureCompression decompresses gzip encoded request bodies so the rest of the gokit server flow
+// deals with plain WDMP. The negotiation is remembered so the WRP payload sent to XMiDT is gzip
+// encoded as well. It's a no-op when compression is disabled.
+func captureCompression(enabled bool) kithttp.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		if !enabled || !strings.EqualFold(r.Header.Get(contentEncodingHeaderKey), gzipEncoding) {
+			return ctx
+		}
+
+		var c compression
+
+		body, err := gunzip(r.Body)
+		r.Body.Close()
+
+		if err == common.ErrRequestBodyTooLarge {
+			c.err = err
+			body = nil
+		} else if err != nil {
+			c.err = ErrInvalidGzipBody
+			body = nil
+		}
+
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		r.Header.Del(contentEncodingHeaderKey)
+
+		return context.WithValue(ctx, compressionContextKey{}, &c)
+	}
+}
+
+// decodeCompressedRequest decorates decoder such that requests whose body could not be decompressed are rejected
+func decodeCompressedRequest(decoder kithttp.DecodeRequestFunc) kithttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		if c, ok := ctx.Value(compressionContextKey{}).(*compression); ok && c.err != nil {
+			return nil, c.err
+		}
+
+		return decoder(ctx, r)
+	}
+}
+
+// compressionFromContext reports whether gzip encoding was negotiated for the request
+func compressionFromContext(ctx context.Context) bool {
+	c, ok := ctx.Value(compressionContextKey{}).(*compression)
+	return ok && c.err == nil
+}
+
+func gunzip(r io.Reader) ([]byte, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	defer gr.Close()
+	return ioutil.ReadAll(gr)
+}
+
+func gzipBytes(p []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write(p); err != nil {
+		return nil, err
+	}
+
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,125 @@
+package translation
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/tr1d1um/common"
+	"github.com/xmidt-org/wrp-go/wrp"
+)
+
+func TestCaptureCompression(t *testing.T) {
+	body := []byte(`{"parameters":[{"name":"deviceName","value":"newName","dataType":0}]}`)
+	compressed, err := gzipBytes(body)
+	require.Nil(t, err)
+
+	tests := []struct {
+		name             string
+		enabled          bool
+		encoding         string
+		body             []byte
+		expectedBody     []byte
+		expectedCompress bool
+		expectedErr      error
+	}{
+		{
+			name:         "Disabled",
+			encoding:     "gzip",
+			body:         compressed,
+			expectedBody: compressed,
+		},
+		{
+			name:         "NotEncoded",
+			enabled:      true,
+			body:         body,
+			expectedBody: body,
+		},
+		{
+			name:             "Gzip",
+			enabled:          true,
+			encoding:         "GZIP",
+			body:             compressed,
+			expectedBody:     body,
+			expectedCompress: true,
+		},
+		{
+			name:         "InvalidGzip",
+			enabled:      true,
+			encoding:     "gzip",
+			body:         body,
+			expectedBody: []byte{},
+			expectedErr:  ErrInvalidGzipBody,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			r := httptest.NewRequest(http.MethodPatch, "http://localhost:8080", bytes.NewReader(test.body))
+			if test.encoding != "" {
+				r.Header.Set(contentEncodingHeaderKey, test.encoding)
+			}
+
+			ctx := captureCompression(test.enabled)(context.Background(), r)
+
+			data, err := ioutil.ReadAll(r.Body)
+			assert.Nil(err)
+			assert.Equal(test.expectedBody, data)
+			assert.Equal(test.expectedCompress, compressionFromContext(ctx))
+
+			var decoded bool
+			_, err = decodeCompressedRequest(func(_ context.Context, _ *http.Request) (interface{}, error) {
+				decoded = true
+				return nil, nil
+			})(ctx, r)
+
+			assert.Equal(test.expectedErr, err)
+			assert.Equal(test.expectedErr == nil, decoded)
+		})
+	}
+}
+
+func TestSendWRPCompressed(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	m := new(common.MockTr1d1umTransactor)
+	s := NewService(&ServiceOptions{
+		XmidtWrpURL:       "http://localhost/wrp",
+		WRPSource:         "dns:tr1d1um-xyz-example.com",
+		Tr1d1umTransactor: m,
+	})
+
+	expected := wrp.MustEncode(wrp.Message{
+		Type:   wrp.SimpleRequestResponseMessageType,
+		Source: "dns:tr1d1um-xyz-example.com",
+	}, wrp.Msgpack)
+
+	var sent *http.Request
+	m.On("Transact", mock.AnythingOfType("*http.Request")).Run(func(args mock.Arguments) {
+		sent = args.Get(0).(*http.Request)
+	}).Return(nil, nil)
+
+	ctx := context.WithValue(context.Background(), compressionContextKey{}, new(compression))
+	_, err := s.SendWRP(ctx, &wrp.Message{
+		Type: wrp.SimpleRequestResponseMessageType,
+	}, "token")
+
+	assert.Nil(err)
+	m.AssertExpectations(t)
+
+	require.NotNil(sent)
+	assert.Equal("gzip", sent.Header.Get(contentEncodingHeaderKey))
+
+	data, err := gunzip(sent.Body)
+	require.Nil(err)
+	assert.Equal(expected, data)
+}
@@ -0,0 +1,62 @@
+package translation
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/xmidt-org/tr1d1um/common"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+)
+
+// payloadContentType returns the media type of the request body, without parameters, or defaultType
+// when the client didn't send one
+func payloadContentType(r *http.Request, defaultType string) (string, error) {
+	value := r.Header.Get(contentTypeHeaderKey)
+	if value == "" {
+		return defaultType, nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(value)
+	if err != nil {
+		return "", common.NewCodedError(fmt.Errorf("malformed content type '%s'", value), http.StatusUnsupportedMediaType)
+	}
+
+	return mediaType, nil
+}
+
+// decodePayloadContentTypeRequest decorates decoder such that the WRP payload content type is the Content-Type
+// of the request, or defaultType when it's missing. Requests whose content type isn't one of allowed
+// (case-insensitive) are rejected with a 415. All content types are allowed when allowed is empty.
+func decodePayloadContentTypeRequest(defaultType string, allowed []string, decoder kithttp.DecodeRequestFunc) kithttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		contentType, err := payloadContentType(r, defaultType)
+		if err != nil {
+			return nil, err
+		}
+
+		if contentType != "" && len(allowed) > 0 && !containsFold(allowed, contentType) {
+			return nil, common.NewCodedError(fmt.Errorf("unsupported content type '%s'", contentType), http.StatusUnsupportedMediaType)
+		}
+
+		request, err := decoder(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+
+		request.(*wrpRequest).WRPMessage.ContentType = contentType
+		return request, nil
+	}
+}
+
+func containsFold(elements []string, i string) bool {
+	for _, e := range elements {
+		if strings.EqualFold(e, i) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package translation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xmidt-org/tr1d1um/common"
+	"github.com/xmidt-org/wrp-go/wrp"
+)
+
+func TestDecodePayloadContentTypeRequest(t *testing.T) {
+	tests := []struct {
+		name                string
+		contentType         string
+		allowed             []string
+		expectedContentType string
+		expectedCode        int
+	}{
+		{name: "Default", expectedContentType: "application/json"},
+		{name: "FromRequest", contentType: "application/octet-stream", expectedContentType: "application/octet-stream"},
+		{name: "WithParameters", contentType: "application/json; charset=utf-8", expectedContentType: "application/json"},
+		{name: "Allowed", contentType: "Application/JSON", allowed: []string{"application/json"}, expectedContentType: "application/json"},
+		{name: "DefaultAllowed", allowed: []string{"application/json"}, expectedContentType: "application/json"},
+		{name: "NotAllowed", contentType: "text/plain", allowed: []string{"application/json"}, expectedCode: http.StatusUnsupportedMediaType},
+		{name: "Malformed", contentType: "application/", expectedCode: http.StatusUnsupportedMediaType},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			r := httptest.NewRequest(http.MethodPatch, "http://localhost:8090/api", nil)
+			if test.contentType != "" {
+				r.Header.Set(contentTypeHeaderKey, test.contentType)
+			}
+
+			decoder := decodePayloadContentTypeRequest("application/json", test.allowed, func(context.Context, *http.Request) (interface{}, error) {
+				return &wrpRequest{WRPMessage: new(wrp.Message)}, nil
+			})
+
+			request, err := decoder(context.Background(), r)
+			if test.expectedCode != 0 {
+				ce, ok := err.(common.CodedError)
+				if assert.True(ok) {
+					assert.Equal(test.expectedCode, ce.StatusCode())
+				}
+				return
+			}
+
+			assert.Nil(err)
+			assert.Equal(test.expectedContentType, request.(*wrpRequest).WRPMessage.ContentType)
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package translation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/xmidt-org/tr1d1um/common"
+	"github.com/xmidt-org/wrp-go/wrp"
+)
+
+type dedupEntry struct {
+	done      chan struct{}
+	completed bool
+	resp      *common.XmidtResponse
+	expires   time.Time
+}
+
+// Dedup keeps side-effecting WRP messages, such as SETs, which clients send twice in quick succession
+// (usually because of their own retries) from reaching devices twice. Messages are duplicates when their
+// transaction uuid, destination and payload match and they're sent with the same credentials, as
+// transaction uuids are picked by clients. Duplicates get the response of the original message,
+// waiting for it if it's still in flight, for a window after it completes.
+type Dedup struct {
+	window time.Duration
+	now    func() time.Time
+
+	lock       sync.Mutex
+	entries    map[string]*dedupEntry
+	lastPruned time.Time
+}
+
+// NewDedup is the constructor for Dedup. It returns nil, which disables deduplication, when window isn't positive
+func NewDedup(window time.Duration) *Dedup {
+	if window <= 0 {
+		return nil
+	}
+
+	return &Dedup{
+		window:  window,
+		now:     time.Now,
+		entries: make(map[string]*dedupEntry),
+	}
+}
+
+// Then decorates s such that duplicate WRP messages aren't sent. It's a no-op for nil dedups
+func (d *Dedup) Then(s Service) Service {
+	if d == nil {
+		return s
+	}
+
+	return &dedupService{Service: s, dedup: d}
+}
+
+type dedupService struct {
+	Service
+	dedup *Dedup
+}
+
+func (s *dedupService) SendWRP(ctx context.Context, wrpMsg *wrp.Message, authHeaderValue string) (*common.XmidtResponse, error) {
+	// dry runs and reads have no side effects worth guarding against
+	if idempotent, _ := ctx.Value(common.ContextKeyIdempotent).(bool); idempotent || dryRunFromContext(ctx) {
+		return s.Service.SendWRP(ctx, wrpMsg, authHeaderValue)
+	}
+
+	key := dedupKey(wrpMsg, common.CallerCredentials(ctx, authHeaderValue))
+	for {
+		e, original := s.dedup.reserve(key)
+		if original {
+			resp, err := s.Service.SendWRP(ctx, wrpMsg, authHeaderValue)
+			s.dedup.complete(key, e, resp, err)
+			return resp, err
+		}
+
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		if e.resp != nil {
+			return duplicateResponse(e.resp), nil
+		}
+
+		// the original message failed so this one is sent on its own
+	}
+}
+
+// reserve returns the entry of the message with the given key, reporting whether it's a new one which
+// the caller must send and complete
+func (d *Dedup) reserve(key string) (*dedupEntry, bool) {
+	now := d.now()
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if e, ok := d.entries[key]; ok && (!e.completed || now.Before(e.expires)) {
+		return e, false
+	}
+
+	// expired entries of messages which aren't resent would pile up otherwise
+	if now.Sub(d.lastPruned) >= d.window {
+		for k, e := range d.entries {
+			if e.completed && !now.Before(e.expires) {
+				delete(d.entries, k)
+			}
+		}
+		d.lastPruned = now
+	}
+
+	e := &dedupEntry{done: make(chan struct{})}
+	d.entries[key] = e
+	return e, true
+}
+
+// complete records the outcome of the original message. Failed messages, which may not have reached the
+// device, aren't remembered so that retries go through
+func (d *Dedup) complete(key string, e *dedupEntry, resp *common.XmidtResponse, err error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if err == nil && resp.Code < http.StatusInternalServerError {
+		e.resp = resp
+		e.completed = true
+		e.expires = d.now().Add(d.window)
+	} else if d.entries[key] == e {
+		delete(d.entries, key)
+	}
+
+	close(e.done)
+}
+
+// dedupKey identifies a message sent with the given credentials. Only a hash of the credentials is
+// kept so that they aren't held in memory
+func dedupKey(wrpMsg *wrp.Message, credentials string) string {
+	payload, auth := sha256.Sum256(wrpMsg.Payload), sha256.Sum256([]byte(credentials))
+	return wrpMsg.TransactionUUID + " " + wrpMsg.Destination + " " + hex.EncodeToString(payload[:]) + " " + hex.EncodeToString(auth[:])
+}
+
+// duplicateResponse returns a copy of resp whose forwarded headers can be changed without affecting
+// the responses of other duplicates
+func duplicateResponse(resp *common.XmidtResponse) *common.XmidtResponse {
+	headers := make(http.Header, len(resp.ForwardedHeaders))
+	for k, values := range resp.ForwardedHeaders {
+		headers[k] = append([]string(nil), values...)
+	}
+
+	duplicate := *resp
+	duplicate.ForwardedHeaders = headers
+	return &duplicate
+}
@@ -0,0 +1,151 @@
+package translation
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/bascule"
+	"github.com/xmidt-org/tr1d1um/common"
+	"github.com/xmidt-org/wrp-go/wrp"
+)
+
+func TestNewDedup(t *testing.T) {
+	assert := assert.New(t)
+
+	d := NewDedup(0)
+	assert.Nil(d)
+
+	m := new(MockService)
+	assert.Equal(m, d.Then(m))
+}
+
+func TestDedup(t *testing.T) {
+	newMessage := func(tid, payload string) *wrp.Message {
+		return &wrp.Message{TransactionUUID: tid, Destination: "mac:112233445566/config", Payload: []byte(payload)}
+	}
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		second        *wrp.Message
+		secondAuth    string
+		secondCtx     context.Context
+		code          int
+		elapsed       time.Duration
+		expectedSends int
+	}{
+		{name: "Duplicate", ctx: context.Background(), second: newMessage("tid-1", "set"), code: http.StatusOK, expectedSends: 1},
+		{name: "DifferentPayload", ctx: context.Background(), second: newMessage("tid-1", "other"), code: http.StatusOK, expectedSends: 2},
+		{name: "DifferentTransaction", ctx: context.Background(), second: newMessage("tid-2", "set"), code: http.StatusOK, expectedSends: 2},
+		{name: "DifferentCredentials", ctx: context.Background(), second: newMessage("tid-1", "set"), secondAuth: "other", code: http.StatusOK, expectedSends: 2},
+		{name: "DifferentPrincipal", ctx: certAuthenticated("client0"), second: newMessage("tid-1", "set"), secondCtx: certAuthenticated("client1"), code: http.StatusOK, expectedSends: 2},
+		{name: "Expired", ctx: context.Background(), second: newMessage("tid-1", "set"), code: http.StatusOK, elapsed: time.Minute, expectedSends: 2},
+		{name: "Failed", ctx: context.Background(), second: newMessage("tid-1", "set"), code: http.StatusServiceUnavailable, expectedSends: 2},
+		{name: "Idempotent", ctx: context.WithValue(context.Background(), common.ContextKeyIdempotent, true), second: newMessage("tid-1", "set"), code: http.StatusOK, expectedSends: 2},
+		{name: "DryRun", ctx: withDryRun(context.Background()), second: newMessage("tid-1", "set"), code: http.StatusOK, expectedSends: 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			now := time.Now()
+			d := NewDedup(10 * time.Second)
+			d.now = func() time.Time { return now }
+
+			m := new(MockService)
+			m.On("SendWRP", mock.Anything, mock.Anything, mock.Anything).Return(&common.XmidtResponse{Code: test.code, ForwardedHeaders: http.Header{}}, nil)
+			s := d.Then(m)
+
+			_, err := s.SendWRP(test.ctx, newMessage("tid-1", "set"), "auth")
+			assert.Nil(err)
+
+			secondAuth := "auth"
+			if test.secondAuth != "" {
+				secondAuth = test.secondAuth
+			}
+
+			secondCtx := test.ctx
+			if test.secondCtx != nil {
+				secondCtx = test.secondCtx
+			}
+
+			now = now.Add(test.elapsed)
+			resp, err := s.SendWRP(secondCtx, test.second, secondAuth)
+			assert.Nil(err)
+			assert.Equal(test.code, resp.Code)
+
+			m.AssertNumberOfCalls(t, "SendWRP", test.expectedSends)
+		})
+	}
+}
+
+// certAuthenticated returns a context authenticated as principal without an Authorization header
+func certAuthenticated(principal string) context.Context {
+	return bascule.WithAuthentication(context.Background(), bascule.Authentication{
+		Authorization: "Cert",
+		Token:         bascule.NewToken("cert", principal, bascule.NewAttributes()),
+	})
+}
+
+func TestDedupInFlight(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sent := make(chan struct{})
+	release := make(chan struct{})
+
+	m := new(MockService)
+	m.On("SendWRP", mock.Anything, mock.Anything, "auth").Run(func(mock.Arguments) {
+		close(sent)
+		<-release
+	}).Return(&common.XmidtResponse{Code: http.StatusOK, ForwardedHeaders: http.Header{"X-Test": []string{"original"}}}, nil).Once()
+
+	s := NewDedup(10 * time.Second).Then(m)
+
+	original := make(chan *common.XmidtResponse, 1)
+	go func() {
+		resp, _ := s.SendWRP(context.Background(), &wrp.Message{TransactionUUID: "tid-1", Payload: []byte("set")}, "auth")
+		original <- resp
+	}()
+
+	<-sent
+
+	duplicate := make(chan *common.XmidtResponse, 1)
+	go func() {
+		resp, _ := s.SendWRP(context.Background(), &wrp.Message{TransactionUUID: "tid-1", Payload: []byte("set")}, "auth")
+		duplicate <- resp
+	}()
+
+	close(release)
+
+	first, second := <-original, <-duplicate
+	require.NotNil(first)
+	require.NotNil(second)
+
+	assert.Equal(http.StatusOK, second.Code)
+	assert.Equal("original", second.ForwardedHeaders.Get("X-Test"))
+
+	second.ForwardedHeaders.Set("X-Test", "changed")
+	assert.Equal("original", first.ForwardedHeaders.Get("X-Test"))
+
+	m.AssertNumberOfCalls(t, "SendWRP", 1)
+}
+
+func TestDedupCanceled(t *testing.T) {
+	assert := assert.New(t)
+
+	d := NewDedup(10 * time.Second)
+	d.reserve(dedupKey(&wrp.Message{TransactionUUID: "tid-1"}, "auth"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := d.Then(new(MockService)).SendWRP(ctx, &wrp.Message{TransactionUUID: "tid-1"}, "auth")
+	assert.Equal(context.Canceled, err)
+}
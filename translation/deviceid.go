@@ -0,0 +1,128 @@
+package translation
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/xmidt-org/tr1d1um/common"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"github.com/xmidt-org/webpa-common/device"
+)
+
+var (
+	uuidPattern     = regexp.MustCompile(`^[0-9a-f]{8}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{12}$`)
+	serialPattern   = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+	dnsLabelPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+)
+
+// DeviceIDFormat validates and canonicalizes the ids (the part after "scheme:") of a device id scheme
+type DeviceIDFormat struct {
+	scheme    string
+	normalize func(string) (string, bool)
+}
+
+// deviceIDFormats are the supported device id schemes. device.ParseID already validates and
+// lower cases mac addresses
+var deviceIDFormats = map[string]DeviceIDFormat{
+	"mac": {
+		scheme:    "mac",
+		normalize: func(id string) (string, bool) { return id, true },
+	},
+	"uuid": {
+		scheme: "uuid",
+		normalize: func(id string) (string, bool) {
+			id = strings.ToLower(id)
+			return id, uuidPattern.MatchString(id)
+		},
+	},
+	"serial": {
+		scheme: "serial",
+		normalize: func(id string) (string, bool) {
+			return id, serialPattern.MatchString(id)
+		},
+	},
+	"dns": {
+		scheme:    "dns",
+		normalize: normalizeDNSName,
+	},
+}
+
+func normalizeDNSName(id string) (string, bool) {
+	id = strings.ToLower(strings.TrimSuffix(id, "."))
+	if len(id) > 253 {
+		return id, false
+	}
+
+	for _, label := range strings.Split(id, ".") {
+		if !dnsLabelPattern.MatchString(label) {
+			return id, false
+		}
+	}
+
+	return id, true
+}
+
+// ParseDeviceIDFormats returns the formats of the given device id schemes (mac, uuid, serial or dns).
+// All of them are returned when no schemes are given.
+func ParseDeviceIDFormats(schemes []string) ([]DeviceIDFormat, error) {
+	if len(schemes) == 0 {
+		formats := make([]DeviceIDFormat, 0, len(deviceIDFormats))
+		for _, f := range deviceIDFormats {
+			formats = append(formats, f)
+		}
+		return formats, nil
+	}
+
+	formats := make([]DeviceIDFormat, 0, len(schemes))
+	for _, scheme := range schemes {
+		f, ok := deviceIDFormats[strings.ToLower(scheme)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported device id scheme '%s'", scheme)
+		}
+		formats = append(formats, f)
+	}
+	return formats, nil
+}
+
+// decodeDeviceIDRequest decorates decoder such that requests for device ids (the "deviceid" path variable)
+// which are malformed or use a scheme other than the given ones are rejected with a 400. Valid ids are
+// replaced by their canonical form (i.e. lower cased) before decoder sees them. All the supported schemes
+// are accepted when formats is empty.
+func decodeDeviceIDRequest(formats []DeviceIDFormat, decoder kithttp.DecodeRequestFunc) kithttp.DecodeRequestFunc {
+	if len(formats) == 0 {
+		formats, _ = ParseDeviceIDFormats(nil)
+	}
+
+	byScheme := make(map[string]DeviceIDFormat, len(formats))
+	for _, f := range formats {
+		byScheme[f.scheme] = f
+	}
+
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		vars := mux.Vars(r)
+
+		id, err := device.ParseID(vars["deviceid"])
+		if err != nil {
+			return nil, common.NewBadRequestError(err)
+		}
+
+		parts := strings.SplitN(string(id), ":", 2)
+		f, ok := byScheme[parts[0]]
+		if !ok {
+			return nil, common.NewBadRequestError(fmt.Errorf("unsupported device id scheme '%s'", parts[0]))
+		}
+
+		normalized, ok := f.normalize(parts[1])
+		if !ok {
+			return nil, common.NewBadRequestError(fmt.Errorf("invalid %s device id '%s'", f.scheme, parts[1]))
+		}
+
+		vars["deviceid"] = f.scheme + ":" + normalized
+		return decoder(ctx, r)
+	}
+}
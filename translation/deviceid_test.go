@@ -0,0 +1,84 @@
+package translation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/tr1d1um/common"
+)
+
+func TestParseDeviceIDFormats(t *testing.T) {
+	assert := assert.New(t)
+
+	formats, err := ParseDeviceIDFormats(nil)
+	assert.Nil(err)
+	assert.Len(formats, len(deviceIDFormats))
+
+	formats, err = ParseDeviceIDFormats([]string{"MAC", "uuid"})
+	assert.Nil(err)
+	assert.Len(formats, 2)
+
+	_, err = ParseDeviceIDFormats([]string{"mac", "imei"})
+	assert.NotNil(err)
+}
+
+func TestDecodeDeviceIDRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		schemes    []string
+		deviceID   string
+		expectedID string
+		invalid    bool
+	}{
+		{name: "Mac", deviceID: "mac:11:22:33:AA:BB:CC", expectedID: "mac:112233aabbcc"},
+		{name: "MacUpperScheme", deviceID: "MAC:112233AABBCC", expectedID: "mac:112233aabbcc"},
+		{name: "MacTooShort", deviceID: "mac:112233", invalid: true},
+		{name: "MacNotHex", deviceID: "mac:11223344556g", invalid: true},
+		{name: "UUID", deviceID: "uuid:3F2504E0-4F89-11D3-9A0C-0305E82C3301", expectedID: "uuid:3f2504e0-4f89-11d3-9a0c-0305e82c3301"},
+		{name: "UUIDNoDashes", deviceID: "uuid:3f2504e04f8911d39a0c0305e82c3301", expectedID: "uuid:3f2504e04f8911d39a0c0305e82c3301"},
+		{name: "UUIDMalformed", deviceID: "uuid:3f2504e0-4f89-11d3", invalid: true},
+		{name: "Serial", deviceID: "serial:AB-1234.x_9", expectedID: "serial:AB-1234.x_9"},
+		{name: "SerialMalformed", deviceID: "serial:-AB 1234", invalid: true},
+		{name: "DNS", deviceID: "dns:Device.Example.com", expectedID: "dns:device.example.com"},
+		{name: "DNSMalformed", deviceID: "dns:device..example.com", invalid: true},
+		{name: "UnknownScheme", deviceID: "imei:490154203237518", invalid: true},
+		{name: "Empty", deviceID: "", invalid: true},
+		{name: "SchemeNotAllowed", schemes: []string{"mac"}, deviceID: "serial:AB1234", invalid: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			formats, err := ParseDeviceIDFormats(test.schemes)
+			require.Nil(err)
+
+			var decodedID string
+			decoder := decodeDeviceIDRequest(formats, func(_ context.Context, r *http.Request) (interface{}, error) {
+				decodedID = mux.Vars(r)["deviceid"]
+				return nil, nil
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8090/api", nil)
+			r = mux.SetURLVars(r, map[string]string{"deviceid": test.deviceID, "service": "config"})
+
+			_, err = decoder(context.Background(), r)
+			if test.invalid {
+				ce, ok := err.(common.CodedError)
+				require.True(ok)
+				assert.Equal(http.StatusBadRequest, ce.StatusCode())
+				assert.Empty(decodedID)
+				return
+			}
+
+			assert.Nil(err)
+			assert.Equal(test.expectedID, decodedID)
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package translation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/xmidt-org/tr1d1um/common"
+	"github.com/xmidt-org/wrp-go/wrp"
+)
+
+// HeaderTr1d1umDryRun makes Tr1d1um build and validate the WRP message for a request and
+// return it instead of sending it to the device
+const HeaderTr1d1umDryRun = "X-Tr1d1um-Dry-Run"
+
+// dryRunQueryParam asks for a dry run the same way as HeaderTr1d1umDryRun, for clients which can't set headers.
+// The header takes precedence when both are set.
+const dryRunQueryParam = "dryRun"
+
+type dryRunContextKey struct{}
+
+// dryRunResponse is the JSON representation of the WRP message a dry run request would have sent,
+// using the WRP field names. The WDMP payload is shown as is rather than base64 encoded.
+type dryRunResponse struct {
+	Type                    wrp.MessageType   `json:"msg_type"`
+	Source                  string            `json:"source,omitempty"`
+	Destination             string            `json:"dest,omitempty"`
+	TransactionUUID         string            `json:"transaction_uuid,omitempty"`
+	ContentType             string            `json:"content_type,omitempty"`
+	Accept                  string            `json:"accept,omitempty"`
+	Status                  *int64            `json:"status,omitempty"`
+	RequestDeliveryResponse *int64            `json:"rdr,omitempty"`
+	Headers                 []string          `json:"headers,omitempty"`
+	Metadata                map[string]string `json:"metadata,omitempty"`
+	Spans                   [][]string        `json:"spans,omitempty"`
+	IncludeSpans            *bool             `json:"include_spans,omitempty"`
+	Path                    string            `json:"path,omitempty"`
+	Payload                 json.RawMessage   `json:"payload,omitempty"`
+	ServiceName             string            `json:"service_name,omitempty"`
+	URL                     string            `json:"url,omitempty"`
+	PartnerIDs              []string          `json:"partner_ids,omitempty"`
+}
+
+func newDryRunResponse(m *wrp.Message) *dryRunResponse {
+	payload := json.RawMessage(m.Payload)
+	if len(m.Payload) > 0 && !json.Valid(m.Payload) {
+		// non JSON payloads are shown as a string
+		payload, _ = json.Marshal(string(m.Payload))
+	}
+
+	return &dryRunResponse{
+		Type:                    m.Type,
+		Source:                  m.Source,
+		Destination:             m.Destination,
+		TransactionUUID:         m.TransactionUUID,
+		ContentType:             m.ContentType,
+		Accept:                  m.Accept,
+		Status:                  m.Status,
+		RequestDeliveryResponse: m.RequestDeliveryResponse,
+		Headers:                 m.Headers,
+		Metadata:                m.Metadata,
+		Spans:                   m.Spans,
+		IncludeSpans:            m.IncludeSpans,
+		Path:                    m.Path,
+		Payload:                 payload,
+		ServiceName:             m.ServiceName,
+		URL:                     m.URL,
+		PartnerIDs:              m.PartnerIDs,
+	}
+}
+
+// isDryRun reports whether the request asks for a dry run through either the HeaderTr1d1umDryRun header
+// or the dryRun query parameter
+func isDryRun(r *http.Request) (bool, error) {
+	source, v := HeaderTr1d1umDryRun+" header", r.Header.Get(HeaderTr1d1umDryRun)
+	if v == "" {
+		source, v = dryRunQueryParam+" query parameter", r.URL.Query().Get(dryRunQueryParam)
+	}
+
+	if v == "" {
+		return false, nil
+	}
+
+	dryRun, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, common.NewBadRequestError(fmt.Errorf("invalid %s value '%s'", source, v))
+	}
+
+	return dryRun, nil
+}
+
+func withDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunContextKey{}, true)
+}
+
+func dryRunFromContext(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunContextKey{}).(bool)
+	return dryRun
+}
+
+func encodeDryRunResponse(ctx context.Context, w http.ResponseWriter, resp *dryRunResponse) error {
+	w.Header().Set(contentTypeHeaderKey, "application/json; charset=utf-8")
+	w.Header().Set(common.HeaderWPATID, ctx.Value(common.ContextKeyRequestTID).(string))
+	w.WriteHeader(http.StatusOK)
+
+	return json.NewEncoder(w).Encode(resp)
+}
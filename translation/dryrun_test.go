@@ -0,0 +1,95 @@
+package translation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/tr1d1um/common"
+	"github.com/xmidt-org/wrp-go/wrp"
+)
+
+func TestIsDryRun(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		query       string
+		expected    bool
+		expectedErr bool
+	}{
+		{name: "NoHeader"},
+		{name: "Enabled", header: "true", expected: true},
+		{name: "Disabled", header: "false"},
+		{name: "Invalid", header: "sure", expectedErr: true},
+		{name: "QueryEnabled", query: "?dryRun=true", expected: true},
+		{name: "QueryDisabled", query: "?dryRun=0"},
+		{name: "QueryInvalid", query: "?dryRun=maybe", expectedErr: true},
+		{name: "HeaderPrecedence", header: "false", query: "?dryRun=true"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			r := httptest.NewRequest(http.MethodPatch, "http://localhost:8090/api"+test.query, nil)
+			if test.header != "" {
+				r.Header.Set(HeaderTr1d1umDryRun, test.header)
+			}
+
+			dryRun, err := isDryRun(r)
+			assert.Equal(test.expected, dryRun)
+			if test.expectedErr {
+				assert.Equal(http.StatusBadRequest, err.(common.CodedError).StatusCode())
+			} else {
+				assert.Nil(err)
+			}
+		})
+	}
+}
+
+func TestDryRunEndpoint(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	m := new(common.MockTr1d1umTransactor)
+	s := NewService(&ServiceOptions{
+		XmidtWrpURL:       "http://localhost/wrp",
+		WRPSource:         "dns:tr1d1um-xyz-example.com",
+		Tr1d1umTransactor: m,
+	})
+
+	r := &wrpRequest{
+		WRPMessage: &wrp.Message{
+			Type:        wrp.SimpleRequestResponseMessageType,
+			Destination: "mac:112233445566/config",
+			Payload:     []byte(`{"command":"GET","names":["deviceName"]}`),
+		},
+		AuthHeaderValue: "a0",
+		DryRun:          true,
+	}
+
+	resp, err := makeTranslationEndpoint(s)(context.Background(), r)
+	require.Nil(err)
+
+	// the message must never reach XMiDT
+	m.AssertNotCalled(t, "Transact", mock.Anything)
+
+	recorder := httptest.NewRecorder()
+	ctx := context.WithValue(context.Background(), common.ContextKeyRequestTID, "tid")
+	require.Nil(encodeResponse(ctx, recorder, resp))
+
+	assert.Equal(http.StatusOK, recorder.Code)
+	assert.Equal("tid", recorder.Header().Get(common.HeaderWPATID))
+
+	var body map[string]interface{}
+	require.Nil(json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.Equal("dns:tr1d1um-xyz-example.com", body["source"])
+	assert.Equal("mac:112233445566/config", body["dest"])
+	assert.EqualValues(wrp.SimpleRequestResponseMessageType, body["msg_type"])
+	assert.Equal(map[string]interface{}{"command": "GET", "names": []interface{}{"deviceName"}}, body["payload"])
+}
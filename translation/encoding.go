@@ -0,0 +1,85 @@
+package translation
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"strings"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/xmidt-org/wrp-go/wrp"
+)
+
+const acceptHeaderKey = "Accept"
+
+type wrpEncodingContextKey struct{}
+
+// wrpFormat returns the WRP format with the given media type, if it's either application/msgpack or application/json
+func wrpFormat(contentType string) (wrp.Format, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return wrp.Msgpack, false
+	}
+
+	switch strings.ToLower(mediaType) {
+	case wrp.Msgpack.ContentType():
+		return wrp.Msgpack, true
+	case wrp.JSON.ContentType():
+		return wrp.JSON, true
+	}
+
+	return wrp.Msgpack, false
+}
+
+// xmidtFormat returns the WRP format of a XMiDT response body given its Content-Type. XMiDT responses are
+// msgpack encoded unless they say otherwise
+func xmidtFormat(contentType string) wrp.Format {
+	format, _ := wrpFormat(contentType)
+	return format
+}
+
+// captureWRPEncoding makes the WRP encoding clients ask for through the Accept header available to the
+// response encoder. The first of application/msgpack and application/json listed wins. It's a no-op when
+// negotiation is disabled, in which case clients get the device response payload only.
+func captureWRPEncoding(enabled bool) kithttp.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		if !enabled {
+			return ctx
+		}
+
+		for _, accepted := range strings.Split(r.Header.Get(acceptHeaderKey), ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+			if err != nil || params["q"] == "0" {
+				continue
+			}
+
+			if format, ok := wrpFormat(mediaType); ok {
+				return context.WithValue(ctx, wrpEncodingContextKey{}, format)
+			}
+		}
+
+		return ctx
+	}
+}
+
+// wrpEncodingFromContext returns the WRP encoding negotiated for the request, if any
+func wrpEncodingFromContext(ctx context.Context) (wrp.Format, bool) {
+	format, ok := ctx.Value(wrpEncodingContextKey{}).(wrp.Format)
+	return format, ok
+}
+
+// encodeWRPResponse writes the whole WRP message of the device response in the negotiated format. The XMiDT
+// response body is written as is when it's already in that format so payloads go through untouched.
+func encodeWRPResponse(w http.ResponseWriter, format, received wrp.Format, body []byte, message *wrp.Message) error {
+	if format != received {
+		body = nil
+		if err := wrp.NewEncoderBytes(&body, format).Encode(message); err != nil {
+			return err
+		}
+	}
+
+	w.Header().Set(contentTypeHeaderKey, format.ContentType())
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write(body)
+	return err
+}
@@ -0,0 +1,102 @@
+package translation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/tr1d1um/common"
+	"github.com/xmidt-org/wrp-go/wrp"
+)
+
+func TestCaptureWRPEncoding(t *testing.T) {
+	tests := []struct {
+		name           string
+		enabled        bool
+		accept         string
+		expectedFormat wrp.Format
+		expectedOK     bool
+	}{
+		{name: "Disabled", accept: "application/msgpack"},
+		{name: "NoAccept", enabled: true},
+		{name: "Msgpack", enabled: true, accept: "application/msgpack", expectedFormat: wrp.Msgpack, expectedOK: true},
+		{name: "JSON", enabled: true, accept: "text/html, application/json;q=0.9", expectedFormat: wrp.JSON, expectedOK: true},
+		{name: "FirstListed", enabled: true, accept: "application/json, application/msgpack", expectedFormat: wrp.JSON, expectedOK: true},
+		{name: "Refused", enabled: true, accept: "application/msgpack;q=0, application/json", expectedFormat: wrp.JSON, expectedOK: true},
+		{name: "Other", enabled: true, accept: "*/*"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:8090/api", nil)
+			if test.accept != "" {
+				r.Header.Set(acceptHeaderKey, test.accept)
+			}
+
+			format, ok := wrpEncodingFromContext(captureWRPEncoding(test.enabled)(context.Background(), r))
+			assert.Equal(test.expectedOK, ok)
+			if test.expectedOK {
+				assert.Equal(test.expectedFormat, format)
+			}
+		})
+	}
+}
+
+func TestEncodeResponseWRPEncoding(t *testing.T) {
+	message := &wrp.Message{
+		Type:            wrp.SimpleRequestResponseMessageType,
+		Source:          "mac:112233445566/config",
+		Destination:     "dns:tr1d1um.example.com",
+		TransactionUUID: "test-tid",
+		ContentType:     "application/octet-stream",
+		// not valid UTF-8 so a lossy conversion would show
+		Payload: []byte{0x00, 0xff, 0xfe, 0x7b, 0x22, 0x80},
+	}
+
+	tests := []struct {
+		name      string
+		received  wrp.Format
+		requested wrp.Format
+	}{
+		{name: "MsgpackToMsgpack", received: wrp.Msgpack, requested: wrp.Msgpack},
+		{name: "MsgpackToJSON", received: wrp.Msgpack, requested: wrp.JSON},
+		{name: "JSONToMsgpack", received: wrp.JSON, requested: wrp.Msgpack},
+		{name: "JSONToJSON", received: wrp.JSON, requested: wrp.JSON},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			body := wrp.MustEncode(message, test.received)
+			response := &common.XmidtResponse{
+				Code:        http.StatusOK,
+				Body:        body,
+				ContentType: test.received.ContentType(),
+			}
+
+			ctx := context.WithValue(ctxTID, wrpEncodingContextKey{}, test.requested)
+
+			recorder := httptest.NewRecorder()
+			require.Nil(encodeResponse(ctx, recorder, response))
+			assert.Equal(http.StatusOK, recorder.Code)
+			assert.Equal(test.requested.ContentType(), recorder.Header().Get(contentTypeHeaderKey))
+
+			if test.received == test.requested {
+				assert.Equal(body, recorder.Body.Bytes())
+			}
+
+			actual := new(wrp.Message)
+			require.Nil(wrp.NewDecoderBytes(recorder.Body.Bytes(), test.requested).Decode(actual))
+			assert.Equal(message.Payload, actual.Payload)
+			assert.Equal(message.TransactionUUID, actual.TransactionUUID)
+			assert.Equal(message.ContentType, actual.ContentType)
+		})
+	}
+}
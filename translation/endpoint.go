@@ -10,11 +10,20 @@ import (
 type wrpRequest struct {
 	WRPMessage      *wrp.Message
 	AuthHeaderValue string
+	DryRun          bool
 }
 
 func makeTranslationEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		wrpReq := (request).(*wrpRequest)
-		return s.SendWRP(wrpReq.WRPMessage, wrpReq.AuthHeaderValue)
+		if !wrpReq.DryRun {
+			return s.SendWRP(ctx, wrpReq.WRPMessage, wrpReq.AuthHeaderValue)
+		}
+
+		if _, err := s.SendWRP(withDryRun(ctx), wrpReq.WRPMessage, wrpReq.AuthHeaderValue); err != nil {
+			return nil, err
+		}
+
+		return newDryRunResponse(wrpReq.WRPMessage), nil
 	}
 }
@@ -2,6 +2,7 @@ package translation
 
 import (
 	"errors"
+	"net/http"
 
 	"github.com/xmidt-org/tr1d1um/common"
 )
@@ -9,9 +10,12 @@ import (
 // Error values definitions for the translation service
 var (
 	ErrEmptyNames        = common.NewBadRequestError(errors.New("names parameter is required"))
+	ErrInvalidBatchNames = common.NewBadRequestError(errors.New("batch GET body must be a JSON array of parameter names"))
 	ErrInvalidService    = common.NewBadRequestError(errors.New("unsupported Service"))
 	ErrUnsupportedMethod = common.NewBadRequestError(errors.New("unsupported method. Could not decode request payload"))
 
+	ErrWildcardGetDisabled = common.NewBadRequestError(errors.New("wildcard parameter names (ending with '.') are not allowed"))
+
 	//Set command errors
 	ErrInvalidSetWDMP = common.NewBadRequestError(errors.New("invalid SET message"))
 	ErrNewCIDRequired = common.NewBadRequestError(errors.New("newCid is required for TEST_AND_SET"))
@@ -24,4 +28,24 @@ var (
 	//Replace command error
 	ErrMissingRows = common.NewBadRequestError(errors.New("rows property is required"))
 	ErrInvalidRows = common.NewBadRequestError(errors.New("rows property is invalid"))
+
+	//Compression errors
+	ErrInvalidGzipBody = common.NewBadRequestError(errors.New("request body is not valid gzip"))
+
+	//Checksum errors
+	ErrChecksumMismatch = common.NewBadRequestError(errors.New("request body does not match its checksum"))
+
+	//Response validation errors
+	ErrTransactionIDMismatch = common.NewCodedErrorWithErrorCode(errors.New("XMiDT response does not belong to this request"),
+		http.StatusBadGateway, common.ErrorCodeTransactionIDMismatch)
+
+	//Online pre-check errors
+	ErrDeviceOffline = common.NewCodedErrorWithErrorCode(errors.New("device is not connected"), http.StatusNotFound, common.ErrorCodeDeviceOffline)
+
+	//Partner errors
+	ErrPartnerRequired   = common.NewCodedError(errors.New("token does not identify a partner"), http.StatusForbidden)
+	ErrPartnerIDRequired = common.NewCodedError(errors.New("request does not identify a partner through its token or headers"), http.StatusForbidden)
+
+	//Token freshness errors
+	ErrStaleToken = common.NewCodedError(errors.New("token is too old for the requested operation. Please authenticate again"), http.StatusUnauthorized)
 )
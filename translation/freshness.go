@@ -0,0 +1,84 @@
+package translation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/xmidt-org/bascule"
+)
+
+// issuedAtKey is the token attribute (JWT claim) which holds the time the token was issued at
+const issuedAtKey = "iat"
+
+// decodeFreshTokenRequest decorates decoder such that operations (WDMP commands) configured in maxAges
+// are rejected when the token of the request was issued longer ago than the operation allows.
+// Tokens which don't report when they were issued (i.e. Basic auth) can't be proven fresh and are rejected as well.
+// Command names are matched case insensitively.
+func decodeFreshTokenRequest(maxAges map[string]time.Duration, now func() time.Time, decoder kithttp.DecodeRequestFunc) kithttp.DecodeRequestFunc {
+	if len(maxAges) == 0 {
+		return decoder
+	}
+
+	commandMaxAges := make(map[string]time.Duration, len(maxAges))
+	for command, maxAge := range maxAges {
+		commandMaxAges[strings.ToUpper(command)] = maxAge
+	}
+
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		decodedRequest, err := decoder(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+
+		var wdmp struct {
+			Command string `json:"command"`
+		}
+
+		if err := json.Unmarshal(decodedRequest.(*wrpRequest).WRPMessage.Payload, &wdmp); err != nil {
+			return nil, err
+		}
+
+		maxAge, ok := commandMaxAges[wdmp.Command]
+		if !ok {
+			return decodedRequest, nil
+		}
+
+		issuedAt, ok := tokenIssuedAt(ctx)
+		if !ok || now().Sub(issuedAt) > maxAge {
+			return nil, ErrStaleToken
+		}
+
+		return decodedRequest, nil
+	}
+}
+
+// tokenIssuedAt returns the time at which the token of the request was issued, if known
+func tokenIssuedAt(ctx context.Context) (time.Time, bool) {
+	auth, ok := bascule.FromContext(ctx)
+	if !ok || auth.Token == nil || auth.Token.Attributes() == nil {
+		return time.Time{}, false
+	}
+
+	iat, ok := auth.Token.Attributes().Get(issuedAtKey)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	switch v := iat.(type) {
+	case float64:
+		return time.Unix(int64(v), 0), true
+	case int64:
+		return time.Unix(v, 0), true
+	case int:
+		return time.Unix(int64(v), 0), true
+	case json.Number:
+		seconds, err := v.Int64()
+		return time.Unix(seconds, 0), err == nil
+	default:
+		return time.Time{}, false
+	}
+}
@@ -0,0 +1,100 @@
+package translation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xmidt-org/bascule"
+	"github.com/xmidt-org/wrp-go/wrp"
+)
+
+func TestDecodeFreshTokenRequest(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+
+	maxAges := map[string]time.Duration{
+		"set":          5 * time.Minute,
+		"REPLACE_ROWS": time.Hour,
+	}
+
+	tests := []struct {
+		name        string
+		command     string
+		attrs       map[string]interface{}
+		noAuth      bool
+		expectedErr error
+	}{
+		{
+			name:    "UnrestrictedOperation",
+			command: CommandGet,
+			noAuth:  true,
+		},
+		{
+			name:    "FreshToken",
+			command: CommandSet,
+			attrs:   map[string]interface{}{"iat": float64(now.Add(-time.Minute).Unix())},
+		},
+		{
+			name:        "StaleToken",
+			command:     CommandSet,
+			attrs:       map[string]interface{}{"iat": float64(now.Add(-10 * time.Minute).Unix())},
+			expectedErr: ErrStaleToken,
+		},
+		{
+			name:    "OperationSpecificMaxAge",
+			command: CommandReplaceRows,
+			attrs:   map[string]interface{}{"iat": json.Number("1599999000")},
+		},
+		{
+			name:        "OperationSpecificMaxAgeStale",
+			command:     CommandReplaceRows,
+			attrs:       map[string]interface{}{"iat": int64(now.Add(-2 * time.Hour).Unix())},
+			expectedErr: ErrStaleToken,
+		},
+		{
+			name:        "NoIssuedAt",
+			command:     CommandSet,
+			attrs:       map[string]interface{}{},
+			expectedErr: ErrStaleToken,
+		},
+		{
+			name:        "NoAuth",
+			command:     CommandSet,
+			noAuth:      true,
+			expectedErr: ErrStaleToken,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			expected := &wrpRequest{
+				WRPMessage: &wrp.Message{Payload: []byte(`{"command": "` + test.command + `"}`)},
+			}
+
+			decoder := decodeFreshTokenRequest(maxAges, func() time.Time { return now },
+				func(context.Context, *http.Request) (interface{}, error) {
+					return expected, nil
+				})
+
+			ctx := context.Background()
+			if !test.noAuth {
+				ctx = bascule.WithAuthentication(ctx, bascule.Authentication{
+					Token: bascule.NewToken("jwt", "client0", bascule.NewAttributesFromMap(test.attrs)),
+				})
+			}
+
+			actual, err := decoder(ctx, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+			assert.Equal(test.expectedErr, err)
+
+			if test.expectedErr == nil {
+				assert.Equal(expected, actual)
+			}
+		})
+	}
+}
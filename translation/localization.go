@@ -0,0 +1,171 @@
+package translation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+)
+
+const acceptLanguageHeaderKey = "Accept-Language"
+
+// MessageMapping translates a known device status code (and optionally message) into a
+// client-facing text for a given language
+type MessageMapping struct {
+	//StatusCode is the device status code this mapping applies to
+	StatusCode int
+
+	//Message restricts the mapping to device responses with this exact message
+	//(Optional)
+	Message string
+
+	//Language is the language tag (i.e. "en", "es-MX") of Text
+	Language string
+
+	//Text replaces the device message in the response to the client
+	Text string
+}
+
+// LocalizationConfig describes how device response messages are translated for clients
+type LocalizationConfig struct {
+	//DefaultLanguage is used when clients don't send an Accept-Language header
+	//or when none of the requested languages have mappings
+	DefaultLanguage string
+
+	//Mappings is the table of known device messages
+	Mappings []MessageMapping
+}
+
+type localizationContextKey struct{}
+
+type localization struct {
+	config         *LocalizationConfig
+	acceptLanguage string
+}
+
+// captureLocalization makes the localization config and the client language preferences available
+// to the response encoder
+func captureLocalization(config *LocalizationConfig) kithttp.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		if config == nil || len(config.Mappings) == 0 {
+			return ctx
+		}
+
+		return context.WithValue(ctx, localizationContextKey{}, &localization{
+			config:         config,
+			acceptLanguage: r.Header.Get(acceptLanguageHeaderKey),
+		})
+	}
+}
+
+// localizeFromContext rewrites the message of a device response payload per the localization found in ctx.
+// The original device message is preserved in the originalMessage field.
+// The payload is returned untouched if no mapping applies.
+func localizeFromContext(ctx context.Context, payload []byte) []byte {
+	l, ok := ctx.Value(localizationContextKey{}).(*localization)
+	if !ok {
+		return payload
+	}
+
+	var deviceResponse map[string]interface{}
+	if err := json.Unmarshal(payload, &deviceResponse); err != nil {
+		return payload
+	}
+
+	statusCode, ok := deviceResponse["statusCode"].(float64)
+	if !ok {
+		return payload
+	}
+
+	message, _ := deviceResponse["message"].(string)
+
+	mapping := l.config.find(int(statusCode), message, parseAcceptLanguage(l.acceptLanguage))
+	if mapping == nil {
+		return payload
+	}
+
+	deviceResponse["originalMessage"] = message
+	deviceResponse["message"] = mapping.Text
+
+	localized, err := json.Marshal(deviceResponse)
+	if err != nil {
+		return payload
+	}
+
+	return localized
+}
+
+// find returns the mapping for the first of the given languages which has one, falling back
+// to the default language
+func (c *LocalizationConfig) find(statusCode int, message string, languages []string) *MessageMapping {
+	for _, language := range append(languages, c.DefaultLanguage) {
+		if language == "" {
+			continue
+		}
+
+		for i, m := range c.Mappings {
+			if m.StatusCode != statusCode || (m.Message != "" && m.Message != message) {
+				continue
+			}
+
+			if strings.EqualFold(m.Language, language) || strings.EqualFold(m.Language, primaryLanguage(language)) {
+				return &c.Mappings[i]
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseAcceptLanguage returns the language tags of an Accept-Language header value sorted by preference
+func parseAcceptLanguage(value string) (languages []string) {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var tags []weighted
+
+	for _, part := range strings.Split(value, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if parsed, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+
+		if q > 0 {
+			tags = append(tags, weighted{tag: tag, q: q})
+		}
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+
+	for _, t := range tags {
+		languages = append(languages, t.tag)
+	}
+
+	return
+}
+
+func primaryLanguage(tag string) string {
+	if i := strings.Index(tag, "-"); i > 0 {
+		return tag[:i]
+	}
+	return tag
+}
@@ -0,0 +1,118 @@
+package translation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testLocalization = &LocalizationConfig{
+	DefaultLanguage: "en",
+	Mappings: []MessageMapping{
+		{StatusCode: 520, Language: "en", Text: "The device could not process the request"},
+		{StatusCode: 520, Language: "es", Text: "El dispositivo no pudo procesar la solicitud"},
+		{StatusCode: 531, Message: "Service Unavailable", Language: "en", Text: "Try again later"},
+	},
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	assert := assert.New(t)
+	assert.Empty(parseAcceptLanguage(""))
+	assert.Equal([]string{"es-MX"}, parseAcceptLanguage("es-MX"))
+	assert.Equal([]string{"fr", "es", "en"}, parseAcceptLanguage("en;q=0.5, es;q=0.8, fr, *;q=0.1"))
+	assert.Equal([]string{"de"}, parseAcceptLanguage("de, en;q=0"))
+}
+
+func TestLocalizeFromContext(t *testing.T) {
+	tests := []struct {
+		name             string
+		config           *LocalizationConfig
+		acceptLanguage   string
+		payload          string
+		expectedMessage  string
+		expectedOriginal interface{}
+		unchanged        bool
+	}{
+		{
+			name:      "Disabled",
+			payload:   `{"statusCode": 520, "message": "Error"}`,
+			unchanged: true,
+		},
+		{
+			name:             "DefaultLanguage",
+			config:           testLocalization,
+			payload:          `{"statusCode": 520, "message": "Error"}`,
+			expectedMessage:  "The device could not process the request",
+			expectedOriginal: "Error",
+		},
+		{
+			name:             "AcceptLanguage",
+			config:           testLocalization,
+			acceptLanguage:   "es-MX, en;q=0.5",
+			payload:          `{"statusCode": 520, "message": "Error"}`,
+			expectedMessage:  "El dispositivo no pudo procesar la solicitud",
+			expectedOriginal: "Error",
+		},
+		{
+			name:           "UnknownLanguageFallsBack",
+			config:         testLocalization,
+			acceptLanguage: "fr",
+			payload:        `{"statusCode": 520, "message": "Error"}`,
+
+			expectedMessage:  "The device could not process the request",
+			expectedOriginal: "Error",
+		},
+		{
+			name:             "MessageMatch",
+			config:           testLocalization,
+			payload:          `{"statusCode": 531, "message": "Service Unavailable"}`,
+			expectedMessage:  "Try again later",
+			expectedOriginal: "Service Unavailable",
+		},
+		{
+			name:      "MessageMismatch",
+			config:    testLocalization,
+			payload:   `{"statusCode": 531, "message": "Something else"}`,
+			unchanged: true,
+		},
+		{
+			name:      "UnknownStatusCode",
+			config:    testLocalization,
+			payload:   `{"statusCode": 200, "message": "Success"}`,
+			unchanged: true,
+		},
+		{
+			name:      "NotJSON",
+			config:    testLocalization,
+			payload:   `{"statusCode":`,
+			unchanged: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			r := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+			r.Header.Set(acceptLanguageHeaderKey, test.acceptLanguage)
+			ctx := captureLocalization(test.config)(context.Background(), r)
+
+			actual := localizeFromContext(ctx, []byte(test.payload))
+			if test.unchanged {
+				assert.Equal(test.payload, string(actual))
+				return
+			}
+
+			var response map[string]interface{}
+			require.Nil(json.Unmarshal(actual, &response))
+			assert.Equal(test.expectedMessage, response["message"])
+			assert.Equal(test.expectedOriginal, response["originalMessage"])
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package translation
+
+import (
+	"net/http"
+
+	"github.com/xmidt-org/tr1d1um/common"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/wrp-go/wrp"
+)
+
+// checkTransactionID verifies the successful response resp from XMiDT carries the transaction id of the
+// request message. A mismatch means the response was routed to the wrong request so, unless allowMismatch
+// is set, it fails with a 502 rather than being returned to the client.
+func (w *service) checkTransactionID(resp *common.XmidtResponse, request *wrp.Message) (*common.XmidtResponse, error) {
+	if resp == nil || resp.Code != http.StatusOK {
+		return resp, nil
+	}
+
+	response := new(wrp.Message)
+	if err := wrp.NewDecoderBytes(resp.Body, xmidtFormat(resp.ContentType)).Decode(response); err != nil {
+		// leave it to the response encoder to deal with undecodable messages
+		return resp, nil
+	}
+
+	if response.TransactionUUID == request.TransactionUUID {
+		return resp, nil
+	}
+
+	w.transactionIDMismatches.Add(1)
+	logging.Error(w.logger).Log(logging.MessageKey(), "XMiDT response transaction id does not match the request one",
+		"expected", request.TransactionUUID, "actual", response.TransactionUUID, "destination", request.Destination)
+
+	if w.allowTransactionIDMismatch {
+		return resp, nil
+	}
+
+	return nil, ErrTransactionIDMismatch
+}
@@ -0,0 +1,70 @@
+package translation
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/xmidt-org/tr1d1um/common"
+	"github.com/xmidt-org/wrp-go/wrp"
+)
+
+func TestCheckTransactionID(t *testing.T) {
+	tests := []struct {
+		name               string
+		code               int
+		responseTID        string
+		allowMismatch      bool
+		expectedErr        error
+		expectedMismatches float64
+	}{
+		{name: "Match", code: http.StatusOK, responseTID: "tid"},
+		{name: "NotOK", code: http.StatusNotFound, responseTID: "other"},
+		{name: "Mismatch", code: http.StatusOK, responseTID: "other", expectedErr: ErrTransactionIDMismatch, expectedMismatches: 1},
+		{name: "MissingTID", code: http.StatusOK, expectedErr: ErrTransactionIDMismatch, expectedMismatches: 1},
+		{name: "MismatchAllowed", code: http.StatusOK, responseTID: "other", allowMismatch: true, expectedMismatches: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			m := new(common.MockTr1d1umTransactor)
+			mismatches := generic.NewCounter("mismatches")
+
+			s := NewService(&ServiceOptions{
+				XmidtWrpURL:                "http://localhost/wrp",
+				Tr1d1umTransactor:          m,
+				TransactionIDMismatches:    mismatches,
+				AllowTransactionIDMismatch: test.allowMismatch,
+			})
+
+			xmidtResponse := &common.XmidtResponse{
+				Code:             test.code,
+				ForwardedHeaders: make(http.Header),
+				Body: wrp.MustEncode(wrp.Message{
+					Type:            wrp.SimpleRequestResponseMessageType,
+					TransactionUUID: test.responseTID,
+				}, wrp.Msgpack),
+			}
+
+			m.On("Transact", mock.Anything).Return(xmidtResponse, nil)
+
+			resp, err := s.SendWRP(context.Background(), &wrp.Message{
+				Type:            wrp.SimpleRequestResponseMessageType,
+				TransactionUUID: "tid",
+			}, "token")
+
+			assert.Equal(test.expectedErr, err)
+			if test.expectedErr == nil {
+				assert.Equal(xmidtResponse, resp)
+			} else {
+				assert.Nil(resp)
+			}
+			assert.Equal(test.expectedMismatches, mismatches.Value())
+		})
+	}
+}
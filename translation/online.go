@@ -0,0 +1,130 @@
+package translation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xmidt-org/tr1d1um/common"
+	"github.com/xmidt-org/wrp-go/wrp"
+)
+
+// StatFunc fetches the statistics of a device from XMiDT, which responds with 404 for devices that aren't connected
+type StatFunc func(ctx context.Context, authHeaderValue, deviceID string) (*common.XmidtResponse, error)
+
+type onlineStatus struct {
+	online  bool
+	expires time.Time
+}
+
+// OnlinePreCheck fails requests for offline devices fast rather than having them wait for the response
+// timeout. The online status of devices is cached for a while so that bursts of requests to the same
+// device don't double the load on XMiDT. Statuses are only shared by requests with the same credentials
+// so that XMiDT keeps authorizing every caller, see onlineStatusKey.
+type OnlinePreCheck struct {
+	stat StatFunc
+	ttl  time.Duration
+	now  func() time.Time
+
+	lock       sync.Mutex
+	statuses   map[string]onlineStatus
+	lastPruned time.Time
+}
+
+// NewOnlinePreCheck is the constructor for OnlinePreCheck. Statuses aren't cached when ttl isn't positive
+func NewOnlinePreCheck(stat StatFunc, ttl time.Duration) *OnlinePreCheck {
+	return &OnlinePreCheck{
+		stat:     stat,
+		ttl:      ttl,
+		now:      time.Now,
+		statuses: make(map[string]onlineStatus),
+	}
+}
+
+// Then decorates s such that WRP messages for offline devices aren't sent. It's a no-op for nil checks
+func (o *OnlinePreCheck) Then(s Service) Service {
+	if o == nil {
+		return s
+	}
+
+	return &onlineCheckService{Service: s, check: o}
+}
+
+type onlineCheckService struct {
+	Service
+	check *OnlinePreCheck
+}
+
+func (s *onlineCheckService) SendWRP(ctx context.Context, wrpMsg *wrp.Message, authHeaderValue string) (*common.XmidtResponse, error) {
+	// dry runs don't reach the device
+	if !dryRunFromContext(ctx) && !s.check.online(ctx, authHeaderValue, strings.SplitN(wrpMsg.Destination, "/", 2)[0]) {
+		return nil, ErrDeviceOffline
+	}
+
+	return s.Service.SendWRP(ctx, wrpMsg, authHeaderValue)
+}
+
+// onlineStatusKey identifies the online status of a device fetched on behalf of a caller with the given
+// credentials, which are hashed so that they aren't kept in memory
+func onlineStatusKey(deviceID, credentials string) string {
+	sum := sha256.Sum256([]byte(credentials))
+	return deviceID + " " + hex.EncodeToString(sum[:])
+}
+
+// online reports whether the device is connected to XMiDT. Devices are assumed online when their
+// status can't be determined so that requests aren't failed because of the pre-check itself. Only
+// the definite answers of XMiDT are cached: other statuses (i.e. 401 or 503) are left for the request
+// itself to run into.
+func (o *OnlinePreCheck) online(ctx context.Context, authHeaderValue, deviceID string) bool {
+	now := o.now()
+	key := onlineStatusKey(deviceID, common.CallerCredentials(ctx, authHeaderValue))
+
+	o.lock.Lock()
+	status, ok := o.statuses[key]
+	o.lock.Unlock()
+
+	if ok && now.Before(status.expires) {
+		return status.online
+	}
+
+	resp, err := o.stat(ctx, authHeaderValue, deviceID)
+	if err != nil {
+		return true
+	}
+
+	switch resp.Code {
+	case http.StatusOK:
+		o.store(key, true, now)
+		return true
+	case http.StatusNotFound:
+		o.store(key, false, now)
+		return false
+	default:
+		return true
+	}
+}
+
+func (o *OnlinePreCheck) store(key string, online bool, now time.Time) {
+	if o.ttl <= 0 {
+		return
+	}
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	// expired statuses of devices which aren't requested anymore would pile up otherwise
+	if now.Sub(o.lastPruned) >= o.ttl {
+		for k, status := range o.statuses {
+			if !now.Before(status.expires) {
+				delete(o.statuses, k)
+			}
+		}
+		o.lastPruned = now
+	}
+
+	o.statuses[key] = onlineStatus{online: online, expires: now.Add(o.ttl)}
+}
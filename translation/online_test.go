@@ -0,0 +1,125 @@
+package translation
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/xmidt-org/tr1d1um/common"
+	"github.com/xmidt-org/wrp-go/wrp"
+)
+
+func TestOnlinePreCheck(t *testing.T) {
+	tests := []struct {
+		name         string
+		statCode     int
+		statErr      error
+		dryRun       bool
+		expectedErr  error
+		expectedSent bool
+	}{
+		{name: "Online", statCode: http.StatusOK, expectedSent: true},
+		{name: "Offline", statCode: http.StatusNotFound, expectedErr: ErrDeviceOffline},
+		{name: "Forbidden", statCode: http.StatusForbidden, expectedSent: true},
+		{name: "ServerError", statCode: http.StatusServiceUnavailable, expectedSent: true},
+		{name: "Unknown", statErr: errors.New("XMiDT is unreachable"), expectedSent: true},
+		{name: "DryRun", statCode: http.StatusNotFound, dryRun: true, expectedSent: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var requestedDevice string
+			check := NewOnlinePreCheck(func(_ context.Context, _, deviceID string) (*common.XmidtResponse, error) {
+				requestedDevice = deviceID
+				if test.statErr != nil {
+					return nil, test.statErr
+				}
+				return &common.XmidtResponse{Code: test.statCode}, nil
+			}, time.Minute)
+
+			m := new(MockService)
+			m.On("SendWRP", mock.Anything, mock.Anything, "auth").Return(&common.XmidtResponse{Code: http.StatusOK}, nil)
+
+			ctx := context.Background()
+			if test.dryRun {
+				ctx = withDryRun(ctx)
+			}
+
+			_, err := check.Then(m).SendWRP(ctx, &wrp.Message{Destination: "mac:112233445566/config"}, "auth")
+			assert.Equal(test.expectedErr, err)
+
+			if test.expectedSent {
+				m.AssertCalled(t, "SendWRP", mock.Anything, mock.Anything, "auth")
+			} else {
+				m.AssertNotCalled(t, "SendWRP", mock.Anything, mock.Anything, "auth")
+			}
+
+			if !test.dryRun {
+				assert.Equal("mac:112233445566", requestedDevice)
+			}
+		})
+	}
+}
+
+func TestOnlinePreCheckCache(t *testing.T) {
+	assert := assert.New(t)
+
+	stats := 0
+	check := NewOnlinePreCheck(func(context.Context, string, string) (*common.XmidtResponse, error) {
+		stats++
+		return &common.XmidtResponse{Code: http.StatusNotFound}, nil
+	}, time.Minute)
+
+	now := time.Now()
+	check.now = func() time.Time { return now }
+
+	assert.False(check.online(context.Background(), "", "mac:112233445566"))
+	assert.False(check.online(context.Background(), "", "mac:112233445566"))
+	assert.Equal(1, stats)
+
+	now = now.Add(time.Minute)
+	assert.False(check.online(context.Background(), "", "mac:112233445566"))
+	assert.Equal(2, stats)
+
+	assert.False(check.online(context.Background(), "", "mac:665544332211"))
+	assert.Equal(3, stats)
+
+	// statuses aren't shared across callers
+	assert.False(check.online(context.Background(), "Bearer other", "mac:665544332211"))
+	assert.False(check.online(certAuthenticated("client0"), "", "mac:665544332211"))
+	assert.Equal(5, stats)
+	assert.False(check.online(certAuthenticated("client0"), "", "mac:665544332211"))
+	assert.Equal(5, stats)
+}
+
+func TestOnlinePreCheckCacheUncertain(t *testing.T) {
+	assert := assert.New(t)
+
+	code, stats := http.StatusForbidden, 0
+	check := NewOnlinePreCheck(func(context.Context, string, string) (*common.XmidtResponse, error) {
+		stats++
+		return &common.XmidtResponse{Code: code}, nil
+	}, time.Minute)
+
+	// answers other than 200 and 404 don't tell whether the device is online, so they aren't cached
+	assert.True(check.online(context.Background(), "Bearer xyz", "mac:112233445566"))
+	assert.True(check.online(context.Background(), "Bearer xyz", "mac:112233445566"))
+	assert.Equal(2, stats)
+
+	code = http.StatusOK
+	assert.True(check.online(context.Background(), "Bearer xyz", "mac:112233445566"))
+	assert.True(check.online(context.Background(), "Bearer xyz", "mac:112233445566"))
+	assert.Equal(3, stats)
+}
+
+func TestOnlinePreCheckDisabled(t *testing.T) {
+	var check *OnlinePreCheck
+	m := new(MockService)
+	assert.Equal(t, m, check.Then(m))
+}
@@ -0,0 +1,97 @@
+package translation
+
+import (
+	"context"
+	"net/http"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/xmidt-org/bascule"
+)
+
+// PartnerIDsConfig configures how the partner ids of WRP messages are derived from the authenticated principal
+type PartnerIDsConfig struct {
+	//Claim is the token attribute (i.e. "allowedResources.allowedPartners") holding the partner ids,
+	//either as a list or a single string
+	Claim string
+
+	//RequirePartner makes requests whose token lacks Claim fail with a 403. Otherwise, DefaultPartner is used
+	RequirePartner bool
+
+	//DefaultPartner is the partner id of requests whose token lacks Claim
+	//(Optional) when empty, such requests keep the partner ids of the X-Xmidt-Partner-Id header
+	DefaultPartner string
+}
+
+// claimedPartnerIDs returns the partner ids the token of the request holds in claim
+func claimedPartnerIDs(ctx context.Context, claim string) ([]string, bool) {
+	auth, ok := bascule.FromContext(ctx)
+	if !ok || auth.Token == nil || auth.Token.Attributes() == nil {
+		return nil, false
+	}
+
+	attributes := auth.Token.Attributes()
+	if partnerIDs, ok := attributes.GetStringSlice(claim); ok && len(partnerIDs) > 0 {
+		return partnerIDs, true
+	}
+
+	if partnerID, ok := attributes.GetString(claim); ok && partnerID != "" {
+		return []string{partnerID}, true
+	}
+
+	return nil, false
+}
+
+// decodePartnerIDsRequest decorates decoder such that the partner ids of WRP messages come from the configured
+// claim of the token. Requests without the claim are rejected when a partner is required or get the default
+// partner otherwise. A nil config returns decoder as is.
+func decodePartnerIDsRequest(config *PartnerIDsConfig, decoder kithttp.DecodeRequestFunc) kithttp.DecodeRequestFunc {
+	if config == nil || config.Claim == "" {
+		return decoder
+	}
+
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		partnerIDs, ok := claimedPartnerIDs(ctx, config.Claim)
+		if !ok && config.RequirePartner {
+			return nil, ErrPartnerRequired
+		}
+
+		request, err := decoder(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok && config.DefaultPartner != "" {
+			partnerIDs, ok = []string{config.DefaultPartner}, true
+		}
+
+		if ok {
+			request.(*wrpRequest).WRPMessage.PartnerIDs = partnerIDs
+		}
+
+		return request, nil
+	}
+}
+
+// decodeRequirePartnerIDRequest decorates decoder such that requests whose WRP message ends up without
+// partner ids, wherever they come from, are rejected with a 403. Unless required is set, decoder is
+// returned as is.
+func decodeRequirePartnerIDRequest(required bool, decoder kithttp.DecodeRequestFunc) kithttp.DecodeRequestFunc {
+	if !required {
+		return decoder
+	}
+
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		request, err := decoder(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, partnerID := range request.(*wrpRequest).WRPMessage.PartnerIDs {
+			if partnerID != "" {
+				return request, nil
+			}
+		}
+
+		return nil, ErrPartnerIDRequired
+	}
+}
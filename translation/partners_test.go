@@ -0,0 +1,111 @@
+package translation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xmidt-org/bascule"
+	"github.com/xmidt-org/wrp-go/wrp"
+)
+
+func TestDecodePartnerIDsRequest(t *testing.T) {
+	tests := []struct {
+		name               string
+		config             *PartnerIDsConfig
+		attributes         map[string]interface{}
+		expectedPartnerIDs []string
+		expectedErr        error
+	}{
+		{
+			name:               "Disabled",
+			attributes:         map[string]interface{}{"partners": []interface{}{"comcast"}},
+			expectedPartnerIDs: []string{"fromHeader"},
+		},
+		{
+			name:               "ClaimList",
+			config:             &PartnerIDsConfig{Claim: "partners"},
+			attributes:         map[string]interface{}{"partners": []interface{}{"comcast", "sky"}},
+			expectedPartnerIDs: []string{"comcast", "sky"},
+		},
+		{
+			name:               "ClaimString",
+			config:             &PartnerIDsConfig{Claim: "partner", RequirePartner: true},
+			attributes:         map[string]interface{}{"partner": "comcast"},
+			expectedPartnerIDs: []string{"comcast"},
+		},
+		{
+			name:        "Required",
+			config:      &PartnerIDsConfig{Claim: "partners", RequirePartner: true, DefaultPartner: "comcast"},
+			attributes:  map[string]interface{}{"sub": "client"},
+			expectedErr: ErrPartnerRequired,
+		},
+		{
+			name:               "Default",
+			config:             &PartnerIDsConfig{Claim: "partners", DefaultPartner: "comcast"},
+			attributes:         map[string]interface{}{"partners": []interface{}{}},
+			expectedPartnerIDs: []string{"comcast"},
+		},
+		{
+			name:               "NoDefault",
+			config:             &PartnerIDsConfig{Claim: "partners"},
+			attributes:         map[string]interface{}{"sub": "client"},
+			expectedPartnerIDs: []string{"fromHeader"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			ctx := bascule.WithAuthentication(context.Background(), bascule.Authentication{
+				Token: bascule.NewToken("jwt", "client", bascule.NewAttributesFromMap(test.attributes)),
+			})
+
+			decoder := decodePartnerIDsRequest(test.config, func(context.Context, *http.Request) (interface{}, error) {
+				return &wrpRequest{WRPMessage: &wrp.Message{PartnerIDs: []string{"fromHeader"}}}, nil
+			})
+
+			request, err := decoder(ctx, httptest.NewRequest(http.MethodGet, "http://localhost:8090/api", nil))
+			assert.Equal(test.expectedErr, err)
+			if test.expectedErr == nil {
+				assert.Equal(test.expectedPartnerIDs, request.(*wrpRequest).WRPMessage.PartnerIDs)
+			}
+		})
+	}
+}
+
+func TestDecodeRequirePartnerIDRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		required    bool
+		partnerIDs  []string
+		expectedErr error
+	}{
+		{name: "NotRequired"},
+		{name: "Present", required: true, partnerIDs: []string{"comcast"}},
+		{name: "Missing", required: true, expectedErr: ErrPartnerIDRequired},
+		{name: "Blank", required: true, partnerIDs: []string{""}, expectedErr: ErrPartnerIDRequired},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decoder := decodeRequirePartnerIDRequest(test.required, func(context.Context, *http.Request) (interface{}, error) {
+				return &wrpRequest{WRPMessage: &wrp.Message{PartnerIDs: test.partnerIDs}}, nil
+			})
+
+			_, err := decoder(context.Background(), httptest.NewRequest(http.MethodGet, "http://localhost:8090/api", nil))
+			assert.Equal(t, test.expectedErr, err)
+		})
+	}
+}
+
+func TestGetPartnerIDsHeaders(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(getPartnerIDs(http.Header{}))
+	assert.Equal([]string{"comcast", "sky"}, getPartnerIDs(http.Header{"X-Webpa-Partner-Id": []string{"comcast, sky"}}))
+	assert.Equal([]string{"cox"}, getPartnerIDs(http.Header{"X-Xmidt-Partner-Id": []string{"cox"}, "X-Webpa-Partner-Id": []string{"comcast"}}))
+}
@@ -0,0 +1,59 @@
+package translation
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/xmidt-org/tr1d1um/common"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+)
+
+// HeaderTr1d1umQOS sets the WRP quality of service (priority) of a request, from 0 (lowest) to 99 (highest)
+const HeaderTr1d1umQOS = "X-Tr1d1um-Qos"
+
+// qosMetadataKey is the WRP metadata entry which carries the quality of service, as the WRP messages of this
+// wrp-go version have no field for it
+const qosMetadataKey = "qos"
+
+const maxQOS = 99
+
+func clampQOS(qos int) int {
+	if qos < 0 {
+		return 0
+	} else if qos > maxQOS {
+		return maxQOS
+	}
+	return qos
+}
+
+// decodeQOSRequest decorates decoder such that the WRP quality of service is taken from the HeaderTr1d1umQOS
+// header, clamped to the 0-99 range, or is defaultQOS when the header is missing. Requests with non integer
+// values are rejected with a 400. No quality of service is set for requests without the header when
+// defaultQOS is negative.
+func decodeQOSRequest(defaultQOS int, decoder kithttp.DecodeRequestFunc) kithttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		qos := defaultQOS
+		if value := r.Header.Get(HeaderTr1d1umQOS); value != "" {
+			var err error
+			if qos, err = strconv.Atoi(value); err != nil {
+				return nil, common.NewBadRequestError(fmt.Errorf("invalid %s header value '%s'", HeaderTr1d1umQOS, value))
+			}
+			qos = clampQOS(qos)
+		}
+
+		request, err := decoder(ctx, r)
+		if err != nil || qos < 0 {
+			return request, err
+		}
+
+		wrpMsg := request.(*wrpRequest).WRPMessage
+		if wrpMsg.Metadata == nil {
+			wrpMsg.Metadata = make(map[string]string)
+		}
+		wrpMsg.Metadata[qosMetadataKey] = strconv.Itoa(clampQOS(qos))
+		return request, nil
+	}
+}
@@ -0,0 +1,57 @@
+package translation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xmidt-org/tr1d1um/common"
+	"github.com/xmidt-org/wrp-go/wrp"
+)
+
+func TestDecodeQOSRequest(t *testing.T) {
+	tests := []struct {
+		name         string
+		defaultQOS   int
+		header       string
+		expectedQOS  string
+		expectedCode int
+	}{
+		{name: "NoDefault", defaultQOS: -1},
+		{name: "Default", defaultQOS: 25, expectedQOS: "25"},
+		{name: "ClampedDefault", defaultQOS: 150, expectedQOS: "99"},
+		{name: "Header", defaultQOS: 25, header: "75", expectedQOS: "75"},
+		{name: "HeaderWithoutDefault", defaultQOS: -1, header: "0", expectedQOS: "0"},
+		{name: "ClampedHigh", defaultQOS: -1, header: "100", expectedQOS: "99"},
+		{name: "ClampedLow", defaultQOS: -1, header: "-5", expectedQOS: "0"},
+		{name: "Invalid", defaultQOS: 25, header: "high", expectedCode: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			r := httptest.NewRequest(http.MethodPatch, "http://localhost:8090/api", nil)
+			if test.header != "" {
+				r.Header.Set(HeaderTr1d1umQOS, test.header)
+			}
+
+			decoder := decodeQOSRequest(test.defaultQOS, func(context.Context, *http.Request) (interface{}, error) {
+				return &wrpRequest{WRPMessage: new(wrp.Message)}, nil
+			})
+
+			request, err := decoder(context.Background(), r)
+			if test.expectedCode != 0 {
+				if assert.NotNil(err) {
+					assert.Equal(test.expectedCode, err.(common.CodedError).StatusCode())
+				}
+				return
+			}
+
+			assert.Nil(err)
+			assert.Equal(test.expectedQOS, request.(*wrpRequest).WRPMessage.Metadata[qosMetadataKey])
+		})
+	}
+}
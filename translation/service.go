@@ -2,11 +2,15 @@ package translation
 
 import (
 	"bytes"
-
+	"context"
 	"net/http"
 
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
 	"github.com/xmidt-org/bascule/acquire"
 	"github.com/xmidt-org/tr1d1um/common"
+	"github.com/xmidt-org/webpa-common/logging"
 
 	"github.com/xmidt-org/wrp-go/wrp"
 )
@@ -14,7 +18,7 @@ import (
 // Service represents the Webpa-Tr1d1um component that translates WDMP data into WRP
 // which is compatible with the XMiDT API.
 type Service interface {
-	SendWRP(*wrp.Message, string) (*common.XmidtResponse, error)
+	SendWRP(context.Context, *wrp.Message, string) (*common.XmidtResponse, error)
 }
 
 // ServiceOptions defines the options needed to build a new translation WRP service.
@@ -31,16 +35,46 @@ type ServiceOptions struct {
 	//Tr1d1umTransactor is the component that's responsible to make the HTTP
 	//request to the XMiDT API and return only data we care about.
 	common.Tr1d1umTransactor
+
+	//Logger is used to report responses whose transaction id doesn't match the request one
+	//(Optional) defaults to the webpa-common default logger
+	Logger kitlog.Logger
+
+	//TransactionIDMismatches counts responses whose transaction id doesn't match the request one
+	//(Optional)
+	TransactionIDMismatches metrics.Counter
+
+	//AllowTransactionIDMismatch makes responses whose transaction id doesn't match the request one
+	//be returned to clients rather than failing with 502. Mismatches are still logged and counted.
+	AllowTransactionIDMismatch bool
+
+	//WRPSink mirrors outbound WRP messages for troubleshooting
+	//(Optional)
+	WRPSink *WRPSink
 }
 
 // NewService constructs a new translation service instance given some options.
 func NewService(o *ServiceOptions) Service {
-	return &service{
-		xmidtWrpURL:  o.XmidtWrpURL,
-		wrpSource:    o.WRPSource,
-		transactor:   o.Tr1d1umTransactor,
-		authAcquirer: o.AuthAcquirer,
+	s := &service{
+		xmidtWrpURL:                o.XmidtWrpURL,
+		wrpSource:                  o.WRPSource,
+		transactor:                 o.Tr1d1umTransactor,
+		authAcquirer:               o.AuthAcquirer,
+		logger:                     o.Logger,
+		transactionIDMismatches:    o.TransactionIDMismatches,
+		allowTransactionIDMismatch: o.AllowTransactionIDMismatch,
+		wrpSink:                    o.WRPSink,
+	}
+
+	if s.logger == nil {
+		s.logger = logging.DefaultLogger()
 	}
+
+	if s.transactionIDMismatches == nil {
+		s.transactionIDMismatches = discard.NewCounter()
+	}
+
+	return s
 }
 
 type service struct {
@@ -51,10 +85,18 @@ type service struct {
 	xmidtWrpURL string
 
 	wrpSource string
+
+	logger kitlog.Logger
+
+	transactionIDMismatches metrics.Counter
+
+	allowTransactionIDMismatch bool
+
+	wrpSink *WRPSink
 }
 
 // SendWRP sends the given wrpMsg to the XMiDT cluster and returns the response if any.
-func (w *service) SendWRP(wrpMsg *wrp.Message, authHeaderValue string) (*common.XmidtResponse, error) {
+func (w *service) SendWRP(ctx context.Context, wrpMsg *wrp.Message, authHeaderValue string) (*common.XmidtResponse, error) {
 	wrpMsg.Source = w.wrpSource
 
 	var payload []byte
@@ -65,12 +107,34 @@ func (w *service) SendWRP(wrpMsg *wrp.Message, authHeaderValue string) (*common.
 		return nil, err
 	}
 
-	r, err := http.NewRequest(http.MethodPost, w.xmidtWrpURL, bytes.NewBuffer(payload))
+	// the message is valid at this point so it's safe to stop short of sending it
+	if dryRunFromContext(ctx) {
+		return &common.XmidtResponse{
+			Code:             http.StatusOK,
+			ForwardedHeaders: make(http.Header),
+			Body:             payload,
+		}, nil
+	}
+
+	encoded := payload
+
+	compress := compressionFromContext(ctx)
+	if compress {
+		if payload, err = gzipBytes(payload); err != nil {
+			return nil, err
+		}
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, w.xmidtWrpURL, bytes.NewBuffer(payload))
 
 	if err != nil {
 		return nil, err
 	}
 
+	if compress {
+		r.Header.Set(contentEncodingHeaderKey, gzipEncoding)
+	}
+
 	if w.authAcquirer != nil {
 		authHeaderValue, err = w.authAcquirer.Acquire()
 		if err != nil {
@@ -81,5 +145,12 @@ func (w *service) SendWRP(wrpMsg *wrp.Message, authHeaderValue string) (*common.
 	r.Header.Set("Content-Type", wrp.Msgpack.ContentType())
 	r.Header.Set("Authorization", authHeaderValue)
 
-	return w.transactor.Transact(r)
+	w.wrpSink.Mirror(wrpMsg, encoded, authHeaderValue)
+
+	resp, err := w.transactor.Transact(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return w.checkTransactionID(resp, wrpMsg)
 }
@@ -0,0 +1,29 @@
+package translation
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/xmidt-org/tr1d1um/common"
+)
+
+// ServicesPath is the path, relative to the API prefix, at which the supported services are listed
+const ServicesPath = "/services"
+
+type servicesResponse struct {
+	Services []string `json:"services"`
+}
+
+// servicesHandler lists the services the translation endpoints currently accept
+func servicesHandler(settings *common.Settings) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		services := settings.Load().ValidServices
+		if services == nil {
+			services = []string{}
+		}
+
+		w.Header().Set(contentTypeHeaderKey, "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(servicesResponse{Services: services})
+	})
+}
@@ -0,0 +1,51 @@
+package translation
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xmidt-org/tr1d1um/common"
+)
+
+func TestServicesHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		settings     *common.Settings
+		expectedBody string
+	}{
+		{
+			name:         "Configured",
+			settings:     common.NewSettings(common.Snapshot{ValidServices: []string{"config", "iot"}}),
+			expectedBody: `{"services":["config","iot"]}`,
+		},
+		{
+			name:         "None",
+			settings:     common.NewSettings(common.Snapshot{}),
+			expectedBody: `{"services":[]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			w := httptest.NewRecorder()
+			servicesHandler(test.settings).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost/api/v2/services", nil))
+
+			assert.Equal(http.StatusOK, w.Code)
+			assert.Equal("application/json; charset=utf-8", w.Header().Get(contentTypeHeaderKey))
+			assert.JSONEq(test.expectedBody, w.Body.String())
+		})
+	}
+
+	t.Run("Reloaded", func(t *testing.T) {
+		settings := common.NewSettings(common.Snapshot{ValidServices: []string{"config"}})
+		settings.Store(common.Snapshot{ValidServices: []string{"config", "iot"}})
+
+		w := httptest.NewRecorder()
+		servicesHandler(settings).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost/api/v2/services", nil))
+		assert.JSONEq(t, `{"services":["config","iot"]}`, w.Body.String())
+	})
+}
@@ -0,0 +1,205 @@
+package translation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/xmidt-org/tr1d1um/common"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+)
+
+// knownSetFields are the fields SET request bodies and their parameters may have
+var (
+	knownSetFields      = map[string]bool{"command": true, "old-cid": true, "new-cid": true, "sync-cmc": true, "parameters": true}
+	knownSetParamFields = map[string]bool{"name": true, "dataType": true, "value": true, "attributes": true}
+)
+
+// SetPayloadProblem is a single problem found with the structure of a SET request body
+type SetPayloadProblem struct {
+	// Element is where the problem is (i.e. "parameters[1].dataType")
+	Element string `json:"element"`
+
+	// Problem describes what's wrong with the element
+	Problem string `json:"problem"`
+}
+
+// SetPayloadError lists all the problems found with the structure of a SET request body. API consumers get them
+// in the "problems" field of the 400 response.
+type SetPayloadError struct {
+	Problems []SetPayloadProblem
+}
+
+func (e *SetPayloadError) Error() string {
+	return fmt.Sprintf("invalid SET message: %d problem(s) found", len(e.Problems))
+}
+
+// StatusCode implements common.CodedError
+func (e *SetPayloadError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// ErrorCode implements common.ErrorCoder
+func (e *SetPayloadError) ErrorCode() string {
+	return common.ErrorCodeInvalidSetPayload
+}
+
+func (e *SetPayloadError) add(element, format string, args ...interface{}) {
+	e.Problems = append(e.Problems, SetPayloadProblem{Element: element, Problem: fmt.Sprintf(format, args...)})
+}
+
+// decodeSetPayloadRequest decorates decoder such that the structure of SET request bodies is validated up front,
+// reporting all the problems found at once rather than the first one only. Unknown fields are problems as well
+// when strict is set.
+func decodeSetPayloadRequest(strict bool, decoder kithttp.DecodeRequestFunc) kithttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		if r.Method != http.MethodPatch {
+			return decoder(ctx, r)
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			if err == common.ErrRequestBodyTooLarge {
+				return nil, err
+			}
+			return nil, common.NewBadRequestError(err)
+		}
+
+		if err := validateSetPayload(body, strict); err != nil {
+			return nil, err
+		}
+
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		return decoder(ctx, r)
+	}
+}
+
+// validateSetPayload checks body is a JSON object whose parameters are well formed and either all SET or all
+// SET_ATTRIBUTES ones. Empty bodies are valid as TEST_AND_SET requests may have no parameters.
+func validateSetPayload(body []byte, strict bool) error {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+
+	var (
+		problems = new(SetPayloadError)
+		wdmp     map[string]json.RawMessage
+	)
+
+	if err := json.Unmarshal(body, &wdmp); err != nil {
+		problems.add("body", "must be a JSON object: %s", err.Error())
+		return problems
+	}
+
+	if strict {
+		for _, field := range unknownFields(wdmp, knownSetFields) {
+			problems.add(field, "is not a known field")
+		}
+	}
+
+	if rawParams, ok := wdmp["parameters"]; ok {
+		var params []json.RawMessage
+		if err := json.Unmarshal(rawParams, &params); err != nil {
+			problems.add("parameters", "must be an array")
+		}
+
+		var (
+			first        = -1
+			firstIsAttrs bool
+		)
+
+		for i, rawParam := range params {
+			element := fmt.Sprintf("parameters[%d]", i)
+
+			var param map[string]json.RawMessage
+			if err := json.Unmarshal(rawParam, &param); err != nil || param == nil {
+				problems.add(element, "must be an object")
+				continue
+			}
+
+			isAttrs := validateSetParam(element, param, strict, problems)
+			if first < 0 {
+				first, firstIsAttrs = i, isAttrs
+			} else if isAttrs != firstIsAttrs {
+				problems.add(element, "can't be mixed with parameters[%d] as parameters must either all have a value or all have attributes only", first)
+			}
+		}
+	}
+
+	if len(problems.Problems) > 0 {
+		return problems
+	}
+
+	return nil
+}
+
+// validateSetParam adds the problems of the given SET parameter. It reports whether the parameter has attributes only
+func validateSetParam(element string, param map[string]json.RawMessage, strict bool, problems *SetPayloadError) bool {
+	if strict {
+		for _, field := range unknownFields(param, knownSetParamFields) {
+			problems.add(element+"."+field, "is not a known field")
+		}
+	}
+
+	var name string
+	if raw, ok := param["name"]; !ok || isNull(raw) {
+		problems.add(element+".name", "is required")
+	} else if err := json.Unmarshal(raw, &name); err != nil {
+		problems.add(element+".name", "must be a string")
+	} else if name == "" {
+		problems.add(element+".name", "must not be empty")
+	}
+
+	rawDataType, hasDataType := param["dataType"]
+	hasDataType = hasDataType && !isNull(rawDataType)
+	if hasDataType {
+		var dataType float64
+		if err := json.Unmarshal(rawDataType, &dataType); err != nil || dataType != math.Trunc(dataType) {
+			problems.add(element+".dataType", "must be an integer")
+		} else if dataType < 0 || dataType > math.MaxInt8 {
+			problems.add(element+".dataType", "must be between 0 and %d", math.MaxInt8)
+		}
+	}
+
+	rawValue, hasValue := param["value"]
+	hasValue = hasValue && !isNull(rawValue)
+	if hasValue && !hasDataType {
+		problems.add(element+".dataType", "is required along with a value")
+	}
+
+	rawAttributes, hasAttributes := param["attributes"]
+	hasAttributes = hasAttributes && !isNull(rawAttributes)
+	if hasAttributes {
+		var attributes map[string]interface{}
+		if err := json.Unmarshal(rawAttributes, &attributes); err != nil {
+			problems.add(element+".attributes", "must be an object")
+			hasAttributes = false
+		}
+	}
+
+	return hasAttributes && !hasDataType && !hasValue
+}
+
+func isNull(raw json.RawMessage) bool {
+	return strings.TrimSpace(string(raw)) == "null"
+}
+
+// unknownFields returns the sorted fields of object which aren't known
+func unknownFields(object map[string]json.RawMessage, known map[string]bool) []string {
+	var fields []string
+	for field := range object {
+		if !known[field] {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,141 @@
+package translation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xmidt-org/tr1d1um/common"
+)
+
+func TestValidateSetPayload(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		strict           bool
+		expectedProblems []SetPayloadProblem
+	}{
+		{name: "Empty"},
+		{name: "Set", body: `{"parameters": [{"name": "p1", "dataType": 0, "value": "v1"}, {"name": "p2", "dataType": 1, "value": 2}]}`},
+		{name: "SetAttrs", body: `{"parameters": [{"name": "p1", "attributes": {"notify": 1}}]}`},
+		{
+			name:             "NotAnObject",
+			body:             `[]`,
+			expectedProblems: []SetPayloadProblem{{"body", "must be a JSON object: "}},
+		},
+		{
+			name:             "ParametersNotAnArray",
+			body:             `{"parameters": {"name": "p1"}}`,
+			expectedProblems: []SetPayloadProblem{{"parameters", "must be an array"}},
+		},
+		{
+			name: "BadParameters",
+			body: `{"parameters": ["p1", {"dataType": 0, "value": "v"}, {"name": "", "dataType": -1}, {"name": 3, "dataType": 1.5}, {"name": "p5", "value": "v"}, {"name": "p6", "attributes": []}]}`,
+			expectedProblems: []SetPayloadProblem{
+				{"parameters[0]", "must be an object"},
+				{"parameters[1].name", "is required"},
+				{"parameters[2].name", "must not be empty"},
+				{"parameters[2].dataType", "must be between 0 and 127"},
+				{"parameters[3].name", "must be a string"},
+				{"parameters[3].dataType", "must be an integer"},
+				{"parameters[4].dataType", "is required along with a value"},
+				{"parameters[5].attributes", "must be an object"},
+			},
+		},
+		{
+			name:             "Mixed",
+			body:             `{"parameters": [{"name": "p1", "dataType": 0, "value": "v1"}, {"name": "p2", "attributes": {"notify": 1}}]}`,
+			expectedProblems: []SetPayloadProblem{{"parameters[1]", "can't be mixed with parameters[0] as parameters must either all have a value or all have attributes only"}},
+		},
+		{
+			name: "UnknownFieldsLenient",
+			body: `{"params": [], "parameters": [{"name": "p1", "dataType": 0, "value": "v1", "type": 0}]}`,
+		},
+		{
+			name:   "UnknownFieldsStrict",
+			body:   `{"params": [], "parameters": [{"name": "p1", "dataType": 0, "value": "v1", "type": 0}]}`,
+			strict: true,
+			expectedProblems: []SetPayloadProblem{
+				{"params", "is not a known field"},
+				{"parameters[0].type", "is not a known field"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			err := validateSetPayload([]byte(test.body), test.strict)
+			if len(test.expectedProblems) == 0 {
+				assert.Nil(err)
+				return
+			}
+
+			if !assert.IsType(&SetPayloadError{}, err) {
+				return
+			}
+
+			// problems may end with an encoding/json error whose text depends on the Go version,
+			// so only their beginning is asserted
+			problems := err.(*SetPayloadError).Problems
+			if assert.Len(problems, len(test.expectedProblems)) {
+				for i, expected := range test.expectedProblems {
+					assert.Equal(expected.Element, problems[i].Element)
+					assert.True(strings.HasPrefix(problems[i].Problem, expected.Problem), "problem '%s' should start with '%s'", problems[i].Problem, expected.Problem)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeSetPayloadRequest(t *testing.T) {
+	t.Run("Invalid", func(t *testing.T) {
+		assert := assert.New(t)
+
+		decoder := decodeSetPayloadRequest(false, func(context.Context, *http.Request) (interface{}, error) {
+			assert.Fail("decoder should not be called for invalid bodies")
+			return nil, nil
+		})
+
+		r := httptest.NewRequest(http.MethodPatch, "http://localhost:8090/api", strings.NewReader(`{"parameters": [{"dataType": 0}]}`))
+		_, err := decoder(context.Background(), r)
+		if assert.NotNil(err) {
+			assert.Equal(http.StatusBadRequest, err.(common.CodedError).StatusCode())
+			assert.Equal(common.ErrorCodeInvalidSetPayload, err.(common.ErrorCoder).ErrorCode())
+		}
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		assert := assert.New(t)
+		body := `{"parameters": [{"name": "p1", "dataType": 0, "value": "v1"}]}`
+
+		decoder := decodeSetPayloadRequest(true, func(_ context.Context, r *http.Request) (interface{}, error) {
+			payload, err := requestPayload(r)
+			assert.Nil(err)
+			return payload, nil
+		})
+
+		r := httptest.NewRequest(http.MethodPatch, "http://localhost:8090/api", strings.NewReader(body))
+		payload, err := decoder(context.Background(), r)
+		assert.Nil(err)
+		assert.Contains(string(payload.([]byte)), `"name":"p1"`)
+	})
+}
+
+func TestEncodeSetPayloadError(t *testing.T) {
+	assert := assert.New(t)
+
+	w := httptest.NewRecorder()
+	encodeError(ctxTID, &SetPayloadError{Problems: []SetPayloadProblem{{"parameters[0].name", "is required"}}}, w)
+
+	var body map[string]interface{}
+	assert.Nil(json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(http.StatusBadRequest, w.Code)
+	assert.Equal(common.ErrorCodeInvalidSetPayload, body["code"])
+	assert.Equal([]interface{}{map[string]interface{}{"element": "parameters[0].name", "problem": "is required"}}, body["problems"])
+}
@@ -0,0 +1,241 @@
+package translation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/wrp-go/wrp"
+)
+
+const (
+	defaultWRPSinkQueueSize = 1000
+	wrpSinkPostTimeout      = 10 * time.Second
+	wrpSinkDrainTimeout     = 5 * time.Second
+	redactedCredentials     = "[REDACTED]"
+)
+
+var errNoWRPSinkTarget = errors.New("a target is required when the WRP sink is enabled")
+
+// WRPSinkConfig configures the debug sink outbound WRP messages are mirrored to
+type WRPSinkConfig struct {
+	// Enabled mirrors outbound WRP messages to Target. It's meant for troubleshooting and should be off in production
+	Enabled bool
+
+	// Target is either the path of the file records are appended to or the http(s) URL they are posted to
+	Target string
+
+	// QueueSize is the number of records which may wait to be written. Records are dropped once it's full
+	// (Optional) defaults to 1000
+	QueueSize int
+}
+
+// wrpSinkRecord is what the sink gets for each outbound WRP message, as a JSON line
+type wrpSinkRecord struct {
+	Time            time.Time `json:"time"`
+	TransactionUUID string    `json:"transactionUUID"`
+	Destination     string    `json:"destination"`
+	Authorization   string    `json:"authorization,omitempty"`
+
+	// WRP is the msgpack encoded message exactly as sent to the XMiDT API, before any compression
+	WRP []byte `json:"wrp"`
+}
+
+// WRPSink asynchronously mirrors outbound WRP messages to a debug target. Mirroring never blocks nor fails
+// requests: records are dropped when the sink falls behind or is stopped and write failures are only logged.
+type WRPSink struct {
+	records      chan wrpSinkRecord
+	write        func(context.Context, []byte) error
+	close        func() error
+	logger       kitlog.Logger
+	drainTimeout time.Duration
+	done         sync.WaitGroup
+
+	// ctx is canceled once the drain timeout elapses so that the remaining records are dropped
+	ctx    context.Context
+	cancel func()
+
+	lock    sync.RWMutex
+	stopped bool
+}
+
+// NewWRPSink builds and starts the WRP sink. It returns nil when the sink is disabled
+func NewWRPSink(c WRPSinkConfig, logger kitlog.Logger) (*WRPSink, error) {
+	if !c.Enabled {
+		return nil, nil
+	}
+
+	if c.Target == "" {
+		return nil, errNoWRPSinkTarget
+	}
+
+	if c.QueueSize <= 0 {
+		c.QueueSize = defaultWRPSinkQueueSize
+	}
+
+	if logger == nil {
+		logger = logging.DefaultLogger()
+	}
+
+	s := &WRPSink{
+		records:      make(chan wrpSinkRecord, c.QueueSize),
+		logger:       logger,
+		drainTimeout: wrpSinkDrainTimeout,
+	}
+
+	if strings.HasPrefix(c.Target, "http://") || strings.HasPrefix(c.Target, "https://") {
+		client := &http.Client{Timeout: wrpSinkPostTimeout}
+		s.write = func(ctx context.Context, record []byte) error {
+			r, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Target, bytes.NewReader(record))
+			if err != nil {
+				return err
+			}
+			r.Header.Set("Content-Type", "application/json")
+
+			resp, err := client.Do(r)
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+			if resp.StatusCode >= http.StatusBadRequest {
+				return fmt.Errorf("WRP sink responded with %d", resp.StatusCode)
+			}
+			return nil
+		}
+		s.close = func() error { return nil }
+	} else {
+		f, err := os.OpenFile(c.Target, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open WRP sink file: %v", err)
+		}
+		s.write = writeTo(f)
+		s.close = f.Close
+	}
+
+	s.start()
+	return s, nil
+}
+
+func writeTo(w io.Writer) func(context.Context, []byte) error {
+	return func(_ context.Context, record []byte) error {
+		_, err := w.Write(record)
+		return err
+	}
+}
+
+func (s *WRPSink) start() {
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.done.Add(1)
+	go s.run()
+}
+
+func (s *WRPSink) run() {
+	defer s.done.Done()
+
+	for record := range s.records {
+		if s.ctx.Err() != nil {
+			// the drain timed out
+			continue
+		}
+
+		data, err := json.Marshal(record)
+		if err == nil {
+			err = s.write(s.ctx, append(data, '\n'))
+		}
+
+		if err != nil {
+			logging.Error(s.logger).Log(logging.MessageKey(), "Failed to write to the WRP sink", "tid", record.TransactionUUID,
+				logging.ErrorKey(), err)
+		}
+	}
+}
+
+// Mirror queues a copy of the given outbound WRP message and its msgpack encoding for the sink. The credentials
+// of authHeaderValue are redacted. It's a no-op for nil sinks.
+func (s *WRPSink) Mirror(wrpMsg *wrp.Message, payload []byte, authHeaderValue string) {
+	if s == nil {
+		return
+	}
+
+	record := wrpSinkRecord{
+		Time:            time.Now(),
+		TransactionUUID: wrpMsg.TransactionUUID,
+		Destination:     wrpMsg.Destination,
+		Authorization:   redactAuthorization(authHeaderValue),
+		WRP:             append([]byte(nil), payload...),
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.stopped {
+		logging.Debug(s.logger).Log(logging.MessageKey(), "WRP sink is stopped, dropping record", "tid", record.TransactionUUID)
+		return
+	}
+
+	select {
+	case s.records <- record:
+	default:
+		logging.Debug(s.logger).Log(logging.MessageKey(), "WRP sink is full, dropping record", "tid", record.TransactionUUID)
+	}
+}
+
+// Stop writes the queued records, for up to the drain timeout, and releases the sink target. Records which
+// are still queued, or mirrored afterwards, are dropped. It's a no-op for nil sinks.
+func (s *WRPSink) Stop() error {
+	if s == nil {
+		return nil
+	}
+
+	s.lock.Lock()
+	if s.stopped {
+		s.lock.Unlock()
+		return nil
+	}
+	s.stopped = true
+	close(s.records)
+	s.lock.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		s.done.Wait()
+		close(drained)
+	}()
+
+	timer := time.NewTimer(s.drainTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-drained:
+	case <-timer.C:
+		logging.Error(s.logger).Log(logging.MessageKey(), "WRP sink drain timed out, dropping the queued records", "queued", len(s.records))
+		s.cancel()
+		<-drained
+	}
+
+	s.cancel()
+	return s.close()
+}
+
+// redactAuthorization keeps the scheme of an Authorization header value only (i.e. "Bearer [REDACTED]")
+func redactAuthorization(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	if i := strings.IndexByte(value, ' '); i > 0 {
+		return value[:i] + " " + redactedCredentials
+	}
+
+	return redactedCredentials
+}
@@ -0,0 +1,175 @@
+package translation
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/wrp-go/wrp"
+)
+
+func TestNewWRPSink(t *testing.T) {
+	assert := assert.New(t)
+
+	s, err := NewWRPSink(WRPSinkConfig{Target: "/tmp/wrp.jsonl"}, nil)
+	assert.Nil(s)
+	assert.Nil(err)
+
+	s, err = NewWRPSink(WRPSinkConfig{Enabled: true}, nil)
+	assert.Nil(s)
+	assert.Equal(errNoWRPSinkTarget, err)
+
+	_, err = NewWRPSink(WRPSinkConfig{Enabled: true, Target: filepath.Join(os.TempDir(), "missing", "dir", "wrp.jsonl")}, nil)
+	assert.NotNil(err)
+}
+
+func TestWRPSinkFile(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "wrpsink")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	target := filepath.Join(dir, "wrp.jsonl")
+	s, err := NewWRPSink(WRPSinkConfig{Enabled: true, Target: target}, logging.DefaultLogger())
+	require.Nil(err)
+
+	msg := &wrp.Message{Type: wrp.SimpleRequestResponseMessageType, TransactionUUID: "tid", Destination: "mac:112233445566/config"}
+	payload := wrp.MustEncode(msg, wrp.Msgpack)
+	s.Mirror(msg, payload, "Bearer secret.jwt.value")
+	s.Mirror(msg, payload, "")
+	assert.Nil(s.Stop())
+
+	f, err := os.Open(target)
+	require.Nil(err)
+	defer f.Close()
+
+	var records []wrpSinkRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record wrpSinkRecord
+		require.Nil(json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+
+	require.Len(records, 2)
+	assert.Equal("tid", records[0].TransactionUUID)
+	assert.Equal("mac:112233445566/config", records[0].Destination)
+	assert.Equal("Bearer [REDACTED]", records[0].Authorization)
+	assert.Equal(payload, records[0].WRP)
+	assert.Empty(records[1].Authorization)
+}
+
+func TestWRPSinkHTTP(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	received := make(chan wrpSinkRecord, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var record wrpSinkRecord
+		assert.Nil(json.NewDecoder(r.Body).Decode(&record))
+		received <- record
+	}))
+	defer server.Close()
+
+	s, err := NewWRPSink(WRPSinkConfig{Enabled: true, Target: server.URL}, logging.DefaultLogger())
+	require.Nil(err)
+
+	s.Mirror(&wrp.Message{TransactionUUID: "tid"}, []byte("wrp"), "Basic dXNlcjpwYXNz")
+	assert.Nil(s.Stop())
+
+	record := <-received
+	assert.Equal("tid", record.TransactionUUID)
+	assert.Equal("Basic [REDACTED]", record.Authorization)
+	assert.Equal([]byte("wrp"), record.WRP)
+}
+
+func TestWRPSinkFull(t *testing.T) {
+	assert := assert.New(t)
+
+	blocked := make(chan struct{})
+	s := &WRPSink{
+		records: make(chan wrpSinkRecord, 1),
+		write:   func(context.Context, []byte) error { <-blocked; return nil },
+		close:   func() error { return nil },
+		logger:  logging.DefaultLogger(),
+	}
+
+	// nothing drains the queue so the second record must be dropped rather than block
+	s.Mirror(&wrp.Message{TransactionUUID: "first"}, nil, "")
+	s.Mirror(&wrp.Message{TransactionUUID: "second"}, nil, "")
+	assert.Len(s.records, 1)
+	close(blocked)
+}
+
+func TestWRPSinkStop(t *testing.T) {
+	t.Run("MirrorAfterStop", func(t *testing.T) {
+		assert := assert.New(t)
+
+		var written int
+		s := &WRPSink{
+			records:      make(chan wrpSinkRecord, 1),
+			write:        func(context.Context, []byte) error { written++; return nil },
+			close:        func() error { return nil },
+			logger:       logging.DefaultLogger(),
+			drainTimeout: time.Second,
+		}
+		s.start()
+
+		assert.Nil(s.Stop())
+		assert.NotPanics(func() { s.Mirror(&wrp.Message{TransactionUUID: "late"}, nil, "") })
+		assert.Nil(s.Stop())
+		assert.Zero(written)
+	})
+
+	t.Run("DrainTimeout", func(t *testing.T) {
+		assert := assert.New(t)
+
+		var written int
+		s := &WRPSink{
+			records: make(chan wrpSinkRecord, 10),
+			write: func(ctx context.Context, _ []byte) error {
+				// a target which never answers
+				<-ctx.Done()
+				written++
+				return ctx.Err()
+			},
+			close:        func() error { return nil },
+			logger:       logging.DefaultLogger(),
+			drainTimeout: 50 * time.Millisecond,
+		}
+		s.start()
+
+		for i := 0; i < 5; i++ {
+			s.Mirror(&wrp.Message{TransactionUUID: "tid"}, nil, "")
+		}
+
+		start := time.Now()
+		assert.Nil(s.Stop())
+		assert.True(time.Since(start) < time.Second)
+		assert.Equal(1, written)
+	})
+}
+
+func TestRedactAuthorization(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("", redactAuthorization(""))
+	assert.Equal("Bearer [REDACTED]", redactAuthorization("Bearer abc.def.ghi"))
+	assert.Equal("[REDACTED]", redactAuthorization("token"))
+
+	var s *WRPSink
+	s.Mirror(&wrp.Message{}, nil, "")
+	assert.Nil(s.Stop())
+}
@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/xmidt-org/tr1d1um/common"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/xmidt-org/wrp-go/wrp"
 
 	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
 	kithttp "github.com/go-kit/kit/transport/http"
 
 	"github.com/gorilla/mux"
@@ -42,40 +44,169 @@ type Options struct {
 	//APIRouter is assumed to be a subrouter with the API prefix path (i.e. 'api/v2')
 	APIRouter *mux.Router
 
-	Authenticate                *alice.Chain
-	Log                         kitlog.Logger
-	ValidServices               []string
-	ReducedLoggingResponseCodes []int
+	Authenticate *alice.Chain
+	Log          kitlog.Logger
+
+	//Settings provides the valid services and reduced logging response codes, which can be reloaded at runtime
+	Settings *common.Settings
+
+	//Localization translates known device messages into client-facing text
+	//(Optional)
+	Localization *LocalizationConfig
+
+	//TokenMaxAge is the max age of the token allowed per operation (WDMP command) i.e. SET
+	//(Optional)
+	TokenMaxAge map[string]time.Duration
+
+	//AnalyticsLogger receives a record per completed transaction
+	//(Optional)
+	AnalyticsLogger kitlog.Logger
+
+	//LatencyHistogram observes the end-to-end latency of requests
+	//(Optional)
+	LatencyHistogram metrics.Histogram
+
+	//PartnerRequests counts requests per partner
+	//(Optional)
+	PartnerRequests metrics.Counter
+
+	//KnownPartners are the partners PartnerRequests reports individually. Others are grouped together
+	KnownPartners []string
+
+	//Compression enables gzip encoding negotiation through the Content-Encoding header
+	Compression bool
+
+	//ChecksumAlgorithms are the algorithms request body checksums are verified with
+	//(Optional) checksums are ignored when empty
+	ChecksumAlgorithms []ChecksumAlgorithm
+
+	//StrictQueryParams makes requests with unsupported or malformed query parameters fail with 400
+	//rather than having such parameters ignored
+	StrictQueryParams bool
+
+	//RequireContentLength makes write requests without a Content-Length header (i.e. chunked) fail with 411
+	RequireContentLength bool
+
+	//AllowWildcardGet allows GET requests for wildcard parameter names which address a whole subtree (i.e. "Device.WiFi.")
+	AllowWildcardGet bool
+
+	//StrictPayloadValidation makes SET requests whose bodies have unknown fields fail with 400
+	StrictPayloadValidation bool
+
+	//MaxRequestBodyBytes is the size limit of request bodies. Larger requests fail with 413
+	//(Optional) no limit is enforced when it's not positive
+	MaxRequestBodyBytes int64
+
+	//PartnerIDs derives the partner ids of WRP messages from a token claim
+	//(Optional)
+	PartnerIDs *PartnerIDsConfig
+
+	//RequirePartnerID makes requests which don't identify a partner, through either their token or headers,
+	//fail with a 403
+	RequirePartnerID bool
+
+	//DeviceIDSchemes restricts the device id schemes partners may use
+	//(Optional)
+	DeviceIDSchemes *common.DeviceIDSchemes
+
+	//NegotiateEncoding makes clients which accept application/msgpack or application/json get the whole WRP
+	//message of device responses in that encoding rather than its payload only
+	NegotiateEncoding bool
+
+	//DefaultContentType is the WRP payload content type of requests without a Content-Type header
+	//(Optional)
+	DefaultContentType string
+
+	//AllowedContentTypes are the request content types WRP payloads may have. Others are rejected with a 415
+	//(Optional) all content types are allowed when empty
+	AllowedContentTypes []string
+
+	//DefaultQOS is the WRP quality of service (0-99) of requests without the X-Tr1d1um-Qos header
+	//(Optional) negative values set none
+	DefaultQOS int
+
+	//DeviceIDFormats are the device id schemes requests may address devices through. Malformed ids are rejected
+	//(Optional) all supported schemes are accepted when empty
+	DeviceIDFormats []DeviceIDFormat
+
+	//DeviceRateLimiter throttles requests per device
+	//(Optional)
+	DeviceRateLimiter *common.RateLimiter
+
+	//ReadDuringWrite handles reads issued while a write to the same device is in flight
+	//(Optional)
+	ReadDuringWrite *common.ReadDuringWrite
+
+	//OperationLevels overrides the log level of requests per WDMP command
+	//(Optional)
+	OperationLevels *common.OperationLevels
+
+	//AllowedMethods restricts the HTTP methods of the device endpoints. Others are rejected with a 405
+	//whether or not capabilities are checked
+	//(Optional) all supported methods are allowed when empty
+	AllowedMethods []string
+}
+
+// supportedQueryParams are the query parameters each method of the device endpoints understands
+var supportedQueryParams = common.QueryParams{
+	http.MethodGet:    {"names", "attributes", dryRunQueryParam},
+	http.MethodPatch:  {dryRunQueryParam},
+	http.MethodPost:   {dryRunQueryParam},
+	http.MethodPut:    {dryRunQueryParam},
+	http.MethodDelete: {dryRunQueryParam},
 }
 
 // ConfigHandler sets up the server that powers the translation service
 func ConfigHandler(c *Options) {
+	finalizers := []kithttp.ServerFinalizerFunc{common.TransactionLogging(c.Settings, c.Log)}
+	if c.AnalyticsLogger != nil {
+		finalizers = append(finalizers, analyticsLogging(c.AnalyticsLogger))
+	}
+
 	opts := []kithttp.ServerOption{
-		kithttp.ServerBefore(common.Capture(c.Log), captureWDMPParameters),
+		kithttp.ServerBefore(common.CaptureOperationLogger(c.OperationLevels, wdmpCommand), common.Capture(c.Log), captureChecksum(c.ChecksumAlgorithms), captureCompression(c.Compression), captureBatchGet, captureWildcardGet(c.AllowWildcardGet), captureWDMPParameters, captureLocalization(c.Localization), captureWRPEncoding(c.NegotiateEncoding),
+			captureAnalytics(c.AnalyticsLogger), captureIdempotency),
 		kithttp.ServerErrorEncoder(common.ErrorLogEncoder(c.Log, encodeError)),
-		kithttp.ServerFinalizer(common.TransactionLogging(c.ReducedLoggingResponseCodes, c.Log)),
+		kithttp.ServerFinalizer(finalizers...),
 	}
 
+	decoder := decodeAllowedParametersRequest(c.Settings, decodePayloadContentTypeRequest(c.DefaultContentType, c.AllowedContentTypes, decodeQOSRequest(c.DefaultQOS, decodeRequirePartnerIDRequest(c.RequirePartnerID, decodePartnerIDsRequest(c.PartnerIDs, decodeSetPayloadRequest(c.StrictPayloadValidation, decodeRequest))))))
+	decoder = decodeChecksumRequest(decodeCompressedRequest(decodeBatchGetRequest(decodeWildcardGetRequest(decodeFreshTokenRequest(c.TokenMaxAge, time.Now, decoder)))))
+	decoder = common.StrictQueryParams(c.StrictQueryParams, supportedQueryParams, decoder)
+	decoder = common.RequireContentLength(c.RequireContentLength, decoder)
+	decoder = common.RestrictDeviceIDSchemes(c.DeviceIDSchemes, decoder)
+	decoder = decodeDeviceIDRequest(c.DeviceIDFormats, decoder)
+
 	WRPHandler := kithttp.NewServer(
 		makeTranslationEndpoint(c.S),
-		decodeValidServiceRequest(c.ValidServices, decodeRequest),
+		decodeValidServiceRequest(c.Settings, decoder),
 		encodeResponse,
 		opts...,
 	)
 
-	c.APIRouter.Handle("/device/{deviceid}/{service}", c.Authenticate.Then(common.Welcome(WRPHandler))).
-		Methods(http.MethodGet, http.MethodPatch)
+	instrument := common.InstrumentLatency(c.LatencyHistogram, "translation", wrp.SimpleRequestResponseMessageType.String())
+	countPartner := common.InstrumentPartnerRequests(c.PartnerRequests, c.KnownPartners, "translation")
+	handler := countPartner(common.Welcome(c.DeviceRateLimiter.Then(c.ReadDuringWrite.Then(common.LimitRequestBody(c.MaxRequestBodyBytes)(WRPHandler)))))
+
+	common.HandleMethods(c.APIRouter, "/device/{deviceid}/{service}", instrument(c.Authenticate.Then(handler)),
+		[]string{http.MethodGet, http.MethodPatch, http.MethodPost}, c.AllowedMethods)
+
+	common.HandleMethods(c.APIRouter, "/device/{deviceid}/{service}/{parameter}", instrument(c.Authenticate.Then(handler)),
+		[]string{http.MethodDelete, http.MethodPut, http.MethodPost}, c.AllowedMethods)
 
-	c.APIRouter.Handle("/device/{deviceid}/{service}/{parameter}", c.Authenticate.Then(common.Welcome(WRPHandler))).
-		Methods(http.MethodDelete, http.MethodPut, http.MethodPost)
+	c.APIRouter.Handle(ServicesPath, c.Authenticate.Then(servicesHandler(c.Settings))).
+		Methods(http.MethodGet)
 }
 
 // getPartnerIDs returns the array that represents the partner-ids that were
 // passed in as headers.  This function handles multiple duplicate headers.
+// The X-Webpa-Partner-Id header is only used in the absence of the X-Xmidt-Partner-Id one.
 func getPartnerIDs(h http.Header) []string {
 	headers, ok := h[wrphttp.PartnerIdHeader]
 	if !ok {
-		return nil
+		if headers, ok = h[common.HeaderWebpaPartnerID]; !ok {
+			return nil
+		}
 	}
 
 	var partners []string
@@ -115,20 +246,72 @@ func decodeRequest(ctx context.Context, r *http.Request) (decodedRequest interfa
 	var (
 		payload []byte
 		wrpMsg  *wrp.Message
+		dryRun  bool
 	)
-	if payload, err = requestPayload(r); err == nil {
+
+	if dryRun, err = isDryRun(r); err != nil {
+		return
+	}
+
+	if names, ok := batchGetFromContext(ctx); ok {
+		payload, err = requestBatchGetPayload(names)
+	} else {
+		payload, err = requestPayload(r)
+	}
+
+	if err == nil {
 		var tid = ctx.Value(common.ContextKeyRequestTID).(string)
 		partnerIDs := getPartnerIDsDecodeRequest(ctx, r)
 		if wrpMsg, err = wrap(payload, tid, mux.Vars(r), partnerIDs); err == nil {
+			recordWRPAnalytics(ctx, wrpMsg)
 			decodedRequest = &wrpRequest{
 				WRPMessage:      wrpMsg,
 				AuthHeaderValue: r.Header.Get(authHeaderKey),
+				DryRun:          dryRun,
 			}
 		}
 	}
 	return
 }
 
+// wdmpCommand returns the WDMP command a request translates into without reading its body.
+// SET_ATTRIBUTES requests can't be told apart from SET ones this way and are reported as SET.
+func wdmpCommand(r *http.Request) string {
+	switch r.Method {
+	case http.MethodGet:
+		if r.URL.Query().Get("attributes") != "" {
+			return CommandGetAttrs
+		}
+		return CommandGet
+	case http.MethodPatch:
+		if r.Header.Get(HeaderWPASyncNewCID) != "" {
+			return CommandTestSet
+		}
+		return CommandSet
+	case http.MethodDelete:
+		return CommandDeleteRow
+	case http.MethodPut:
+		return CommandReplaceRows
+	case http.MethodPost:
+		if isBatchGetRequest(r) {
+			return CommandGet
+		}
+		return CommandAddRow
+	}
+	return ""
+}
+
+// captureIdempotency marks the XMiDT requests of GET and GET_ATTRIBUTES commands as idempotent so that they may
+// be retried. The other commands change the device state, which failed attempts may have partially done.
+func captureIdempotency(ctx context.Context, r *http.Request) context.Context {
+	switch wdmpCommand(r) {
+	case CommandGet, CommandGetAttrs:
+		return context.WithValue(ctx, common.ContextKeyIdempotent, true)
+	default:
+		return context.WithValue(ctx, common.ContextKeyIdempotent, false)
+	}
+}
+
 func requestPayload(r *http.Request) (payload []byte, err error) {
 
 	switch r.Method {
@@ -153,6 +336,10 @@ func requestPayload(r *http.Request) (payload []byte, err error) {
 /* Response Encoding */
 
 func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	if dryRun, ok := response.(*dryRunResponse); ok {
+		return encodeDryRunResponse(ctx, w, dryRun)
+	}
+
 	var resp = response.(*common.XmidtResponse)
 
 	//equivalent to forwarding all headers
@@ -169,22 +356,40 @@ func encodeResponse(ctx context.Context, w http.ResponseWriter, response interfa
 
 	wrpModel := new(wrp.Message)
 
-	if err = wrp.NewDecoderBytes(resp.Body, wrp.Msgpack).Decode(wrpModel); err == nil {
+	received := xmidtFormat(resp.ContentType)
+
+	if err = wrp.NewDecoderBytes(resp.Body, received).Decode(wrpModel); err == nil {
+		if format, ok := wrpEncodingFromContext(ctx); ok {
+			return encodeWRPResponse(w, format, received, resp.Body, wrpModel)
+		}
 
 		var deviceResponseModel struct {
-			StatusCode int `json:"statusCode"`
+			StatusCode int               `json:"statusCode"`
+			Parameters []json.RawMessage `json:"parameters"`
 		}
 
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 
+		if names, ok := batchGetFromContext(ctx); ok {
+			return encodeBatchGetResponse(w, names, localizeFromContext(ctx, wrpModel.Payload))
+		}
+
 		// if possible, use the device response status code
 		if errUnmarshall := json.Unmarshal(wrpModel.Payload, &deviceResponseModel); errUnmarshall == nil {
 			if deviceResponseModel.StatusCode != 0 && deviceResponseModel.StatusCode != http.StatusInternalServerError {
-				w.WriteHeader(deviceResponseModel.StatusCode)
+				statusCode := deviceResponseModel.StatusCode
+
+				// wildcard GETs the device could only partially fulfill still carry results for some parameters.
+				// Each parameter reports its own outcome so the request as a whole isn't failed
+				if statusCode != http.StatusOK && len(deviceResponseModel.Parameters) > 0 && wildcardGetFromContext(ctx) {
+					statusCode = http.StatusMultiStatus
+				}
+
+				w.WriteHeader(statusCode)
 			}
 		}
 
-		_, err = w.Write(wrpModel.Payload)
+		_, err = w.Write(localizeFromContext(ctx, wrpModel.Payload))
 	}
 
 	return
@@ -196,6 +401,14 @@ func encodeError(ctx context.Context, err error, w http.ResponseWriter) {
 	w.Header().Set(contentTypeHeaderKey, "application/json; charset=utf-8")
 	w.Header().Set(common.HeaderWPATID, ctx.Value(common.ContextKeyRequestTID).(string))
 
+	if h, ok := err.(kithttp.Headerer); ok {
+		for k, values := range h.Headers() {
+			for _, v := range values {
+				w.Header().Add(k, v)
+			}
+		}
+	}
+
 	if ce, ok := err.(common.CodedError); ok {
 		w.WriteHeader(ce.StatusCode())
 	} else {
@@ -206,10 +419,19 @@ func encodeError(ctx context.Context, err error, w http.ResponseWriter) {
 		err = common.ErrTr1d1umInternal
 	}
 
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	body := map[string]interface{}{
 		"message": err.Error(),
-	})
+	}
+
+	if ec, ok := err.(common.ErrorCoder); ok && ec.ErrorCode() != "" {
+		body["code"] = ec.ErrorCode()
+	}
+
+	if pe, ok := err.(*SetPayloadError); ok {
+		body["problems"] = pe.Problems
+	}
 
+	json.NewEncoder(w).Encode(body)
 }
 
 /* Request-type specific decoding functions */
@@ -259,6 +481,10 @@ func requestAddPayload(m map[string]string, input io.Reader) (p []byte, err erro
 
 	payload, err := ioutil.ReadAll(input)
 
+	if err != nil {
+		return nil, err
+	}
+
 	if len(payload) < 1 {
 		return nil, ErrMissingRow
 	}
@@ -529,3 +529,43 @@ func TestEncodeError(t *testing.T) {
 		assert.EqualValues(expected.String(), w.Body.String())
 	})
 }
+
+func TestWDMPCommand(t *testing.T) {
+	tests := []struct {
+		method   string
+		url      string
+		headers  map[string]string
+		vars     map[string]string
+		expected string
+	}{
+		{method: http.MethodGet, url: "/device/mac:112233445566/config?names=a", expected: CommandGet},
+		{method: http.MethodGet, url: "/device/mac:112233445566/config?names=a&attributes=notify", expected: CommandGetAttrs},
+		{method: http.MethodPatch, url: "/device/mac:112233445566/config", expected: CommandSet},
+		{method: http.MethodPatch, url: "/device/mac:112233445566/config", headers: map[string]string{HeaderWPASyncNewCID: "1"}, expected: CommandTestSet},
+		{method: http.MethodDelete, url: "/device/mac:112233445566/config/table.1.", expected: CommandDeleteRow},
+		{method: http.MethodPut, url: "/device/mac:112233445566/config/table.", expected: CommandReplaceRows},
+		{method: http.MethodPost, url: "/device/mac:112233445566/config/table.", vars: map[string]string{"parameter": "table."}, expected: CommandAddRow},
+		{method: http.MethodPost, url: "/device/mac:112233445566/config", expected: CommandGet},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expected, func(t *testing.T) {
+			r := httptest.NewRequest(test.method, "http://localhost:8080/api/v2"+test.url, nil)
+			for k, v := range test.headers {
+				r.Header.Set(k, v)
+			}
+			r = mux.SetURLVars(r, test.vars)
+			assert.Equal(t, test.expected, wdmpCommand(r))
+		})
+	}
+}
+
+func TestCaptureIdempotency(t *testing.T) {
+	assert := assert.New(t)
+
+	r := httptest.NewRequest(http.MethodGet, "http://localhost:8080/api/v2/device/mac:112233445566/config?names=a", nil)
+	assert.Equal(true, captureIdempotency(context.Background(), r).Value(common.ContextKeyIdempotent))
+
+	r = httptest.NewRequest(http.MethodPatch, "http://localhost:8080/api/v2/device/mac:112233445566/config", nil)
+	assert.Equal(false, captureIdempotency(context.Background(), r).Value(common.ContextKeyIdempotent))
+}
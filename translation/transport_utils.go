@@ -105,10 +105,10 @@ func wrap(WDMP []byte, tid string, pathVars map[string]string, partnerIDs []stri
 	}, nil
 }
 
-func decodeValidServiceRequest(services []string, decoder kithttp.DecodeRequestFunc) kithttp.DecodeRequestFunc {
+func decodeValidServiceRequest(settings *common.Settings, decoder kithttp.DecodeRequestFunc) kithttp.DecodeRequestFunc {
 	return func(c context.Context, r *http.Request) (interface{}, error) {
 
-		if !contains(mux.Vars(r)["service"], services) {
+		if !contains(mux.Vars(r)["service"], settings.Load().ValidServices) {
 			return nil, ErrInvalidService
 		}
 
@@ -141,9 +141,15 @@ func captureWDMPParameters(ctx context.Context, r *http.Request) (nctx context.C
 	nctx = ctx
 
 	if r.Method == http.MethodPatch {
-		bodyBytes, _ := ioutil.ReadAll(r.Body)
+		bodyBytes, err := ioutil.ReadAll(r.Body)
 		r.Body.Close()
 
+		if err != nil {
+			// let the decoder report why the body could not be read (i.e. it's too large)
+			r.Body = ioutil.NopCloser(errReader{err})
+			return
+		}
+
 		r.Body = ioutil.NopCloser(bytes.NewBuffer(bodyBytes))
 
 		if wdmp, e := loadWDMP(bodyBytes, r.Header.Get(HeaderWPASyncNewCID), r.Header.Get(HeaderWPASyncOldCID), r.Header.Get(HeaderWPASyncCMC)); e == nil {
@@ -161,6 +167,15 @@ func captureWDMPParameters(ctx context.Context, r *http.Request) (nctx context.C
 	return
 }
 
+// errReader fails all reads with err
+type errReader struct {
+	err error
+}
+
+func (e errReader) Read([]byte) (int, error) {
+	return 0, e.err
+}
+
 func getParamNames(params []setParam) (paramNames []string) {
 	paramNames = make([]string, len(params))
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gorilla/mux"
@@ -177,7 +178,7 @@ func TestWrapInWRP(t *testing.T) {
 }
 
 func TestDecodeValidServiceRequest(t *testing.T) {
-	f := decodeValidServiceRequest([]string{"s0"}, func(_ context.Context, _ *http.Request) (interface{}, error) {
+	f := decodeValidServiceRequest(common.NewSettings(common.Snapshot{ValidServices: []string{"s0"}}), func(_ context.Context, _ *http.Request) (interface{}, error) {
 		return nil, nil
 	})
 
@@ -200,6 +201,21 @@ func TestDecodeValidServiceRequest(t *testing.T) {
 	})
 }
 
+func TestCaptureWDMPParametersBodyTooLarge(t *testing.T) {
+	assert := assert.New(t)
+
+	var err error
+	handler := common.LimitRequestBody(10)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captureWDMPParameters(r.Context(), r)
+		_, err = requestSetPayload(r.Body, "", "", "")
+	}))
+
+	r := httptest.NewRequest(http.MethodPatch, "http://localhost:8090/api", strings.NewReader(`{"parameters":[{"name":"deviceName","value":"newName","dataType":0}]}`))
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Equal(common.ErrRequestBodyTooLarge, err)
+}
+
 func TestContains(t *testing.T) {
 	assert := assert.New(t)
 	assert.False(contains("a", nil))
@@ -0,0 +1,56 @@
+package translation
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+)
+
+type wildcardGetContextKey struct{}
+
+// isWildcardName reports whether the given TR-181 parameter name addresses a whole subtree (i.e. "Device.WiFi.")
+func isWildcardName(name string) bool {
+	return strings.HasSuffix(name, ".")
+}
+
+// captureWildcardGet flags GET requests, batch ones included, for at least one wildcard parameter name. Devices expand
+// such names into all the parameters under the subtree themselves.
+func captureWildcardGet(allowed bool) kithttp.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		names, batch := batchGetFromContext(ctx)
+		if !batch {
+			if r.Method != http.MethodGet {
+				return ctx
+			}
+
+			names = strings.Split(r.FormValue("names"), ",")
+		}
+
+		for _, name := range names {
+			if isWildcardName(name) {
+				return context.WithValue(ctx, wildcardGetContextKey{}, allowed)
+			}
+		}
+
+		return ctx
+	}
+}
+
+// decodeWildcardGetRequest decorates decoder such that wildcard GET requests are rejected when they're not allowed
+func decodeWildcardGetRequest(decoder kithttp.DecodeRequestFunc) kithttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		if allowed, ok := ctx.Value(wildcardGetContextKey{}).(bool); ok && !allowed {
+			return nil, ErrWildcardGetDisabled
+		}
+
+		return decoder(ctx, r)
+	}
+}
+
+// wildcardGetFromContext reports whether the request is a wildcard GET
+func wildcardGetFromContext(ctx context.Context) bool {
+	allowed, _ := ctx.Value(wildcardGetContextKey{}).(bool)
+	return allowed
+}
@@ -0,0 +1,81 @@
+package translation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xmidt-org/tr1d1um/common"
+	"github.com/xmidt-org/wrp-go/wrp"
+)
+
+func TestWildcardGet(t *testing.T) {
+	tests := []struct {
+		name             string
+		allowed          bool
+		method           string
+		names            string
+		expectedErr      error
+		expectedWildcard bool
+	}{
+		{name: "NoWildcard", method: http.MethodGet, names: "Device.WiFi.SSID.1.Enable"},
+		{name: "NotGet", method: http.MethodPatch, names: "Device.WiFi."},
+		{name: "Allowed", allowed: true, method: http.MethodGet, names: "Device.DeviceInfo.Manufacturer,Device.WiFi.", expectedWildcard: true},
+		{name: "Disabled", method: http.MethodGet, names: "Device.WiFi.", expectedErr: ErrWildcardGetDisabled},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			r := httptest.NewRequest(test.method, "http://localhost:8090/api?names="+test.names, nil)
+			ctx := captureWildcardGet(test.allowed)(context.Background(), r)
+
+			_, err := decodeWildcardGetRequest(func(_ context.Context, _ *http.Request) (interface{}, error) {
+				return nil, nil
+			})(ctx, r)
+
+			assert.Equal(test.expectedErr, err)
+			assert.Equal(test.expectedWildcard, wildcardGetFromContext(ctx))
+		})
+	}
+}
+
+func TestEncodeResponseWildcardGetPartial(t *testing.T) {
+	payload := `{"statusCode":520,"parameters":[{"name":"Device.WiFi.","value":[],"message":"Success"},{"name":"Device.Unknown.","message":"Invalid parameter name"}]}`
+
+	response := &common.XmidtResponse{
+		Code: http.StatusOK,
+		Body: wrp.MustEncode(&wrp.Message{
+			Type:    wrp.SimpleRequestResponseMessageType,
+			Payload: []byte(payload),
+		}, wrp.Msgpack),
+	}
+
+	tests := []struct {
+		name         string
+		wildcard     bool
+		expectedCode int
+	}{
+		{name: "Wildcard", wildcard: true, expectedCode: http.StatusMultiStatus},
+		{name: "NoWildcard", expectedCode: 520},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			ctx := context.WithValue(context.Background(), common.ContextKeyRequestTID, "tid")
+			if test.wildcard {
+				ctx = context.WithValue(ctx, wildcardGetContextKey{}, true)
+			}
+
+			recorder := httptest.NewRecorder()
+			assert.Nil(encodeResponse(ctx, recorder, response))
+			assert.Equal(test.expectedCode, recorder.Code)
+			assert.Equal(payload, recorder.Body.String())
+		})
+	}
+}